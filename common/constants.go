@@ -125,6 +125,17 @@ var BatchUpdateInterval int
 
 var RelayTimeout int // unit is second
 
+// Prometheus /metrics 端点，MetricsToken 非空时需以 Bearer Token 访问
+var MetricsEnabled = false
+var MetricsToken string
+
+// OpenTelemetry 链路追踪，TracingEndpoint 为空时使用 OTEL_EXPORTER_OTLP_ENDPOINT 等标准环境变量
+var TracingEnabled = false
+var TracingExporter string
+var TracingEndpoint string
+var TracingServiceName string
+var TracingSampleRatio float64
+
 var RelayMaxIdleConns int
 var RelayMaxIdleConnsPerHost int
 
@@ -195,6 +206,13 @@ const (
 	TokenStatusExhausted = 4
 )
 
+// 令牌的模型降级策略，0 表示跟随系统设置
+const (
+	TokenModelFallbackDefault  = 0
+	TokenModelFallbackEnabled  = 1
+	TokenModelFallbackDisabled = 2
+)
+
 const (
 	RedemptionCodeStatusEnabled  = 1 // don't use 0, 0 is the default value!
 	RedemptionCodeStatusDisabled = 2 // also don't use 0
@@ -80,6 +80,13 @@ func InitEnv() {
 
 	// Initialize variables from constants.go that were using environment variables
 	DebugEnabled = os.Getenv("DEBUG") == "true"
+	LogFormat = strings.ToLower(GetEnvOrDefaultString("LOG_FORMAT", LogFormatText))
+	if LogFormat != LogFormatJSON && LogFormat != LogFormatLogfmt {
+		LogFormat = LogFormatText
+	}
+	if err := SetLogLevels(GetEnvOrDefaultString("LOG_LEVEL", "info"), nil); err != nil {
+		log.Println(err.Error())
+	}
 	MemoryCacheEnabled = os.Getenv("MEMORY_CACHE_ENABLED") == "true"
 	IsMasterNode = os.Getenv("NODE_TYPE") != "slave"
 	TLSInsecureSkipVerify = GetEnvOrDefaultBool("TLS_INSECURE_SKIP_VERIFY", false)
@@ -120,6 +127,15 @@ func InitEnv() {
 	CriticalRateLimitEnable = GetEnvOrDefaultBool("CRITICAL_RATE_LIMIT_ENABLE", true)
 	CriticalRateLimitNum = GetEnvOrDefault("CRITICAL_RATE_LIMIT", 20)
 	CriticalRateLimitDuration = int64(GetEnvOrDefault("CRITICAL_RATE_LIMIT_DURATION", 20*60))
+
+	MetricsEnabled = GetEnvOrDefaultBool("METRICS_ENABLED", false)
+	MetricsToken = GetEnvOrDefaultString("METRICS_TOKEN", "")
+
+	TracingEnabled = GetEnvOrDefaultBool("TRACING_ENABLED", false)
+	TracingExporter = GetEnvOrDefaultString("TRACING_EXPORTER", "otlp")
+	TracingEndpoint = GetEnvOrDefaultString("TRACING_OTLP_ENDPOINT", "")
+	TracingServiceName = GetEnvOrDefaultString("TRACING_SERVICE_NAME", "new-api")
+	TracingSampleRatio = GetEnvOrDefaultFloat("TRACING_SAMPLE_RATIO", 1)
 	initConstantEnv()
 }
 
@@ -147,6 +163,8 @@ func initConstantEnv() {
 	constant.TaskQueryLimit = GetEnvOrDefault("TASK_QUERY_LIMIT", 1000)
 	// 异步任务超时时间（分钟），超过此时间未完成的任务将被标记为失败并退款。0 表示禁用。
 	constant.TaskTimeoutMinutes = GetEnvOrDefault("TASK_TIMEOUT_MINUTES", 1440)
+	// 本地生成文件（如本地批处理结果）的保存目录
+	constant.FileStoragePath = GetEnvOrDefaultString("FILE_STORAGE_PATH", "./files")
 
 	soraPatchStr := GetEnvOrDefaultString("TASK_PRICE_PATCH", "")
 	if soraPatchStr != "" {
@@ -0,0 +1,90 @@
+package limiter
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/go-redis/redis/v8"
+)
+
+//go:embed lua/token_bucket.lua
+var tokenBucketScriptSource string
+
+//go:embed lua/concurrency.lua
+var concurrencyScriptSource string
+
+var (
+	tokenBucketScript = redis.NewScript(tokenBucketScriptSource)
+	concurrencyScript = redis.NewScript(concurrencyScriptSource)
+)
+
+// BucketMode 令牌桶的扣减方式
+type BucketMode string
+
+const (
+	BucketTake  BucketMode = "take"  // 令牌足够时扣减，否则拒绝
+	BucketPeek  BucketMode = "peek"  // 只检查是否还有剩余令牌
+	BucketForce BucketMode = "force" // 无条件扣减，允许透支（用于事后按实际用量扣减）
+)
+
+// BucketResult 令牌桶限流结果
+type BucketResult struct {
+	Allowed   bool
+	Remaining int64
+	Reset     time.Duration // 令牌桶恢复满所需时间
+}
+
+// Limiter 令牌桶与并发计数，Redis 与内存各有一份实现
+type Limiter interface {
+	// Bucket 容量为 capacity、每 period 恢复满的令牌桶
+	Bucket(ctx context.Context, key string, mode BucketMode, requested int64, capacity int64, period time.Duration) (BucketResult, error)
+	// AcquireConcurrency 以 requestId 占用一个并发名额，返回是否成功及当前并发数；
+	// 超过 ttl 仍未释放的名额视为泄漏，不再计入并发数
+	AcquireConcurrency(ctx context.Context, key string, requestId string, limit int64, ttl time.Duration) (bool, int64, error)
+	ReleaseConcurrency(ctx context.Context, key string, requestId string) error
+}
+
+// Get 启用 Redis 时返回 Redis 实现，否则返回进程内实现
+func Get(ctx context.Context) Limiter {
+	if common.RedisEnabled {
+		return New(ctx, common.RDB)
+	}
+	return defaultMemoryLimiter()
+}
+
+func (rl *RedisLimiter) Bucket(ctx context.Context, key string, mode BucketMode, requested int64, capacity int64, period time.Duration) (BucketResult, error) {
+	rate := float64(capacity) / period.Seconds()
+	values, err := tokenBucketScript.Run(ctx, rl.client, []string{key}, requested, rate, capacity, string(mode)).Int64Slice()
+	if err != nil {
+		return BucketResult{}, fmt.Errorf("token bucket failed: %w", err)
+	}
+	if len(values) != 3 {
+		return BucketResult{}, fmt.Errorf("token bucket returned %d values", len(values))
+	}
+	return BucketResult{
+		Allowed:   values[0] == 1,
+		Remaining: values[1],
+		Reset:     time.Duration(values[2]) * time.Millisecond,
+	}, nil
+}
+
+func (rl *RedisLimiter) AcquireConcurrency(ctx context.Context, key string, requestId string, limit int64, ttl time.Duration) (bool, int64, error) {
+	values, err := concurrencyScript.Run(ctx, rl.client, []string{key}, "acquire", requestId, limit, ttl.Milliseconds()).Int64Slice()
+	if err != nil {
+		return false, 0, fmt.Errorf("acquire concurrency failed: %w", err)
+	}
+	if len(values) != 2 {
+		return false, 0, fmt.Errorf("concurrency script returned %d values", len(values))
+	}
+	return values[0] == 1, values[1], nil
+}
+
+func (rl *RedisLimiter) ReleaseConcurrency(ctx context.Context, key string, requestId string) error {
+	if err := concurrencyScript.Run(ctx, rl.client, []string{key}, "release", requestId).Err(); err != nil {
+		return fmt.Errorf("release concurrency failed: %w", err)
+	}
+	return nil
+}
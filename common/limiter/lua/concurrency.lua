@@ -0,0 +1,33 @@
+-- 并发数限制，每个请求在有序集合中占一个成员，分数为占用时间
+-- KEYS[1]: 有序集合唯一标识
+-- ARGV[1]: acquire 或 release
+-- ARGV[2]: 请求唯一标识
+-- ARGV[3]: 最大并发数
+-- ARGV[4]: 名额最长占用时间（毫秒），超时未释放的名额视为泄漏，在计数前清理
+-- 返回: {是否成功, 当前并发数}
+
+local key = KEYS[1]
+local action = ARGV[1]
+local member = ARGV[2]
+
+if action == 'release' then
+    redis.call('ZREM', key, member)
+    return {1, redis.call('ZCARD', key)}
+end
+
+local limit = tonumber(ARGV[3])
+local ttl = tonumber(ARGV[4])
+
+-- 获取当前时间（Redis服务器时间，毫秒）
+local now = redis.call('TIME')
+local nowMs = tonumber(now[1]) * 1000 + math.floor(tonumber(now[2]) / 1000)
+
+redis.call('ZREMRANGEBYSCORE', key, '-inf', nowMs - ttl)
+local current = redis.call('ZCARD', key)
+if current >= limit then
+    return {0, current}
+end
+redis.call('ZADD', key, nowMs, member)
+-- 整个集合在最后一个名额超时后过期
+redis.call('PEXPIRE', key, ttl)
+return {1, current + 1}
@@ -0,0 +1,49 @@
+-- 支持预检与透支的令牌桶，用于令牌级 RPM / TPM 限制
+-- KEYS[1]: 限流器唯一标识
+-- ARGV[1]: 请求令牌数
+-- ARGV[2]: 令牌生成速率 (每秒，可为小数)
+-- ARGV[3]: 桶容量
+-- ARGV[4]: 模式 take: 令牌足够时扣减; peek: 仅检查是否还有剩余令牌; force: 无条件扣减（允许透支）
+-- 返回: {是否允许, 剩余令牌(向下取整), 恢复满所需毫秒}
+
+local key = KEYS[1]
+local requested = tonumber(ARGV[1])
+local rate = tonumber(ARGV[2])
+local capacity = tonumber(ARGV[3])
+local mode = ARGV[4]
+
+-- 获取当前时间（Redis服务器时间，毫秒）
+local now = redis.call('TIME')
+local nowMs = tonumber(now[1]) * 1000 + math.floor(tonumber(now[2]) / 1000)
+
+-- 获取桶状态
+local bucket = redis.call('HMGET', key, 'tokens', 'last_ms')
+local tokens = tonumber(bucket[1])
+local last_ms = tonumber(bucket[2])
+
+if not tokens or not last_ms then
+    tokens = capacity
+else
+    local elapsed = math.max(0, nowMs - last_ms)
+    tokens = math.min(capacity, tokens + elapsed * rate / 1000)
+end
+
+local allowed = 0
+if mode == 'peek' then
+    if tokens > 0 then
+        allowed = 1
+    end
+elseif mode == 'force' then
+    tokens = math.max(tokens - requested, -capacity)
+    allowed = 1
+elseif tokens >= requested then
+    tokens = tokens - requested
+    allowed = 1
+end
+
+local reset_ms = math.ceil((capacity - tokens) * 1000 / rate)
+redis.call('HMSET', key, 'tokens', tokens, 'last_ms', nowMs)
+-- 桶恢复满后即可过期
+redis.call('PEXPIRE', key, reset_ms + 60000)
+
+return {allowed, math.floor(tokens), reset_ms}
@@ -0,0 +1,127 @@
+package limiter
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+type memoryBucket struct {
+	tokens   float64
+	lastTime time.Time
+	expireAt time.Time
+}
+
+// MemoryLimiter 进程内的令牌桶与并发计数，未启用 Redis 时使用
+type MemoryLimiter struct {
+	mutex   sync.Mutex
+	buckets map[string]*memoryBucket
+	slots   map[string]map[string]time.Time // 并发名额，请求标识 -> 占用时间
+}
+
+var (
+	memoryInstance *MemoryLimiter
+	memoryOnce     sync.Once
+)
+
+func defaultMemoryLimiter() *MemoryLimiter {
+	memoryOnce.Do(func() {
+		memoryInstance = NewMemoryLimiter()
+		go memoryInstance.clearExpiredBuckets(time.Minute)
+	})
+	return memoryInstance
+}
+
+func NewMemoryLimiter() *MemoryLimiter {
+	return &MemoryLimiter{
+		buckets: make(map[string]*memoryBucket),
+		slots:   make(map[string]map[string]time.Time),
+	}
+}
+
+func (l *MemoryLimiter) clearExpiredBuckets(interval time.Duration) {
+	for {
+		time.Sleep(interval)
+		now := time.Now()
+		l.mutex.Lock()
+		for key, bucket := range l.buckets {
+			if now.After(bucket.expireAt) {
+				delete(l.buckets, key)
+			}
+		}
+		l.mutex.Unlock()
+	}
+}
+
+func (l *MemoryLimiter) Bucket(_ context.Context, key string, mode BucketMode, requested int64, capacity int64, period time.Duration) (BucketResult, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := time.Now()
+	rate := float64(capacity) / period.Seconds()
+	bucket, ok := l.buckets[key]
+	if !ok {
+		bucket = &memoryBucket{tokens: float64(capacity), lastTime: now}
+		l.buckets[key] = bucket
+	} else {
+		elapsed := math.Max(0, now.Sub(bucket.lastTime).Seconds())
+		bucket.tokens = math.Min(float64(capacity), bucket.tokens+elapsed*rate)
+		bucket.lastTime = now
+	}
+
+	allowed := false
+	switch mode {
+	case BucketPeek:
+		allowed = bucket.tokens > 0
+	case BucketForce:
+		bucket.tokens = math.Max(bucket.tokens-float64(requested), -float64(capacity))
+		allowed = true
+	default:
+		if bucket.tokens >= float64(requested) {
+			bucket.tokens -= float64(requested)
+			allowed = true
+		}
+	}
+
+	reset := time.Duration(math.Ceil((float64(capacity)-bucket.tokens)/rate*1000)) * time.Millisecond
+	bucket.expireAt = now.Add(reset + time.Minute)
+	return BucketResult{
+		Allowed:   allowed,
+		Remaining: int64(math.Floor(bucket.tokens)),
+		Reset:     reset,
+	}, nil
+}
+
+func (l *MemoryLimiter) AcquireConcurrency(_ context.Context, key string, requestId string, limit int64, ttl time.Duration) (bool, int64, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	slots, ok := l.slots[key]
+	if !ok {
+		slots = make(map[string]time.Time)
+		l.slots[key] = slots
+	}
+	// 与 Redis 实现一致，计数前清理超时未释放的名额
+	now := time.Now()
+	for id, acquiredAt := range slots {
+		if now.Sub(acquiredAt) >= ttl {
+			delete(slots, id)
+		}
+	}
+	current := int64(len(slots))
+	if current >= limit {
+		return false, current, nil
+	}
+	slots[requestId] = now
+	return true, current + 1, nil
+}
+
+func (l *MemoryLimiter) ReleaseConcurrency(_ context.Context, key string, requestId string) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	delete(l.slots[key], requestId)
+	if len(l.slots[key]) == 0 {
+		delete(l.slots, key)
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package limiter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryLimiterBucket(t *testing.T) {
+	l := NewMemoryLimiter()
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		result, err := l.Bucket(ctx, "rpm", BucketTake, 1, 2, time.Minute)
+		require.NoError(t, err)
+		require.True(t, result.Allowed)
+	}
+	result, err := l.Bucket(ctx, "rpm", BucketTake, 1, 2, time.Minute)
+	require.NoError(t, err)
+	require.False(t, result.Allowed)
+	require.Greater(t, result.Reset, time.Duration(0))
+
+	// 事后扣减允许透支，透支后 peek 拒绝
+	result, err = l.Bucket(ctx, "tpm", BucketForce, 150, 100, time.Minute)
+	require.NoError(t, err)
+	require.True(t, result.Allowed)
+	require.Equal(t, int64(-50), result.Remaining)
+	result, err = l.Bucket(ctx, "tpm", BucketPeek, 0, 100, time.Minute)
+	require.NoError(t, err)
+	require.False(t, result.Allowed)
+}
+
+func TestMemoryLimiterConcurrency(t *testing.T) {
+	l := NewMemoryLimiter()
+	ctx := context.Background()
+
+	acquired, current, err := l.AcquireConcurrency(ctx, "c", "req-1", 1, time.Minute)
+	require.NoError(t, err)
+	require.True(t, acquired)
+	require.Equal(t, int64(1), current)
+
+	acquired, _, err = l.AcquireConcurrency(ctx, "c", "req-2", 1, time.Minute)
+	require.NoError(t, err)
+	require.False(t, acquired)
+
+	require.NoError(t, l.ReleaseConcurrency(ctx, "c", "req-1"))
+	acquired, _, err = l.AcquireConcurrency(ctx, "c", "req-2", 1, time.Minute)
+	require.NoError(t, err)
+	require.True(t, acquired)
+}
+
+func TestMemoryLimiterConcurrencyDropsLeakedSlots(t *testing.T) {
+	l := NewMemoryLimiter()
+	ctx := context.Background()
+
+	// req-1 未释放（进程内请求异常退出），超过存活时间后不再占用名额
+	acquired, _, err := l.AcquireConcurrency(ctx, "c", "req-1", 1, 50*time.Millisecond)
+	require.NoError(t, err)
+	require.True(t, acquired)
+	time.Sleep(60 * time.Millisecond)
+	acquired, current, err := l.AcquireConcurrency(ctx, "c", "req-2", 1, 50*time.Millisecond)
+	require.NoError(t, err)
+	require.True(t, acquired)
+	require.Equal(t, int64(1), current)
+}
@@ -0,0 +1,162 @@
+package common
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// 日志输出格式，text 保持原有的纯文本格式
+const (
+	LogFormatText   = "text"
+	LogFormatJSON   = "json"
+	LogFormatLogfmt = "logfmt"
+)
+
+const (
+	LogLevelDebug = iota
+	LogLevelInfo
+	LogLevelWarn
+	LogLevelError
+)
+
+// 日志模块：请求日志按路由标签（relay、api、web 等）区分，系统日志与访问日志单独成模块
+const (
+	LogModuleSystem = "system"
+	LogModuleAccess = "access"
+)
+
+var LogFormat = LogFormatText
+
+var logLevelNames = []string{"debug", "info", "warn", "error"}
+
+type LogField struct {
+	Key   string
+	Value any
+}
+
+type logLevelState struct {
+	level   int
+	modules map[string]int
+}
+
+var logLevels atomic.Pointer[logLevelState]
+
+func init() {
+	logLevels.Store(&logLevelState{level: LogLevelInfo})
+}
+
+func ParseLogLevel(level string) (int, bool) {
+	switch strings.ToLower(strings.TrimSpace(level)) {
+	case "debug":
+		return LogLevelDebug, true
+	case "info", "":
+		return LogLevelInfo, true
+	case "warn", "warning":
+		return LogLevelWarn, true
+	case "error", "err":
+		return LogLevelError, true
+	default:
+		return 0, false
+	}
+}
+
+func LogLevelName(level int) string {
+	if level < 0 || level >= len(logLevelNames) {
+		return "info"
+	}
+	return logLevelNames[level]
+}
+
+// SetLogLevels 动态设置全局日志级别与各模块级别，模块级别优先
+func SetLogLevels(level string, modules map[string]string) error {
+	state := &logLevelState{modules: make(map[string]int, len(modules))}
+	var ok bool
+	if state.level, ok = ParseLogLevel(level); !ok {
+		return errors.New("invalid log level: " + level)
+	}
+	for module, moduleLevel := range modules {
+		l, ok := ParseLogLevel(moduleLevel)
+		if !ok {
+			return fmt.Errorf("invalid log level for module %s: %s", module, moduleLevel)
+		}
+		state.modules[strings.TrimSpace(module)] = l
+	}
+	logLevels.Store(state)
+	return nil
+}
+
+// LogLevelEnabled 判断模块在该级别是否输出；未单独配置的模块在 DEBUG 模式下输出所有级别
+func LogLevelEnabled(module string, level int) bool {
+	state := logLevels.Load()
+	if l, ok := state.modules[module]; ok {
+		return level >= l
+	}
+	if DebugEnabled {
+		return true
+	}
+	return level >= state.level
+}
+
+// FormatStructuredLog 按 LogFormat 将日志编码为一行 JSON 或 logfmt，值为零值的字段会被省略
+func FormatStructuredLog(t time.Time, level int, module string, msg string, fields ...LogField) string {
+	all := make([]LogField, 0, len(fields)+4)
+	all = append(all,
+		LogField{Key: "time", Value: t.Format(time.RFC3339Nano)},
+		LogField{Key: "level", Value: LogLevelName(level)},
+		LogField{Key: "module", Value: module},
+		LogField{Key: "msg", Value: msg},
+	)
+	for _, field := range fields {
+		if field.Value == nil || field.Value == "" || field.Value == 0 {
+			continue
+		}
+		all = append(all, field)
+	}
+
+	var buf bytes.Buffer
+	if LogFormat == LogFormatLogfmt {
+		for i, field := range all {
+			if i > 0 {
+				buf.WriteByte(' ')
+			}
+			buf.WriteString(field.Key)
+			buf.WriteByte('=')
+			buf.WriteString(logfmtValue(field.Value))
+		}
+	} else {
+		buf.WriteByte('{')
+		for i, field := range all {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			buf.WriteString(strconv.Quote(field.Key))
+			buf.WriteByte(':')
+			value, err := Marshal(field.Value)
+			if err != nil {
+				value, _ = Marshal(fmt.Sprintf("%v", field.Value))
+			}
+			buf.Write(value)
+		}
+		buf.WriteByte('}')
+	}
+	buf.WriteByte('\n')
+	return buf.String()
+}
+
+func logfmtValue(v any) string {
+	s := fmt.Sprintf("%v", v)
+	if s == "" || strings.ContainsAny(s, " =\"\t\r\n") {
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+func WriteStructuredLog(w io.Writer, level int, module string, msg string, fields ...LogField) {
+	_, _ = io.WriteString(w, FormatStructuredLog(time.Now(), level, module, msg, fields...))
+}
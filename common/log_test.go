@@ -0,0 +1,45 @@
+package common
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatStructuredLog(t *testing.T) {
+	format := LogFormat
+	t.Cleanup(func() { LogFormat = format })
+	ts := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	LogFormat = LogFormatJSON
+	line := FormatStructuredLog(ts, LogLevelWarn, "relay", "upstream \"slow\"",
+		LogField{Key: "request_id", Value: "req-1"},
+		LogField{Key: "user_id", Value: 7},
+		LogField{Key: "channel_id", Value: 0},
+	)
+	require.Equal(t, `{"time":"2025-01-02T03:04:05Z","level":"warn","module":"relay","msg":"upstream \"slow\"","request_id":"req-1","user_id":7}`+"\n", line)
+
+	LogFormat = LogFormatLogfmt
+	line = FormatStructuredLog(ts, LogLevelInfo, "system", "channel disabled", LogField{Key: "channel_id", Value: 3})
+	require.Equal(t, `time=2025-01-02T03:04:05Z level=info module=system msg="channel disabled" channel_id=3`+"\n", line)
+}
+
+func TestLogLevelEnabled(t *testing.T) {
+	debug := DebugEnabled
+	DebugEnabled = false
+	t.Cleanup(func() {
+		DebugEnabled = debug
+		require.NoError(t, SetLogLevels("info", nil))
+	})
+
+	require.NoError(t, SetLogLevels("warn", map[string]string{"relay": "debug"}))
+	require.False(t, LogLevelEnabled("api", LogLevelInfo))
+	require.True(t, LogLevelEnabled("api", LogLevelError))
+	require.True(t, LogLevelEnabled("relay", LogLevelDebug))
+
+	require.Error(t, SetLogLevels("verbose", nil))
+	require.Error(t, SetLogLevels("info", map[string]string{"relay": "trace"}))
+	// 无效配置不影响当前级别
+	require.True(t, LogLevelEnabled("relay", LogLevelDebug))
+}
@@ -1,6 +1,7 @@
 package common
 
 import (
+	"bytes"
 	"encoding/base64"
 	"encoding/json"
 	"net/url"
@@ -9,6 +10,7 @@ import (
 	"strings"
 	"unsafe"
 
+	goahocorasick "github.com/anknown/ahocorasick"
 	"github.com/samber/lo"
 )
 
@@ -252,3 +254,17 @@ func MaskSensitiveInfo(str string) string {
 
 	return str
 }
+
+// NewAcMachine 以小写形式构建词表的 AC 自动机，构建失败时返回 nil
+func NewAcMachine(dict []string) *goahocorasick.Machine {
+	runes := make([][]rune, 0, len(dict))
+	for _, word := range dict {
+		runes = append(runes, bytes.Runes(bytes.TrimSpace([]byte(strings.ToLower(word)))))
+	}
+	m := new(goahocorasick.Machine)
+	if err := m.Build(runes); err != nil {
+		SysError("failed to build ac machine: " + err.Error())
+		return nil
+	}
+	return m
+}
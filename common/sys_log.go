@@ -9,16 +9,34 @@ import (
 )
 
 func SysLog(s string) {
+	if !LogLevelEnabled(LogModuleSystem, LogLevelInfo) {
+		return
+	}
+	if LogFormat != LogFormatText {
+		WriteStructuredLog(gin.DefaultWriter, LogLevelInfo, LogModuleSystem, s)
+		return
+	}
 	t := time.Now()
 	_, _ = fmt.Fprintf(gin.DefaultWriter, "[SYS] %v | %s \n", t.Format("2006/01/02 - 15:04:05"), s)
 }
 
 func SysError(s string) {
+	if !LogLevelEnabled(LogModuleSystem, LogLevelError) {
+		return
+	}
+	if LogFormat != LogFormatText {
+		WriteStructuredLog(gin.DefaultErrorWriter, LogLevelError, LogModuleSystem, s)
+		return
+	}
 	t := time.Now()
 	_, _ = fmt.Fprintf(gin.DefaultErrorWriter, "[SYS] %v | %s \n", t.Format("2006/01/02 - 15:04:05"), s)
 }
 
 func FatalLog(v ...any) {
+	if LogFormat != LogFormatText {
+		WriteStructuredLog(gin.DefaultErrorWriter, LogLevelError, LogModuleSystem, fmt.Sprint(v...), LogField{Key: "fatal", Value: true})
+		os.Exit(1)
+	}
 	t := time.Now()
 	_, _ = fmt.Fprintf(gin.DefaultErrorWriter, "[FATAL] %v | %v \n", t.Format("2006/01/02 - 15:04:05"), v)
 	os.Exit(1)
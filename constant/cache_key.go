@@ -9,6 +9,8 @@ const (
 )
 
 const (
-	TokenFiledRemainQuota = "RemainQuota"
-	TokenFieldGroup       = "Group"
+	TokenFiledRemainQuota     = "RemainQuota"
+	TokenFiledBudgetUsed      = "BudgetUsed"
+	TokenFiledBudgetResetTime = "BudgetResetTime"
+	TokenFieldGroup           = "Group"
 )
@@ -10,6 +10,9 @@ const (
 	ContextKeyOriginalModel    ContextKey = "original_model"
 	ContextKeyRequestStartTime ContextKey = "request_start_time"
 
+	// Distribute 因请求模型没有可用渠道而降级时记录用户请求的原始模型
+	ContextKeyModelFallbackFrom ContextKey = "model_fallback_from"
+
 	/* token related keys */
 	ContextKeyTokenUnlimited         ContextKey = "token_unlimited_quota"
 	ContextKeyTokenKey               ContextKey = "token_key"
@@ -19,6 +22,17 @@ const (
 	ContextKeyTokenModelLimitEnabled ContextKey = "token_model_limit_enabled"
 	ContextKeyTokenModelLimit        ContextKey = "token_model_limit"
 	ContextKeyTokenCrossGroupRetry   ContextKey = "token_cross_group_retry"
+	ContextKeyTokenModelFallback     ContextKey = "token_model_fallback"
+	ContextKeyTokenRpmLimit          ContextKey = "token_rpm_limit"
+	ContextKeyTokenTpmLimit          ContextKey = "token_tpm_limit"
+	ContextKeyTokenConcurrencyLimit  ContextKey = "token_concurrency_limit"
+	ContextKeyTokenOrganizationId    ContextKey = "token_organization_id"
+	ContextKeyTokenCallbackUrl       ContextKey = "token_callback_url"
+
+	/* management key related keys */
+	ContextKeyManagementScope     ContextKey = "management_scope"
+	ContextKeyManagementKeyId     ContextKey = "management_key_id"
+	ContextKeyManagementKeyScopes ContextKey = "management_key_scopes"
 
 	/* channel related keys */
 	ContextKeyChannelId                ContextKey = "channel_id"
@@ -65,4 +79,16 @@ const (
 
 	// ContextKeyLanguage stores the user's language preference for i18n
 	ContextKeyLanguage ContextKey = "language"
+
+	// ContextKeyBatchId marks a batch line (local worker request or upstream result settlement), billed with the batch ratio
+	ContextKeyBatchId ContextKey = "batch_id"
+
+	// ContextKeyPayloadCapture stores the payload capture session of the current upstream attempt
+	ContextKeyPayloadCapture ContextKey = "payload_capture"
+
+	// ContextKeyResponseCacheHit marks a request served from the response cache
+	ContextKeyResponseCacheHit ContextKey = "response_cache_hit"
+
+	// ContextKeyModerationResults stores the moderation results of the request, recorded in the consume log
+	ContextKeyModerationResults ContextKey = "moderation_results"
 )
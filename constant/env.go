@@ -17,6 +17,7 @@ var GenerateDefaultToken bool
 var ErrorLogEnabled bool
 var TaskQueryLimit int
 var TaskTimeoutMinutes int
+var FileStoragePath string
 
 // temporary variable for sora patch, will be removed in future
 var TaskPricePatches []string
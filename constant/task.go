@@ -5,6 +5,8 @@ type TaskPlatform string
 const (
 	TaskPlatformSuno       TaskPlatform = "suno"
 	TaskPlatformMidjourney              = "mj"
+	// TaskPlatformFineTuning OpenAI fine_tuning jobs，轮询走通用任务循环
+	TaskPlatformFineTuning TaskPlatform = "fine_tuning"
 )
 
 const (
@@ -17,6 +19,7 @@ const (
 	TaskActionReferenceGenerate = "referenceGenerate"
 	TaskActionRemix             = "remixGenerate"
 	TaskActionAudioGenerate     = "audioGenerate"
+	TaskActionFineTune          = "fineTune"
 )
 
 var SunoModel2Action = map[string]string{
@@ -0,0 +1,92 @@
+package controller
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 单次导出的最大记录数
+const auditLogExportLimit = 10000
+
+// recordAudit 记录管理操作审计，写入失败时按请求记录错误日志；此时修改已经生效，不再回滚
+func recordAudit(c *gin.Context, action string, targetType string, targetId any, before any, after any) {
+	if err := service.RecordAudit(c, action, targetType, targetId, before, after); err != nil {
+		logger.LogError(c, fmt.Sprintf("failed to record audit log (%s %s %v): %s", action, targetType, targetId, err.Error()))
+	}
+}
+
+func parseAuditLogFilter(c *gin.Context) model.AuditLogFilter {
+	actorId, _ := strconv.Atoi(c.Query("actor_id"))
+	startTimestamp, _ := strconv.ParseInt(c.Query("start_timestamp"), 10, 64)
+	endTimestamp, _ := strconv.ParseInt(c.Query("end_timestamp"), 10, 64)
+	return model.AuditLogFilter{
+		ActorId:        actorId,
+		Action:         c.Query("action"),
+		TargetType:     c.Query("target_type"),
+		TargetId:       c.Query("target_id"),
+		StartTimestamp: startTimestamp,
+		EndTimestamp:   endTimestamp,
+	}
+}
+
+// GetAuditLogs 分页查询审计日志
+func GetAuditLogs(c *gin.Context) {
+	pageInfo := common.GetPageQuery(c)
+	logs, total, err := model.GetAuditLogs(parseAuditLogFilter(c), pageInfo.GetStartIdx(), pageInfo.GetPageSize())
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	pageInfo.SetTotal(int(total))
+	pageInfo.SetItems(logs)
+	common.ApiSuccess(c, pageInfo)
+}
+
+// ExportAuditLogs 按筛选条件导出审计日志，format 支持 csv（默认）与 json
+func ExportAuditLogs(c *gin.Context) {
+	logs, _, err := model.GetAuditLogs(parseAuditLogFilter(c), 0, auditLogExportLimit)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	filename := fmt.Sprintf("audit_logs_%s", time.Now().Format("20060102150405"))
+	if c.Query("format") == "json" {
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s.json", filename))
+		c.JSON(http.StatusOK, logs)
+		return
+	}
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s.csv", filename))
+	c.Status(http.StatusOK)
+	writer := csv.NewWriter(c.Writer)
+	_ = writer.Write([]string{"id", "created_at", "actor_id", "actor_name", "actor_role", "management_key_id", "ip",
+		"action", "target_type", "target_id", "changed_fields", "before", "after"})
+	for _, log := range logs {
+		_ = writer.Write([]string{
+			strconv.Itoa(log.Id),
+			time.Unix(log.CreatedAt, 0).Format(time.RFC3339),
+			strconv.Itoa(log.ActorId),
+			log.ActorName,
+			strconv.Itoa(log.ActorRole),
+			strconv.Itoa(log.ManagementKeyId),
+			log.Ip,
+			log.Action,
+			log.TargetType,
+			log.TargetId,
+			log.ChangedFields,
+			log.Before,
+			log.After,
+		})
+	}
+	writer.Flush()
+}
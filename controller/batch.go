@@ -0,0 +1,339 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/middleware"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/service"
+	"github.com/QuantumNous/new-api/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UpdateBatchBulk 薄入口，实际轮询逻辑在 service 层
+func UpdateBatchBulk() {
+	service.BatchPollingLoop()
+}
+
+func batchNotFound(c *gin.Context, batchId string) {
+	c.JSON(http.StatusNotFound, gin.H{
+		"error": types.OpenAIError{
+			Message: fmt.Sprintf("No batch found with id '%s'.", batchId),
+			Type:    "invalid_request_error",
+			Param:   "batch_id",
+			Code:    "batch_not_found",
+		},
+	})
+}
+
+// BatchCreate POST /v1/batches
+func BatchCreate(c *gin.Context) {
+	var req dto.OpenAIBatchRequest
+	if err := common.UnmarshalBodyReusable(c, &req); err != nil {
+		fileError(c, types.NewErrorWithStatusCode(err, types.ErrorCodeInvalidRequest, http.StatusBadRequest, types.ErrOptionWithSkipRetry()))
+		return
+	}
+	if !slices.Contains(service.SupportedBatchEndpoints, req.Endpoint) {
+		fileError(c, types.NewErrorWithStatusCode(fmt.Errorf("unsupported endpoint %q", req.Endpoint),
+			types.ErrorCodeInvalidRequest, http.StatusBadRequest, types.ErrOptionWithSkipRetry()))
+		return
+	}
+	req.CompletionWindow = common.GetStringIfEmpty(req.CompletionWindow, "24h")
+	if req.CompletionWindow != "24h" {
+		fileError(c, types.NewErrorWithStatusCode(fmt.Errorf("completion_window must be 24h"),
+			types.ErrorCodeInvalidRequest, http.StatusBadRequest, types.ErrOptionWithSkipRetry()))
+		return
+	}
+
+	userId := c.GetInt("id")
+	inputFile, err := model.GetUserFileById(userId, req.InputFileId)
+	if err != nil {
+		fileError(c, types.NewError(err, types.ErrorCodeQueryDataError, types.ErrOptionWithSkipRetry()))
+		return
+	}
+	if inputFile == nil || inputFile.IsLocal() {
+		fileNotFound(c, req.InputFileId)
+		return
+	}
+	if inputFile.Purpose != "batch" {
+		fileError(c, types.NewErrorWithStatusCode(fmt.Errorf("file %s must be uploaded with purpose 'batch'", inputFile.FileId),
+			types.ErrorCodeInvalidRequest, http.StatusBadRequest, types.ErrOptionWithSkipRetry()))
+		return
+	}
+	channel, err := service.GetPinnedFileChannel(inputFile)
+	if err != nil {
+		fileError(c, types.NewOpenAIError(err, types.ErrorCodeGetChannelFailed, http.StatusServiceUnavailable))
+		return
+	}
+	userQuota, err := model.GetUserQuota(userId, false)
+	if err != nil {
+		fileError(c, types.NewError(err, types.ErrorCodeQueryDataError, types.ErrOptionWithSkipRetry()))
+		return
+	}
+	if userQuota <= 0 {
+		fileError(c, types.NewErrorWithStatusCode(fmt.Errorf("insufficient user quota"),
+			types.ErrorCodeInsufficientUserQuota, http.StatusForbidden, types.ErrOptionWithSkipRetry()))
+		return
+	}
+
+	batch := &model.Batch{
+		UserId:           userId,
+		TokenId:          common.GetContextKeyInt(c, constant.ContextKeyTokenId),
+		Group:            common.GetContextKeyString(c, constant.ContextKeyUsingGroup),
+		Endpoint:         req.Endpoint,
+		InputFileId:      inputFile.FileId,
+		CompletionWindow: req.CompletionWindow,
+		ChannelId:        channel.Id,
+		ChannelKeyIdx:    inputFile.ChannelKeyIdx,
+	}
+	if len(req.Metadata) > 0 {
+		batch.Metadata, _ = common.Marshal(req.Metadata)
+	}
+
+	if service.IsNativeBatchChannel(channel.Type) {
+		batch.Mode = model.BatchModeUpstream
+		resp, err := service.CreateUpstreamBatch(c.Request.Context(), channel, inputFile, batch, req.Metadata)
+		if err != nil {
+			fileError(c, types.NewOpenAIError(err, types.ErrorCodeDoRequestFailed, http.StatusBadGateway))
+			return
+		}
+		if resp != nil {
+			fileError(c, service.RelayErrorHandler(c.Request.Context(), resp, false))
+			return
+		}
+	} else {
+		now := common.GetTimestamp()
+		batch.Mode = model.BatchModeLocal
+		batch.BatchId = "batch_" + common.GetRandomString(24)
+		batch.Status = model.BatchStatusValidating
+		batch.CreatedAt = now
+		batch.ExpiresAt = now + int64((24 * time.Hour).Seconds())
+		if err = service.SaveLocalBatchInput(c.Request.Context(), channel, inputFile, batch); err != nil {
+			var apiErr *types.NewAPIError
+			if !errors.As(err, &apiErr) {
+				apiErr = types.NewOpenAIError(err, types.ErrorCodeDoRequestFailed, http.StatusBadGateway)
+			}
+			fileError(c, apiErr)
+			return
+		}
+	}
+	if err = batch.Insert(); err != nil {
+		service.RemoveLocalBatchInput(batch)
+		logger.LogError(c, fmt.Sprintf("failed to save batch %s: %s", batch.BatchId, err.Error()))
+		fileError(c, types.NewError(err, types.ErrorCodeUpdateDataError, types.ErrOptionWithSkipRetry()))
+		return
+	}
+	c.JSON(http.StatusOK, service.BatchToOpenAIBatch(batch))
+}
+
+func getUserBatchOrAbort(c *gin.Context) *model.Batch {
+	batchId := c.Param("id")
+	batch, err := model.GetUserBatchById(c.GetInt("id"), batchId)
+	if err != nil {
+		fileError(c, types.NewError(err, types.ErrorCodeQueryDataError, types.ErrOptionWithSkipRetry()))
+		return nil
+	}
+	if batch == nil {
+		batchNotFound(c, batchId)
+		return nil
+	}
+	return batch
+}
+
+// BatchRetrieve GET /v1/batches/:id
+func BatchRetrieve(c *gin.Context) {
+	batch := getUserBatchOrAbort(c)
+	if batch == nil {
+		return
+	}
+	c.JSON(http.StatusOK, service.BatchToOpenAIBatch(batch))
+}
+
+// BatchList GET /v1/batches
+func BatchList(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	if limit <= 0 {
+		limit = 20
+	} else if limit > 100 {
+		limit = 100
+	}
+	batches, hasMore, err := model.ListUserBatches(c.GetInt("id"), c.Query("after"), limit)
+	if err != nil {
+		fileError(c, types.NewError(err, types.ErrorCodeQueryDataError, types.ErrOptionWithSkipRetry()))
+		return
+	}
+	list := &dto.OpenAIBatchList{
+		Object:  "list",
+		Data:    make([]*dto.OpenAIBatch, 0, len(batches)),
+		HasMore: hasMore,
+	}
+	for _, batch := range batches {
+		list.Data = append(list.Data, service.BatchToOpenAIBatch(batch))
+	}
+	if len(batches) > 0 {
+		list.FirstId = batches[0].BatchId
+		list.LastId = batches[len(batches)-1].BatchId
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// BatchCancel POST /v1/batches/:id/cancel
+func BatchCancel(c *gin.Context) {
+	batch := getUserBatchOrAbort(c)
+	if batch == nil {
+		return
+	}
+	if batch.IsFinished() || batch.Status == model.BatchStatusCancelling {
+		fileError(c, types.NewErrorWithStatusCode(fmt.Errorf("cannot cancel a batch with status '%s'", batch.Status),
+			types.ErrorCodeInvalidRequest, http.StatusConflict, types.ErrOptionWithSkipRetry()))
+		return
+	}
+	fromStatus := batch.Status
+	if batch.Mode == model.BatchModeUpstream {
+		resp, err := service.CancelUpstreamBatch(c.Request.Context(), batch)
+		if err != nil {
+			fileError(c, types.NewOpenAIError(err, types.ErrorCodeDoRequestFailed, http.StatusBadGateway))
+			return
+		}
+		if resp != nil {
+			fileError(c, service.RelayErrorHandler(c.Request.Context(), resp, false))
+			return
+		}
+	} else {
+		now := common.GetTimestamp()
+		batch.CancellingAt = now
+		if batch.Status == model.BatchStatusValidating {
+			// 尚未开始执行，直接取消
+			batch.Status = model.BatchStatusCancelled
+			batch.CancelledAt = now
+		} else {
+			// 由执行中的 worker 感知后收尾
+			batch.Status = model.BatchStatusCancelling
+		}
+	}
+	won, err := batch.UpdateWithStatus(fromStatus)
+	if err != nil {
+		fileError(c, types.NewError(err, types.ErrorCodeUpdateDataError, types.ErrOptionWithSkipRetry()))
+		return
+	}
+	if won && batch.Status == model.BatchStatusCancelled {
+		service.RemoveLocalBatchInput(batch)
+	}
+	if !won {
+		// 状态已被 worker 推进，返回最新状态
+		if latest, err := model.GetUserBatchById(batch.UserId, batch.BatchId); err == nil && latest != nil {
+			batch = latest
+		}
+	}
+	c.JSON(http.StatusOK, service.BatchToOpenAIBatch(batch))
+}
+
+// ---------------------------------------------------------------------------
+// 本地批处理执行
+// ---------------------------------------------------------------------------
+
+// batchLineRelayFormat 批处理接口对应的 relay 格式
+func batchLineRelayFormat(endpoint string) types.RelayFormat {
+	switch endpoint {
+	case "/v1/embeddings":
+		return types.RelayFormatEmbedding
+	case "/v1/responses":
+		return types.RelayFormatOpenAIResponses
+	default:
+		return types.RelayFormatOpenAI
+	}
+}
+
+// setupBatchLineContext 以批处理所属的令牌与用户写入上下文，校验与 TokenAuth 一致；
+// 令牌的 IP 限制只针对客户端请求，已在创建批处理时校验，这里不再检查
+func setupBatchLineContext(c *gin.Context, token *model.Token, batch *model.Batch) *types.NewAPIError {
+	token, err := model.ValidateUserToken(token.Key)
+	if err != nil {
+		return types.NewErrorWithStatusCode(err, types.ErrorCodeAccessDenied, http.StatusUnauthorized, types.ErrOptionWithSkipRetry())
+	}
+	userCache, err := model.GetUserCache(token.UserId)
+	if err != nil {
+		return types.NewError(err, types.ErrorCodeQueryDataError, types.ErrOptionWithSkipRetry())
+	}
+	if userCache.Status != common.UserStatusEnabled {
+		return types.NewErrorWithStatusCode(fmt.Errorf("用户已被封禁"), types.ErrorCodeAccessDenied, http.StatusForbidden, types.ErrOptionWithSkipRetry())
+	}
+	userCache.WriteContext(c)
+	common.SetContextKey(c, constant.ContextKeyUsingGroup, batch.Group)
+	if err = middleware.SetupContextForToken(c, token); err != nil {
+		return types.NewError(err, types.ErrorCodeAccessDenied, types.ErrOptionWithSkipRetry())
+	}
+	common.SetContextKey(c, constant.ContextKeyBatchId, batch.BatchId)
+	return nil
+}
+
+// relayBatchLine 依次执行令牌限流、Distribute 与 Relay，计费由 Relay 中的 BillingSession 完成，
+// 并通过 ContextKeyBatchId 应用批处理倍率
+func relayBatchLine(c *gin.Context, token *model.Token, batch *model.Batch) {
+	if apiErr := setupBatchLineContext(c, token, batch); apiErr != nil {
+		fileError(c, apiErr)
+		return
+	}
+	release, err := service.CheckTokenRateLimit(c)
+	if err != nil {
+		var limitErr *service.TokenRateLimitError
+		if errors.As(err, &limitErr) {
+			fileError(c, types.NewErrorWithStatusCode(errors.New(limitErr.Message), types.ErrorCodeAccessDenied, http.StatusTooManyRequests, types.ErrOptionWithSkipRetry()))
+			return
+		}
+		fileError(c, types.NewError(err, types.ErrorCodeQueryDataError, types.ErrOptionWithSkipRetry()))
+		return
+	}
+	if release != nil {
+		defer release()
+	}
+	middleware.Distribute()(c)
+	if c.IsAborted() {
+		return
+	}
+	Relay(c, batchLineRelayFormat(batch.Endpoint))
+}
+
+// ExecuteBatchLine 在本地执行批处理中的一行请求，结果按 OpenAI batch 输出格式返回
+func ExecuteBatchLine(ctx context.Context, token *model.Token, batch *model.Batch, line *dto.BatchRequestLine) *dto.BatchResponseLine {
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	requestId := common.GetTimeString() + common.GetRandomString(8)
+	ctx = context.WithValue(ctx, common.RequestIdKey, requestId)
+	c.Request = httptest.NewRequest(http.MethodPost, line.Url, bytes.NewReader(line.Body)).WithContext(ctx)
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Set(common.RequestIdKey, requestId)
+	c.Header(common.RequestIdKey, requestId)
+	relayBatchLine(c, token, batch)
+
+	result := &dto.BatchResponseLine{
+		Id:       "batch_req_" + common.GetRandomString(24),
+		CustomId: line.CustomId,
+		Response: &dto.BatchResponseBody{
+			StatusCode: recorder.Code,
+			RequestId:  recorder.Header().Get(common.RequestIdKey),
+			Body:       recorder.Body.Bytes(),
+		},
+	}
+	if common.GetJsonType(result.Response.Body) != "object" {
+		result.Response.Body = nil
+		result.Error = &dto.OpenAIBatchError{
+			Code:    "invalid_response",
+			Message: "request did not return a JSON body",
+		}
+	}
+	return result
+}
@@ -0,0 +1,57 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/service"
+	"github.com/QuantumNous/new-api/setting/ratio_setting"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecuteBatchLineWithIpRestrictedToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := setupRelayTestDB(t, "batch_execute")
+	service.InitHttpClient()
+	ratio_setting.InitRatioSettings()
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprint(w, `{"id":"chatcmpl-1","object":"chat.completion","created":1,"model":"gpt-4o-mini","choices":[{"index":0,"message":{"role":"assistant","content":"ok"},"finish_reason":"stop"}],"usage":{"prompt_tokens":5,"completion_tokens":1,"total_tokens":6}}`)
+	}))
+	t.Cleanup(upstream.Close)
+
+	user := &model.User{Username: "batchline", Password: "password123", Status: common.UserStatusEnabled, Group: "default", Quota: 100000000}
+	require.NoError(t, db.Create(user).Error)
+	// 批处理在后台执行，没有客户端 IP，IP 限制不应导致该行失败
+	allowIps := "10.0.0.1"
+	token := &model.Token{UserId: user.Id, Key: "batchlinetokenkey", Name: "t", Status: common.TokenStatusEnabled, ExpiredTime: -1, UnlimitedQuota: true, AllowIps: &allowIps}
+	require.NoError(t, db.Create(token).Error)
+	baseURL := upstream.URL
+	channel := &model.Channel{Type: constant.ChannelTypeOpenAI, Key: "sk-a", Name: "c", Status: common.ChannelStatusEnabled, Models: "gpt-4o-mini", Group: "default", BaseURL: &baseURL}
+	require.NoError(t, db.Create(channel).Error)
+	require.NoError(t, channel.AddAbilities(nil))
+
+	batch := &model.Batch{BatchId: "batch_line", UserId: user.Id, TokenId: token.Id, Group: "default", Endpoint: "/v1/chat/completions"}
+	result := ExecuteBatchLine(context.Background(), token, batch, &dto.BatchRequestLine{
+		CustomId: "a",
+		Url:      "/v1/chat/completions",
+		Body:     []byte(`{"model":"gpt-4o-mini","messages":[{"role":"user","content":"hi"}]}`),
+	})
+	require.NotNil(t, result.Response)
+	require.Equal(t, http.StatusOK, result.Response.StatusCode, string(result.Response.Body))
+	require.NotEmpty(t, result.Response.RequestId)
+
+	var log model.Log
+	require.NoError(t, db.Order("id desc").First(&log).Error)
+	require.Contains(t, log.Other, `"batch_id":"batch_line"`)
+}
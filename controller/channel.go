@@ -245,6 +245,15 @@ func FixChannelsAbilities(c *gin.Context) {
 	})
 }
 
+// GetChannelHealth 返回自适应路由统计的渠道健康状况与熔断状态
+func GetChannelHealth(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    model.GetChannelHealthStats(),
+	})
+}
+
 func SearchChannels(c *gin.Context) {
 	keyword := c.Query("keyword")
 	group := c.Query("group")
@@ -655,6 +664,9 @@ func AddChannel(c *gin.Context) {
 		common.ApiError(c, err)
 		return
 	}
+	for i := range channels {
+		recordAudit(c, model.AuditActionCreate, model.AuditTargetChannel, channels[i].Id, nil, channels[i])
+	}
 	service.ResetProxyClientCache()
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
@@ -665,12 +677,14 @@ func AddChannel(c *gin.Context) {
 
 func DeleteChannel(c *gin.Context) {
 	id, _ := strconv.Atoi(c.Param("id"))
+	originChannel, _ := model.GetChannelById(id, true)
 	channel := model.Channel{Id: id}
 	err := channel.Delete()
 	if err != nil {
 		common.ApiError(c, err)
 		return
 	}
+	recordAudit(c, model.AuditActionDelete, model.AuditTargetChannel, id, originChannel, nil)
 	model.InitChannelCache()
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
@@ -680,11 +694,17 @@ func DeleteChannel(c *gin.Context) {
 }
 
 func DeleteDisabledChannel(c *gin.Context) {
+	originChannels, err := model.GetDisabledChannels()
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
 	rows, err := model.DeleteDisabledChannel()
 	if err != nil {
 		common.ApiError(c, err)
 		return
 	}
+	recordChannelBatchAudit(c, model.AuditActionDelete, originChannels, nil)
 	model.InitChannelCache()
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
@@ -694,6 +714,34 @@ func DeleteDisabledChannel(c *gin.Context) {
 	return
 }
 
+// recordChannelBatchAudit 为批量或按标签操作的每个渠道记录一条审计，after 中缺失的渠道视为已删除
+func recordChannelBatchAudit(c *gin.Context, action string, before []*model.Channel, after []*model.Channel) {
+	afterById := make(map[int]*model.Channel, len(after))
+	for _, channel := range after {
+		afterById[channel.Id] = channel
+	}
+	for _, origin := range before {
+		recordAudit(c, action, model.AuditTargetChannel, origin.Id, origin, afterById[origin.Id])
+	}
+}
+
+// recordChannelBatchUpdateAudit 重新读取 before 中的渠道并记录修改前后的快照
+func recordChannelBatchUpdateAudit(c *gin.Context, before []*model.Channel) {
+	ids := make([]int, 0, len(before))
+	for _, channel := range before {
+		ids = append(ids, channel.Id)
+	}
+	if len(ids) == 0 {
+		return
+	}
+	after, err := model.GetChannelsByIds(ids)
+	if err != nil {
+		common.SysError("failed to load channels for audit: " + err.Error())
+		return
+	}
+	recordChannelBatchAudit(c, model.AuditActionUpdate, before, after)
+}
+
 type ChannelTag struct {
 	Tag            string  `json:"tag"`
 	NewTag         *string `json:"new_tag"`
@@ -716,11 +764,17 @@ func DisableTagChannels(c *gin.Context) {
 		})
 		return
 	}
+	originChannels, err := model.GetChannelsByTag(channelTag.Tag, false, true)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
 	err = model.DisableChannelByTag(channelTag.Tag)
 	if err != nil {
 		common.ApiError(c, err)
 		return
 	}
+	recordChannelBatchUpdateAudit(c, originChannels)
 	model.InitChannelCache()
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
@@ -739,11 +793,17 @@ func EnableTagChannels(c *gin.Context) {
 		})
 		return
 	}
+	originChannels, err := model.GetChannelsByTag(channelTag.Tag, false, true)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
 	err = model.EnableChannelByTag(channelTag.Tag)
 	if err != nil {
 		common.ApiError(c, err)
 		return
 	}
+	recordChannelBatchUpdateAudit(c, originChannels)
 	model.InitChannelCache()
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
@@ -791,11 +851,17 @@ func EditTagChannels(c *gin.Context) {
 		}
 		channelTag.HeaderOverride = common.GetPointer[string](trimmed)
 	}
+	originChannels, err := model.GetChannelsByTag(channelTag.Tag, false, true)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
 	err = model.EditChannelByTag(channelTag.Tag, channelTag.NewTag, channelTag.ModelMapping, channelTag.Models, channelTag.Groups, channelTag.Priority, channelTag.Weight, channelTag.ParamOverride, channelTag.HeaderOverride)
 	if err != nil {
 		common.ApiError(c, err)
 		return
 	}
+	recordChannelBatchUpdateAudit(c, originChannels)
 	model.InitChannelCache()
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
@@ -819,11 +885,17 @@ func DeleteChannelBatch(c *gin.Context) {
 		})
 		return
 	}
+	originChannels, err := model.GetChannelsByIds(channelBatch.Ids)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
 	err = model.BatchDeleteChannels(channelBatch.Ids)
 	if err != nil {
 		common.ApiError(c, err)
 		return
 	}
+	recordChannelBatchAudit(c, model.AuditActionDelete, originChannels, nil)
 	model.InitChannelCache()
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
@@ -958,6 +1030,9 @@ func UpdateChannel(c *gin.Context) {
 		common.ApiError(c, err)
 		return
 	}
+	if updatedChannel, err := model.GetChannelById(channel.Id, true); err == nil {
+		recordAudit(c, model.AuditActionUpdate, model.AuditTargetChannel, channel.Id, originChannel, updatedChannel)
+	}
 	model.InitChannelCache()
 	service.ResetProxyClientCache()
 	channel.Key = ""
@@ -1100,11 +1175,17 @@ func BatchSetChannelTag(c *gin.Context) {
 		})
 		return
 	}
+	originChannels, err := model.GetChannelsByIds(channelBatch.Ids)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
 	err = model.BatchSetChannelTag(channelBatch.Ids, channelBatch.Tag)
 	if err != nil {
 		common.ApiError(c, err)
 		return
 	}
+	recordChannelBatchUpdateAudit(c, originChannels)
 	model.InitChannelCache()
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
@@ -1197,11 +1278,14 @@ func CopyChannel(c *gin.Context) {
 	}
 
 	// insert
-	if err := model.BatchInsertChannels([]model.Channel{clone}); err != nil {
+	clones := []model.Channel{clone}
+	if err := model.BatchInsertChannels(clones); err != nil {
 		common.SysError("failed to clone channel: " + err.Error())
 		c.JSON(http.StatusOK, gin.H{"success": false, "message": "复制渠道失败，请稍后重试"})
 		return
 	}
+	clone = clones[0]
+	recordAudit(c, model.AuditActionCreate, model.AuditTargetChannel, clone.Id, nil, clone)
 	model.InitChannelCache()
 	// success
 	c.JSON(http.StatusOK, gin.H{"success": true, "message": "", "data": gin.H{"id": clone.Id}})
@@ -1268,6 +1352,12 @@ func ManageMultiKeys(c *gin.Context) {
 	lock.Lock()
 	defer lock.Unlock()
 
+	// 多 key 操作会原地修改 channel，先单独读取一份修改前的快照用于审计
+	var originChannel *model.Channel
+	if request.Action != "get_key_status" {
+		originChannel, _ = model.GetChannelById(channel.Id, true)
+	}
+
 	switch request.Action {
 	case "get_key_status":
 		keys := channel.GetKeys()
@@ -1419,6 +1509,7 @@ func ManageMultiKeys(c *gin.Context) {
 			return
 		}
 
+		recordAudit(c, request.Action, model.AuditTargetChannel, channel.Id, originChannel, channel)
 		model.InitChannelCache()
 		c.JSON(http.StatusOK, gin.H{
 			"success": true,
@@ -1461,6 +1552,7 @@ func ManageMultiKeys(c *gin.Context) {
 			return
 		}
 
+		recordAudit(c, request.Action, model.AuditTargetChannel, channel.Id, originChannel, channel)
 		model.InitChannelCache()
 		c.JSON(http.StatusOK, gin.H{
 			"success": true,
@@ -1485,6 +1577,7 @@ func ManageMultiKeys(c *gin.Context) {
 			return
 		}
 
+		recordAudit(c, request.Action, model.AuditTargetChannel, channel.Id, originChannel, channel)
 		model.InitChannelCache()
 		c.JSON(http.StatusOK, gin.H{
 			"success": true,
@@ -1532,6 +1625,7 @@ func ManageMultiKeys(c *gin.Context) {
 			return
 		}
 
+		recordAudit(c, request.Action, model.AuditTargetChannel, channel.Id, originChannel, channel)
 		model.InitChannelCache()
 		c.JSON(http.StatusOK, gin.H{
 			"success": true,
@@ -1612,6 +1706,7 @@ func ManageMultiKeys(c *gin.Context) {
 			return
 		}
 
+		recordAudit(c, request.Action, model.AuditTargetChannel, channel.Id, originChannel, channel)
 		model.InitChannelCache()
 		c.JSON(http.StatusOK, gin.H{
 			"success": true,
@@ -1680,6 +1775,7 @@ func ManageMultiKeys(c *gin.Context) {
 			return
 		}
 
+		recordAudit(c, request.Action, model.AuditTargetChannel, channel.Id, originChannel, channel)
 		model.InitChannelCache()
 		c.JSON(http.StatusOK, gin.H{
 			"success": true,
@@ -35,14 +35,14 @@ func MigrateConsoleSetting(c *gin.Context) {
 				arr = arr[:50]
 			}
 			bytes, _ := json.Marshal(arr)
-			model.UpdateOption("console_setting.api_info", string(bytes))
+			updateOptionWithAudit(c, "console_setting.api_info", string(bytes))
 		}
-		model.UpdateOption("ApiInfo", "")
+		updateOptionWithAudit(c, "ApiInfo", "")
 	}
 	// Announcements 直接搬
 	if v := valMap["Announcements"]; v != "" {
-		model.UpdateOption("console_setting.announcements", v)
-		model.UpdateOption("Announcements", "")
+		updateOptionWithAudit(c, "console_setting.announcements", v)
+		updateOptionWithAudit(c, "Announcements", "")
 	}
 	// FAQ 转换
 	if v := valMap["FAQ"]; v != "" {
@@ -66,9 +66,9 @@ func MigrateConsoleSetting(c *gin.Context) {
 				out = out[:50]
 			}
 			bytes, _ := json.Marshal(out)
-			model.UpdateOption("console_setting.faq", string(bytes))
+			updateOptionWithAudit(c, "console_setting.faq", string(bytes))
 		}
-		model.UpdateOption("FAQ", "")
+		updateOptionWithAudit(c, "FAQ", "")
 	}
 	// Uptime Kuma 迁移到新的 groups 结构（console_setting.uptime_kuma_groups）
 	url := valMap["UptimeKumaUrl"]
@@ -85,14 +85,14 @@ func MigrateConsoleSetting(c *gin.Context) {
 			},
 		}
 		bytes, _ := json.Marshal(groups)
-		model.UpdateOption("console_setting.uptime_kuma_groups", string(bytes))
+		updateOptionWithAudit(c, "console_setting.uptime_kuma_groups", string(bytes))
 	}
 	// 清空旧键内容
 	if url != "" {
-		model.UpdateOption("UptimeKumaUrl", "")
+		updateOptionWithAudit(c, "UptimeKumaUrl", "")
 	}
 	if slug != "" {
-		model.UpdateOption("UptimeKumaSlug", "")
+		updateOptionWithAudit(c, "UptimeKumaSlug", "")
 	}
 
 	// 删除旧键记录
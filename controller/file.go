@@ -0,0 +1,315 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/service"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+	"github.com/QuantumNous/new-api/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+func fileError(c *gin.Context, newAPIError *types.NewAPIError) {
+	c.JSON(newAPIError.StatusCode, gin.H{
+		"error": newAPIError.ToOpenAIError(),
+	})
+}
+
+func fileNotFound(c *gin.Context, fileId string) {
+	c.JSON(http.StatusNotFound, gin.H{
+		"error": types.OpenAIError{
+			Message: fmt.Sprintf("No such File object: %s", fileId),
+			Type:    "invalid_request_error",
+			Param:   "id",
+			Code:    "file_not_found",
+		},
+	})
+}
+
+func fileToDTO(file *model.File) *dto.OpenAIFile {
+	return &dto.OpenAIFile{
+		Id:        file.FileId,
+		Object:    "file",
+		Bytes:     file.Bytes,
+		CreatedAt: file.CreatedAt,
+		ExpiresAt: file.ExpiresAt,
+		Filename:  file.Filename,
+		Purpose:   file.Purpose,
+		Status:    file.Status,
+	}
+}
+
+type uploadFileForm struct {
+	Purpose  string
+	Filename string
+	Bytes    int64
+}
+
+// parseUploadFileForm 流式扫描 multipart 请求体，只读取 purpose 与文件大小，不把文件读入内存
+func parseUploadFileForm(storage common.BodyStorage, contentType string) (*uploadFileForm, error) {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil || mediaType != gin.MIMEMultipartPOSTForm {
+		return nil, errors.New("content type must be multipart/form-data")
+	}
+	boundary := params["boundary"]
+	if boundary == "" {
+		return nil, errors.New("multipart boundary not found")
+	}
+	if _, err = storage.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	form := &uploadFileForm{}
+	hasFile := false
+	reader := multipart.NewReader(common.ReaderOnly(storage), boundary)
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch part.FormName() {
+		case "purpose":
+			value, err := io.ReadAll(io.LimitReader(part, 256))
+			if err != nil {
+				return nil, err
+			}
+			form.Purpose = strings.TrimSpace(string(value))
+		case "file":
+			hasFile = true
+			form.Filename = part.FileName()
+			form.Bytes, err = io.Copy(io.Discard, part)
+			if err != nil {
+				return nil, err
+			}
+		}
+		_ = part.Close()
+	}
+	if !hasFile {
+		return nil, errors.New("missing required parameter: 'file'")
+	}
+	if form.Purpose == "" {
+		return nil, errors.New("missing required parameter: 'purpose'")
+	}
+	if _, err = storage.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	return form, nil
+}
+
+// FileUpload POST /v1/files
+// 请求体经 BodyStorage 缓存（大文件落盘），原样转发给 Distribute 选中的渠道，并记录文件与渠道的绑定关系
+func FileUpload(c *gin.Context) {
+	channelId := common.GetContextKeyInt(c, constant.ContextKeyChannelId)
+	channel, err := model.CacheGetChannel(channelId)
+	if err != nil {
+		fileError(c, types.NewError(err, types.ErrorCodeGetChannelFailed, types.ErrOptionWithSkipRetry()))
+		return
+	}
+	if !service.IsOpenAIFileChannel(channel.Type) {
+		fileError(c, types.NewErrorWithStatusCode(fmt.Errorf("channel type %d does not support the files API", channel.Type),
+			types.ErrorCodeInvalidApiType, http.StatusBadRequest, types.ErrOptionWithSkipRetry()))
+		return
+	}
+
+	storage, err := common.GetBodyStorage(c)
+	if err != nil {
+		fileError(c, types.NewErrorWithStatusCode(err, types.ErrorCodeReadRequestBodyFailed, http.StatusBadRequest, types.ErrOptionWithSkipRetry()))
+		return
+	}
+	contentType := c.Request.Header.Get("Content-Type")
+	form, err := parseUploadFileForm(storage, contentType)
+	if err != nil {
+		fileError(c, types.NewErrorWithStatusCode(err, types.ErrorCodeInvalidRequest, http.StatusBadRequest, types.ErrOptionWithSkipRetry()))
+		return
+	}
+	if maxMB := operation_setting.GetFileSetting().MaxFileSizeMB; maxMB > 0 && form.Bytes > int64(maxMB)<<20 {
+		fileError(c, types.NewErrorWithStatusCode(fmt.Errorf("file size exceeds the limit of %d MB", maxMB),
+			types.ErrorCodeInvalidRequest, http.StatusRequestEntityTooLarge, types.ErrOptionWithSkipRetry()))
+		return
+	}
+
+	keyIndex := common.GetContextKeyInt(c, constant.ContextKeyChannelMultiKeyIndex)
+	resp, err := service.DoOpenAIFileRequest(c.Request.Context(), channel, keyIndex, http.MethodPost, "files",
+		common.ReaderOnly(storage), contentType, storage.Size())
+	if err != nil {
+		fileError(c, types.NewOpenAIError(err, types.ErrorCodeDoRequestFailed, http.StatusBadGateway))
+		return
+	}
+	if resp.StatusCode != http.StatusOK {
+		fileError(c, service.RelayErrorHandler(c.Request.Context(), resp, false))
+		return
+	}
+	defer service.CloseResponseBodyGracefully(resp)
+	var upstreamFile dto.OpenAIFile
+	if err = common.DecodeJson(resp.Body, &upstreamFile); err != nil || upstreamFile.Id == "" {
+		fileError(c, types.NewOpenAIError(fmt.Errorf("invalid upstream file response: %v", err), types.ErrorCodeBadResponseBody, http.StatusBadGateway))
+		return
+	}
+
+	file := &model.File{
+		FileId:         upstreamFile.Id,
+		UserId:         c.GetInt("id"),
+		TokenId:        common.GetContextKeyInt(c, constant.ContextKeyTokenId),
+		Purpose:        common.GetStringIfEmpty(upstreamFile.Purpose, form.Purpose),
+		Filename:       common.GetStringIfEmpty(upstreamFile.Filename, form.Filename),
+		Bytes:          upstreamFile.Bytes,
+		Status:         common.GetStringIfEmpty(upstreamFile.Status, "processed"),
+		ChannelId:      channel.Id,
+		ChannelKeyIdx:  keyIndex,
+		UpstreamFileId: upstreamFile.Id,
+		ExpiresAt:      upstreamFile.ExpiresAt,
+		CreatedAt:      upstreamFile.CreatedAt,
+	}
+	if file.Bytes == 0 {
+		file.Bytes = form.Bytes
+	}
+	if err = file.Insert(); err != nil {
+		logger.LogError(c, fmt.Sprintf("failed to save file %s uploaded to channel #%d: %s", file.FileId, channel.Id, err.Error()))
+		fileError(c, types.NewError(err, types.ErrorCodeUpdateDataError, types.ErrOptionWithSkipRetry()))
+		return
+	}
+	c.JSON(http.StatusOK, fileToDTO(file))
+}
+
+// FileList GET /v1/files
+func FileList(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	if limit <= 0 || limit > 10000 {
+		limit = 10000
+	}
+	ascending := c.Query("order") == "asc"
+	files, hasMore, err := model.ListUserFiles(c.GetInt("id"), c.Query("purpose"), c.Query("after"), limit, ascending)
+	if err != nil {
+		fileError(c, types.NewError(err, types.ErrorCodeQueryDataError, types.ErrOptionWithSkipRetry()))
+		return
+	}
+	list := &dto.OpenAIFileList{
+		Object:  "list",
+		Data:    make([]*dto.OpenAIFile, 0, len(files)),
+		HasMore: hasMore,
+	}
+	for _, file := range files {
+		list.Data = append(list.Data, fileToDTO(file))
+	}
+	if len(files) > 0 {
+		list.FirstId = files[0].FileId
+		list.LastId = files[len(files)-1].FileId
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+func getUserFileOrAbort(c *gin.Context) *model.File {
+	fileId := c.Param("id")
+	file, err := model.GetUserFileById(c.GetInt("id"), fileId)
+	if err != nil {
+		fileError(c, types.NewError(err, types.ErrorCodeQueryDataError, types.ErrOptionWithSkipRetry()))
+		return nil
+	}
+	if file == nil {
+		fileNotFound(c, fileId)
+		return nil
+	}
+	return file
+}
+
+// FileRetrieve GET /v1/files/:id
+func FileRetrieve(c *gin.Context) {
+	file := getUserFileOrAbort(c)
+	if file == nil {
+		return
+	}
+	c.JSON(http.StatusOK, fileToDTO(file))
+}
+
+// FileDelete DELETE /v1/files/:id
+func FileDelete(c *gin.Context) {
+	file := getUserFileOrAbort(c)
+	if file == nil {
+		return
+	}
+	channel, err := service.GetPinnedFileChannel(file)
+	if file.IsLocal() {
+		// 本地生成的文件（如本地批处理输出）只需删除磁盘文件
+		if err := os.Remove(file.StoragePath); err != nil && !os.IsNotExist(err) {
+			logger.LogWarn(c, fmt.Sprintf("failed to remove local file %s: %s", file.StoragePath, err.Error()))
+		}
+	} else if err == nil {
+		resp, err := service.DoOpenAIFileRequest(c.Request.Context(), channel, file.ChannelKeyIdx, http.MethodDelete,
+			"files/"+file.UpstreamFileId, nil, "", -1)
+		if err != nil {
+			fileError(c, types.NewOpenAIError(err, types.ErrorCodeDoRequestFailed, http.StatusBadGateway))
+			return
+		}
+		// 上游已不存在时仍然删除本地记录
+		if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+			fileError(c, service.RelayErrorHandler(c.Request.Context(), resp, false))
+			return
+		}
+		service.CloseResponseBodyGracefully(resp)
+	} else {
+		logger.LogWarn(c, fmt.Sprintf("delete file %s locally only: %s", file.FileId, err.Error()))
+	}
+	if err := file.Delete(); err != nil {
+		fileError(c, types.NewError(err, types.ErrorCodeUpdateDataError, types.ErrOptionWithSkipRetry()))
+		return
+	}
+	c.JSON(http.StatusOK, &dto.OpenAIFileDeleteResponse{
+		Id:      file.FileId,
+		Object:  "file",
+		Deleted: true,
+	})
+}
+
+// FileContent GET /v1/files/:id/content
+func FileContent(c *gin.Context) {
+	file := getUserFileOrAbort(c)
+	if file == nil {
+		return
+	}
+	if file.IsLocal() {
+		c.Header("Content-Type", "application/octet-stream")
+		c.File(file.StoragePath)
+		return
+	}
+	channel, err := service.GetPinnedFileChannel(file)
+	if err != nil {
+		fileError(c, types.NewOpenAIError(err, types.ErrorCodeGetChannelFailed, http.StatusServiceUnavailable))
+		return
+	}
+	resp, err := service.DoOpenAIFileRequest(c.Request.Context(), channel, file.ChannelKeyIdx, http.MethodGet,
+		"files/"+file.UpstreamFileId+"/content", nil, "", -1)
+	if err != nil {
+		fileError(c, types.NewOpenAIError(err, types.ErrorCodeDoRequestFailed, http.StatusBadGateway))
+		return
+	}
+	if resp.StatusCode != http.StatusOK {
+		fileError(c, service.RelayErrorHandler(c.Request.Context(), resp, false))
+		return
+	}
+	defer service.CloseResponseBodyGracefully(resp)
+	for _, key := range []string{"Content-Type", "Content-Length", "Content-Disposition"} {
+		if value := resp.Header.Get(key); value != "" {
+			c.Writer.Header().Set(key, value)
+		}
+	}
+	c.Writer.WriteHeader(http.StatusOK)
+	if _, err = io.Copy(c.Writer, resp.Body); err != nil {
+		logger.LogError(c, fmt.Sprintf("failed to stream content of file %s: %s", file.FileId, err.Error()))
+	}
+}
@@ -0,0 +1,64 @@
+package controller
+
+import (
+	"bytes"
+	"io"
+	"mime/multipart"
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/stretchr/testify/require"
+)
+
+func buildUploadBody(t *testing.T, purpose string, content []byte) ([]byte, string) {
+	t.Helper()
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+	if purpose != "" {
+		require.NoError(t, writer.WriteField("purpose", purpose))
+	}
+	if content != nil {
+		part, err := writer.CreateFormFile("file", "batch.jsonl")
+		require.NoError(t, err)
+		_, err = part.Write(content)
+		require.NoError(t, err)
+	}
+	require.NoError(t, writer.Close())
+	return buf.Bytes(), writer.FormDataContentType()
+}
+
+func TestParseUploadFileForm(t *testing.T) {
+	content := []byte(`{"custom_id":"1"}` + "\n")
+	body, contentType := buildUploadBody(t, "batch", content)
+	storage, err := common.CreateBodyStorage(body)
+	require.NoError(t, err)
+	defer storage.Close()
+
+	form, err := parseUploadFileForm(storage, contentType)
+	require.NoError(t, err)
+	require.Equal(t, "batch", form.Purpose)
+	require.Equal(t, "batch.jsonl", form.Filename)
+	require.Equal(t, int64(len(content)), form.Bytes)
+
+	// 解析后需要回到起点，保证原始请求体可以完整转发
+	forwarded, err := io.ReadAll(storage)
+	require.NoError(t, err)
+	require.Equal(t, body, forwarded)
+}
+
+func TestParseUploadFileFormMissingFields(t *testing.T) {
+	body, contentType := buildUploadBody(t, "", []byte("data"))
+	storage, err := common.CreateBodyStorage(body)
+	require.NoError(t, err)
+	_, err = parseUploadFileForm(storage, contentType)
+	require.ErrorContains(t, err, "purpose")
+
+	body, contentType = buildUploadBody(t, "assistants", nil)
+	storage, err = common.CreateBodyStorage(body)
+	require.NoError(t, err)
+	_, err = parseUploadFileForm(storage, contentType)
+	require.ErrorContains(t, err, "file")
+
+	_, err = parseUploadFileForm(storage, "application/json")
+	require.Error(t, err)
+}
@@ -0,0 +1,199 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/service"
+	"github.com/QuantumNous/new-api/types"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tidwall/sjson"
+)
+
+func fineTuningJobNotFound(c *gin.Context, jobId string) {
+	c.JSON(http.StatusNotFound, gin.H{
+		"error": types.OpenAIError{
+			Message: fmt.Sprintf("Could not find fine tune: %s", jobId),
+			Type:    "invalid_request_error",
+			Param:   "fine_tune_id",
+			Code:    "fine_tune_not_found",
+		},
+	})
+}
+
+// fineTuningJobToResponse 将上游任务对象中的 id 替换为公开的 task id
+func fineTuningJobToResponse(task *model.Task) json.RawMessage {
+	if len(task.Data) == 0 {
+		return json.RawMessage("{}")
+	}
+	data, err := sjson.SetBytes(task.Data, "id", task.TaskID)
+	if err != nil {
+		return task.Data
+	}
+	return data
+}
+
+func getUserFineTuningJobOrAbort(c *gin.Context) *model.Task {
+	jobId := c.Param("id")
+	task, exist, err := model.GetByTaskId(c.GetInt("id"), jobId)
+	if err != nil {
+		fileError(c, types.NewError(err, types.ErrorCodeQueryDataError, types.ErrOptionWithSkipRetry()))
+		return nil
+	}
+	if !exist || task.Platform != constant.TaskPlatformFineTuning {
+		fineTuningJobNotFound(c, jobId)
+		return nil
+	}
+	return task
+}
+
+// doFineTuningJobRequest 在任务提交时的渠道和 key 上请求上游微调接口
+func doFineTuningJobRequest(c *gin.Context, task *model.Task, method string, path string) (*http.Response, bool) {
+	channel, err := model.CacheGetChannel(task.ChannelId)
+	if err != nil {
+		fileError(c, types.NewOpenAIError(err, types.ErrorCodeGetChannelFailed, http.StatusServiceUnavailable))
+		return nil, false
+	}
+	key := task.PrivateData.Key
+	if key == "" {
+		key = service.GetChannelKeyByIndex(channel, 0)
+	}
+	resp, err := service.DoOpenAIFileRequestWithKey(c.Request.Context(), channel, key, method,
+		"fine_tuning/jobs/"+task.GetUpstreamTaskID()+path, nil, "", -1)
+	if err != nil {
+		fileError(c, types.NewOpenAIError(err, types.ErrorCodeDoRequestFailed, http.StatusBadGateway))
+		return nil, false
+	}
+	if resp.StatusCode != http.StatusOK {
+		fileError(c, service.RelayErrorHandler(c.Request.Context(), resp, false))
+		return nil, false
+	}
+	return resp, true
+}
+
+// FineTuningJobList GET /v1/fine_tuning/jobs
+func FineTuningJobList(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	if limit <= 0 {
+		limit = 20
+	} else if limit > 100 {
+		limit = 100
+	}
+	userId := c.GetInt("id")
+	var afterId int64
+	if after := c.Query("after"); after != "" {
+		task, exist, err := model.GetByTaskId(userId, after)
+		if err != nil {
+			fileError(c, types.NewError(err, types.ErrorCodeQueryDataError, types.ErrOptionWithSkipRetry()))
+			return
+		}
+		if exist {
+			afterId = task.ID
+		}
+	}
+	tasks, hasMore, err := model.GetUserTasksByPlatform(userId, constant.TaskPlatformFineTuning, afterId, limit)
+	if err != nil {
+		fileError(c, types.NewError(err, types.ErrorCodeQueryDataError, types.ErrOptionWithSkipRetry()))
+		return
+	}
+	data := make([]json.RawMessage, 0, len(tasks))
+	for _, task := range tasks {
+		data = append(data, fineTuningJobToResponse(task))
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"object":   "list",
+		"data":     data,
+		"has_more": hasMore,
+	})
+}
+
+// FineTuningJobRetrieve GET /v1/fine_tuning/jobs/:id
+func FineTuningJobRetrieve(c *gin.Context) {
+	task := getUserFineTuningJobOrAbort(c)
+	if task == nil {
+		return
+	}
+	c.Data(http.StatusOK, "application/json", fineTuningJobToResponse(task))
+}
+
+// FineTuningJobCancel POST /v1/fine_tuning/jobs/:id/cancel
+// 上游确认取消后立即将任务置为失败并退还预扣额度，与轮询通过状态 CAS 互斥，只有一方结算
+func FineTuningJobCancel(c *gin.Context) {
+	task := getUserFineTuningJobOrAbort(c)
+	if task == nil {
+		return
+	}
+	if task.Status == model.TaskStatusSuccess || task.Status == model.TaskStatusFailure {
+		fileError(c, types.NewErrorWithStatusCode(fmt.Errorf("job %s has already finished", task.TaskID),
+			types.ErrorCodeInvalidRequest, http.StatusBadRequest, types.ErrOptionWithSkipRetry()))
+		return
+	}
+	resp, ok := doFineTuningJobRequest(c, task, http.MethodPost, "/cancel")
+	if !ok {
+		return
+	}
+	defer service.CloseResponseBodyGracefully(resp)
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		fileError(c, types.NewOpenAIError(err, types.ErrorCodeReadResponseBodyFailed, http.StatusBadGateway))
+		return
+	}
+	fromStatus := task.Status
+	task.Data = body
+	var job dto.FineTuningJob
+	if err := common.Unmarshal(body, &job); err == nil && job.Status == "cancelled" {
+		task.Status = model.TaskStatusFailure
+		task.Progress = "100%"
+		task.FinishTime = common.GetTimestamp()
+		task.FailReason = "fine-tuning job cancelled"
+	}
+	won, err := task.UpdateWithStatus(fromStatus)
+	if err != nil {
+		logger.LogError(c, fmt.Sprintf("failed to update cancelled fine-tuning job %s: %s", task.TaskID, err.Error()))
+	} else if won && task.Status == model.TaskStatusFailure {
+		service.RefundTaskQuota(c, task, task.FailReason)
+		service.EnqueueTaskCallback(c, task)
+	}
+	c.Data(http.StatusOK, "application/json", fineTuningJobToResponse(task))
+}
+
+// proxyFineTuningJobList 透传上游的 events / checkpoints 列表（保留分页参数）
+func proxyFineTuningJobList(c *gin.Context, subPath string) {
+	task := getUserFineTuningJobOrAbort(c)
+	if task == nil {
+		return
+	}
+	path := subPath
+	if c.Request.URL.RawQuery != "" {
+		path += "?" + c.Request.URL.RawQuery
+	}
+	resp, ok := doFineTuningJobRequest(c, task, http.MethodGet, path)
+	if !ok {
+		return
+	}
+	defer service.CloseResponseBodyGracefully(resp)
+	c.Writer.Header().Set("Content-Type", "application/json")
+	c.Writer.WriteHeader(http.StatusOK)
+	if _, err := io.Copy(c.Writer, resp.Body); err != nil {
+		logger.LogError(c, fmt.Sprintf("failed to stream %s of fine-tuning job %s: %s", subPath, task.TaskID, err.Error()))
+	}
+}
+
+// FineTuningJobEvents GET /v1/fine_tuning/jobs/:id/events
+func FineTuningJobEvents(c *gin.Context) {
+	proxyFineTuningJobList(c, "/events")
+}
+
+// FineTuningJobCheckpoints GET /v1/fine_tuning/jobs/:id/checkpoints
+func FineTuningJobCheckpoints(c *gin.Context) {
+	proxyFineTuningJobList(c, "/checkpoints")
+}
@@ -0,0 +1,154 @@
+package controller
+
+import (
+	"strconv"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+const maxUserManagementKeys = 20
+
+type managementKeyRequest struct {
+	Id          int      `json:"id"`
+	Name        string   `json:"name"`
+	Scopes      []string `json:"scopes"`
+	AllowIps    *string  `json:"allow_ips"`
+	ExpiredTime int64    `json:"expired_time"`
+	Status      int      `json:"status"`
+}
+
+func (req *managementKeyRequest) validate() ([]string, string) {
+	if req.Name == "" || len(req.Name) > 64 {
+		return nil, "名称不能为空且长度不能超过 64"
+	}
+	if req.ExpiredTime != -1 && req.ExpiredTime != 0 && req.ExpiredTime < common.GetTimestamp() {
+		return nil, "过期时间不能早于当前时间"
+	}
+	scopes, err := model.NormalizeManagementScopes(req.Scopes)
+	if err != nil {
+		return nil, err.Error()
+	}
+	if len(scopes) == 0 {
+		return nil, "至少需要选择一个权限范围"
+	}
+	return scopes, ""
+}
+
+// GetManagementScopes 获取可分配给管理密钥的权限范围
+func GetManagementScopes(c *gin.Context) {
+	common.ApiSuccess(c, model.ManagementScopes)
+}
+
+// GetManagementKeys 获取当前用户的管理密钥列表
+func GetManagementKeys(c *gin.Context) {
+	keys, err := model.GetUserManagementKeys(c.GetInt("id"))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, keys)
+}
+
+// CreateManagementKey 创建管理密钥，明文密钥仅在此处返回一次
+func CreateManagementKey(c *gin.Context) {
+	var req managementKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	scopes, msg := req.validate()
+	if msg != "" {
+		common.ApiErrorMsg(c, msg)
+		return
+	}
+	userId := c.GetInt("id")
+	keys, err := model.GetUserManagementKeys(userId)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if len(keys) >= maxUserManagementKeys {
+		common.ApiErrorMsg(c, "已达到管理密钥数量上限 ("+strconv.Itoa(maxUserManagementKeys)+")")
+		return
+	}
+	rawKey, err := model.GenerateManagementKey()
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	key := model.ManagementKey{
+		UserId:      userId,
+		Name:        req.Name,
+		AllowIps:    req.AllowIps,
+		Status:      common.TokenStatusEnabled,
+		ExpiredTime: req.ExpiredTime,
+	}
+	if key.ExpiredTime == 0 {
+		key.ExpiredTime = -1
+	}
+	key.SetScopes(scopes)
+	if err := key.Insert(rawKey); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, gin.H{
+		"key":            rawKey,
+		"management_key": key,
+	})
+}
+
+// UpdateManagementKey 更新管理密钥的名称、权限、IP 限制、过期时间与状态
+func UpdateManagementKey(c *gin.Context) {
+	var req managementKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	scopes, msg := req.validate()
+	if msg != "" {
+		common.ApiErrorMsg(c, msg)
+		return
+	}
+	key, err := model.GetManagementKeyByIds(req.Id, c.GetInt("id"))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	key.Name = req.Name
+	key.AllowIps = req.AllowIps
+	key.ExpiredTime = req.ExpiredTime
+	if key.ExpiredTime == 0 {
+		key.ExpiredTime = -1
+	}
+	if req.Status == common.TokenStatusEnabled || req.Status == common.TokenStatusDisabled {
+		key.Status = req.Status
+	}
+	key.SetScopes(scopes)
+	if err := key.Update(); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, key)
+}
+
+// DeleteManagementKey 删除管理密钥
+func DeleteManagementKey(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	key, err := model.GetManagementKeyByIds(id, c.GetInt("id"))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if err := key.Delete(); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, nil)
+}
@@ -0,0 +1,64 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/pkg/mediastore"
+	"github.com/QuantumNous/new-api/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// serveMediaObject 从媒体存储读取产物并写回，读取失败时不写任何内容，由调用方决定如何处理
+func serveMediaObject(c *gin.Context, object *model.MediaObject) error {
+	store, err := service.GetMediaStore(object.Backend)
+	if err != nil {
+		return err
+	}
+	body, size, err := store.Get(c.Request.Context(), object.Key)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+	if size <= 0 {
+		size = -1
+	}
+	c.DataFromReader(http.StatusOK, size, object.ContentType, body, map[string]string{
+		"Cache-Control": "private, max-age=86400",
+	})
+	return nil
+}
+
+// MediaContent 通过签名地址访问持久化的任务产物，签名即鉴权
+func MediaContent(c *gin.Context) {
+	key := c.Param("key")
+	expiresAt, _ := strconv.ParseInt(c.Query("expires"), 10, 64)
+	if !service.VerifyMediaSignature(key, expiresAt, c.Query("signature"), common.GetTimestamp()) {
+		videoProxyError(c, http.StatusForbidden, "invalid_request_error", "Invalid or expired media url")
+		return
+	}
+	object, err := model.GetMediaObjectByKey(key)
+	if err != nil {
+		logger.LogError(c.Request.Context(), fmt.Sprintf("Failed to query media object %s: %s", key, err.Error()))
+		videoProxyError(c, http.StatusInternalServerError, "server_error", "Failed to query media object")
+		return
+	}
+	if object == nil {
+		videoProxyError(c, http.StatusNotFound, "invalid_request_error", "Media not found")
+		return
+	}
+	if err := serveMediaObject(c, object); err != nil {
+		if errors.Is(err, mediastore.ErrNotFound) {
+			videoProxyError(c, http.StatusNotFound, "invalid_request_error", "Media not found")
+			return
+		}
+		logger.LogError(c.Request.Context(), fmt.Sprintf("Failed to read media object %s: %s", key, err.Error()))
+		videoProxyError(c, http.StatusBadGateway, "server_error", "Failed to read media content")
+	}
+}
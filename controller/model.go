@@ -180,6 +180,14 @@ func ListModels(c *gin.Context, modelType int) {
 		} else {
 			models = model.GetGroupEnabledModels(group)
 		}
+		// 用户自己的微调模型不在分组 abilities 中，单独追加
+		if fineTunedModels, err := model.GetUserFineTunedModelNames(userId); err == nil {
+			for _, name := range fineTunedModels {
+				if !common.StringsContains(models, name) {
+					models = append(models, name)
+				}
+			}
+		}
 		for _, modelName := range models {
 			if !acceptUnsetRatioModel {
 				_, _, exist := ratio_setting.GetModelRatioOrPrice(modelName)
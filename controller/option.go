@@ -233,6 +233,24 @@ func UpdateOption(c *gin.Context) {
 			})
 			return
 		}
+	case "BatchRatio":
+		err = ratio_setting.UpdateBatchRatioByJSONString(option.Value.(string))
+		if err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": "批处理倍率设置失败: " + err.Error(),
+			})
+			return
+		}
+	case "FineTuningRatio":
+		err = ratio_setting.UpdateFineTuningRatioByJSONString(option.Value.(string))
+		if err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": "微调倍率设置失败: " + err.Error(),
+			})
+			return
+		}
 	case "ModelRequestRateLimitGroup":
 		err = setting.CheckModelRequestRateLimitGroup(option.Value.(string))
 		if err != nil {
@@ -287,6 +305,33 @@ func UpdateOption(c *gin.Context) {
 			})
 			return
 		}
+	case "log_setting.level":
+		err = operation_setting.ValidateLogLevel(option.Value.(string))
+		if err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
+		}
+	case "log_setting.module_levels":
+		err = operation_setting.ValidateLogModuleLevels(option.Value.(string))
+		if err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
+		}
+	case "moderation_setting.policies":
+		err = operation_setting.ValidateModerationPolicies(option.Value.(string))
+		if err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
+		}
 	case "console_setting.uptime_kuma_groups":
 		err = console_setting.ValidateConsoleSettings(option.Value.(string), "UptimeKumaGroups")
 		if err != nil {
@@ -297,7 +342,7 @@ func UpdateOption(c *gin.Context) {
 			return
 		}
 	}
-	err = model.UpdateOption(option.Key, option.Value.(string))
+	err = updateOptionWithAudit(c, option.Key, option.Value.(string))
 	if err != nil {
 		common.ApiError(c, err)
 		return
@@ -308,3 +353,19 @@ func UpdateOption(c *gin.Context) {
 	})
 	return
 }
+
+// updateOptionWithAudit 更新配置项并记录修改前后的值，所有管理端修改配置的入口都应经过这里
+func updateOptionWithAudit(c *gin.Context, key string, value string) error {
+	common.OptionMapRWMutex.RLock()
+	originValue, hasOrigin := common.OptionMap[key]
+	common.OptionMapRWMutex.RUnlock()
+	if err := model.UpdateOption(key, value); err != nil {
+		return err
+	}
+	var before any
+	if hasOrigin {
+		before = map[string]string{key: originValue}
+	}
+	recordAudit(c, model.AuditActionUpdate, model.AuditTargetOption, key, before, map[string]string{key: value})
+	return nil
+}
@@ -0,0 +1,469 @@
+package controller
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/i18n"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/model"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type organizationRequest struct {
+	Name   string `json:"name"`
+	Status int    `json:"status"`
+}
+
+type organizationMemberRequest struct {
+	Username   string `json:"username"`
+	Role       string `json:"role"`
+	SpendLimit *int   `json:"spend_limit"`
+	ResetUsed  bool   `json:"reset_used"`
+}
+
+type organizationQuotaRequest struct {
+	Quota int `json:"quota"`
+}
+
+// organizationMemberFor 校验当前用户是 :id 组织的成员且角色不低于 required
+func organizationMemberFor(c *gin.Context, required string) (*model.OrganizationMember, bool) {
+	orgId, err := strconv.Atoi(c.Param("id"))
+	if err != nil || orgId <= 0 {
+		common.ApiErrorI18n(c, i18n.MsgOrganizationInvalidId)
+		return nil, false
+	}
+	member, err := model.GetOrganizationMember(orgId, c.GetInt("id"))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			common.ApiError(c, model.ErrOrganizationNotMember)
+		} else {
+			common.ApiError(c, err)
+		}
+		return nil, false
+	}
+	if !model.OrganizationRoleAtLeast(member.Role, required) {
+		common.ApiErrorI18n(c, i18n.MsgOrganizationPermissionDenied)
+		return nil, false
+	}
+	return member, true
+}
+
+// canManageOrganizationRole 所有者可以管理任意角色，管理员只能管理开发者与查看者
+func canManageOrganizationRole(operatorRole string, role string) bool {
+	if operatorRole == model.OrganizationRoleOwner {
+		return true
+	}
+	return model.OrganizationRoleAtLeast(operatorRole, model.OrganizationRoleAdmin) &&
+		!model.OrganizationRoleAtLeast(role, model.OrganizationRoleAdmin)
+}
+
+func validateOrganizationName(name string) (string, bool) {
+	name = strings.TrimSpace(name)
+	return name, name != "" && len(name) <= 64
+}
+
+// GetSelfOrganizations 获取当前用户加入的组织
+func GetSelfOrganizations(c *gin.Context) {
+	orgs, err := model.GetUserOrganizations(c.GetInt("id"))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, orgs)
+}
+
+// CreateOrganization 创建组织，创建者成为所有者
+func CreateOrganization(c *gin.Context) {
+	var req organizationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	name, ok := validateOrganizationName(req.Name)
+	if !ok {
+		common.ApiErrorI18n(c, i18n.MsgOrganizationNameLength)
+		return
+	}
+	org := model.Organization{Name: name, OwnerId: c.GetInt("id")}
+	if err := model.CreateOrganization(&org); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, org)
+}
+
+func GetOrganization(c *gin.Context) {
+	member, ok := organizationMemberFor(c, model.OrganizationRoleViewer)
+	if !ok {
+		return
+	}
+	org, err := model.GetOrganizationById(member.OrganizationId)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, model.OrganizationWithRole{Organization: *org, Role: member.Role})
+}
+
+func UpdateOrganization(c *gin.Context) {
+	member, ok := organizationMemberFor(c, model.OrganizationRoleAdmin)
+	if !ok {
+		return
+	}
+	var req organizationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	org, err := model.GetOrganizationById(member.OrganizationId)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if req.Name != "" {
+		name, ok := validateOrganizationName(req.Name)
+		if !ok {
+			common.ApiErrorI18n(c, i18n.MsgOrganizationNameLength)
+			return
+		}
+		org.Name = name
+	}
+	if req.Status != 0 {
+		if req.Status != model.OrganizationStatusEnabled && req.Status != model.OrganizationStatusDisabled {
+			common.ApiErrorI18n(c, i18n.MsgOrganizationInvalidStatus)
+			return
+		}
+		org.Status = req.Status
+	}
+	if err := model.UpdateOrganization(org); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, org)
+}
+
+// DeleteOrganization 删除组织，剩余额度退回所有者
+func DeleteOrganization(c *gin.Context) {
+	member, ok := organizationMemberFor(c, model.OrganizationRoleOwner)
+	if !ok {
+		return
+	}
+	if err := model.DeleteOrganization(member.OrganizationId); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, nil)
+}
+
+// DepositOrganizationQuota 将个人额度转入组织钱包
+func DepositOrganizationQuota(c *gin.Context) {
+	member, ok := organizationMemberFor(c, model.OrganizationRoleAdmin)
+	if !ok {
+		return
+	}
+	var req organizationQuotaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if err := model.DepositOrganizationQuota(member.OrganizationId, member.UserId, req.Quota); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	model.RecordLog(member.UserId, model.LogTypeManage,
+		"向组织 #"+strconv.Itoa(member.OrganizationId)+" 转入额度 "+logger.LogQuota(req.Quota))
+	common.ApiSuccess(c, nil)
+}
+
+func GetOrganizationMembers(c *gin.Context) {
+	member, ok := organizationMemberFor(c, model.OrganizationRoleViewer)
+	if !ok {
+		return
+	}
+	members, err := model.GetOrganizationMembers(member.OrganizationId)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, members)
+}
+
+// AddOrganizationMember 按用户名添加成员
+func AddOrganizationMember(c *gin.Context) {
+	operator, ok := organizationMemberFor(c, model.OrganizationRoleAdmin)
+	if !ok {
+		return
+	}
+	var req organizationMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if req.Role == "" {
+		req.Role = model.OrganizationRoleDeveloper
+	}
+	if !model.IsValidOrganizationRole(req.Role) || req.Role == model.OrganizationRoleOwner {
+		common.ApiErrorI18n(c, i18n.MsgOrganizationInvalidRole)
+		return
+	}
+	if !canManageOrganizationRole(operator.Role, req.Role) {
+		common.ApiErrorI18n(c, i18n.MsgOrganizationPermissionDenied)
+		return
+	}
+	userId, err := model.GetUserIdByUsername(strings.TrimSpace(req.Username))
+	if err != nil {
+		common.ApiErrorI18n(c, i18n.MsgUserNotExists)
+		return
+	}
+	if _, err := model.GetOrganizationMember(operator.OrganizationId, userId); err == nil {
+		common.ApiErrorI18n(c, i18n.MsgOrganizationMemberExists)
+		return
+	}
+	member := model.OrganizationMember{
+		OrganizationId: operator.OrganizationId,
+		UserId:         userId,
+		Role:           req.Role,
+	}
+	if req.SpendLimit != nil {
+		if *req.SpendLimit < 0 {
+			common.ApiErrorI18n(c, i18n.MsgOrganizationSpendLimitNegative)
+			return
+		}
+		member.SpendLimit = *req.SpendLimit
+	}
+	if err := member.Insert(); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, member)
+}
+
+// UpdateOrganizationMember 修改成员角色或消费上限，reset_used 清零成员已用额度
+func UpdateOrganizationMember(c *gin.Context) {
+	operator, ok := organizationMemberFor(c, model.OrganizationRoleAdmin)
+	if !ok {
+		return
+	}
+	userId, _ := strconv.Atoi(c.Param("user_id"))
+	member, err := model.GetOrganizationMember(operator.OrganizationId, userId)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	var req organizationMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if !canManageOrganizationRole(operator.Role, member.Role) {
+		common.ApiErrorI18n(c, i18n.MsgOrganizationPermissionDenied)
+		return
+	}
+	if req.Role != "" && req.Role != member.Role {
+		if !model.IsValidOrganizationRole(req.Role) {
+			common.ApiErrorI18n(c, i18n.MsgOrganizationInvalidRole)
+			return
+		}
+		if member.Role == model.OrganizationRoleOwner || req.Role == model.OrganizationRoleOwner {
+			common.ApiErrorI18n(c, i18n.MsgOrganizationOwnerImmutable)
+			return
+		}
+		if !canManageOrganizationRole(operator.Role, req.Role) {
+			common.ApiErrorI18n(c, i18n.MsgOrganizationPermissionDenied)
+			return
+		}
+		member.Role = req.Role
+	}
+	if req.SpendLimit != nil {
+		if *req.SpendLimit < 0 {
+			common.ApiErrorI18n(c, i18n.MsgOrganizationSpendLimitNegative)
+			return
+		}
+		member.SpendLimit = *req.SpendLimit
+	}
+	if req.ResetUsed {
+		member.UsedQuota = 0
+	}
+	if err := member.Update(); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, member)
+}
+
+// DeleteOrganizationMember 移除成员，成员也可以主动退出；所有者不能被移除
+func DeleteOrganizationMember(c *gin.Context) {
+	operator, ok := organizationMemberFor(c, model.OrganizationRoleViewer)
+	if !ok {
+		return
+	}
+	userId, _ := strconv.Atoi(c.Param("user_id"))
+	member, err := model.GetOrganizationMember(operator.OrganizationId, userId)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if member.Role == model.OrganizationRoleOwner {
+		common.ApiErrorI18n(c, i18n.MsgOrganizationOwnerCannotRemove)
+		return
+	}
+	if member.UserId != operator.UserId && !canManageOrganizationRole(operator.Role, member.Role) {
+		common.ApiErrorI18n(c, i18n.MsgOrganizationPermissionDenied)
+		return
+	}
+	if err := model.DeleteOrganizationMember(member.OrganizationId, member.UserId); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, nil)
+}
+
+// GetOrganizationTokens 获取组织令牌，查看者与开发者只能看到自己的令牌
+func GetOrganizationTokens(c *gin.Context) {
+	member, ok := organizationMemberFor(c, model.OrganizationRoleViewer)
+	if !ok {
+		return
+	}
+	tokens, err := model.GetOrganizationTokens(member.OrganizationId)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if !model.OrganizationRoleAtLeast(member.Role, model.OrganizationRoleAdmin) {
+		visible := make([]*model.Token, 0, len(tokens))
+		for _, token := range tokens {
+			if token.UserId == member.UserId {
+				visible = append(visible, token)
+			}
+		}
+		tokens = visible
+	}
+	common.ApiSuccess(c, buildMaskedTokenResponses(tokens))
+}
+
+// AddOrganizationToken 创建组织令牌，令牌归属当前成员，消费从组织钱包扣除
+func AddOrganizationToken(c *gin.Context) {
+	member, ok := organizationMemberFor(c, model.OrganizationRoleDeveloper)
+	if !ok {
+		return
+	}
+	cleanToken, ok := newTokenFromRequest(c, member.UserId)
+	if !ok {
+		return
+	}
+	cleanToken.OrganizationId = member.OrganizationId
+	if err := cleanToken.Insert(); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, buildMaskedTokenResponse(cleanToken))
+}
+
+// DeleteOrganizationToken 删除组织令牌，管理员可删除任意成员的令牌
+func DeleteOrganizationToken(c *gin.Context) {
+	member, ok := organizationMemberFor(c, model.OrganizationRoleViewer)
+	if !ok {
+		return
+	}
+	tokenId, _ := strconv.Atoi(c.Param("token_id"))
+	token, err := model.GetTokenById(tokenId)
+	if err != nil || token.OrganizationId != member.OrganizationId {
+		common.ApiErrorI18n(c, i18n.MsgOrganizationTokenNotFound)
+		return
+	}
+	if token.UserId != member.UserId && !model.OrganizationRoleAtLeast(member.Role, model.OrganizationRoleAdmin) {
+		common.ApiErrorI18n(c, i18n.MsgOrganizationPermissionDenied)
+		return
+	}
+	if err := token.Delete(); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, nil)
+}
+
+// GetOrganizationLogs 分页查询组织日志
+func GetOrganizationLogs(c *gin.Context) {
+	member, ok := organizationMemberFor(c, model.OrganizationRoleViewer)
+	if !ok {
+		return
+	}
+	pageInfo := common.GetPageQuery(c)
+	logType, _ := strconv.Atoi(c.Query("type"))
+	startTimestamp, _ := strconv.ParseInt(c.Query("start_timestamp"), 10, 64)
+	endTimestamp, _ := strconv.ParseInt(c.Query("end_timestamp"), 10, 64)
+	userId, _ := strconv.Atoi(c.Query("user_id"))
+	modelName := c.Query("model_name")
+	logs, total, err := model.GetOrganizationLogs(member.OrganizationId, logType, startTimestamp, endTimestamp, userId, modelName, pageInfo.GetStartIdx(), pageInfo.GetPageSize())
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	pageInfo.SetTotal(int(total))
+	pageInfo.SetItems(logs)
+	common.ApiSuccess(c, pageInfo)
+}
+
+// GetOrganizationUsage 按成员与模型汇总组织消费
+func GetOrganizationUsage(c *gin.Context) {
+	member, ok := organizationMemberFor(c, model.OrganizationRoleViewer)
+	if !ok {
+		return
+	}
+	startTimestamp, _ := strconv.ParseInt(c.Query("start_timestamp"), 10, 64)
+	endTimestamp, _ := strconv.ParseInt(c.Query("end_timestamp"), 10, 64)
+	usage, err := model.GetOrganizationUsage(member.OrganizationId, startTimestamp, endTimestamp)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, usage)
+}
+
+// GetAllOrganizations 管理员查看全部组织
+func GetAllOrganizations(c *gin.Context) {
+	pageInfo := common.GetPageQuery(c)
+	orgs, total, err := model.GetAllOrganizations(pageInfo.GetStartIdx(), pageInfo.GetPageSize())
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	pageInfo.SetTotal(int(total))
+	pageInfo.SetItems(orgs)
+	common.ApiSuccess(c, pageInfo)
+}
+
+// AdjustOrganizationQuota 管理员增减组织额度
+func AdjustOrganizationQuota(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	var req organizationQuotaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if req.Quota == 0 {
+		common.ApiErrorI18n(c, i18n.MsgOrganizationQuotaDeltaZero)
+		return
+	}
+	before, err := model.GetOrganizationById(id)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if err := model.AdjustOrganizationQuota(id, req.Quota); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	after, err := model.GetOrganizationById(id)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	recordAudit(c, model.AuditActionUpdate, model.AuditTargetOrganization, id, before, after)
+	common.ApiSuccess(c, after)
+}
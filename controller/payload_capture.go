@@ -0,0 +1,120 @@
+package controller
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/service"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+
+	"github.com/gin-gonic/gin"
+)
+
+type payloadCaptureRuleRequest struct {
+	TargetType string `json:"target_type"`
+	TargetId   int    `json:"target_id"`
+	TtlMinutes int    `json:"ttl_minutes"`
+	Remark     string `json:"remark"`
+}
+
+// GetPayloadCaptureRules 获取抓包规则列表（含已到期但尚未清理的规则）
+func GetPayloadCaptureRules(c *gin.Context) {
+	rules, err := model.GetPayloadCaptureRules()
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, rules)
+}
+
+// CreatePayloadCaptureRule 为令牌、用户或渠道开启限时抓包
+func CreatePayloadCaptureRule(c *gin.Context) {
+	var req payloadCaptureRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	targetType, err := model.NormalizePayloadCaptureTarget(req.TargetType)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if req.TargetId <= 0 {
+		common.ApiErrorMsg(c, "目标 id 无效")
+		return
+	}
+	maxMinutes := operation_setting.GetPayloadCaptureSetting().MaxRuleTTLHours * 60
+	if req.TtlMinutes <= 0 || req.TtlMinutes > maxMinutes {
+		common.ApiErrorMsg(c, "有效期必须在 1 到 "+strconv.Itoa(maxMinutes)+" 分钟之间")
+		return
+	}
+	if len(req.Remark) > 255 {
+		common.ApiErrorMsg(c, "备注长度不能超过 255")
+		return
+	}
+	rule := model.PayloadCaptureRule{
+		TargetType: targetType,
+		TargetId:   req.TargetId,
+		Remark:     req.Remark,
+		CreatedBy:  c.GetInt("id"),
+		ExpiresAt:  time.Now().Add(time.Duration(req.TtlMinutes) * time.Minute).Unix(),
+	}
+	if err := rule.Insert(); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if err := service.ReloadPayloadCaptureRules(); err != nil {
+		common.SysError("failed to reload payload capture rules: " + err.Error())
+	}
+	recordAudit(c, model.AuditActionCreate, model.AuditTargetPayloadCapture, rule.Id, nil, rule)
+	common.ApiSuccess(c, rule)
+}
+
+// DeletePayloadCaptureRule 提前结束抓包
+func DeletePayloadCaptureRule(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	rule, err := model.DeletePayloadCaptureRule(id)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if err := service.ReloadPayloadCaptureRules(); err != nil {
+		common.SysError("failed to reload payload capture rules: " + err.Error())
+	}
+	recordAudit(c, model.AuditActionDelete, model.AuditTargetPayloadCapture, rule.Id, rule, nil)
+	common.ApiSuccess(c, nil)
+}
+
+// GetPayloadCaptures 分页查询抓包记录，列表不包含请求与响应内容
+func GetPayloadCaptures(c *gin.Context) {
+	pageInfo := common.GetPageQuery(c)
+	userId, _ := strconv.Atoi(c.Query("user_id"))
+	tokenId, _ := strconv.Atoi(c.Query("token_id"))
+	channelId, _ := strconv.Atoi(c.Query("channel_id"))
+	captures, total, err := model.GetPayloadCaptures(model.PayloadCaptureFilter{
+		RequestId: c.Query("request_id"),
+		UserId:    userId,
+		TokenId:   tokenId,
+		ChannelId: channelId,
+	}, pageInfo.GetStartIdx(), pageInfo.GetPageSize())
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	pageInfo.SetTotal(int(total))
+	pageInfo.SetItems(captures)
+	common.ApiSuccess(c, pageInfo)
+}
+
+// GetPayloadCapture 获取单条抓包记录的完整内容
+func GetPayloadCapture(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	capture, err := model.GetPayloadCaptureById(id)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, capture)
+}
@@ -13,8 +13,8 @@ import (
 	"github.com/QuantumNous/new-api/constant"
 	"github.com/QuantumNous/new-api/dto"
 	"github.com/QuantumNous/new-api/logger"
-	"github.com/QuantumNous/new-api/middleware"
 	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/pkg/tracing"
 	"github.com/QuantumNous/new-api/relay"
 	relaycommon "github.com/QuantumNous/new-api/relay/common"
 	relayconstant "github.com/QuantumNous/new-api/relay/constant"
@@ -29,6 +29,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 func relayHandler(c *gin.Context, info *relaycommon.RelayInfo) *types.NewAPIError {
@@ -60,14 +61,24 @@ func relayHandler(c *gin.Context, info *relaycommon.RelayInfo) *types.NewAPIErro
 
 func geminiRelayHandler(c *gin.Context, info *relaycommon.RelayInfo) *types.NewAPIError {
 	var err *types.NewAPIError
-	if strings.Contains(c.Request.URL.Path, "embed") {
+	switch relayconstant.GetGeminiAction(c.Request.URL.Path) {
+	case relayconstant.GeminiActionEmbedContent, relayconstant.GeminiActionBatchEmbedContents:
 		err = relay.GeminiEmbeddingHandler(c, info)
-	} else {
+	default:
 		err = relay.GeminiHelper(c, info)
 	}
 	return err
 }
 
+// RelayGemini Gemini 原生接口按 action 分发，countTokens 不计费
+func RelayGemini(c *gin.Context) {
+	if relayconstant.GetGeminiAction(c.Request.URL.Path) == relayconstant.GeminiActionCountTokens {
+		relayCountTokens(c, types.RelayFormatGemini, relay.GeminiCountTokensHelper)
+		return
+	}
+	Relay(c, types.RelayFormatGemini)
+}
+
 func Relay(c *gin.Context, relayFormat types.RelayFormat) {
 
 	requestId := c.GetString(common.RequestIdKey)
@@ -128,9 +139,10 @@ func Relay(c *gin.Context, relayFormat types.RelayFormat) {
 
 	needSensitiveCheck := setting.ShouldCheckPromptSensitive()
 	needCountToken := constant.CountToken
-	// Avoid building huge CombineText (strings.Join) when token counting and sensitive check are both disabled.
+	needModeration := service.ModerationPolicyFor(relayInfo) != nil
+	// Avoid building huge CombineText (strings.Join) when token counting, sensitive check and moderation are all disabled.
 	var meta *types.TokenCountMeta
-	if needSensitiveCheck || needCountToken {
+	if needSensitiveCheck || needCountToken || needModeration {
 		meta = request.GetTokenCountMeta()
 	} else {
 		meta = fastTokenCountMetaForPricing(request)
@@ -145,6 +157,13 @@ func Relay(c *gin.Context, relayFormat types.RelayFormat) {
 		}
 	}
 
+	if needModeration && meta != nil {
+		newAPIError = service.ModerateInput(c, relayInfo, request, meta.CombineText)
+		if newAPIError != nil {
+			return
+		}
+	}
+
 	tokens, err := service.EstimateRequestToken(c, meta, relayInfo)
 	if err != nil {
 		newAPIError = types.NewError(err, types.ErrorCodeCountTokenFailed)
@@ -190,50 +209,72 @@ func Relay(c *gin.Context, relayFormat types.RelayFormat) {
 	relayInfo.RetryIndex = 0
 	relayInfo.LastError = nil
 
-	for ; retryParam.GetRetry() <= common.RetryTimes; retryParam.IncreaseRetry() {
-		relayInfo.RetryIndex = retryParam.GetRetry()
-		channel, channelErr := getChannel(c, relayInfo, retryParam)
-		if channelErr != nil {
-			logger.LogError(c, channelErr.Error())
-			newAPIError = channelErr
-			break
-		}
+	fallbackCandidates := service.GetModelFallbackCandidates(c, relayInfo.OriginModelName)
+	if relayInfo.FallbackFromModel != "" {
+		// 分发阶段已经降级
+		fallbackCandidates = service.GetRemainingModelFallbackCandidates(c, relayInfo.FallbackFromModel, relayInfo.OriginModelName)
+	}
+	for {
+		for ; retryParam.GetRetry() <= common.RetryTimes; retryParam.IncreaseRetry() {
+			relayInfo.RetryIndex = retryParam.GetRetry()
+			channel, channelErr := getChannel(c, relayInfo, retryParam)
+			if channelErr != nil {
+				logger.LogError(c, channelErr.Error())
+				newAPIError = channelErr
+				break
+			}
 
-		addUsedChannel(c, channel.Id)
-		bodyStorage, bodyErr := common.GetBodyStorage(c)
-		if bodyErr != nil {
-			// Ensure consistent 413 for oversized bodies even when error occurs later (e.g., retry path)
-			if common.IsRequestBodyTooLargeError(bodyErr) || errors.Is(bodyErr, common.ErrRequestBodyTooLarge) {
-				newAPIError = types.NewErrorWithStatusCode(bodyErr, types.ErrorCodeReadRequestBodyFailed, http.StatusRequestEntityTooLarge, types.ErrOptionWithSkipRetry())
-			} else {
-				newAPIError = types.NewErrorWithStatusCode(bodyErr, types.ErrorCodeReadRequestBodyFailed, http.StatusBadRequest, types.ErrOptionWithSkipRetry())
+			addUsedChannel(c, channel.Id)
+			bodyStorage, bodyErr := common.GetBodyStorage(c)
+			if bodyErr != nil {
+				// Ensure consistent 413 for oversized bodies even when error occurs later (e.g., retry path)
+				if common.IsRequestBodyTooLargeError(bodyErr) || errors.Is(bodyErr, common.ErrRequestBodyTooLarge) {
+					newAPIError = types.NewErrorWithStatusCode(bodyErr, types.ErrorCodeReadRequestBodyFailed, http.StatusRequestEntityTooLarge, types.ErrOptionWithSkipRetry())
+				} else {
+					newAPIError = types.NewErrorWithStatusCode(bodyErr, types.ErrorCodeReadRequestBodyFailed, http.StatusBadRequest, types.ErrOptionWithSkipRetry())
+				}
+				break
 			}
-			break
-		}
-		c.Request.Body = io.NopCloser(bodyStorage)
+			c.Request.Body = io.NopCloser(bodyStorage)
 
-		switch relayFormat {
-		case types.RelayFormatOpenAIRealtime:
-			newAPIError = relay.WssHelper(c, relayInfo)
-		case types.RelayFormatClaude:
-			newAPIError = relay.ClaudeHelper(c, relayInfo)
-		case types.RelayFormatGemini:
-			newAPIError = geminiRelayHandler(c, relayInfo)
-		default:
-			newAPIError = relayHandler(c, relayInfo)
-		}
+			endAttemptSpan := startAttemptSpan(c, relayInfo, channel)
+			attemptStart := time.Now()
+			switch relayFormat {
+			case types.RelayFormatOpenAIRealtime:
+				newAPIError = relay.WssHelper(c, relayInfo)
+			case types.RelayFormatClaude:
+				newAPIError = relay.ClaudeHelper(c, relayInfo)
+			case types.RelayFormatGemini:
+				newAPIError = geminiRelayHandler(c, relayInfo)
+			default:
+				newAPIError = relayHandler(c, relayInfo)
+			}
+			service.RecordChannelHealth(c, relayInfo, channel.Id, attemptStart, newAPIError)
+			service.FinishPayloadCapture(c, relayInfo, newAPIError)
+			endAttemptSpan(newAPIError)
 
-		if newAPIError == nil {
-			relayInfo.LastError = nil
-			return
-		}
+			if newAPIError == nil {
+				relayInfo.LastError = nil
+				return
+			}
 
-		newAPIError = service.NormalizeViolationFeeError(newAPIError)
-		relayInfo.LastError = newAPIError
+			newAPIError = service.NormalizeViolationFeeError(newAPIError)
+			relayInfo.LastError = newAPIError
 
-		processChannelError(c, *types.NewChannelError(channel.Id, channel.Type, channel.Name, channel.ChannelInfo.IsMultiKey, common.GetContextKeyString(c, constant.ContextKeyChannelKey), channel.GetAutoBan()), newAPIError)
+			processChannelError(c, *types.NewChannelError(channel.Id, channel.Type, channel.Name, channel.ChannelInfo.IsMultiKey, common.GetContextKeyString(c, constant.ContextKeyChannelKey), channel.GetAutoBan()), newAPIError)
 
-		if !shouldRetry(c, newAPIError, common.RetryTimes-retryParam.GetRetry()) {
+			if !shouldRetry(c, newAPIError, common.RetryTimes-retryParam.GetRetry()) {
+				break
+			}
+		}
+
+		// 当前模型的渠道全部失败后，按降级链切换模型重新选择渠道
+		if !shouldFallbackModel(c, newAPIError) {
+			break
+		}
+		var switched bool
+		fallbackCandidates, switched = switchToFallbackModel(c, relayInfo, retryParam, meta, fallbackCandidates)
+		if !switched {
 			break
 		}
 	}
@@ -245,6 +286,22 @@ func Relay(c *gin.Context, relayFormat types.RelayFormat) {
 	}
 }
 
+// RelayResponsesWebSocket Responses API 的 WebSocket 模式，连接期间固定使用 Distribute 选中的渠道，不做重试
+func RelayResponsesWebSocket(c *gin.Context) {
+	ws, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		helper.WssError(c, ws, types.NewError(err, types.ErrorCodeGetChannelFailed, types.ErrOptionWithSkipRetry()).ToOpenAIError())
+		return
+	}
+	defer ws.Close()
+
+	if newAPIError := relay.ResponsesWssHelper(c, ws); newAPIError != nil {
+		logger.LogError(c, fmt.Sprintf("relay error: %s", newAPIError.Error()))
+		newAPIError.SetMessage(common.MessageWithRequestId(newAPIError.Error(), c.GetString(common.RequestIdKey)))
+		helper.WssError(c, ws, newAPIError.ToOpenAIError())
+	}
+}
+
 var upgrader = websocket.Upgrader{
 	Subprotocols: []string{"realtime"}, // WS 握手支持的协议，如果有使用 Sec-WebSocket-Protocol，则必须在此声明对应的 Protocol TODO add other protocol
 	CheckOrigin: func(r *http.Request) bool {
@@ -252,6 +309,36 @@ var upgrader = websocket.Upgrader{
 	},
 }
 
+// startAttemptSpan 为一次上游尝试创建 span 并挂到 c.Request 的 context 上，
+// 使请求转换、上游请求与计费结算成为其子 span；返回的函数结束 span 并恢复原 context
+func startAttemptSpan(c *gin.Context, info *relaycommon.RelayInfo, channel *model.Channel) func(*types.NewAPIError) {
+	if !tracing.Enabled() {
+		return func(*types.NewAPIError) {}
+	}
+	parent := c.Request.Context()
+	ctx, span := tracing.Start(parent, "relay.attempt",
+		attribute.Int("relay.retry_index", info.RetryIndex),
+		attribute.String("relay.model", info.OriginModelName),
+		attribute.Int("relay.channel_id", channel.Id),
+		attribute.Int("relay.channel_type", channel.Type),
+		attribute.Int("relay.key_index", common.GetContextKeyInt(c, constant.ContextKeyChannelMultiKeyIndex)),
+	)
+	c.Request = c.Request.WithContext(ctx)
+	return func(apiErr *types.NewAPIError) {
+		if apiErr != nil {
+			span.SetAttributes(
+				attribute.Int("http.response.status_code", apiErr.StatusCode),
+				attribute.String("error.code", string(apiErr.GetErrorCode())),
+			)
+			tracing.End(span, apiErr)
+		} else {
+			span.SetAttributes(attribute.Int("http.response.status_code", c.Writer.Status()))
+			span.End()
+		}
+		c.Request = c.Request.WithContext(parent)
+	}
+}
+
 func addUsedChannel(c *gin.Context, channelId int) {
 	useChannel := c.GetStringSlice("use_channel")
 	useChannel = append(useChannel, fmt.Sprintf("%d", channelId))
@@ -301,6 +388,8 @@ func getChannel(c *gin.Context, info *relaycommon.RelayInfo, retryParam *service
 			AutoBan: &autoBanInt,
 		}, nil
 	}
+	_, span := tracing.Start(c.Request.Context(), "channel.select", attribute.Int("relay.retry_index", retryParam.GetRetry()))
+	defer span.End()
 	channel, selectGroup, err := service.CacheGetRandomSatisfiedChannel(retryParam)
 
 	info.PriceData.GroupRatioInfo = helper.HandleGroupRatio(c, info)
@@ -312,10 +401,14 @@ func getChannel(c *gin.Context, info *relaycommon.RelayInfo, retryParam *service
 		return nil, types.NewError(fmt.Errorf("分组 %s 下模型 %s 的可用渠道不存在（retry）", selectGroup, info.OriginModelName), types.ErrorCodeGetChannelFailed, types.ErrOptionWithSkipRetry())
 	}
 
-	newAPIError := middleware.SetupContextForSelectedChannel(c, channel, info.OriginModelName)
+	newAPIError := service.SetupContextForSelectedChannel(c, channel, info.OriginModelName)
 	if newAPIError != nil {
 		return nil, newAPIError
 	}
+	span.SetAttributes(
+		attribute.Int("relay.channel_id", channel.Id),
+		attribute.Int("relay.key_index", common.GetContextKeyInt(c, constant.ContextKeyChannelMultiKeyIndex)),
+	)
 	return channel, nil
 }
 
@@ -351,6 +444,54 @@ func shouldRetry(c *gin.Context, openaiErr *types.NewAPIError, retryTimes int) b
 	return operation_setting.ShouldRetryByStatusCode(code)
 }
 
+// shouldFallbackModel 当前模型的渠道均已失败，且错误属于可重试的渠道/上游错误时才降级
+func shouldFallbackModel(c *gin.Context, openaiErr *types.NewAPIError) bool {
+	if openaiErr == nil {
+		return false
+	}
+	if _, ok := c.Get("specific_channel_id"); ok {
+		return false
+	}
+	if openaiErr.GetErrorCode() == types.ErrorCodeGetChannelFailed {
+		return true
+	}
+	return shouldRetry(c, openaiErr, 1)
+}
+
+// switchToFallbackModel 切换到下一个可计价的降级模型，并按降级模型重新计价（结算时按新价格补扣或返还预扣费），
+// 返回剩余的降级模型以及是否切换成功
+func switchToFallbackModel(c *gin.Context, info *relaycommon.RelayInfo, retryParam *service.RetryParam, meta *types.TokenCountMeta, candidates []string) ([]string, bool) {
+	for len(candidates) > 0 {
+		fallbackModel := candidates[0]
+		candidates = candidates[1:]
+
+		previousModel := info.OriginModelName
+		info.OriginModelName = fallbackModel
+		if _, err := helper.ModelPriceHelper(c, info, info.GetEstimatePromptTokens(), meta); err != nil {
+			logger.LogWarn(c, fmt.Sprintf("skip fallback model %s: %s", fallbackModel, err.Error()))
+			info.OriginModelName = previousModel
+			continue
+		}
+		logger.LogWarn(c, fmt.Sprintf("模型 %s 的渠道均不可用，降级为模型 %s", previousModel, fallbackModel))
+
+		if info.FallbackFromModel == "" {
+			info.FallbackFromModel = previousModel
+		}
+		if info.ChannelMeta == nil {
+			// 强制 getChannel 按降级模型重新选择渠道，而不是复用分发中间件选出的渠道
+			info.ChannelMeta = &relaycommon.ChannelMeta{}
+		}
+		common.SetContextKey(c, constant.ContextKeyOriginalModel, fallbackModel)
+		common.SetContextKey(c, constant.ContextKeyAutoGroupIndex, 0)
+		common.SetContextKey(c, constant.ContextKeyAutoGroupRetryIndex, 0)
+		retryParam.ModelName = fallbackModel
+		retryParam.SetRetry(0)
+		c.Header(service.ModelFallbackHeader, fallbackModel)
+		return candidates, true
+	}
+	return candidates, false
+}
+
 func processChannelError(c *gin.Context, channelError types.ChannelError, err *types.NewAPIError) {
 	logger.LogError(c, fmt.Sprintf("channel error (channel #%d, status code: %d): %s", channelError.ChannelId, err.StatusCode, err.Error()))
 	// 不要使用context获取渠道信息，异步处理时可能会出现渠道信息不一致的情况
@@ -441,6 +582,47 @@ func RelayMidjourney(c *gin.Context) {
 	}
 }
 
+// RelayClaudeCountTokens 处理 /v1/messages/count_tokens
+func RelayClaudeCountTokens(c *gin.Context) {
+	relayCountTokens(c, types.RelayFormatClaude, relay.ClaudeCountTokensHelper)
+}
+
+// relayCountTokens 处理 token 计数请求，不预扣费也不重试
+func relayCountTokens(c *gin.Context, relayFormat types.RelayFormat, handler func(*gin.Context, *relaycommon.RelayInfo) *types.NewAPIError) {
+	requestId := c.GetString(common.RequestIdKey)
+	var newAPIError *types.NewAPIError
+	defer func() {
+		if newAPIError != nil {
+			logger.LogError(c, fmt.Sprintf("count tokens error: %s", newAPIError.Error()))
+			newAPIError.SetMessage(common.MessageWithRequestId(newAPIError.Error(), requestId))
+			if relayFormat == types.RelayFormatClaude {
+				c.JSON(newAPIError.StatusCode, gin.H{
+					"type":  "error",
+					"error": newAPIError.ToClaudeError(),
+				})
+			} else {
+				c.JSON(newAPIError.StatusCode, gin.H{
+					"error": newAPIError.ToOpenAIError(),
+				})
+			}
+		}
+	}()
+
+	request, err := helper.GetAndValidateRequest(c, relayFormat)
+	if err != nil {
+		newAPIError = types.NewError(err, types.ErrorCodeInvalidRequest)
+		return
+	}
+
+	relayInfo, err := relaycommon.GenRelayInfo(c, relayFormat, request, nil)
+	if err != nil {
+		newAPIError = types.NewError(err, types.ErrorCodeGenRelayInfoFailed)
+		return
+	}
+
+	newAPIError = handler(c, relayInfo)
+}
+
 func RelayNotImplemented(c *gin.Context) {
 	err := types.OpenAIError{
 		Message: "API not implemented",
@@ -496,6 +678,12 @@ func RelayTask(c *gin.Context) {
 		return
 	}
 
+	callbackURL, err := service.ResolveTaskCallbackURL(c)
+	if err != nil {
+		respondTaskError(c, service.TaskErrorWrapperLocal(err, "invalid_callback_url", http.StatusBadRequest))
+		return
+	}
+
 	var result *relay.TaskSubmitResult
 	var taskErr *dto.TaskError
 	defer func() {
@@ -517,7 +705,7 @@ func RelayTask(c *gin.Context) {
 		if lockedCh, ok := relayInfo.LockedChannel.(*model.Channel); ok && lockedCh != nil {
 			channel = lockedCh
 			if retryParam.GetRetry() > 0 {
-				if setupErr := middleware.SetupContextForSelectedChannel(c, channel, relayInfo.OriginModelName); setupErr != nil {
+				if setupErr := service.SetupContextForSelectedChannel(c, channel, relayInfo.OriginModelName); setupErr != nil {
 					taskErr = service.TaskErrorWrapperLocal(setupErr.Err, "setup_locked_channel_failed", http.StatusInternalServerError)
 					break
 				}
@@ -545,6 +733,11 @@ func RelayTask(c *gin.Context) {
 		c.Request.Body = io.NopCloser(bodyStorage)
 
 		result, taskErr = relay.RelayTaskSubmit(c, relayInfo)
+		var captureErr *types.NewAPIError
+		if taskErr != nil {
+			captureErr = types.NewOpenAIError(taskErr.Error, types.ErrorCodeBadResponseStatusCode, taskErr.StatusCode)
+		}
+		service.FinishPayloadCapture(c, relayInfo, captureErr)
 		if taskErr == nil {
 			break
 		}
@@ -578,7 +771,9 @@ func RelayTask(c *gin.Context) {
 		task.PrivateData.UpstreamTaskID = result.UpstreamTaskID
 		task.PrivateData.BillingSource = relayInfo.BillingSource
 		task.PrivateData.SubscriptionId = relayInfo.SubscriptionId
+		task.PrivateData.OrganizationId = relayInfo.OrganizationId
 		task.PrivateData.TokenId = relayInfo.TokenId
+		task.PrivateData.CallbackURL = callbackURL
 		task.PrivateData.BillingContext = &model.TaskBillingContext{
 			ModelPrice:      relayInfo.PriceData.ModelPrice,
 			GroupRatio:      relayInfo.PriceData.GroupRatioInfo.GroupRatio,
@@ -0,0 +1,104 @@
+package controller
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/middleware"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/service"
+	"github.com/QuantumNous/new-api/setting/model_setting"
+	"github.com/QuantumNous/new-api/setting/ratio_setting"
+	"github.com/QuantumNous/new-api/types"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// setupRelayTestDB 通过 InitDB 打开内存 SQLite，使 model 中依赖列名的查询（令牌、渠道）可用
+func setupRelayTestDB(t *testing.T, name string) *gorm.DB {
+	t.Setenv("SQL_DSN", "")
+	t.Setenv("LOG_SQL_DSN", "")
+	sqlitePath := common.SQLitePath
+	common.SQLitePath = "file:" + name + "?mode=memory&cache=shared"
+	t.Cleanup(func() { common.SQLitePath = sqlitePath })
+	common.RedisEnabled = false
+	require.NoError(t, model.InitDB())
+	require.NoError(t, model.InitLogDB())
+	db := model.DB
+	require.NoError(t, db.AutoMigrate(&model.User{}, &model.Token{}, &model.Channel{}, &model.Ability{}, &model.Log{}, &model.UserSubscription{}))
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			_ = sqlDB.Close()
+		}
+	})
+	return db
+}
+
+func TestRelayFallsBackWhenModelHasNoChannel(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := setupRelayTestDB(t, "relay_fallback")
+	service.InitHttpClient()
+	ratio_setting.InitRatioSettings()
+
+	settings := model_setting.GetModelFallbackSettings()
+	saved := *settings
+	t.Cleanup(func() { *settings = saved })
+	settings.Enabled = true
+	settings.DefaultEnabled = true
+	settings.Chains = map[string][]string{"gpt-4o": {"gpt-4o-mini"}}
+
+	var upstreamModel string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var req struct {
+			Model string `json:"model"`
+		}
+		_ = common.Unmarshal(body, &req)
+		upstreamModel = req.Model
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprintf(w, `{"id":"chatcmpl-1","object":"chat.completion","created":1,"model":%q,"choices":[{"index":0,"message":{"role":"assistant","content":"ok"},"finish_reason":"stop"}],"usage":{"prompt_tokens":5,"completion_tokens":1,"total_tokens":6}}`, req.Model)
+	}))
+	t.Cleanup(upstream.Close)
+
+	user := &model.User{Username: "fallback", Password: "password123", Status: common.UserStatusEnabled, Group: "default", Quota: 100000000}
+	require.NoError(t, db.Create(user).Error)
+	token := &model.Token{UserId: user.Id, Key: "fallbacktokenkey", Name: "t", Status: common.TokenStatusEnabled, ExpiredTime: -1, UnlimitedQuota: true}
+	require.NoError(t, db.Create(token).Error)
+
+	// gpt-4o 的渠道已被自动禁用，只有降级模型还有可用渠道
+	baseURL := upstream.URL
+	disabled := &model.Channel{Type: constant.ChannelTypeOpenAI, Key: "sk-a", Name: "disabled", Status: common.ChannelStatusAutoDisabled, Models: "gpt-4o", Group: "default", BaseURL: &baseURL}
+	enabled := &model.Channel{Type: constant.ChannelTypeOpenAI, Key: "sk-b", Name: "enabled", Status: common.ChannelStatusEnabled, Models: "gpt-4o-mini", Group: "default", BaseURL: &baseURL}
+	require.NoError(t, db.Create(disabled).Error)
+	require.NoError(t, db.Create(enabled).Error)
+	require.NoError(t, disabled.AddAbilities(nil))
+	require.NoError(t, enabled.AddAbilities(nil))
+
+	engine := gin.New()
+	engine.Use(middleware.RequestId())
+	engine.POST("/v1/chat/completions", middleware.TokenAuth(), middleware.Distribute(), func(c *gin.Context) {
+		Relay(c, types.RelayFormatOpenAI)
+	})
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(`{"model":"gpt-4o","messages":[{"role":"user","content":"hi"}]}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer sk-"+token.Key)
+	recorder := httptest.NewRecorder()
+	engine.ServeHTTP(recorder, req)
+
+	require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+	require.Equal(t, "gpt-4o-mini", recorder.Header().Get(service.ModelFallbackHeader))
+	require.Equal(t, "gpt-4o-mini", upstreamModel)
+
+	var log model.Log
+	require.NoError(t, db.Order("id desc").First(&log).Error)
+	require.Equal(t, "gpt-4o-mini", log.ModelName)
+	require.Contains(t, log.Other, `"fallback_from_model":"gpt-4o"`)
+}
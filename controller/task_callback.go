@@ -0,0 +1,71 @@
+package controller
+
+import (
+	"strconv"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/i18n"
+	"github.com/QuantumNous/new-api/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+func getTaskCallbacks(c *gin.Context, userId int) {
+	pageInfo := common.GetPageQuery(c)
+	filter := model.TaskCallbackFilter{
+		UserId: userId,
+		TaskId: c.Query("task_id"),
+		Status: c.Query("status"),
+	}
+	callbacks, total, err := model.GetTaskCallbacks(filter, pageInfo.GetStartIdx(), pageInfo.GetPageSize())
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	pageInfo.SetTotal(int(total))
+	pageInfo.SetItems(callbacks)
+	common.ApiSuccess(c, pageInfo)
+}
+
+func redeliverTaskCallback(c *gin.Context, userId int) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.ApiErrorI18n(c, i18n.MsgTaskCallbackInvalidId)
+		return
+	}
+	callback, err := model.GetTaskCallbackById(id, userId)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if callback.Status == model.TaskCallbackStatusPending && callback.Attempts == 0 {
+		common.ApiErrorI18n(c, i18n.MsgTaskCallbackPending)
+		return
+	}
+	if err := model.ResetTaskCallback(callback); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, callback)
+}
+
+// GetSelfTaskCallbacks 当前用户的任务回调投递记录
+func GetSelfTaskCallbacks(c *gin.Context) {
+	getTaskCallbacks(c, c.GetInt("id"))
+}
+
+// GetAllTaskCallbacks 管理员查询全部投递记录，可按 user_id 过滤
+func GetAllTaskCallbacks(c *gin.Context) {
+	userId, _ := strconv.Atoi(c.Query("user_id"))
+	getTaskCallbacks(c, userId)
+}
+
+// RedeliverSelfTaskCallback 重新投递当前用户的一条回调
+func RedeliverSelfTaskCallback(c *gin.Context) {
+	redeliverTaskCallback(c, c.GetInt("id"))
+}
+
+// RedeliverTaskCallback 管理员重新投递任意回调
+func RedeliverTaskCallback(c *gin.Context) {
+	redeliverTaskCallback(c, 0)
+}
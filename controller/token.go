@@ -9,6 +9,7 @@ import (
 	"github.com/QuantumNous/new-api/common"
 	"github.com/QuantumNous/new-api/i18n"
 	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/service"
 	"github.com/QuantumNous/new-api/setting/operation_setting"
 
 	"github.com/gin-gonic/gin"
@@ -160,55 +161,81 @@ func GetTokenUsage(c *gin.Context) {
 			"model_limits":         token.GetModelLimitsMap(),
 			"model_limits_enabled": token.ModelLimitsEnabled,
 			"expires_at":           expiredAt,
+			"budget_period":        token.BudgetPeriod,
+			"budget_quota":         token.BudgetQuota,
+			"budget_used":          token.BudgetUsed,
+			"budget_reset_time":    token.BudgetResetTime,
 		},
 	})
 }
 
-func AddToken(c *gin.Context) {
-	token := model.Token{}
-	err := c.ShouldBindJSON(&token)
-	if err != nil {
-		common.ApiError(c, err)
-		return
-	}
+// validateTokenRequest 校验创建/更新令牌时用户可设置的字段，失败时已写入响应
+func validateTokenRequest(c *gin.Context, token *model.Token) bool {
 	if len(token.Name) > 50 {
 		common.ApiErrorI18n(c, i18n.MsgTokenNameTooLong)
-		return
+		return false
 	}
 	// 非无限额度时，检查额度值是否超出有效范围
 	if !token.UnlimitedQuota {
 		if token.RemainQuota < 0 {
 			common.ApiErrorI18n(c, i18n.MsgTokenQuotaNegative)
-			return
+			return false
 		}
 		maxQuotaValue := int((1000000000 * common.QuotaPerUnit))
 		if token.RemainQuota > maxQuotaValue {
 			common.ApiErrorI18n(c, i18n.MsgTokenQuotaExceedMax, map[string]any{"Max": maxQuotaValue})
-			return
+			return false
 		}
 	}
+	if token.RpmLimit < 0 || token.TpmLimit < 0 || token.ConcurrencyLimit < 0 {
+		common.ApiErrorI18n(c, i18n.MsgTokenRateLimitNegative)
+		return false
+	}
+	if model.NormalizeTokenBudgetPeriod(token.BudgetPeriod) != model.SubscriptionResetNever && token.BudgetQuota <= 0 {
+		common.ApiErrorI18n(c, i18n.MsgTokenBudgetInvalid)
+		return false
+	}
+	if token.CallbackUrl != "" && service.ValidateTaskCallbackURL(token.CallbackUrl) != nil {
+		common.ApiErrorI18n(c, i18n.MsgTokenCallbackUrlInvalid)
+		return false
+	}
+	return true
+}
+
+// newTokenFromRequest 校验请求并生成新令牌（个人令牌与组织令牌共用），失败时已写入响应。
+// 令牌数量上限按令牌归属的用户计算
+func newTokenFromRequest(c *gin.Context, userId int) (*model.Token, bool) {
+	token := model.Token{}
+	err := c.ShouldBindJSON(&token)
+	if err != nil {
+		common.ApiError(c, err)
+		return nil, false
+	}
+	if !validateTokenRequest(c, &token) {
+		return nil, false
+	}
 	// 检查用户令牌数量是否已达上限
 	maxTokens := operation_setting.GetMaxUserTokens()
-	count, err := model.CountUserTokens(c.GetInt("id"))
+	count, err := model.CountUserTokens(userId)
 	if err != nil {
 		common.ApiError(c, err)
-		return
+		return nil, false
 	}
 	if int(count) >= maxTokens {
 		c.JSON(http.StatusOK, gin.H{
 			"success": false,
 			"message": fmt.Sprintf("已达到最大令牌数量限制 (%d)", maxTokens),
 		})
-		return
+		return nil, false
 	}
 	key, err := common.GenerateKey()
 	if err != nil {
 		common.ApiErrorI18n(c, i18n.MsgTokenGenerateFailed)
 		common.SysLog("failed to generate token key: " + err.Error())
-		return
+		return nil, false
 	}
-	cleanToken := model.Token{
-		UserId:             c.GetInt("id"),
+	cleanToken := &model.Token{
+		UserId:             userId,
 		Name:               token.Name,
 		Key:                key,
 		CreatedTime:        common.GetTimestamp(),
@@ -221,8 +248,22 @@ func AddToken(c *gin.Context) {
 		AllowIps:           token.AllowIps,
 		Group:              token.Group,
 		CrossGroupRetry:    token.CrossGroupRetry,
+		ModelFallback:      token.ModelFallback,
+		RpmLimit:           token.RpmLimit,
+		TpmLimit:           token.TpmLimit,
+		ConcurrencyLimit:   token.ConcurrencyLimit,
+		CallbackUrl:        token.CallbackUrl,
+	}
+	cleanToken.SetBudget(token.BudgetPeriod, token.BudgetQuota, common.GetTimestamp())
+	return cleanToken, true
+}
+
+func AddToken(c *gin.Context) {
+	cleanToken, ok := newTokenFromRequest(c, c.GetInt("id"))
+	if !ok {
+		return
 	}
-	err = cleanToken.Insert()
+	err := cleanToken.Insert()
 	if err != nil {
 		common.ApiError(c, err)
 		return
@@ -256,27 +297,22 @@ func UpdateToken(c *gin.Context) {
 		common.ApiError(c, err)
 		return
 	}
-	if len(token.Name) > 50 {
-		common.ApiErrorI18n(c, i18n.MsgTokenNameTooLong)
+	if !validateTokenRequest(c, &token) {
 		return
 	}
-	if !token.UnlimitedQuota {
-		if token.RemainQuota < 0 {
-			common.ApiErrorI18n(c, i18n.MsgTokenQuotaNegative)
-			return
-		}
-		maxQuotaValue := int((1000000000 * common.QuotaPerUnit))
-		if token.RemainQuota > maxQuotaValue {
-			common.ApiErrorI18n(c, i18n.MsgTokenQuotaExceedMax, map[string]any{"Max": maxQuotaValue})
-			return
-		}
-	}
 	cleanToken, err := model.GetTokenByIds(token.Id, userId)
 	if err != nil {
 		common.ApiError(c, err)
 		return
 	}
 	if token.Status == common.TokenStatusEnabled {
+		// 成员被移出组织后，其组织令牌不能再启用
+		if cleanToken.OrganizationId > 0 && cleanToken.Status != common.TokenStatusEnabled {
+			if _, err := model.GetOrganizationMember(cleanToken.OrganizationId, userId); err != nil {
+				common.ApiErrorI18n(c, i18n.MsgOrganizationTokenMemberLeft)
+				return
+			}
+		}
 		if cleanToken.Status == common.TokenStatusExpired && cleanToken.ExpiredTime <= common.GetTimestamp() && cleanToken.ExpiredTime != -1 {
 			common.ApiErrorI18n(c, i18n.MsgTokenExpiredCannotEnable)
 			return
@@ -286,6 +322,7 @@ func UpdateToken(c *gin.Context) {
 			return
 		}
 	}
+	budgetReset := false
 	if statusOnly != "" {
 		cleanToken.Status = token.Status
 	} else {
@@ -299,8 +336,17 @@ func UpdateToken(c *gin.Context) {
 		cleanToken.AllowIps = token.AllowIps
 		cleanToken.Group = token.Group
 		cleanToken.CrossGroupRetry = token.CrossGroupRetry
+		cleanToken.ModelFallback = token.ModelFallback
+		cleanToken.RpmLimit = token.RpmLimit
+		cleanToken.TpmLimit = token.TpmLimit
+		cleanToken.ConcurrencyLimit = token.ConcurrencyLimit
+		cleanToken.CallbackUrl = token.CallbackUrl
+		budgetReset = cleanToken.SetBudget(token.BudgetPeriod, token.BudgetQuota, common.GetTimestamp())
 	}
 	err = cleanToken.Update()
+	if err == nil && budgetReset {
+		err = model.ResetTokenBudgetUsed(cleanToken.Id)
+	}
 	if err != nil {
 		common.ApiError(c, err)
 		return
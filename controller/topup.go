@@ -408,5 +408,6 @@ func AdminCompleteTopUp(c *gin.Context) {
 		common.ApiError(c, err)
 		return
 	}
+	recordAudit(c, "complete", model.AuditTargetTopUp, req.TradeNo, nil, model.GetTopUpByTradeNo(req.TradeNo))
 	common.ApiSuccess(c, nil)
 }
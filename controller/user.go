@@ -564,6 +564,10 @@ func UpdateUser(c *gin.Context) {
 		common.ApiErrorI18n(c, i18n.MsgUserCannotCreateHigherLevel)
 		return
 	}
+	if originUser.Quota != updatedUser.Quota && !service.ManagementKeyAllows(c, model.ManagementScopeUsersQuota) {
+		common.ApiErrorMsg(c, "无权进行此操作，管理密钥缺少权限 "+model.ManagementScopeUsersQuota)
+		return
+	}
 	if updatedUser.Password == "$I_LOVE_U" {
 		updatedUser.Password = "" // rollback to what it should be
 	}
@@ -572,6 +576,9 @@ func UpdateUser(c *gin.Context) {
 		common.ApiError(c, err)
 		return
 	}
+	if newUser, err := model.GetUserById(updatedUser.Id, false); err == nil {
+		recordAudit(c, model.AuditActionUpdate, model.AuditTargetUser, updatedUser.Id, originUser, newUser)
+	}
 	if originUser.Quota != updatedUser.Quota {
 		model.RecordLog(originUser.Id, model.LogTypeManage, fmt.Sprintf("管理员将用户额度从 %s修改为 %s", logger.LogQuota(originUser.Quota), logger.LogQuota(updatedUser.Quota)))
 	}
@@ -769,6 +776,9 @@ func DeleteUser(c *gin.Context) {
 		return
 	}
 	err = model.HardDeleteUserById(id)
+	if err == nil {
+		recordAudit(c, model.AuditActionDelete, model.AuditTargetUser, id, originUser, nil)
+	}
 	if err != nil {
 		c.JSON(http.StatusOK, gin.H{
 			"success": true,
@@ -866,6 +876,7 @@ func ManageUser(c *gin.Context) {
 		common.ApiErrorI18n(c, i18n.MsgUserNoPermissionHigherLevel)
 		return
 	}
+	originUser := user
 	switch req.Action {
 	case "disable":
 		user.Status = common.UserStatusDisabled
@@ -913,6 +924,7 @@ func ManageUser(c *gin.Context) {
 		common.ApiError(c, err)
 		return
 	}
+	recordAudit(c, req.Action, model.AuditTargetUser, user.Id, originUser, user)
 	clearUser := model.User{
 		Role:   user.Role,
 		Status: user.Status,
@@ -1146,6 +1158,7 @@ func UpdateUserSetting(c *gin.Context) {
 		UpstreamModelUpdateNotifyEnabled: upstreamModelUpdateNotifyEnabled,
 		AcceptUnsetRatioModel:            req.AcceptUnsetModelRatioModel,
 		RecordIpLog:                      req.RecordIpLog,
+		TaskCallbackSecret:               existingSettings.TaskCallbackSecret,
 	}
 
 	// 如果是webhook类型,添加webhook相关设置
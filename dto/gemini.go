@@ -565,6 +565,48 @@ func (r *GeminiBatchEmbeddingRequest) SetModelName(modelName string) {
 	}
 }
 
+// GeminiCountTokensRequest models/{model}:countTokens 请求，contents 与 generateContentRequest 二选一
+type GeminiCountTokensRequest struct {
+	Contents               []GeminiChatContent `json:"contents,omitempty"`
+	GenerateContentRequest *GeminiChatRequest  `json:"generateContentRequest,omitempty"`
+}
+
+// ToGenerateContentRequest 统一为 generateContent 请求，便于计数与转换
+func (r *GeminiCountTokensRequest) ToGenerateContentRequest() *GeminiChatRequest {
+	if r.GenerateContentRequest != nil {
+		return r.GenerateContentRequest
+	}
+	return &GeminiChatRequest{Contents: r.Contents}
+}
+
+func (r *GeminiCountTokensRequest) IsStream(c *gin.Context) bool {
+	return false
+}
+
+func (r *GeminiCountTokensRequest) GetTokenCountMeta() *types.TokenCountMeta {
+	request := r.ToGenerateContentRequest()
+	meta := request.GetTokenCountMeta()
+	meta.MaxTokens = 0
+	// countTokens 的结果包含系统指令
+	if request.SystemInstructions != nil {
+		for _, part := range request.SystemInstructions.Parts {
+			if part.Text != "" {
+				meta.CombineText = part.Text + "\n" + meta.CombineText
+			}
+		}
+	}
+	return meta
+}
+
+func (r *GeminiCountTokensRequest) SetModelName(modelName string) {
+	// GeminiCountTokensRequest does not have a model field, so this method does nothing.
+}
+
+type GeminiCountTokensResponse struct {
+	TotalTokens             int `json:"totalTokens"`
+	CachedContentTokenCount int `json:"cachedContentTokenCount,omitempty"`
+}
+
 type GeminiEmbeddingResponse struct {
 	Embedding ContentEmbedding `json:"embedding"`
 }
@@ -0,0 +1,29 @@
+package dto
+
+// FineTuningJobRequest POST /v1/fine_tuning/jobs 请求体中网关需要关心的字段，其余字段原样透传
+// https://platform.openai.com/docs/api-reference/fine-tuning/create
+type FineTuningJobRequest struct {
+	Model          string `json:"model"`
+	TrainingFile   string `json:"training_file"`
+	ValidationFile string `json:"validation_file,omitempty"`
+	Suffix         string `json:"suffix,omitempty"`
+}
+
+type FineTuningJobError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Param   string `json:"param,omitempty"`
+}
+
+// FineTuningJob 上游返回的微调任务对象（仅解析计费与状态所需字段）
+type FineTuningJob struct {
+	Id             string              `json:"id"`
+	Object         string              `json:"object"`
+	Model          string              `json:"model"`
+	Status         string              `json:"status"`
+	FineTunedModel string              `json:"fine_tuned_model"`
+	TrainedTokens  int                 `json:"trained_tokens"`
+	CreatedAt      int64               `json:"created_at"`
+	FinishedAt     int64               `json:"finished_at"`
+	Error          *FineTuningJobError `json:"error"`
+}
@@ -330,7 +330,7 @@ func (o *OpenAIResponsesResponse) GetSize() string {
 }
 
 type IncompleteDetails struct {
-	Reasoning string `json:"reasoning"`
+	Reason string `json:"reason"`
 }
 
 type ResponsesOutput struct {
@@ -344,6 +344,9 @@ type ResponsesOutput struct {
 	CallId    string                   `json:"call_id,omitempty"`
 	Name      string                   `json:"name,omitempty"`
 	Arguments string                   `json:"arguments,omitempty"`
+	// reasoning
+	Summary          []ResponsesReasoningSummaryPart `json:"summary,omitempty"`
+	EncryptedContent string                          `json:"encrypted_content,omitempty"`
 }
 
 type ResponsesOutputContent struct {
@@ -384,6 +387,11 @@ type ResponsesStreamResponse struct {
 	SummaryIndex *int                           `json:"summary_index,omitempty"`
 	ItemID       string                         `json:"item_id,omitempty"`
 	Part         *ResponsesReasoningSummaryPart `json:"part,omitempty"`
+	// - response.output_text.done / response.reasoning_summary_text.done
+	Text      string `json:"text,omitempty"`
+	Arguments string `json:"arguments,omitempty"`
+	// SequenceNumber 本地转换生成事件时按顺序填写
+	SequenceNumber int `json:"sequence_number"`
 }
 
 // GetOpenAIError 从动态错误类型中提取OpenAIError结构
@@ -8,6 +8,7 @@ const (
 	RealtimeEventTypeConversationCreate = "conversation.item.create"
 	RealtimeEventTypeResponseCreate     = "response.create"
 	RealtimeEventInputAudioBufferAppend = "input_audio_buffer.append"
+	RealtimeEventInputAudioBufferCommit = "input_audio_buffer.commit"
 )
 
 const (
@@ -19,6 +20,17 @@ const (
 	RealtimeEventResponseFunctionCallArgumentsDelta = "response.function_call_arguments.delta"
 	RealtimeEventResponseFunctionCallArgumentsDone  = "response.function_call_arguments.done"
 	RealtimeEventConversationItemCreated            = "conversation.item.created"
+	RealtimeEventResponseCreated                    = "response.created"
+	RealtimeEventResponseTextDelta                  = "response.text.delta"
+	RealtimeEventInputAudioBufferSpeechStarted      = "input_audio_buffer.speech_started"
+	RealtimeEventInputAudioTranscriptionDelta       = "conversation.item.input_audio_transcription.delta"
+)
+
+// GA 版本（/openai/v1/realtime、gpt-realtime）重命名的服务端事件
+const (
+	RealtimeEventResponseOutputAudioDelta           = "response.output_audio.delta"
+	RealtimeEventResponseOutputAudioTranscriptDelta = "response.output_audio_transcript.delta"
+	RealtimeEventResponseOutputTextDelta            = "response.output_text.delta"
 )
 
 type RealtimeEvent struct {
@@ -31,10 +43,18 @@ type RealtimeEvent struct {
 	Response *RealtimeResponse  `json:"response,omitempty"`
 	Delta    string             `json:"delta,omitempty"`
 	Audio    string             `json:"audio,omitempty"`
+
+	ResponseId string `json:"response_id,omitempty"`
+	ItemId     string `json:"item_id,omitempty"`
+	CallId     string `json:"call_id,omitempty"`
+	Name       string `json:"name,omitempty"`
+	Arguments  string `json:"arguments,omitempty"`
 }
 
 type RealtimeResponse struct {
-	Usage *RealtimeUsage `json:"usage"`
+	Id     string         `json:"id,omitempty"`
+	Status string         `json:"status,omitempty"`
+	Usage  *RealtimeUsage `json:"usage"`
 }
 
 type RealtimeUsage struct {
@@ -45,6 +65,21 @@ type RealtimeUsage struct {
 	OutputTokenDetails OutputTokenDetails `json:"output_token_details"`
 }
 
+// Add 累加另一段用量
+func (u *RealtimeUsage) Add(other *RealtimeUsage) {
+	if other == nil {
+		return
+	}
+	u.TotalTokens += other.TotalTokens
+	u.InputTokens += other.InputTokens
+	u.OutputTokens += other.OutputTokens
+	u.InputTokenDetails.CachedTokens += other.InputTokenDetails.CachedTokens
+	u.InputTokenDetails.TextTokens += other.InputTokenDetails.TextTokens
+	u.InputTokenDetails.AudioTokens += other.InputTokenDetails.AudioTokens
+	u.OutputTokenDetails.TextTokens += other.OutputTokenDetails.TextTokens
+	u.OutputTokenDetails.AudioTokens += other.OutputTokenDetails.AudioTokens
+}
+
 type RealtimeSession struct {
 	Modalities              []string                `json:"modalities"`
 	Instructions            string                  `json:"instructions"`
@@ -79,6 +114,8 @@ type RealtimeItem struct {
 	Name      *string           `json:"name,omitempty"`
 	ToolCalls any               `json:"tool_calls,omitempty"`
 	CallId    string            `json:"call_id,omitempty"`
+	Arguments string            `json:"arguments,omitempty"`
+	Output    string            `json:"output,omitempty"`
 }
 type RealtimeContent struct {
 	Type       string `json:"type"`
@@ -52,6 +52,30 @@ type TaskDto struct {
 	Data       json.RawMessage `json:"data"`
 }
 
+// 任务回调事件
+const (
+	TaskCallbackEventSucceeded = "task.succeeded"
+	TaskCallbackEventFailed    = "task.failed"
+)
+
+// TaskCallbackPayload 异步任务到达终态时推送给 callback_url 的内容
+type TaskCallbackPayload struct {
+	Event      string          `json:"event"`
+	TaskID     string          `json:"task_id"`
+	Platform   string          `json:"platform"`
+	Action     string          `json:"action"`
+	Model      string          `json:"model,omitempty"`
+	Status     string          `json:"status"`
+	Progress   string          `json:"progress"`
+	FailReason string          `json:"fail_reason,omitempty"`
+	ResultURL  string          `json:"result_url,omitempty"`
+	Quota      int             `json:"quota"`
+	SubmitTime int64           `json:"submit_time"`
+	FinishTime int64           `json:"finish_time"`
+	Data       json.RawMessage `json:"data,omitempty"`
+	Timestamp  int64           `json:"timestamp"`
+}
+
 type FetchReq struct {
 	IDs []string `json:"ids"`
 }
@@ -16,6 +16,7 @@ type UserSetting struct {
 	SidebarModules                   string  `json:"sidebar_modules,omitempty"`                      // SidebarModules 左侧边栏模块配置
 	BillingPreference                string  `json:"billing_preference,omitempty"`                   // BillingPreference 扣费策略（订阅/钱包）
 	Language                         string  `json:"language,omitempty"`                             // Language 用户语言偏好 (zh, en)
+	TaskCallbackSecret               string  `json:"task_callback_secret,omitempty"`                 // TaskCallbackSecret 异步任务回调签名密钥，首次使用回调时生成
 }
 
 var (
@@ -42,6 +42,9 @@ const (
 	MsgTokenExhausted            = "token.exhausted"
 	MsgTokenStatusUnavailable    = "token.status_unavailable"
 	MsgTokenDbError              = "token.db_error"
+	MsgTokenRateLimitNegative    = "token.rate_limit_negative"
+	MsgTokenBudgetInvalid        = "token.budget_invalid"
+	MsgTokenCallbackUrlInvalid   = "token.callback_url_invalid"
 )
 
 // Redemption related messages
@@ -314,3 +317,25 @@ const (
 	MsgCustomOAuthBindingNotFound   = "custom_oauth.binding_not_found"
 	MsgCustomOAuthProviderIdInvalid = "custom_oauth.provider_id_field_invalid"
 )
+
+// Organization related messages
+const (
+	MsgOrganizationInvalidId          = "organization.invalid_id"
+	MsgOrganizationPermissionDenied   = "organization.permission_denied"
+	MsgOrganizationNameLength         = "organization.name_length"
+	MsgOrganizationInvalidStatus      = "organization.invalid_status"
+	MsgOrganizationInvalidRole        = "organization.invalid_role"
+	MsgOrganizationMemberExists       = "organization.member_exists"
+	MsgOrganizationSpendLimitNegative = "organization.spend_limit_negative"
+	MsgOrganizationOwnerImmutable     = "organization.owner_immutable"
+	MsgOrganizationOwnerCannotRemove  = "organization.owner_cannot_remove"
+	MsgOrganizationTokenNotFound      = "organization.token_not_found"
+	MsgOrganizationTokenMemberLeft    = "organization.token_member_left"
+	MsgOrganizationQuotaDeltaZero     = "organization.quota_delta_zero"
+)
+
+// Task callback related messages
+const (
+	MsgTaskCallbackInvalidId = "task_callback.invalid_id"
+	MsgTaskCallbackPending   = "task_callback.pending"
+)
@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
 	"github.com/QuantumNous/new-api/setting/operation_setting"
 
 	"github.com/bytedance/gopkg/util/gopool"
@@ -24,6 +25,16 @@ const (
 	loggerDebug = "DEBUG"
 )
 
+var loggerLevels = map[string]int{
+	loggerDebug: common.LogLevelDebug,
+	loggerINFO:  common.LogLevelInfo,
+	loggerWarn:  common.LogLevelWarn,
+	loggerError: common.LogLevelError,
+}
+
+// routeTagKey 与 middleware.RouteTagKey 保持一致，请求日志按路由标签区分模块
+const routeTagKey = "route_tag"
+
 const maxLogCount = 1000000
 
 var logCount int
@@ -66,7 +77,7 @@ func LogError(ctx context.Context, msg string) {
 }
 
 func LogDebug(ctx context.Context, msg string, args ...any) {
-	if common.DebugEnabled {
+	if common.LogLevelEnabled(logModule(ctx), common.LogLevelDebug) {
 		if len(args) > 0 {
 			msg = fmt.Sprintf(msg, args...)
 		}
@@ -74,17 +85,47 @@ func LogDebug(ctx context.Context, msg string, args ...any) {
 	}
 }
 
+// logModule 返回请求所属的日志模块，非请求上下文归为 system
+func logModule(ctx context.Context) string {
+	if tag, ok := ctx.Value(routeTagKey).(string); ok && tag != "" {
+		return tag
+	}
+	if ctx.Value(common.RequestIdKey) != nil {
+		return "web"
+	}
+	return common.LogModuleSystem
+}
+
+// contextLogFields 从请求上下文中提取结构化日志字段
+func contextLogFields(ctx context.Context) []common.LogField {
+	return []common.LogField{
+		{Key: "request_id", Value: ctx.Value(common.RequestIdKey)},
+		{Key: "user_id", Value: ctx.Value("id")},
+		{Key: "token_id", Value: ctx.Value("token_id")},
+		{Key: "channel_id", Value: ctx.Value(string(constant.ContextKeyChannelId))},
+		{Key: "model", Value: ctx.Value(string(constant.ContextKeyOriginalModel))},
+	}
+}
+
 func logHelper(ctx context.Context, level string, msg string) {
+	module := logModule(ctx)
+	if !common.LogLevelEnabled(module, loggerLevels[level]) {
+		return
+	}
 	writer := gin.DefaultErrorWriter
 	if level == loggerINFO {
 		writer = gin.DefaultWriter
 	}
-	id := ctx.Value(common.RequestIdKey)
-	if id == nil {
-		id = "SYSTEM"
+	if common.LogFormat != common.LogFormatText {
+		common.WriteStructuredLog(writer, loggerLevels[level], module, msg, contextLogFields(ctx)...)
+	} else {
+		id := ctx.Value(common.RequestIdKey)
+		if id == nil {
+			id = "SYSTEM"
+		}
+		now := time.Now()
+		_, _ = fmt.Fprintf(writer, "[%s] %v | %s | %s \n", level, now.Format("2006/01/02 - 15:04:05"), id, msg)
 	}
-	now := time.Now()
-	_, _ = fmt.Fprintf(writer, "[%s] %v | %s | %s \n", level, now.Format("2006/01/02 - 15:04:05"), id, msg)
 	logCount++ // we don't need accurate count, so no lock here
 	if logCount > maxLogCount && !setupLogWorking {
 		logCount = 0
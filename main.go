@@ -2,6 +2,7 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"embed"
 	"fmt"
 	"log"
@@ -19,6 +20,7 @@ import (
 	"github.com/QuantumNous/new-api/middleware"
 	"github.com/QuantumNous/new-api/model"
 	"github.com/QuantumNous/new-api/oauth"
+	"github.com/QuantumNous/new-api/pkg/tracing"
 	"github.com/QuantumNous/new-api/relay"
 	"github.com/QuantumNous/new-api/router"
 	"github.com/QuantumNous/new-api/service"
@@ -90,6 +92,11 @@ func main() {
 		go model.SyncChannelCache(common.SyncFrequency)
 	}
 
+	if common.RedisEnabled {
+		// 多节点共享自适应路由的熔断状态
+		go model.SyncChannelBreakers(5)
+	}
+
 	// 热更新配置
 	go model.SyncOptions(common.SyncFrequency)
 
@@ -112,6 +119,18 @@ func main() {
 	// Subscription quota reset task (daily/weekly/monthly/custom)
 	service.StartSubscriptionQuotaResetTask()
 
+	// Token budget reset task (daily/weekly/monthly)
+	service.StartTokenBudgetResetTask()
+
+	// Payload capture rule reload and expired capture purge
+	service.StartPayloadCaptureTask()
+
+	// Async task callback delivery with retries (master node only)
+	service.StartTaskCallbackTask()
+
+	// Expired generated media purge (master node only)
+	service.StartMediaStorageTask()
+
 	// Wire task polling adaptor factory (breaks service -> relay import cycle)
 	service.GetTaskAdaptorFunc = func(platform constant.TaskPlatform) service.TaskPollingAdaptor {
 		a := relay.GetTaskAdaptor(platform)
@@ -120,6 +139,10 @@ func main() {
 		}
 		return a
 	}
+	// Wire local batch executor (breaks service -> controller import cycle)
+	service.ExecuteBatchLineFunc = controller.ExecuteBatchLine
+	// Wire upstream batch settlement (breaks service -> relay import cycle)
+	service.SettleBatchLineFunc = relay.SettleBatchLine
 
 	// Channel upstream model update check task
 	controller.StartChannelUpstreamModelUpdateTask()
@@ -131,6 +154,9 @@ func main() {
 		gopool.Go(func() {
 			controller.UpdateTaskBulk()
 		})
+		gopool.Go(func() {
+			controller.UpdateBatchBulk()
+		})
 	}
 	if os.Getenv("BATCH_UPDATE_ENABLED") == "true" {
 		common.BatchUpdateEnabled = true
@@ -146,6 +172,28 @@ func main() {
 		common.SysLog("pprof enabled")
 	}
 
+	if common.TracingEnabled {
+		err = tracing.Init(context.Background(), tracing.Config{
+			Exporter:       common.TracingExporter,
+			Endpoint:       common.TracingEndpoint,
+			ServiceName:    common.TracingServiceName,
+			ServiceVersion: common.Version,
+			SampleRatio:    common.TracingSampleRatio,
+		})
+		if err != nil {
+			common.SysError("failed to initialize tracing: " + err.Error())
+		} else {
+			common.SysLog("tracing enabled with " + common.TracingExporter + " exporter")
+			defer func() {
+				ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				defer cancel()
+				if err := tracing.Shutdown(ctx); err != nil {
+					common.SysError("failed to shutdown tracing: " + err.Error())
+				}
+			}()
+		}
+	}
+
 	err = common.StartPyroScope()
 	if err != nil {
 		common.SysError(fmt.Sprintf("start pyroscope error : %v", err))
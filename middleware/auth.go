@@ -15,8 +15,10 @@ import (
 	"github.com/QuantumNous/new-api/setting/ratio_setting"
 	"github.com/QuantumNous/new-api/types"
 
+	"github.com/bytedance/gopkg/util/gopool"
 	"github.com/gin-contrib/sessions"
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 func validUserInfo(username string, role int) bool {
@@ -37,6 +39,7 @@ func authHelper(c *gin.Context, minRole int) {
 	id := session.Get("id")
 	status := session.Get("status")
 	useAccessToken := false
+	var managementKey *model.ManagementKey
 	if username == nil {
 		// Check access token
 		accessToken := c.Request.Header.Get("Authorization")
@@ -48,7 +51,16 @@ func authHelper(c *gin.Context, minRole int) {
 			c.Abort()
 			return
 		}
-		user := model.ValidateAccessToken(accessToken)
+		var user *model.User
+		if model.IsManagementKey(accessToken) {
+			var ok bool
+			managementKey, user, ok = authManagementKey(c, accessToken)
+			if !ok {
+				return
+			}
+		} else {
+			user = model.ValidateAccessToken(accessToken)
+		}
 		if user != nil && user.Username != "" {
 			if !validUserInfo(user.Username, user.Role) {
 				c.JSON(http.StatusOK, gin.H{
@@ -133,10 +145,64 @@ func authHelper(c *gin.Context, minRole int) {
 	c.Set("group", session.Get("group"))
 	c.Set("user_group", session.Get("group"))
 	c.Set("use_access_token", useAccessToken)
+	if managementKey != nil {
+		common.SetContextKey(c, constant.ContextKeyManagementKeyId, managementKey.Id)
+		common.SetContextKey(c, constant.ContextKeyManagementKeyScopes, managementKey.GetScopes())
+	}
 
 	c.Next()
 }
 
+// authManagementKey 校验管理密钥及其 IP 限制，并检查密钥是否具备当前路由声明的权限
+func authManagementKey(c *gin.Context, accessToken string) (*model.ManagementKey, *model.User, bool) {
+	key, err := model.ValidateManagementKey(accessToken)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "无权进行此操作，" + err.Error(),
+		})
+		c.Abort()
+		return nil, nil, false
+	}
+	if allowIps := key.GetIpLimits(); len(allowIps) > 0 {
+		ip := net.ParseIP(c.ClientIP())
+		if ip == nil || !common.IsIpInCIDRList(ip, allowIps) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"success": false,
+				"message": "无权进行此操作，您的 IP 不在管理密钥允许访问的列表中",
+			})
+			c.Abort()
+			return nil, nil, false
+		}
+	}
+	scope := service.RequiredManagementScope(c)
+	if scope == "" {
+		c.JSON(http.StatusForbidden, gin.H{
+			"success": false,
+			"message": "无权进行此操作，该接口不支持使用管理密钥访问",
+		})
+		c.Abort()
+		return nil, nil, false
+	}
+	if !model.ManagementScopesAllow(key.GetScopes(), scope) {
+		abortManagementScopeDenied(c, scope)
+		return nil, nil, false
+	}
+	user, err := model.GetUserById(key.UserId, false)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": "无权进行此操作，用户信息无效",
+		})
+		c.Abort()
+		return nil, nil, false
+	}
+	gopool.Go(func() {
+		model.UpdateManagementKeyAccessedTime(key.Id)
+	})
+	return key, user, true
+}
+
 func TryUserAuth() func(c *gin.Context) {
 	return func(c *gin.Context) {
 		session := sessions.Default(c)
@@ -247,6 +313,8 @@ func TokenAuthReadOnly() func(c *gin.Context) {
 
 func TokenAuth() func(c *gin.Context) {
 	return func(c *gin.Context) {
+		span, endSpan := startMiddlewareSpan(c, "auth.token")
+		defer endSpan()
 		// 先检测是否为ws
 		if c.Request.Header.Get("Sec-WebSocket-Protocol") != "" {
 			// Sec-WebSocket-Protocol: realtime, openai-insecure-api-key.sk-xxx, openai-beta.realtime-v1
@@ -365,6 +433,8 @@ func TokenAuth() func(c *gin.Context) {
 		if err != nil {
 			return
 		}
+		span.SetAttributes(attribute.Int("user.id", token.UserId), attribute.Int("token.id", token.Id), attribute.String("relay.group", userGroup))
+		endSpan()
 		c.Next()
 	}
 }
@@ -389,6 +459,12 @@ func SetupContextForToken(c *gin.Context, token *model.Token, parts ...string) e
 	}
 	common.SetContextKey(c, constant.ContextKeyTokenGroup, token.Group)
 	common.SetContextKey(c, constant.ContextKeyTokenCrossGroupRetry, token.CrossGroupRetry)
+	common.SetContextKey(c, constant.ContextKeyTokenModelFallback, token.ModelFallback)
+	common.SetContextKey(c, constant.ContextKeyTokenRpmLimit, token.RpmLimit)
+	common.SetContextKey(c, constant.ContextKeyTokenTpmLimit, token.TpmLimit)
+	common.SetContextKey(c, constant.ContextKeyTokenConcurrencyLimit, token.ConcurrencyLimit)
+	common.SetContextKey(c, constant.ContextKeyTokenOrganizationId, token.OrganizationId)
+	common.SetContextKey(c, constant.ContextKeyTokenCallbackUrl, token.CallbackUrl)
 	if len(parts) > 1 {
 		if model.IsAdmin(token.UserId) {
 			c.Set("specific_channel_id", parts[1])
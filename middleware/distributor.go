@@ -16,10 +16,12 @@ import (
 	"github.com/QuantumNous/new-api/model"
 	relayconstant "github.com/QuantumNous/new-api/relay/constant"
 	"github.com/QuantumNous/new-api/service"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
 	"github.com/QuantumNous/new-api/setting/ratio_setting"
 	"github.com/QuantumNous/new-api/types"
 
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 type ModelRequest struct {
@@ -29,6 +31,8 @@ type ModelRequest struct {
 
 func Distribute() func(c *gin.Context) {
 	return func(c *gin.Context) {
+		span, endSpan := startMiddlewareSpan(c, "channel.select")
+		defer endSpan()
 		var channel *model.Channel
 		channelId, ok := common.GetContextKey(c, constant.ContextKeyTokenSpecificChannelId)
 		modelRequest, shouldSelectChannel, err := getModelRequest(c)
@@ -36,6 +40,19 @@ func Distribute() func(c *gin.Context) {
 			abortWithOpenAiMessage(c, http.StatusBadRequest, i18n.T(c, i18n.MsgDistributorInvalidRequest, map[string]any{"Error": err.Error()}))
 			return
 		}
+		// 本站微调生成的模型只允许提交者调用，固定使用训练所在的渠道
+		var fineTuned *model.FineTunedModel
+		if !ok && strings.HasPrefix(modelRequest.Model, "ft:") {
+			if m, err := model.CacheGetFineTunedModel(modelRequest.Model); err == nil {
+				if m.UserId != c.GetInt("id") || !tokenAllowsModel(c, modelRequest.Model) {
+					abortWithOpenAiMessage(c, http.StatusForbidden, i18n.T(c, i18n.MsgDistributorTokenModelForbidden, map[string]any{"Model": modelRequest.Model}))
+					return
+				}
+				fineTuned = m
+				channelId, ok = strconv.Itoa(m.ChannelId), true
+				common.SetContextKey(c, constant.ContextKeyTokenSpecificChannelId, channelId)
+			}
+		}
 		if ok {
 			id, err := strconv.Atoi(channelId.(string))
 			if err != nil {
@@ -129,6 +146,15 @@ func Distribute() func(c *gin.Context) {
 						TokenGroup: usingGroup,
 						Retry:      common.GetPointer(0),
 					})
+					if err != nil || channel == nil {
+						// 请求模型没有可用渠道时先尝试降级链，避免在进入重试流程前直接返回 503
+						if fallbackChannel, fallbackModel, fallbackGroup := service.SelectModelFallbackChannel(c, modelRequest.Model, usingGroup); fallbackChannel != nil {
+							common.SetContextKey(c, constant.ContextKeyModelFallbackFrom, modelRequest.Model)
+							c.Header(service.ModelFallbackHeader, fallbackModel)
+							modelRequest.Model = fallbackModel
+							channel, selectGroup, err = fallbackChannel, fallbackGroup, nil
+						}
+					}
 					if err != nil {
 						showGroup := usingGroup
 						if usingGroup == "auto" {
@@ -151,7 +177,18 @@ func Distribute() func(c *gin.Context) {
 			}
 		}
 		common.SetContextKey(c, constant.ContextKeyRequestStartTime, time.Now())
-		SetupContextForSelectedChannel(c, channel, modelRequest.Model)
+		service.SetupContextForSelectedChannel(c, channel, modelRequest.Model)
+		if fineTuned != nil && channel.ChannelInfo.IsMultiKey {
+			// 微调模型只存在于训练时使用的 key 所属的组织下
+			common.SetContextKey(c, constant.ContextKeyChannelMultiKeyIndex, fineTuned.KeyIndex)
+			common.SetContextKey(c, constant.ContextKeyChannelKey, service.GetChannelKeyByIndex(channel, fineTuned.KeyIndex))
+		}
+		span.SetAttributes(
+			attribute.String("relay.model", modelRequest.Model),
+			attribute.Int("relay.channel_id", common.GetContextKeyInt(c, constant.ContextKeyChannelId)),
+			attribute.Int("relay.key_index", common.GetContextKeyInt(c, constant.ContextKeyChannelMultiKeyIndex)),
+		)
+		endSpan()
 		c.Next()
 		if channel != nil && c.Writer != nil && c.Writer.Status() < http.StatusBadRequest {
 			service.RecordChannelAffinity(c, channel.Id)
@@ -159,6 +196,15 @@ func Distribute() func(c *gin.Context) {
 	}
 }
 
+// tokenAllowsModel 令牌开启模型限制时，模型名或其匹配名需在允许列表中
+func tokenAllowsModel(c *gin.Context, modelName string) bool {
+	if !common.GetContextKeyBool(c, constant.ContextKeyTokenModelLimitEnabled) {
+		return true
+	}
+	limits, _ := common.GetContextKeyType[map[string]bool](c, constant.ContextKeyTokenModelLimit)
+	return limits[modelName] || limits[ratio_setting.FormatMatchingModelName(modelName)]
+}
+
 // getModelFromRequest 从请求中读取模型信息
 // 根据 Content-Type 自动处理：
 // - application/json
@@ -279,6 +325,16 @@ func getModelRequest(c *gin.Context) (*ModelRequest, bool, error) {
 			modelRequest.Model = modelName
 		}
 		c.Set("relay_mode", relayMode)
+	} else if strings.HasPrefix(c.Request.URL.Path, "/v1/fine_tuning/jobs") {
+		req, err := getModelFromRequest(c)
+		if err != nil {
+			return nil, false, err
+		}
+		modelRequest.Model = req.Model
+		c.Set("platform", string(constant.TaskPlatformFineTuning))
+	} else if strings.HasPrefix(c.Request.URL.Path, "/v1/files") {
+		// 文件上传请求不携带模型，按 ?model= 或 file_setting.upload_model 选择渠道
+		modelRequest.Model = common.GetStringIfEmpty(c.Query("model"), operation_setting.GetFileSetting().UploadModel)
 	} else if !strings.HasPrefix(c.Request.URL.Path, "/v1/audio/transcriptions") && !strings.Contains(c.Request.Header.Get("Content-Type"), "multipart/form-data") {
 		req, err := getModelFromRequest(c)
 		if err != nil {
@@ -290,6 +346,10 @@ func getModelRequest(c *gin.Context) (*ModelRequest, bool, error) {
 		//wss://api.openai.com/v1/realtime?model=gpt-4o-realtime-preview-2024-10-01
 		modelRequest.Model = c.Query("model")
 	}
+	if c.Request.Method == http.MethodGet && c.Request.URL.Path == "/v1/responses" {
+		// Responses WebSocket 模式在握手时按 ?model= 选择渠道，连接期间固定使用该渠道
+		modelRequest.Model = c.Query("model")
+	}
 	if strings.HasPrefix(c.Request.URL.Path, "/v1/moderations") {
 		if modelRequest.Model == "" {
 			modelRequest.Model = "text-moderation-stable"
@@ -359,70 +419,6 @@ func getModelRequest(c *gin.Context) (*ModelRequest, bool, error) {
 	return &modelRequest, shouldSelectChannel, nil
 }
 
-func SetupContextForSelectedChannel(c *gin.Context, channel *model.Channel, modelName string) *types.NewAPIError {
-	c.Set("original_model", modelName) // for retry
-	if channel == nil {
-		return types.NewError(errors.New("channel is nil"), types.ErrorCodeGetChannelFailed, types.ErrOptionWithSkipRetry())
-	}
-	common.SetContextKey(c, constant.ContextKeyChannelId, channel.Id)
-	common.SetContextKey(c, constant.ContextKeyChannelName, channel.Name)
-	common.SetContextKey(c, constant.ContextKeyChannelType, channel.Type)
-	common.SetContextKey(c, constant.ContextKeyChannelCreateTime, channel.CreatedTime)
-	common.SetContextKey(c, constant.ContextKeyChannelSetting, channel.GetSetting())
-	common.SetContextKey(c, constant.ContextKeyChannelOtherSetting, channel.GetOtherSettings())
-	paramOverride := channel.GetParamOverride()
-	headerOverride := channel.GetHeaderOverride()
-	if mergedParam, applied := service.ApplyChannelAffinityOverrideTemplate(c, paramOverride); applied {
-		paramOverride = mergedParam
-	}
-	common.SetContextKey(c, constant.ContextKeyChannelParamOverride, paramOverride)
-	common.SetContextKey(c, constant.ContextKeyChannelHeaderOverride, headerOverride)
-	if nil != channel.OpenAIOrganization && *channel.OpenAIOrganization != "" {
-		common.SetContextKey(c, constant.ContextKeyChannelOrganization, *channel.OpenAIOrganization)
-	}
-	common.SetContextKey(c, constant.ContextKeyChannelAutoBan, channel.GetAutoBan())
-	common.SetContextKey(c, constant.ContextKeyChannelModelMapping, channel.GetModelMapping())
-	common.SetContextKey(c, constant.ContextKeyChannelStatusCodeMapping, channel.GetStatusCodeMapping())
-
-	key, index, newAPIError := channel.GetNextEnabledKey()
-	if newAPIError != nil {
-		return newAPIError
-	}
-	if channel.ChannelInfo.IsMultiKey {
-		common.SetContextKey(c, constant.ContextKeyChannelIsMultiKey, true)
-		common.SetContextKey(c, constant.ContextKeyChannelMultiKeyIndex, index)
-	} else {
-		// 必须设置为 false，否则在重试到单个 key 的时候会导致日志显示错误
-		common.SetContextKey(c, constant.ContextKeyChannelIsMultiKey, false)
-	}
-	// c.Request.Header.Set("Authorization", fmt.Sprintf("Bearer %s", key))
-	common.SetContextKey(c, constant.ContextKeyChannelKey, key)
-	common.SetContextKey(c, constant.ContextKeyChannelBaseUrl, channel.GetBaseURL())
-
-	common.SetContextKey(c, constant.ContextKeySystemPromptOverride, false)
-
-	// TODO: api_version统一
-	switch channel.Type {
-	case constant.ChannelTypeAzure:
-		c.Set("api_version", channel.Other)
-	case constant.ChannelTypeVertexAi:
-		c.Set("region", channel.Other)
-	case constant.ChannelTypeXunfei:
-		c.Set("api_version", channel.Other)
-	case constant.ChannelTypeGemini:
-		c.Set("api_version", channel.Other)
-	case constant.ChannelTypeAli:
-		c.Set("plugin", channel.Other)
-	case constant.ChannelCloudflare:
-		c.Set("api_version", channel.Other)
-	case constant.ChannelTypeMokaAI:
-		c.Set("api_version", channel.Other)
-	case constant.ChannelTypeCoze:
-		c.Set("bot_id", channel.Other)
-	}
-	return nil
-}
-
 // extractModelNameFromGeminiPath 从 Gemini API URL 路径中提取模型名
 // 输入格式: /v1beta/models/gemini-2.0-flash:generateContent
 // 输出: gemini-2.0-flash
@@ -4,6 +4,7 @@ import (
 	"fmt"
 
 	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
 	"github.com/gin-gonic/gin"
 )
 
@@ -26,6 +27,26 @@ func SetUpLogger(server *gin.Engine) {
 		if tag == "" {
 			tag = "web"
 		}
+		if !common.LogLevelEnabled(common.LogModuleAccess, common.LogLevelInfo) {
+			return ""
+		}
+		if common.LogFormat != common.LogFormatText {
+			userId, _ := param.Keys["id"].(int)
+			tokenId, _ := param.Keys["token_id"].(int)
+			channelId, _ := param.Keys[string(constant.ContextKeyChannelId)].(int)
+			modelName, _ := param.Keys[string(constant.ContextKeyOriginalModel)].(string)
+			return common.FormatStructuredLog(param.TimeStamp, common.LogLevelInfo, common.LogModuleAccess, param.Method+" "+param.Path,
+				common.LogField{Key: "route", Value: tag},
+				common.LogField{Key: "request_id", Value: requestID},
+				common.LogField{Key: "status", Value: param.StatusCode},
+				common.LogField{Key: "latency_ms", Value: param.Latency.Milliseconds()},
+				common.LogField{Key: "client_ip", Value: param.ClientIP},
+				common.LogField{Key: "user_id", Value: userId},
+				common.LogField{Key: "token_id", Value: tokenId},
+				common.LogField{Key: "channel_id", Value: channelId},
+				common.LogField{Key: "model", Value: modelName},
+			)
+		}
 		return fmt.Sprintf("[GIN] %s | %s | %s | %3d | %13v | %15s | %7s %s\n",
 			param.TimeStamp.Format("2006/01/02 - 15:04:05"),
 			tag,
@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ManagementScope 声明路由允许使用的管理密钥权限，需放在 UserAuth / AdminAuth / RootAuth 之前。
+// 只传资源名（如 "channels"）时，GET 请求需要 channels:read，其余请求需要 channels:write
+func ManagementScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		common.SetContextKey(c, constant.ContextKeyManagementScope, scope)
+		c.Next()
+	}
+}
+
+// RequireManagementScope 在鉴权之后额外要求管理密钥具备指定权限
+func RequireManagementScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !service.ManagementKeyAllows(c, scope) {
+			abortManagementScopeDenied(c, scope)
+			return
+		}
+		c.Next()
+	}
+}
+
+func abortManagementScopeDenied(c *gin.Context, scope string) {
+	c.JSON(http.StatusForbidden, gin.H{
+		"success": false,
+		"message": "无权进行此操作，管理密钥缺少权限 " + scope,
+	})
+	c.Abort()
+}
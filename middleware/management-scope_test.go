@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/model"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequireManagementScopeForOrganizationQuota(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	request := func(scopes []string) int {
+		engine := gin.New()
+		// 模拟 AdminAuth 通过管理密钥鉴权后写入的上下文
+		engine.Use(ManagementScope("users"), func(c *gin.Context) {
+			common.SetContextKey(c, constant.ContextKeyManagementKeyId, 1)
+			common.SetContextKey(c, constant.ContextKeyManagementKeyScopes, scopes)
+		})
+		engine.PUT("/api/organization/:id/quota", RequireManagementScope(model.ManagementScopeUsersQuota), func(c *gin.Context) {
+			c.Status(http.StatusOK)
+		})
+		recorder := httptest.NewRecorder()
+		engine.ServeHTTP(recorder, httptest.NewRequest(http.MethodPut, "/api/organization/1/quota", nil))
+		return recorder.Code
+	}
+
+	// users:write 不能调整组织额度，需要 users:quota
+	require.Equal(t, http.StatusForbidden, request([]string{model.ManagementScopeUsersWrite}))
+	require.Equal(t, http.StatusOK, request([]string{model.ManagementScopeUsersQuota}))
+}
@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/pkg/metrics"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RelayMetrics 记录中继请求的 Prometheus 指标，仅统计 relay 路由
+func RelayMetrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+		if c.GetString(RouteTagKey) != "relay" {
+			return
+		}
+		metrics.ObserveRelayRequest(
+			common.GetContextKeyString(c, constant.ContextKeyOriginalModel),
+			common.GetContextKeyInt(c, constant.ContextKeyChannelId),
+			common.GetContextKeyString(c, constant.ContextKeyUsingGroup),
+			c.Writer.Status(),
+			time.Since(start),
+		)
+	}
+}
+
+// MetricsAuth 校验访问 /metrics 的 Bearer Token，未配置 METRICS_TOKEN 时不校验
+func MetricsAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if common.MetricsToken == "" {
+			c.Next()
+			return
+		}
+		token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(token), []byte(common.MetricsToken)) != 1 {
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+		c.Next()
+	}
+}
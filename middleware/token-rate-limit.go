@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TokenRateLimit 令牌级 RPM / TPM / 并发限制，需在 TokenAuth 之后使用
+func TokenRateLimit() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		release, err := service.CheckTokenRateLimit(c)
+		if err != nil {
+			var limitErr *service.TokenRateLimitError
+			if errors.As(err, &limitErr) {
+				c.JSON(http.StatusTooManyRequests, gin.H{
+					"error": gin.H{
+						"message": common.MessageWithRequestId(limitErr.Message, c.GetString(common.RequestIdKey)),
+						"type":    limitErr.Type,
+						"param":   nil,
+						"code":    "rate_limit_exceeded",
+					},
+				})
+				c.Abort()
+				logger.LogWarn(c.Request.Context(), fmt.Sprintf("token %d | %s", c.GetInt("token_id"), limitErr.Message))
+				return
+			}
+			logger.LogError(c.Request.Context(), "检查令牌限流失败: "+err.Error())
+			abortWithOpenAiMessage(c, http.StatusInternalServerError, "rate_limit_check_failed")
+			return
+		}
+		if release != nil {
+			defer release()
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/pkg/tracing"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RelayTracing 为中继请求创建根 span，并沿用客户端传入的 traceparent
+func RelayTracing() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !tracing.Enabled() {
+			c.Next()
+			return
+		}
+		ctx := tracing.Extract(c.Request.Context(), c.Request.Header)
+		ctx, span := tracing.StartWithKind(ctx, c.Request.Method+" "+c.FullPath(), trace.SpanKindServer,
+			attribute.String("http.request.method", c.Request.Method),
+			attribute.String("http.route", c.FullPath()),
+			attribute.String("request.id", c.GetString(common.RequestIdKey)),
+		)
+		defer span.End()
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+
+		status := c.Writer.Status()
+		span.SetAttributes(
+			attribute.Int("http.response.status_code", status),
+			attribute.String("relay.model", common.GetContextKeyString(c, constant.ContextKeyOriginalModel)),
+			attribute.String("relay.group", common.GetContextKeyString(c, constant.ContextKeyUsingGroup)),
+			attribute.Int("relay.channel_id", common.GetContextKeyInt(c, constant.ContextKeyChannelId)),
+			attribute.Int("user.id", c.GetInt("id")),
+			attribute.Int("token.id", c.GetInt("token_id")),
+		)
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+	}
+}
+
+// startMiddlewareSpan 为鉴权、渠道选择等中间件创建 span；返回的 end 应在 c.Next() 之前调用，
+// 并通过 defer 覆盖中途 Abort 的分支，重复调用是安全的
+func startMiddlewareSpan(c *gin.Context, name string) (trace.Span, func()) {
+	_, span := tracing.Start(c.Request.Context(), name)
+	return span, func() {
+		if c.IsAborted() {
+			status := c.Writer.Status()
+			span.SetAttributes(attribute.Int("http.response.status_code", status))
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+		span.End()
+	}
+}
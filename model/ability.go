@@ -7,6 +7,7 @@ import (
 	"sync"
 
 	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
 
 	"github.com/samber/lo"
 	"gorm.io/gorm"
@@ -121,20 +122,42 @@ func GetChannel(group string, model string, retry int) (*Channel, error) {
 	}
 	channel := Channel{}
 	if len(abilities) > 0 {
-		// Randomly choose one
-		weightSum := uint(0)
-		for _, ability_ := range abilities {
-			weightSum += ability_.Weight + 10
+		weights := make([]int, len(abilities))
+		for i, ability_ := range abilities {
+			weights[i] = int(ability_.Weight) + 10
+		}
+		keys := make([]channelHealthKey, len(abilities))
+		for i, ability_ := range abilities {
+			keys[i] = channelHealthKey{ChannelId: ability_.ChannelId, KeyIndex: -1}
+		}
+		if operation_setting.GetAdaptiveRoutingSetting().Enabled {
+			kept, adjusted := adaptiveWeights(keys, weights)
+			filteredAbilities := make([]Ability, len(kept))
+			filteredKeys := make([]channelHealthKey, len(kept))
+			for i, idx := range kept {
+				filteredAbilities[i] = abilities[idx]
+				filteredKeys[i] = keys[idx]
+			}
+			abilities, keys, weights = filteredAbilities, filteredKeys, adjusted
 		}
 		// Randomly choose one
-		weight := common.GetRandomInt(int(weightSum))
-		for _, ability_ := range abilities {
-			weight -= int(ability_.Weight) + 10
-			//log.Printf("weight: %d, ability weight: %d", weight, *ability_.Weight)
-			if weight <= 0 {
-				channel.Id = ability_.ChannelId
-				break
+		pick := func(weights []int) int {
+			weightSum := 0
+			for _, weight := range weights {
+				weightSum += weight
 			}
+			weight := common.GetRandomInt(weightSum)
+			for i := range weights {
+				weight -= weights[i]
+				//log.Printf("weight: %d, ability weight: %d", weight, *ability_.Weight)
+				if weight <= 0 {
+					return i
+				}
+			}
+			return -1
+		}
+		if idx := pickChannelHealthCandidate(keys, weights, pick); idx >= 0 {
+			channel.Id = abilities[idx].ChannelId
 		}
 	} else {
 		return nil, nil
@@ -0,0 +1,82 @@
+package model
+
+import (
+	"github.com/QuantumNous/new-api/common"
+)
+
+const (
+	AuditActionCreate = "create"
+	AuditActionUpdate = "update"
+	AuditActionDelete = "delete"
+)
+
+const (
+	AuditTargetChannel    = "channel"
+	AuditTargetOption     = "option"
+	AuditTargetUser       = "user"
+	AuditTargetRedemption = "redemption"
+	AuditTargetTopUp      = "topup"
+
+	AuditTargetPayloadCapture = "payload_capture"
+	AuditTargetOrganization   = "organization"
+)
+
+// AuditLog 管理操作审计记录，Before / After 为脱敏后的 JSON 快照
+type AuditLog struct {
+	Id              int    `json:"id"`
+	CreatedAt       int64  `json:"created_at" gorm:"bigint;index"`
+	ActorId         int    `json:"actor_id" gorm:"index"`
+	ActorName       string `json:"actor_name" gorm:"type:varchar(64)"`
+	ActorRole       int    `json:"actor_role"`
+	ManagementKeyId int    `json:"management_key_id"`
+	Ip              string `json:"ip" gorm:"type:varchar(64)"`
+	Action          string `json:"action" gorm:"type:varchar(64);index"`
+	TargetType      string `json:"target_type" gorm:"type:varchar(32);index:idx_audit_target"`
+	TargetId        string `json:"target_id" gorm:"type:varchar(255);index:idx_audit_target"`
+	ChangedFields   string `json:"changed_fields" gorm:"type:text"` // 逗号分隔
+	Before          string `json:"before" gorm:"type:text"`
+	After           string `json:"after" gorm:"type:text"`
+}
+
+type AuditLogFilter struct {
+	ActorId        int
+	Action         string
+	TargetType     string
+	TargetId       string
+	StartTimestamp int64
+	EndTimestamp   int64
+}
+
+func (log *AuditLog) Insert() error {
+	if log.CreatedAt == 0 {
+		log.CreatedAt = common.GetTimestamp()
+	}
+	return LOG_DB.Create(log).Error
+}
+
+func GetAuditLogs(filter AuditLogFilter, startIdx int, num int) (logs []*AuditLog, total int64, err error) {
+	tx := LOG_DB.Model(&AuditLog{})
+	if filter.ActorId != 0 {
+		tx = tx.Where("actor_id = ?", filter.ActorId)
+	}
+	if filter.Action != "" {
+		tx = tx.Where("action = ?", filter.Action)
+	}
+	if filter.TargetType != "" {
+		tx = tx.Where("target_type = ?", filter.TargetType)
+	}
+	if filter.TargetId != "" {
+		tx = tx.Where("target_id = ?", filter.TargetId)
+	}
+	if filter.StartTimestamp != 0 {
+		tx = tx.Where("created_at >= ?", filter.StartTimestamp)
+	}
+	if filter.EndTimestamp != 0 {
+		tx = tx.Where("created_at <= ?", filter.EndTimestamp)
+	}
+	if err = tx.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err = tx.Order("id desc").Limit(num).Offset(startIdx).Find(&logs).Error
+	return logs, total, err
+}
@@ -0,0 +1,153 @@
+package model
+
+import (
+	"errors"
+
+	"github.com/QuantumNous/new-api/common"
+	"gorm.io/gorm"
+)
+
+const (
+	BatchStatusValidating = "validating"
+	BatchStatusFailed     = "failed"
+	BatchStatusInProgress = "in_progress"
+	BatchStatusFinalizing = "finalizing"
+	BatchStatusCompleted  = "completed"
+	BatchStatusExpired    = "expired"
+	BatchStatusCancelling = "cancelling"
+	BatchStatusCancelled  = "cancelled"
+)
+
+const (
+	BatchModeUpstream = "upstream" // 转发给支持 Batch API 的上游渠道
+	BatchModeLocal    = "local"    // 由本地 worker 逐行执行
+)
+
+// Batch 记录 /v1/batches 批处理任务。
+// 上游模式下 BatchId 与上游 batch id 一致；本地模式下由 new-api 生成。
+type Batch struct {
+	Id               int       `json:"id" gorm:"primaryKey;autoIncrement"`
+	BatchId          string    `json:"batch_id" gorm:"type:varchar(191);uniqueIndex"`
+	UserId           int       `json:"user_id" gorm:"index"`
+	TokenId          int       `json:"token_id" gorm:"index"`
+	Group            string    `json:"group" gorm:"type:varchar(64)"`
+	Mode             string    `json:"mode" gorm:"type:varchar(16)"`
+	Endpoint         string    `json:"endpoint" gorm:"type:varchar(64)"`
+	InputFileId      string    `json:"input_file_id" gorm:"type:varchar(191)"`
+	OutputFileId     string    `json:"output_file_id" gorm:"type:varchar(191)"`
+	ErrorFileId      string    `json:"error_file_id" gorm:"type:varchar(191)"`
+	CompletionWindow string    `json:"completion_window" gorm:"type:varchar(16)"`
+	Status           string    `json:"status" gorm:"type:varchar(20);index"`
+	ChannelId        int       `json:"channel_id" gorm:"index"`
+	ChannelKeyIdx    int       `json:"-" gorm:"default:0"`
+	UpstreamBatchId  string    `json:"upstream_batch_id" gorm:"type:varchar(191)"`
+	InputStoragePath string    `json:"-" gorm:"type:varchar(512)"` // 本地模式创建时保存的输入文件副本
+	RequestTotal     int       `json:"request_total"`
+	RequestCompleted int       `json:"request_completed"`
+	RequestFailed    int       `json:"request_failed"`
+	Quota            int       `json:"quota"` // 上游模式下已结算额度，本地模式逐行走 relay 计费
+	Metadata         JSONValue `json:"metadata" gorm:"type:json"`
+	Errors           JSONValue `json:"errors" gorm:"type:json"`
+	CreatedAt        int64     `json:"created_at" gorm:"bigint;index"`
+	InProgressAt     int64     `json:"in_progress_at" gorm:"bigint"`
+	FinalizingAt     int64     `json:"finalizing_at" gorm:"bigint"`
+	CompletedAt      int64     `json:"completed_at" gorm:"bigint"`
+	FailedAt         int64     `json:"failed_at" gorm:"bigint"`
+	ExpiresAt        int64     `json:"expires_at" gorm:"bigint"`
+	ExpiredAt        int64     `json:"expired_at" gorm:"bigint"`
+	CancellingAt     int64     `json:"cancelling_at" gorm:"bigint"`
+	CancelledAt      int64     `json:"cancelled_at" gorm:"bigint"`
+}
+
+func (Batch) TableName() string {
+	return "batches"
+}
+
+// IsFinished 批处理是否已进入终态
+func (b *Batch) IsFinished() bool {
+	switch b.Status {
+	case BatchStatusCompleted, BatchStatusFailed, BatchStatusExpired, BatchStatusCancelled:
+		return true
+	}
+	return false
+}
+
+func (b *Batch) Insert() error {
+	if b.CreatedAt == 0 {
+		b.CreatedAt = common.GetTimestamp()
+	}
+	return DB.Create(b).Error
+}
+
+func (b *Batch) Update() error {
+	return DB.Save(b).Error
+}
+
+// UpdateWithStatus 仅当数据库中的状态仍为 fromStatus 时才保存（CAS），防止取消与 worker 并发覆盖。
+// 与 Task.UpdateWithStatus 相同，不能使用 Save()，否则零行匹配时会回退为 INSERT ON CONFLICT。
+func (b *Batch) UpdateWithStatus(fromStatus string) (bool, error) {
+	result := DB.Model(b).Where("status = ?", fromStatus).Select("*").Updates(b)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// UpdateRequestCounts 只更新请求计数，不覆盖可能被并发修改的状态
+func (b *Batch) UpdateRequestCounts() error {
+	return DB.Model(b).Select("request_total", "request_completed", "request_failed").Updates(b).Error
+}
+
+// GetBatchStatus 读取最新状态，用于本地执行过程中感知取消
+func GetBatchStatus(id int) (string, error) {
+	var status string
+	err := DB.Model(&Batch{}).Where("id = ?", id).Pluck("status", &status).Error
+	return status, err
+}
+
+func GetUserBatchById(userId int, batchId string) (*Batch, error) {
+	if batchId == "" {
+		return nil, nil
+	}
+	var batch Batch
+	err := DB.Where("user_id = ? AND batch_id = ?", userId, batchId).First(&batch).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &batch, nil
+}
+
+// ListUserBatches 按创建时间倒序列出用户批处理，after 为上一页最后一个 batch id
+func ListUserBatches(userId int, after string, limit int) ([]*Batch, bool, error) {
+	var batches []*Batch
+	query := DB.Where("user_id = ?", userId)
+	if after != "" {
+		var cursor Batch
+		if err := DB.Where("user_id = ? AND batch_id = ?", userId, after).First(&cursor).Error; err == nil {
+			query = query.Where("id < ?", cursor.Id)
+		}
+	}
+	err := query.Order("id desc").Limit(limit + 1).Find(&batches).Error
+	if err != nil {
+		return nil, false, err
+	}
+	hasMore := len(batches) > limit
+	if hasMore {
+		batches = batches[:limit]
+	}
+	return batches, hasMore, nil
+}
+
+// GetUnfinishedBatches 获取所有未结束的批处理，供后台 worker 轮询
+func GetUnfinishedBatches(limit int) []*Batch {
+	var batches []*Batch
+	err := DB.Where("status NOT IN ?", []string{BatchStatusCompleted, BatchStatusFailed, BatchStatusExpired, BatchStatusCancelled}).
+		Order("id asc").Limit(limit).Find(&batches).Error
+	if err != nil {
+		return nil
+	}
+	return batches
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/QuantumNous/new-api/common"
 	"github.com/QuantumNous/new-api/constant"
 	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
 	"github.com/QuantumNous/new-api/types"
 
 	"github.com/samber/lo"
@@ -145,10 +146,24 @@ func (channel *Channel) GetNextEnabledKey() (string, int, *types.NewAPIError) {
 		return "", 0, types.NewError(errors.New("no enabled keys"), types.ErrorCodeChannelNoAvailableKey)
 	}
 
+	// Adaptive routing: skip keys whose circuit breaker is open and weight keys by recent health
+	var keyWeights []int
+	if operation_setting.GetAdaptiveRoutingSetting().Enabled {
+		enabledIdx, keyWeights = adaptiveKeyWeights(channel.Id, enabledIdx)
+	}
+
 	switch channel.ChannelInfo.MultiKeyMode {
 	case constant.MultiKeyModeRandom:
 		// Randomly pick one enabled key
-		selectedIdx := enabledIdx[rand.Intn(len(enabledIdx))]
+		if keyWeights == nil {
+			selectedIdx := enabledIdx[rand.Intn(len(enabledIdx))]
+			return keys[selectedIdx], selectedIdx, nil
+		}
+		healthKeys := make([]channelHealthKey, len(enabledIdx))
+		for i, idx := range enabledIdx {
+			healthKeys[i] = channelHealthKey{ChannelId: channel.Id, KeyIndex: idx}
+		}
+		selectedIdx := enabledIdx[pickChannelHealthCandidate(healthKeys, keyWeights, pickWeightedIndex)]
 		return keys[selectedIdx], selectedIdx, nil
 	case constant.MultiKeyModePolling:
 		// Use channel-specific lock to ensure thread-safe polling
@@ -173,14 +188,26 @@ func (channel *Channel) GetNextEnabledKey() (string, int, *types.NewAPIError) {
 		if start < 0 || start >= len(keys) {
 			start = 0
 		}
+		// Keys whose half-open probe is taken by a concurrent request are skipped, the first enabled key is kept as fallback
+		firstIdx := -1
 		for i := 0; i < len(keys); i++ {
 			idx := (start + i) % len(keys)
-			if getStatus(idx) == common.ChannelStatusEnabled {
+			if lo.Contains(enabledIdx, idx) {
+				if firstIdx < 0 {
+					firstIdx = idx
+				}
+				if !markChannelHealthSelected(channel.Id, idx) {
+					continue
+				}
 				// update polling index for next call (point to the next position)
 				channel.ChannelInfo.MultiKeyPollingIndex = (idx + 1) % len(keys)
 				return keys[idx], idx, nil
 			}
 		}
+		if firstIdx >= 0 {
+			channel.ChannelInfo.MultiKeyPollingIndex = (firstIdx + 1) % len(keys)
+			return keys[firstIdx], firstIdx, nil
+		}
 		// Fallback – should not happen, but return first enabled key
 		return keys[enabledIdx[0]], enabledIdx[0], nil
 	default:
@@ -369,11 +396,13 @@ func BatchInsertChannels(channels []Channel) error {
 		}
 	}()
 
-	for _, chunk := range lo.Chunk(channels, 50) {
+	for i, chunk := range lo.Chunk(channels, 50) {
 		if err := tx.Create(&chunk).Error; err != nil {
 			tx.Rollback()
 			return err
 		}
+		// lo.Chunk 会复制元素，回写自增 id 供调用方使用
+		copy(channels[i*50:], chunk)
 		for _, channel_ := range chunk {
 			if err := channel_.AddAbilities(tx); err != nil {
 				tx.Rollback()
@@ -773,6 +802,12 @@ func DeleteChannelByStatus(status int64) (int64, error) {
 	return result.RowsAffected, result.Error
 }
 
+func GetDisabledChannels() ([]*Channel, error) {
+	var channels []*Channel
+	err := DB.Where("status = ? or status = ?", common.ChannelStatusAutoDisabled, common.ChannelStatusManuallyDisabled).Find(&channels).Error
+	return channels, err
+}
+
 func DeleteDisabledChannel() (int64, error) {
 	result := DB.Where("status = ? or status = ?", common.ChannelStatusAutoDisabled, common.ChannelStatusManuallyDisabled).Delete(&Channel{})
 	return result.RowsAffected, result.Error
@@ -11,6 +11,7 @@ import (
 
 	"github.com/QuantumNous/new-api/common"
 	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
 	"github.com/QuantumNous/new-api/setting/ratio_setting"
 )
 
@@ -142,12 +143,10 @@ func GetRandomSatisfiedChannel(group string, model string, retry int) (*Channel,
 	targetPriority := int64(sortedUniquePriorities[retry])
 
 	// get the priority for the given retry number
-	var sumWeight = 0
 	var targetChannels []*Channel
 	for _, channelId := range channels {
 		if channel, ok := channelsIDM[channelId]; ok {
 			if channel.GetPriority() == targetPriority {
-				sumWeight += channel.GetWeight()
 				targetChannels = append(targetChannels, channel)
 			}
 		} else {
@@ -159,32 +158,56 @@ func GetRandomSatisfiedChannel(group string, model string, retry int) (*Channel,
 		return nil, errors.New(fmt.Sprintf("no channel found, group: %s, model: %s, priority: %d", group, model, targetPriority))
 	}
 
-	// smoothing factor and adjustment
-	smoothingFactor := 1
-	smoothingAdjustment := 0
-
-	if sumWeight == 0 {
-		// when all channels have weight 0, set sumWeight to the number of channels and set smoothing adjustment to 100
-		// each channel's effective weight = 100
-		sumWeight = len(targetChannels) * 100
-		smoothingAdjustment = 100
-	} else if sumWeight/len(targetChannels) < 10 {
-		// when the average weight is less than 10, set smoothing factor to 100
-		smoothingFactor = 100
+	weights := make([]int, len(targetChannels))
+	for i, channel := range targetChannels {
+		weights[i] = channel.GetWeight()
+	}
+	if operation_setting.GetAdaptiveRoutingSetting().Enabled {
+		// adaptive routing: shift weight by recent health and skip channels whose circuit breaker is open
+		targetChannels, weights = adaptiveChannelWeights(targetChannels, weights)
 	}
 
-	// Calculate the total weight of all channels up to endIdx
-	totalWeight := sumWeight * smoothingFactor
+	pick := func(weights []int) int {
+		sumWeight := 0
+		for _, weight := range weights {
+			sumWeight += weight
+		}
 
-	// Generate a random value in the range [0, totalWeight)
-	randomWeight := rand.Intn(totalWeight)
+		// smoothing factor and adjustment
+		smoothingFactor := 1
+		smoothingAdjustment := 0
 
-	// Find a channel based on its weight
-	for _, channel := range targetChannels {
-		randomWeight -= channel.GetWeight()*smoothingFactor + smoothingAdjustment
-		if randomWeight < 0 {
-			return channel, nil
+		if sumWeight == 0 {
+			// when all channels have weight 0, set sumWeight to the number of channels and set smoothing adjustment to 100
+			// each channel's effective weight = 100
+			sumWeight = len(weights) * 100
+			smoothingAdjustment = 100
+		} else if sumWeight/len(weights) < 10 {
+			// when the average weight is less than 10, set smoothing factor to 100
+			smoothingFactor = 100
+		}
+
+		// Calculate the total weight of all channels up to endIdx
+		totalWeight := sumWeight * smoothingFactor
+
+		// Generate a random value in the range [0, totalWeight)
+		randomWeight := rand.Intn(totalWeight)
+
+		// Find a channel based on its weight
+		for i, weight := range weights {
+			randomWeight -= weight*smoothingFactor + smoothingAdjustment
+			if randomWeight < 0 {
+				return i
+			}
 		}
+		return -1
+	}
+	keys := make([]channelHealthKey, len(targetChannels))
+	for i, channel := range targetChannels {
+		keys[i] = channelHealthKey{ChannelId: channel.Id, KeyIndex: -1}
+	}
+	if idx := pickChannelHealthCandidate(keys, weights, pick); idx >= 0 {
+		return targetChannels[idx], nil
 	}
 	// return null if no channel is not found
 	return nil, errors.New("channel not found")
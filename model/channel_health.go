@@ -0,0 +1,494 @@
+package model
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+	"github.com/bytedance/gopkg/util/gopool"
+)
+
+const (
+	channelHealthSampleSize = 256
+	channelHealthStatsTTL   = time.Second
+	// 熔断状态在多节点间共享：field 为 "渠道ID:密钥索引"，value 为熔断结束时间（unix 秒）
+	channelBreakerRedisKey = "channel_breaker"
+)
+
+const (
+	ChannelBreakerClosed   = "closed"
+	ChannelBreakerOpen     = "open"
+	ChannelBreakerHalfOpen = "half_open"
+)
+
+type channelHealthKey struct {
+	ChannelId int
+	KeyIndex  int // -1 表示渠道整体，多密钥渠道额外按密钥索引统计
+}
+
+type channelHealthSample struct {
+	at      int64 // unix 毫秒
+	success bool
+	latency int64 // 毫秒
+	ttft    int64 // 毫秒，非流式请求为 0
+}
+
+// ChannelHealthStats 渠道（或密钥）在统计窗口内的健康状况
+type ChannelHealthStats struct {
+	ChannelId   int     `json:"channel_id"`
+	KeyIndex    int     `json:"key_index"`
+	Requests    int     `json:"requests"`
+	SuccessRate float64 `json:"success_rate"`
+	P95Latency  int64   `json:"p95_latency_ms"`
+	AvgTTFT     int64   `json:"avg_ttft_ms"`
+	State       string  `json:"state"`
+	OpenUntil   int64   `json:"open_until,omitempty"`
+}
+
+type channelHealth struct {
+	mu                  sync.Mutex
+	key                 channelHealthKey
+	samples             []channelHealthSample
+	next                int
+	consecutiveFailures int
+	state               string
+	openUntil           int64 // unix 秒
+	openSeconds         int   // 当前熔断时长，半开探测失败后翻倍
+	probeAt             int64 // 半开探测开始时间（unix 秒），0 表示没有进行中的探测
+	statsAt             int64 // 统计缓存时间（unix 毫秒）
+	stats               ChannelHealthStats
+}
+
+var channelHealthMap sync.Map // channelHealthKey -> *channelHealth
+
+func getChannelHealth(key channelHealthKey, create bool) *channelHealth {
+	if v, ok := channelHealthMap.Load(key); ok {
+		return v.(*channelHealth)
+	}
+	if !create {
+		return nil
+	}
+	v, _ := channelHealthMap.LoadOrStore(key, &channelHealth{key: key, state: ChannelBreakerClosed})
+	return v.(*channelHealth)
+}
+
+// currentState 返回熔断状态，熔断时间结束后转为半开，调用方需持有锁
+func (h *channelHealth) currentState(now int64) string {
+	if h.state == ChannelBreakerOpen && now >= h.openUntil {
+		h.state = ChannelBreakerHalfOpen
+		h.probeAt = 0
+	}
+	return h.state
+}
+
+// probeAvailable 返回当前是否可以放行请求，调用方需持有锁
+func (h *channelHealth) probeAvailable(now int64, setting *operation_setting.AdaptiveRoutingSetting) bool {
+	switch h.currentState(now) {
+	case ChannelBreakerOpen:
+		return false
+	case ChannelBreakerHalfOpen:
+		// 半开状态同一时间只放行一个探测请求，探测超时后允许重新探测
+		return h.probeAt == 0 || now-h.probeAt >= int64(setting.HalfOpenProbeTimeout)
+	}
+	return true
+}
+
+// allows 仅用于筛选候选项，不占用探测
+func (h *channelHealth) allows(now int64, setting *operation_setting.AdaptiveRoutingSetting) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.probeAvailable(now, setting)
+}
+
+// trySelect 在同一把锁内检查并占用半开探测，探测已被其他请求占用或仍在熔断时返回 false
+func (h *channelHealth) trySelect(now int64, setting *operation_setting.AdaptiveRoutingSetting) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if !h.probeAvailable(now, setting) {
+		return false
+	}
+	if h.state == ChannelBreakerHalfOpen {
+		h.probeAt = now
+	}
+	return true
+}
+
+func (h *channelHealth) open(now int64, setting *operation_setting.AdaptiveRoutingSetting) {
+	if h.openSeconds == 0 {
+		h.openSeconds = setting.BreakerOpenSeconds
+	} else {
+		h.openSeconds = min(h.openSeconds*2, setting.BreakerMaxOpenSeconds)
+	}
+	h.openSeconds = max(h.openSeconds, 1)
+	h.state = ChannelBreakerOpen
+	h.openUntil = now + int64(h.openSeconds)
+	h.probeAt = 0
+}
+
+// record 记录一次请求结果，返回本次是否触发熔断
+func (h *channelHealth) record(sample channelHealthSample, setting *operation_setting.AdaptiveRoutingSetting) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	now := sample.at / 1000
+	state := h.currentState(now)
+	if sample.success && state == ChannelBreakerHalfOpen {
+		// 半开探测成功，关闭熔断并清空旧样本，避免窗口内的历史失败立即再次触发熔断
+		h.state = ChannelBreakerClosed
+		h.openSeconds = 0
+		h.probeAt = 0
+		h.samples = h.samples[:0]
+		h.next = 0
+	}
+
+	if len(h.samples) < channelHealthSampleSize {
+		h.samples = append(h.samples, sample)
+	} else {
+		h.samples[h.next] = sample
+		h.next = (h.next + 1) % channelHealthSampleSize
+	}
+	h.statsAt = 0
+
+	if sample.success {
+		h.consecutiveFailures = 0
+		return false
+	}
+	h.consecutiveFailures++
+	if !setting.BreakerEnabled {
+		return false
+	}
+	switch state {
+	case ChannelBreakerHalfOpen:
+		h.open(now, setting)
+		return true
+	case ChannelBreakerClosed:
+		stats := h.computeStats(sample.at, setting)
+		if h.consecutiveFailures >= max(setting.BreakerConsecutiveFailures, 1) ||
+			stats.Requests >= max(setting.BreakerMinRequests, 1) && 1-stats.SuccessRate >= setting.BreakerErrorRate {
+			h.open(now, setting)
+			return true
+		}
+	}
+	return false
+}
+
+// computeStats 统计窗口内的成功率、P95 延迟与平均首字延迟，调用方需持有锁
+func (h *channelHealth) computeStats(nowMs int64, setting *operation_setting.AdaptiveRoutingSetting) ChannelHealthStats {
+	stats := ChannelHealthStats{ChannelId: h.key.ChannelId, KeyIndex: h.key.KeyIndex}
+	since := nowMs - int64(setting.WindowSeconds)*1000
+	successes := 0
+	latencies := make([]int64, 0, len(h.samples))
+	var ttftSum, ttftCount int64
+	for _, sample := range h.samples {
+		if sample.at < since {
+			continue
+		}
+		stats.Requests++
+		if !sample.success {
+			continue
+		}
+		successes++
+		latencies = append(latencies, sample.latency)
+		if sample.ttft > 0 {
+			ttftSum += sample.ttft
+			ttftCount++
+		}
+	}
+	if stats.Requests > 0 {
+		stats.SuccessRate = float64(successes) / float64(stats.Requests)
+	}
+	if len(latencies) > 0 {
+		sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+		idx := int(math.Ceil(float64(len(latencies))*0.95)) - 1
+		stats.P95Latency = latencies[max(idx, 0)]
+	}
+	if ttftCount > 0 {
+		stats.AvgTTFT = ttftSum / ttftCount
+	}
+	return stats
+}
+
+func (h *channelHealth) snapshot(nowMs int64, setting *operation_setting.AdaptiveRoutingSetting) ChannelHealthStats {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.statsAt == 0 || nowMs-h.statsAt >= channelHealthStatsTTL.Milliseconds() {
+		h.stats = h.computeStats(nowMs, setting)
+		h.statsAt = nowMs
+	}
+	stats := h.stats
+	stats.State = h.currentState(nowMs / 1000)
+	if stats.State == ChannelBreakerOpen {
+		stats.OpenUntil = h.openUntil
+	}
+	return stats
+}
+
+// RecordChannelHealth 记录渠道一次请求的结果，keyIndex 为多密钥渠道的密钥索引，非多密钥渠道传 -1
+func RecordChannelHealth(channelId int, keyIndex int, success bool, latency time.Duration, ttft time.Duration) {
+	setting := operation_setting.GetAdaptiveRoutingSetting()
+	if !setting.Enabled || channelId <= 0 {
+		return
+	}
+	sample := channelHealthSample{
+		at:      time.Now().UnixMilli(),
+		success: success,
+		latency: latency.Milliseconds(),
+		ttft:    ttft.Milliseconds(),
+	}
+	keys := []channelHealthKey{{ChannelId: channelId, KeyIndex: -1}}
+	if keyIndex >= 0 {
+		keys = append(keys, channelHealthKey{ChannelId: channelId, KeyIndex: keyIndex})
+	}
+	for _, key := range keys {
+		h := getChannelHealth(key, true)
+		if h.record(sample, setting) {
+			h.mu.Lock()
+			openUntil, openSeconds := h.openUntil, h.openSeconds
+			h.mu.Unlock()
+			common.SysLog(fmt.Sprintf("channel #%d (key index %d) circuit breaker opened for %ds", key.ChannelId, key.KeyIndex, openSeconds))
+			if common.RedisEnabled {
+				gopool.Go(func() {
+					field := fmt.Sprintf("%d:%d", key.ChannelId, key.KeyIndex)
+					if err := common.RDB.HSet(context.Background(), channelBreakerRedisKey, field, openUntil).Err(); err != nil {
+						common.SysError("failed to save channel breaker state: " + err.Error())
+					}
+				})
+			}
+		}
+	}
+}
+
+// markChannelHealthSelected 选中半开状态的渠道或密钥时将本次请求作为探测请求，
+// 并发请求已占用探测时返回 false，调用方应改选其他候选项
+func markChannelHealthSelected(channelId int, keyIndex int) bool {
+	setting := operation_setting.GetAdaptiveRoutingSetting()
+	if !setting.Enabled {
+		return true
+	}
+	if h := getChannelHealth(channelHealthKey{ChannelId: channelId, KeyIndex: keyIndex}, false); h != nil {
+		return h.trySelect(time.Now().Unix(), setting)
+	}
+	return true
+}
+
+// pickChannelHealthCandidate 由 pick 按权重选出候选项并占用探测，占用失败时剔除该候选项重新选择；
+// 全部占用失败时返回首次选中的下标，与候选项全部熔断时不剔除保持一致；pick 返回 -1 表示未选中
+func pickChannelHealthCandidate(keys []channelHealthKey, weights []int, pick func(weights []int) int) int {
+	remaining := make([]int, len(keys))
+	for i := range remaining {
+		remaining[i] = i
+	}
+	first := -1
+	for len(remaining) > 0 {
+		remainingWeights := make([]int, len(remaining))
+		for i, idx := range remaining {
+			remainingWeights[i] = weights[idx]
+		}
+		i := pick(remainingWeights)
+		if i < 0 {
+			break
+		}
+		idx := remaining[i]
+		if first < 0 {
+			first = idx
+		}
+		if markChannelHealthSelected(keys[idx].ChannelId, keys[idx].KeyIndex) {
+			return idx
+		}
+		remaining = append(remaining[:i], remaining[i+1:]...)
+	}
+	return first
+}
+
+// channelHealthWeightFactor 根据成功率与相对延迟计算权重系数，样本不足时不调整
+func channelHealthWeightFactor(stats ChannelHealthStats, bestP95 int64, bestTTFT int64, setting *operation_setting.AdaptiveRoutingSetting) float64 {
+	if stats.Requests < max(setting.MinSamples, 1) {
+		return 1
+	}
+	factor := stats.SuccessRate * stats.SuccessRate
+	if bestP95 > 0 && stats.P95Latency > 0 {
+		factor *= math.Sqrt(float64(bestP95) / float64(stats.P95Latency))
+	}
+	if bestTTFT > 0 && stats.AvgTTFT > 0 {
+		factor *= math.Sqrt(float64(bestTTFT) / float64(stats.AvgTTFT))
+	}
+	return math.Max(factor, setting.MinWeightFactor)
+}
+
+// adaptiveWeights 按健康状况调整同一优先级内候选项的权重并剔除熔断中的候选项，
+// 返回保留的候选项下标及其有效权重；候选项全部熔断时不剔除，避免请求直接失败
+func adaptiveWeights(keys []channelHealthKey, weights []int) ([]int, []int) {
+	setting := operation_setting.GetAdaptiveRoutingSetting()
+	nowMs := time.Now().UnixMilli()
+
+	allZero := true
+	for _, weight := range weights {
+		if weight > 0 {
+			allZero = false
+			break
+		}
+	}
+
+	stats := make([]ChannelHealthStats, len(keys))
+	allowed := make([]bool, len(keys))
+	anyAllowed := false
+	var bestP95, bestTTFT int64
+	for i, key := range keys {
+		allowed[i] = true
+		h := getChannelHealth(key, false)
+		if h == nil {
+			anyAllowed = true
+			continue
+		}
+		stats[i] = h.snapshot(nowMs, setting)
+		allowed[i] = h.allows(nowMs/1000, setting)
+		anyAllowed = anyAllowed || allowed[i]
+		if stats[i].Requests < max(setting.MinSamples, 1) {
+			continue
+		}
+		if stats[i].P95Latency > 0 && (bestP95 == 0 || stats[i].P95Latency < bestP95) {
+			bestP95 = stats[i].P95Latency
+		}
+		if stats[i].AvgTTFT > 0 && (bestTTFT == 0 || stats[i].AvgTTFT < bestTTFT) {
+			bestTTFT = stats[i].AvgTTFT
+		}
+	}
+
+	kept := make([]int, 0, len(keys))
+	adjusted := make([]int, 0, len(keys))
+	for i := range keys {
+		if anyAllowed && !allowed[i] {
+			continue
+		}
+		base := weights[i]
+		if allZero {
+			base = 1
+		}
+		weight := 0
+		if base > 0 {
+			weight = int(math.Ceil(float64(base) * 100 * channelHealthWeightFactor(stats[i], bestP95, bestTTFT, setting)))
+		}
+		kept = append(kept, i)
+		adjusted = append(adjusted, weight)
+	}
+	return kept, adjusted
+}
+
+func adaptiveChannelWeights(channels []*Channel, weights []int) ([]*Channel, []int) {
+	keys := make([]channelHealthKey, len(channels))
+	for i, channel := range channels {
+		keys[i] = channelHealthKey{ChannelId: channel.Id, KeyIndex: -1}
+	}
+	kept, adjusted := adaptiveWeights(keys, weights)
+	filtered := make([]*Channel, len(kept))
+	for i, idx := range kept {
+		filtered[i] = channels[idx]
+	}
+	return filtered, adjusted
+}
+
+// adaptiveKeyWeights 多密钥渠道按密钥健康状况过滤与加权，返回保留的密钥索引及权重
+func adaptiveKeyWeights(channelId int, keyIndexes []int) ([]int, []int) {
+	keys := make([]channelHealthKey, len(keyIndexes))
+	weights := make([]int, len(keyIndexes))
+	for i, idx := range keyIndexes {
+		keys[i] = channelHealthKey{ChannelId: channelId, KeyIndex: idx}
+		weights[i] = 1
+	}
+	kept, adjusted := adaptiveWeights(keys, weights)
+	filtered := make([]int, len(kept))
+	for i, k := range kept {
+		filtered[i] = keyIndexes[k]
+	}
+	return filtered, adjusted
+}
+
+// pickWeightedIndex 按权重随机选择下标，权重全为 0 时等概率选择
+func pickWeightedIndex(weights []int) int {
+	total := 0
+	for _, weight := range weights {
+		total += weight
+	}
+	if total <= 0 {
+		return rand.Intn(len(weights))
+	}
+	r := rand.Intn(total)
+	for i, weight := range weights {
+		r -= weight
+		if r < 0 {
+			return i
+		}
+	}
+	return len(weights) - 1
+}
+
+// GetChannelHealthStats 返回所有渠道与密钥的健康统计
+func GetChannelHealthStats() []ChannelHealthStats {
+	setting := operation_setting.GetAdaptiveRoutingSetting()
+	nowMs := time.Now().UnixMilli()
+	result := make([]ChannelHealthStats, 0)
+	channelHealthMap.Range(func(_, value any) bool {
+		result = append(result, value.(*channelHealth).snapshot(nowMs, setting))
+		return true
+	})
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].ChannelId != result[j].ChannelId {
+			return result[i].ChannelId < result[j].ChannelId
+		}
+		return result[i].KeyIndex < result[j].KeyIndex
+	})
+	return result
+}
+
+// SyncChannelBreakers 定期从 Redis 同步其他节点触发的熔断状态
+func SyncChannelBreakers(frequency int) {
+	for {
+		time.Sleep(time.Duration(frequency) * time.Second)
+		if !common.RedisEnabled || !operation_setting.GetAdaptiveRoutingSetting().Enabled {
+			continue
+		}
+		syncChannelBreakersFromRedis()
+	}
+}
+
+func syncChannelBreakersFromRedis() {
+	ctx := context.Background()
+	entries, err := common.RDB.HGetAll(ctx, channelBreakerRedisKey).Result()
+	if err != nil {
+		common.SysError("failed to sync channel breaker state: " + err.Error())
+		return
+	}
+	now := time.Now().Unix()
+	for field, value := range entries {
+		openUntil, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || openUntil <= now {
+			common.RDB.HDel(ctx, channelBreakerRedisKey, field)
+			continue
+		}
+		channelIdStr, keyIndexStr, ok := strings.Cut(field, ":")
+		if !ok {
+			continue
+		}
+		channelId, err1 := strconv.Atoi(channelIdStr)
+		keyIndex, err2 := strconv.Atoi(keyIndexStr)
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		h := getChannelHealth(channelHealthKey{ChannelId: channelId, KeyIndex: keyIndex}, true)
+		h.mu.Lock()
+		if h.currentState(now) == ChannelBreakerClosed || h.openUntil < openUntil && h.state == ChannelBreakerOpen {
+			h.state = ChannelBreakerOpen
+			h.openUntil = openUntil
+			h.probeAt = 0
+		}
+		h.mu.Unlock()
+	}
+}
@@ -0,0 +1,113 @@
+package model
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChannelHealthBreaker(t *testing.T) {
+	setting := operation_setting.GetAdaptiveRoutingSetting()
+	saved := *setting
+	t.Cleanup(func() { *setting = saved })
+	setting.Enabled = true
+	setting.BreakerConsecutiveFailures = 3
+	setting.BreakerOpenSeconds = 10
+	setting.BreakerMaxOpenSeconds = 15
+
+	h := &channelHealth{key: channelHealthKey{ChannelId: 1, KeyIndex: -1}, state: ChannelBreakerClosed}
+	now := time.Now().UnixMilli()
+	failure := channelHealthSample{at: now}
+	require.False(t, h.record(failure, setting))
+	require.False(t, h.record(failure, setting))
+	require.True(t, h.record(failure, setting))
+	require.False(t, h.allows(now/1000, setting))
+
+	// 熔断结束后转为半开，并发选择时只有一个请求占用探测
+	halfOpenAt := h.openUntil
+	require.True(t, h.allows(halfOpenAt, setting))
+	var selected atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if h.trySelect(halfOpenAt, setting) {
+				selected.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	require.EqualValues(t, 1, selected.Load())
+	require.False(t, h.allows(halfOpenAt, setting))
+
+	// 探测失败后熔断时长翻倍（受上限约束）
+	require.True(t, h.record(channelHealthSample{at: halfOpenAt * 1000}, setting))
+	require.Equal(t, halfOpenAt+15, h.openUntil)
+
+	// 探测成功后关闭熔断
+	require.True(t, h.allows(h.openUntil, setting))
+	require.False(t, h.record(channelHealthSample{at: h.openUntil * 1000, success: true, latency: 100}, setting))
+	require.Equal(t, ChannelBreakerClosed, h.state)
+	require.Len(t, h.samples, 1)
+}
+
+func TestAdaptiveWeights(t *testing.T) {
+	setting := operation_setting.GetAdaptiveRoutingSetting()
+	saved := *setting
+	t.Cleanup(func() {
+		*setting = saved
+		for id := 101; id <= 103; id++ {
+			channelHealthMap.Delete(channelHealthKey{ChannelId: id, KeyIndex: -1})
+		}
+	})
+	setting.Enabled = true
+	setting.MinSamples = 5
+	setting.BreakerEnabled = false
+
+	for i := 0; i < 10; i++ {
+		RecordChannelHealth(101, -1, true, 100*time.Millisecond, 0)
+		RecordChannelHealth(102, -1, true, 400*time.Millisecond, 0)
+	}
+	// 样本不足的渠道保持静态权重
+	RecordChannelHealth(103, -1, false, time.Second, 0)
+
+	keys := []channelHealthKey{{101, -1}, {102, -1}, {103, -1}}
+	kept, weights := adaptiveWeights(keys, []int{10, 10, 10})
+	require.Equal(t, []int{0, 1, 2}, kept)
+	require.Equal(t, []int{1000, 500, 1000}, weights)
+
+	// 熔断中的渠道被剔除
+	h := getChannelHealth(channelHealthKey{ChannelId: 102, KeyIndex: -1}, false)
+	h.open(time.Now().Unix(), setting)
+	kept, _ = adaptiveWeights(keys, []int{10, 10, 10})
+	require.Equal(t, []int{0, 2}, kept)
+}
+
+func TestPickChannelHealthCandidateSkipsTakenProbe(t *testing.T) {
+	setting := operation_setting.GetAdaptiveRoutingSetting()
+	saved := *setting
+	key := channelHealthKey{ChannelId: 201, KeyIndex: -1}
+	t.Cleanup(func() {
+		*setting = saved
+		channelHealthMap.Delete(key)
+	})
+	setting.Enabled = true
+	setting.HalfOpenProbeTimeout = 60
+
+	h := getChannelHealth(key, true)
+	h.state = ChannelBreakerHalfOpen
+	now := time.Now().Unix()
+	require.True(t, h.trySelect(now, setting))
+
+	// 半开探测已被占用时改选其他候选项
+	keys := []channelHealthKey{key, {ChannelId: 202, KeyIndex: -1}}
+	pickFirst := func(weights []int) int { return 0 }
+	require.Equal(t, 1, pickChannelHealthCandidate(keys, []int{1, 1}, pickFirst))
+	// 没有其他候选项时仍返回首次选中的候选项，避免请求直接失败
+	require.Equal(t, 0, pickChannelHealthCandidate(keys[:1], []int{1}, pickFirst))
+}
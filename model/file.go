@@ -0,0 +1,102 @@
+package model
+
+import (
+	"errors"
+
+	"github.com/QuantumNous/new-api/common"
+	"gorm.io/gorm"
+)
+
+// File 记录通过 /v1/files 上传到上游渠道的文件。
+// FileId 直接使用上游返回的文件 ID，后续引用该文件的请求（batch、fine-tuning 等）
+// 必须固定发送到 ChannelId 对应的渠道，并使用同一个 key。
+// ChannelId 为 0 表示文件由 new-api 本地生成并保存在 StoragePath（如本地批处理结果）。
+type File struct {
+	Id             int    `json:"id" gorm:"primaryKey;autoIncrement"`
+	FileId         string `json:"file_id" gorm:"type:varchar(191);uniqueIndex"`
+	UserId         int    `json:"user_id" gorm:"index"`
+	TokenId        int    `json:"token_id" gorm:"index"`
+	Purpose        string `json:"purpose" gorm:"type:varchar(64);index"`
+	Filename       string `json:"filename" gorm:"type:varchar(255)"`
+	Bytes          int64  `json:"bytes" gorm:"bigint"`
+	Status         string `json:"status" gorm:"type:varchar(32)"`
+	ChannelId      int    `json:"channel_id" gorm:"index"`
+	ChannelKeyIdx  int    `json:"-" gorm:"default:0"` // 多 key 渠道上传时使用的 key 下标
+	UpstreamFileId string `json:"upstream_file_id" gorm:"type:varchar(191)"`
+	StoragePath    string `json:"-" gorm:"type:varchar(512)"`
+	ExpiresAt      int64  `json:"expires_at" gorm:"bigint"`
+	CreatedAt      int64  `json:"created_at" gorm:"bigint;index"`
+}
+
+func (File) TableName() string {
+	return "files"
+}
+
+// IsLocal 文件是否保存在本地而非上游渠道
+func (f *File) IsLocal() bool {
+	return f.ChannelId == 0 && f.StoragePath != ""
+}
+
+func (f *File) Insert() error {
+	if f.CreatedAt == 0 {
+		f.CreatedAt = common.GetTimestamp()
+	}
+	return DB.Create(f).Error
+}
+
+func (f *File) Update() error {
+	return DB.Save(f).Error
+}
+
+func (f *File) Delete() error {
+	return DB.Delete(f).Error
+}
+
+// GetUserFileById 获取属于指定用户的文件，不存在时返回 (nil, nil)
+func GetUserFileById(userId int, fileId string) (*File, error) {
+	if fileId == "" {
+		return nil, nil
+	}
+	var file File
+	err := DB.Where("user_id = ? AND file_id = ?", userId, fileId).First(&file).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &file, nil
+}
+
+// ListUserFiles 按创建时间倒序列出用户文件，after 为上一页最后一个文件 ID（OpenAI 分页语义）
+func ListUserFiles(userId int, purpose string, after string, limit int, ascending bool) ([]*File, bool, error) {
+	var files []*File
+	query := DB.Where("user_id = ?", userId)
+	if purpose != "" {
+		query = query.Where("purpose = ?", purpose)
+	}
+	if after != "" {
+		var cursor File
+		if err := DB.Where("user_id = ? AND file_id = ?", userId, after).First(&cursor).Error; err == nil {
+			if ascending {
+				query = query.Where("id > ?", cursor.Id)
+			} else {
+				query = query.Where("id < ?", cursor.Id)
+			}
+		}
+	}
+	order := "id desc"
+	if ascending {
+		order = "id asc"
+	}
+	// 多取一条用于判断 has_more
+	err := query.Order(order).Limit(limit + 1).Find(&files).Error
+	if err != nil {
+		return nil, false, err
+	}
+	hasMore := len(files) > limit
+	if hasMore {
+		files = files[:limit]
+	}
+	return files, hasMore, nil
+}
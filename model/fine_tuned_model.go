@@ -0,0 +1,96 @@
+package model
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/setting/ratio_setting"
+
+	"gorm.io/gorm/clause"
+)
+
+// FineTunedModel 微调任务生成的模型，仅提交任务的用户可以调用。
+// 请求时固定路由到训练所在的渠道和 key，按基础模型定价。
+// 没有写入 abilities：abilities 按分组而不是按用户登记，写入后同分组的其他用户也能选到该模型，
+// 且编辑渠道或修复能力表时会按 channel.Models 重建而丢失；这张表即提交者的私有能力表，
+// 由 Distribute 与模型列表读取
+type FineTunedModel struct {
+	Id        int    `json:"id"`
+	UserId    int    `json:"user_id" gorm:"index"`
+	ModelName string `json:"model_name" gorm:"type:varchar(191);uniqueIndex"`
+	BaseModel string `json:"base_model" gorm:"type:varchar(191)"`
+	ChannelId int    `json:"channel_id"`
+	KeyIndex  int    `json:"-" gorm:"default:0"`
+	TaskId    string `json:"task_id" gorm:"type:varchar(191)"`
+	CreatedAt int64  `json:"created_at" gorm:"bigint"`
+}
+
+var (
+	fineTunedModels     = make(map[string]*FineTunedModel)
+	fineTunedModelsLock sync.RWMutex
+)
+
+// Insert 同名模型已存在时忽略
+func (m *FineTunedModel) Insert() error {
+	m.CreatedAt = common.GetTimestamp()
+	result := DB.Clauses(clause.OnConflict{DoNothing: true}).Create(m)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		fineTunedModelsLock.Lock()
+		fineTunedModels[m.ModelName] = m
+		fineTunedModelsLock.Unlock()
+	}
+	ratio_setting.SetFineTunedModelBase(m.ModelName, m.BaseModel)
+	return nil
+}
+
+func GetFineTunedModel(modelName string) (*FineTunedModel, error) {
+	m := FineTunedModel{}
+	if err := DB.Where("model_name = ?", modelName).First(&m).Error; err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// CacheGetFineTunedModel 与渠道缓存一致：开启内存缓存时从缓存读取，否则查询数据库
+func CacheGetFineTunedModel(modelName string) (*FineTunedModel, error) {
+	if !common.MemoryCacheEnabled {
+		return GetFineTunedModel(modelName)
+	}
+	fineTunedModelsLock.RLock()
+	defer fineTunedModelsLock.RUnlock()
+	m, ok := fineTunedModels[modelName]
+	if !ok {
+		return nil, errors.New("fine-tuned model not found")
+	}
+	return m, nil
+}
+
+// GetUserFineTunedModelNames 用户可调用的微调模型，用于模型列表
+func GetUserFineTunedModelNames(userId int) ([]string, error) {
+	var names []string
+	err := DB.Model(&FineTunedModel{}).Where("user_id = ?", userId).Pluck("model_name", &names).Error
+	return names, err
+}
+
+// SyncFineTunedModels 从数据库刷新微调模型缓存与定价映射，随配置一起定期同步
+func SyncFineTunedModels() {
+	var models []*FineTunedModel
+	if err := DB.Find(&models).Error; err != nil {
+		common.SysLog("failed to load fine-tuned models: " + err.Error())
+		return
+	}
+	cache := make(map[string]*FineTunedModel, len(models))
+	bases := make(map[string]string, len(models))
+	for _, m := range models {
+		cache[m.ModelName] = m
+		bases[m.ModelName] = m.BaseModel
+	}
+	fineTunedModelsLock.Lock()
+	fineTunedModels = cache
+	fineTunedModelsLock.Unlock()
+	ratio_setting.SetFineTunedModelBases(bases)
+}
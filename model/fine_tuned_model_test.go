@@ -0,0 +1,54 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/setting/ratio_setting"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFineTunedModelRegistration(t *testing.T) {
+	require.NoError(t, DB.AutoMigrate(&FineTunedModel{}))
+	t.Cleanup(func() {
+		DB.Exec("DELETE FROM fine_tuned_models")
+		ratio_setting.SetFineTunedModelBases(nil)
+	})
+
+	name := "ft:gpt-4o-mini-2024-07-18:org::abc"
+	require.Equal(t, name, ratio_setting.FormatMatchingModelName(name))
+
+	m := &FineTunedModel{UserId: 1, ModelName: name, BaseModel: "gpt-4o-mini-2024-07-18", ChannelId: 3, TaskId: "task_1"}
+	require.NoError(t, m.Insert())
+	// 重复登记不报错
+	require.NoError(t, (&FineTunedModel{UserId: 1, ModelName: name, BaseModel: "gpt-4o-mini-2024-07-18"}).Insert())
+
+	got, err := GetFineTunedModel(name)
+	require.NoError(t, err)
+	require.Equal(t, 1, got.UserId)
+	require.Equal(t, 3, got.ChannelId)
+
+	names, err := GetUserFineTunedModelNames(2)
+	require.NoError(t, err)
+	require.Empty(t, names)
+
+	// 只有登记过的微调模型按基础模型定价
+	require.Equal(t, "gpt-4o-mini-2024-07-18", ratio_setting.FormatMatchingModelName(name))
+	require.Equal(t, "ft:gpt-4o-mini:other::xyz", ratio_setting.FormatMatchingModelName("ft:gpt-4o-mini:other::xyz"))
+
+	ratio_setting.SetFineTunedModelBases(nil)
+	SyncFineTunedModels()
+	require.Equal(t, "gpt-4o-mini-2024-07-18", ratio_setting.FormatMatchingModelName(name))
+
+	// 开启内存缓存时不再查询数据库
+	memoryCacheEnabled := common.MemoryCacheEnabled
+	common.MemoryCacheEnabled = true
+	t.Cleanup(func() { common.MemoryCacheEnabled = memoryCacheEnabled })
+	DB.Exec("DELETE FROM fine_tuned_models")
+	cached, err := CacheGetFineTunedModel(name)
+	require.NoError(t, err)
+	require.Equal(t, 3, cached.ChannelId)
+	SyncFineTunedModels()
+	_, err = CacheGetFineTunedModel(name)
+	require.Error(t, err)
+}
@@ -213,7 +213,11 @@ func InitDB() (err error) {
 func InitLogDB() (err error) {
 	if os.Getenv("LOG_SQL_DSN") == "" {
 		LOG_DB = DB
-		return
+		// 日志数据库与主数据库相同时，仅存于日志库的表（审计日志、请求采样）也需要在主库中迁移
+		if !common.IsMasterNode {
+			return
+		}
+		return migrateLOGDB()
 	}
 	db, err := chooseDB("LOG_SQL_DSN", true)
 	if err == nil {
@@ -280,6 +284,15 @@ func migrateDB() error {
 		&SubscriptionPreConsumeRecord{},
 		&CustomOAuthProvider{},
 		&UserOAuthBinding{},
+		&File{},
+		&Batch{},
+		&FineTunedModel{},
+		&ManagementKey{},
+		&PayloadCaptureRule{},
+		&Organization{},
+		&OrganizationMember{},
+		&TaskCallback{},
+		&MediaObject{},
 	)
 	if err != nil {
 		return err
@@ -328,6 +341,15 @@ func migrateDBFast() error {
 		{&SubscriptionPreConsumeRecord{}, "SubscriptionPreConsumeRecord"},
 		{&CustomOAuthProvider{}, "CustomOAuthProvider"},
 		{&UserOAuthBinding{}, "UserOAuthBinding"},
+		{&File{}, "File"},
+		{&Batch{}, "Batch"},
+		{&FineTunedModel{}, "FineTunedModel"},
+		{&ManagementKey{}, "ManagementKey"},
+		{&PayloadCaptureRule{}, "PayloadCaptureRule"},
+		{&Organization{}, "Organization"},
+		{&OrganizationMember{}, "OrganizationMember"},
+		{&TaskCallback{}, "TaskCallback"},
+		{&MediaObject{}, "MediaObject"},
 	}
 	// 动态计算migration数量，确保errChan缓冲区足够大
 	errChan := make(chan error, len(migrations))
@@ -367,7 +389,7 @@ func migrateDBFast() error {
 
 func migrateLOGDB() error {
 	var err error
-	if err = LOG_DB.AutoMigrate(&Log{}); err != nil {
+	if err = LOG_DB.AutoMigrate(&Log{}, &AuditLog{}, &PayloadCapture{}); err != nil {
 		return err
 	}
 	return nil
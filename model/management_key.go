@@ -0,0 +1,190 @@
+package model
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/samber/lo"
+	"gorm.io/gorm"
+)
+
+// ManagementKeyPrefix 管理密钥前缀，用于与 access token 区分
+const ManagementKeyPrefix = "mk-"
+
+// 管理密钥的权限范围，格式为 资源:操作，write 包含 read
+const (
+	ManagementScopeAll                = "*"
+	ManagementScopeChannelsRead       = "channels:read"
+	ManagementScopeChannelsWrite      = "channels:write"
+	ManagementScopeTokensRead         = "tokens:read"
+	ManagementScopeTokensWrite        = "tokens:write"
+	ManagementScopeUsersRead          = "users:read"
+	ManagementScopeUsersWrite         = "users:write"
+	ManagementScopeUsersQuota         = "users:quota"
+	ManagementScopeLogsRead           = "logs:read"
+	ManagementScopeLogsWrite          = "logs:write"
+	ManagementScopeRedemptionsRead    = "redemptions:read"
+	ManagementScopeRedemptionsWrite   = "redemptions:write"
+	ManagementScopeModelsRead         = "models:read"
+	ManagementScopeModelsWrite        = "models:write"
+	ManagementScopeGroupsRead         = "groups:read"
+	ManagementScopeGroupsWrite        = "groups:write"
+	ManagementScopeSubscriptionsRead  = "subscriptions:read"
+	ManagementScopeSubscriptionsWrite = "subscriptions:write"
+	ManagementScopeTasksRead          = "tasks:read"
+	ManagementScopeOptionsRead        = "options:read"
+	ManagementScopeOptionsWrite       = "options:write"
+	ManagementScopeDeploymentsRead    = "deployments:read"
+	ManagementScopeDeploymentsWrite   = "deployments:write"
+	ManagementScopeAuditRead          = "audit:read"
+)
+
+var ManagementScopes = []string{
+	ManagementScopeAll,
+	ManagementScopeChannelsRead, ManagementScopeChannelsWrite,
+	ManagementScopeTokensRead, ManagementScopeTokensWrite,
+	ManagementScopeUsersRead, ManagementScopeUsersWrite, ManagementScopeUsersQuota,
+	ManagementScopeLogsRead, ManagementScopeLogsWrite,
+	ManagementScopeRedemptionsRead, ManagementScopeRedemptionsWrite,
+	ManagementScopeModelsRead, ManagementScopeModelsWrite,
+	ManagementScopeGroupsRead, ManagementScopeGroupsWrite,
+	ManagementScopeSubscriptionsRead, ManagementScopeSubscriptionsWrite,
+	ManagementScopeTasksRead,
+	ManagementScopeOptionsRead, ManagementScopeOptionsWrite,
+	ManagementScopeDeploymentsRead, ManagementScopeDeploymentsWrite,
+	ManagementScopeAuditRead,
+}
+
+// ManagementKey 用户的管理 API 密钥，只保存密钥的 HMAC，明文仅在创建时返回一次
+type ManagementKey struct {
+	Id           int            `json:"id"`
+	UserId       int            `json:"user_id" gorm:"index"`
+	Name         string         `json:"name" gorm:"type:varchar(64)"`
+	KeyHash      string         `json:"-" gorm:"type:char(64);uniqueIndex"`
+	KeyPrefix    string         `json:"key_prefix" gorm:"type:varchar(16)"`
+	Scopes       string         `json:"scopes" gorm:"type:text"` // 逗号分隔
+	AllowIps     *string        `json:"allow_ips" gorm:"default:''"`
+	Status       int            `json:"status" gorm:"default:1"`
+	ExpiredTime  int64          `json:"expired_time" gorm:"bigint;default:-1"` // -1 means never expired
+	CreatedTime  int64          `json:"created_time" gorm:"bigint"`
+	AccessedTime int64          `json:"accessed_time" gorm:"bigint"`
+	DeletedAt    gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+func NormalizeManagementScopes(scopes []string) ([]string, error) {
+	result := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		scope = strings.TrimSpace(scope)
+		if scope == "" {
+			continue
+		}
+		if !lo.Contains(ManagementScopes, scope) {
+			return nil, errors.New("未知的权限范围: " + scope)
+		}
+		result = append(result, scope)
+	}
+	return lo.Uniq(result), nil
+}
+
+func (key *ManagementKey) GetScopes() []string {
+	if key.Scopes == "" {
+		return []string{}
+	}
+	return strings.Split(key.Scopes, ",")
+}
+
+func (key *ManagementKey) SetScopes(scopes []string) {
+	key.Scopes = strings.Join(scopes, ",")
+}
+
+func (key *ManagementKey) GetIpLimits() []string {
+	if key.AllowIps == nil {
+		return []string{}
+	}
+	return lo.Compact(lo.Map(strings.Split(*key.AllowIps, "\n"), func(ip string, _ int) string {
+		return strings.TrimSpace(strings.ReplaceAll(ip, ",", ""))
+	}))
+}
+
+// ManagementScopesAllow 判断 scopes 是否包含 scope，同一资源的 write 权限包含 read
+func ManagementScopesAllow(scopes []string, scope string) bool {
+	resource, action, _ := strings.Cut(scope, ":")
+	for _, s := range scopes {
+		if s == ManagementScopeAll || s == scope {
+			return true
+		}
+		if action == "read" && s == resource+":write" {
+			return true
+		}
+	}
+	return false
+}
+
+// GenerateManagementKey 生成管理密钥明文
+func GenerateManagementKey() (string, error) {
+	key, err := common.GenerateKey()
+	if err != nil {
+		return "", err
+	}
+	return ManagementKeyPrefix + key, nil
+}
+
+func IsManagementKey(key string) bool {
+	return strings.HasPrefix(strings.TrimPrefix(key, "Bearer "), ManagementKeyPrefix)
+}
+
+func GetUserManagementKeys(userId int) ([]*ManagementKey, error) {
+	var keys []*ManagementKey
+	err := DB.Where("user_id = ?", userId).Order("id desc").Find(&keys).Error
+	return keys, err
+}
+
+func GetManagementKeyByIds(id int, userId int) (*ManagementKey, error) {
+	if id == 0 || userId == 0 {
+		return nil, errors.New("id 或 userId 为空！")
+	}
+	key := ManagementKey{}
+	err := DB.First(&key, "id = ? and user_id = ?", id, userId).Error
+	return &key, err
+}
+
+// ValidateManagementKey 校验管理密钥的状态与有效期，IP 限制由调用方检查
+func ValidateManagementKey(rawKey string) (*ManagementKey, error) {
+	rawKey = strings.TrimSpace(strings.TrimPrefix(rawKey, "Bearer "))
+	if rawKey == "" {
+		return nil, errors.New("未提供管理密钥")
+	}
+	key := ManagementKey{}
+	if err := DB.Where("key_hash = ?", common.GenerateHMAC(rawKey)).First(&key).Error; err != nil {
+		return nil, errors.New("管理密钥无效")
+	}
+	if key.Status != common.TokenStatusEnabled {
+		return nil, errors.New("管理密钥已禁用")
+	}
+	if key.ExpiredTime != -1 && key.ExpiredTime < common.GetTimestamp() {
+		return nil, errors.New("管理密钥已过期")
+	}
+	return &key, nil
+}
+
+func (key *ManagementKey) Insert(rawKey string) error {
+	key.KeyHash = common.GenerateHMAC(rawKey)
+	key.KeyPrefix = rawKey[:min(len(rawKey), len(ManagementKeyPrefix)+4)]
+	key.CreatedTime = common.GetTimestamp()
+	return DB.Create(key).Error
+}
+
+func (key *ManagementKey) Update() error {
+	return DB.Model(key).Select("name", "scopes", "allow_ips", "status", "expired_time").Updates(key).Error
+}
+
+func (key *ManagementKey) Delete() error {
+	return DB.Delete(key).Error
+}
+
+func UpdateManagementKeyAccessedTime(id int) {
+	if err := DB.Model(&ManagementKey{}).Where("id = ?", id).Update("accessed_time", common.GetTimestamp()).Error; err != nil {
+		common.SysLog("failed to update management key accessed time: " + err.Error())
+	}
+}
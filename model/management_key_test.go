@@ -0,0 +1,23 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestManagementScopesAllow(t *testing.T) {
+	scopes := []string{ManagementScopeChannelsWrite, ManagementScopeLogsRead}
+	require.True(t, ManagementScopesAllow(scopes, ManagementScopeChannelsRead))
+	require.True(t, ManagementScopesAllow(scopes, ManagementScopeChannelsWrite))
+	require.True(t, ManagementScopesAllow(scopes, ManagementScopeLogsRead))
+	require.False(t, ManagementScopesAllow(scopes, ManagementScopeLogsWrite))
+	require.False(t, ManagementScopesAllow(scopes, ManagementScopeUsersQuota))
+	require.True(t, ManagementScopesAllow([]string{ManagementScopeAll}, ManagementScopeUsersQuota))
+
+	normalized, err := NormalizeManagementScopes([]string{" logs:read", "logs:read", ""})
+	require.NoError(t, err)
+	require.Equal(t, []string{ManagementScopeLogsRead}, normalized)
+	_, err = NormalizeManagementScopes([]string{"logs:delete"})
+	require.Error(t, err)
+}
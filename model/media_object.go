@@ -0,0 +1,63 @@
+package model
+
+import (
+	"errors"
+
+	"github.com/QuantumNous/new-api/common"
+	"gorm.io/gorm"
+)
+
+// MediaObject 持久化到媒体存储的任务产物，ExpiresAt 为 0 表示永久保留
+type MediaObject struct {
+	Id          int    `json:"id"`
+	CreatedAt   int64  `json:"created_at" gorm:"bigint"`
+	ExpiresAt   int64  `json:"expires_at" gorm:"bigint;index"`
+	TaskId      string `json:"task_id" gorm:"type:varchar(191);index"`
+	UserId      int    `json:"user_id" gorm:"index"`
+	Backend     string `json:"backend" gorm:"type:varchar(16)"`
+	Key         string `json:"key" gorm:"column:object_key;type:varchar(255);uniqueIndex"`
+	ContentType string `json:"content_type" gorm:"type:varchar(128)"`
+	Size        int64  `json:"size"`
+}
+
+func (object *MediaObject) Insert() error {
+	object.CreatedAt = common.GetTimestamp()
+	return DB.Create(object).Error
+}
+
+func (object *MediaObject) Delete() error {
+	return DB.Delete(object).Error
+}
+
+// GetMediaObjectByKey 对象不存在时返回 nil, nil
+func GetMediaObjectByKey(key string) (*MediaObject, error) {
+	object := MediaObject{}
+	err := DB.Where("object_key = ?", key).First(&object).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &object, nil
+}
+
+// GetMediaObjectByTaskId 返回任务最近一次持久化的产物，不存在时返回 nil, nil
+func GetMediaObjectByTaskId(taskId string) (*MediaObject, error) {
+	object := MediaObject{}
+	err := DB.Where("task_id = ?", taskId).Order("id desc").First(&object).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &object, nil
+}
+
+// GetExpiredMediaObjects 返回已超过保留期的产物
+func GetExpiredMediaObjects(now int64, limit int) ([]*MediaObject, error) {
+	var objects []*MediaObject
+	err := DB.Where("expires_at > 0 AND expires_at <= ?", now).Order("expires_at asc").Limit(limit).Find(&objects).Error
+	return objects, err
+}
@@ -116,6 +116,8 @@ func InitOptionMap() {
 	common.OptionMap["ModelPrice"] = ratio_setting.ModelPrice2JSONString()
 	common.OptionMap["CacheRatio"] = ratio_setting.CacheRatio2JSONString()
 	common.OptionMap["CreateCacheRatio"] = ratio_setting.CreateCacheRatio2JSONString()
+	common.OptionMap["BatchRatio"] = ratio_setting.BatchRatio2JSONString()
+	common.OptionMap["FineTuningRatio"] = ratio_setting.FineTuningRatio2JSONString()
 	common.OptionMap["GroupRatio"] = ratio_setting.GroupRatio2JSONString()
 	common.OptionMap["GroupGroupRatio"] = ratio_setting.GroupGroupRatio2JSONString()
 	common.OptionMap["UserUsableGroups"] = setting.UserUsableGroups2JSONString()
@@ -167,6 +169,8 @@ func loadOptionsFromDatabase() {
 			common.SysLog("failed to update option map: " + err.Error())
 		}
 	}
+	// 微调模型缓存与定价映射随倍率配置一起刷新
+	SyncFineTunedModels()
 }
 
 func SyncOptions(frequency int) {
@@ -430,6 +434,10 @@ func updateOptionMap(key string, value string) (err error) {
 		err = ratio_setting.UpdateCacheRatioByJSONString(value)
 	case "CreateCacheRatio":
 		err = ratio_setting.UpdateCreateCacheRatioByJSONString(value)
+	case "BatchRatio":
+		err = ratio_setting.UpdateBatchRatioByJSONString(value)
+	case "FineTuningRatio":
+		err = ratio_setting.UpdateFineTuningRatioByJSONString(value)
 	case "ImageRatio":
 		err = ratio_setting.UpdateImageRatioByJSONString(value)
 	case "AudioRatio":
@@ -489,6 +497,12 @@ func handleConfigUpdate(key, value string) bool {
 		// 同步磁盘缓存配置到 common 包
 		performance_setting.UpdateAndSync()
 	}
+	if configName == "log_setting" {
+		operation_setting.SyncLogLevels()
+	}
+	if configName == "moderation_setting" {
+		operation_setting.SyncModerationPolicies()
+	}
 
 	return true // 已处理
 }
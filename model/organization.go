@@ -0,0 +1,439 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/bytedance/gopkg/util/gopool"
+	"gorm.io/gorm"
+)
+
+const (
+	OrganizationStatusEnabled  = 1
+	OrganizationStatusDisabled = 2
+)
+
+// 组织成员角色，权限依次递减
+const (
+	OrganizationRoleOwner     = "owner"
+	OrganizationRoleAdmin     = "admin"
+	OrganizationRoleDeveloper = "developer"
+	OrganizationRoleViewer    = "viewer"
+)
+
+var organizationRoleLevels = map[string]int{
+	OrganizationRoleOwner:     4,
+	OrganizationRoleAdmin:     3,
+	OrganizationRoleDeveloper: 2,
+	OrganizationRoleViewer:    1,
+}
+
+var (
+	ErrOrganizationQuotaInsufficient = errors.New("组织额度不足")
+	ErrOrganizationSpendLimitReached = errors.New("已达到成员消费上限")
+	ErrOrganizationNotMember         = errors.New("不是该组织的成员")
+)
+
+// Organization 组织，成员共享组织钱包，组织令牌的消费从组织额度中扣除
+type Organization struct {
+	Id           int    `json:"id"`
+	Name         string `json:"name" gorm:"type:varchar(64);index"`
+	Quota        int    `json:"quota" gorm:"default:0"`
+	UsedQuota    int    `json:"used_quota" gorm:"default:0"`
+	RequestCount int    `json:"request_count" gorm:"default:0"`
+	Status       int    `json:"status" gorm:"default:1"`
+	OwnerId      int    `json:"owner_id" gorm:"index"`
+	CreatedAt    int64  `json:"created_at" gorm:"bigint"`
+	UpdatedAt    int64  `json:"updated_at" gorm:"bigint"`
+}
+
+// OrganizationMember 组织成员，SpendLimit 为成员从组织钱包消费的上限，0 表示不限制
+type OrganizationMember struct {
+	Id             int    `json:"id"`
+	OrganizationId int    `json:"organization_id" gorm:"uniqueIndex:idx_org_member"`
+	UserId         int    `json:"user_id" gorm:"uniqueIndex:idx_org_member;index"`
+	Username       string `json:"username" gorm:"-:all"`
+	Role           string `json:"role" gorm:"type:varchar(16)"`
+	SpendLimit     int    `json:"spend_limit" gorm:"default:0"`
+	UsedQuota      int    `json:"used_quota" gorm:"default:0"`
+	CreatedAt      int64  `json:"created_at" gorm:"bigint"`
+}
+
+// OrganizationWithRole 用户所在的组织及其角色
+type OrganizationWithRole struct {
+	Organization
+	Role string `json:"role"`
+}
+
+func IsValidOrganizationRole(role string) bool {
+	_, ok := organizationRoleLevels[role]
+	return ok
+}
+
+// OrganizationRoleAtLeast 判断 role 的权限是否不低于 required
+func OrganizationRoleAtLeast(role string, required string) bool {
+	return organizationRoleLevels[role] >= organizationRoleLevels[required]
+}
+
+// CreateOrganization 创建组织，创建者成为所有者
+func CreateOrganization(org *Organization) error {
+	now := common.GetTimestamp()
+	org.CreatedAt = now
+	org.UpdatedAt = now
+	org.Status = OrganizationStatusEnabled
+	return DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(org).Error; err != nil {
+			return err
+		}
+		return tx.Create(&OrganizationMember{
+			OrganizationId: org.Id,
+			UserId:         org.OwnerId,
+			Role:           OrganizationRoleOwner,
+			CreatedAt:      now,
+		}).Error
+	})
+}
+
+func GetOrganizationById(id int) (*Organization, error) {
+	org := Organization{}
+	err := DB.First(&org, "id = ?", id).Error
+	return &org, err
+}
+
+func GetAllOrganizations(startIdx int, num int) (orgs []*Organization, total int64, err error) {
+	tx := DB.Model(&Organization{})
+	if err = tx.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err = tx.Order("id desc").Limit(num).Offset(startIdx).Find(&orgs).Error
+	return orgs, total, err
+}
+
+// GetUserOrganizations 返回用户加入的组织
+func GetUserOrganizations(userId int) ([]*OrganizationWithRole, error) {
+	var members []*OrganizationMember
+	if err := DB.Where("user_id = ?", userId).Find(&members).Error; err != nil {
+		return nil, err
+	}
+	if len(members) == 0 {
+		return []*OrganizationWithRole{}, nil
+	}
+	roles := make(map[int]string, len(members))
+	ids := make([]int, 0, len(members))
+	for _, m := range members {
+		roles[m.OrganizationId] = m.Role
+		ids = append(ids, m.OrganizationId)
+	}
+	var orgs []*Organization
+	if err := DB.Where("id IN ?", ids).Order("id desc").Find(&orgs).Error; err != nil {
+		return nil, err
+	}
+	result := make([]*OrganizationWithRole, 0, len(orgs))
+	for _, org := range orgs {
+		result = append(result, &OrganizationWithRole{Organization: *org, Role: roles[org.Id]})
+	}
+	return result, nil
+}
+
+func UpdateOrganization(org *Organization) error {
+	org.UpdatedAt = common.GetTimestamp()
+	err := DB.Model(org).Select("name", "status", "updated_at").Updates(org).Error
+	if err == nil {
+		invalidateOrganizationCache(org.Id)
+	}
+	return err
+}
+
+// DeleteOrganization 删除组织：剩余额度退回所有者钱包，组织令牌一并删除
+func DeleteOrganization(id int) error {
+	var ownerId, refund int
+	var keys []string
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		org := Organization{}
+		if err := tx.Set("gorm:query_option", "FOR UPDATE").First(&org, "id = ?", id).Error; err != nil {
+			return err
+		}
+		ownerId, refund = org.OwnerId, org.Quota
+		if refund > 0 {
+			if err := tx.Model(&User{}).Where("id = ?", ownerId).Update("quota", gorm.Expr("quota + ?", refund)).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Model(&Token{}).Where("organization_id = ?", id).Pluck(commonKeyCol, &keys).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("organization_id = ?", id).Delete(&Token{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("organization_id = ?", id).Delete(&OrganizationMember{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&org).Error
+	})
+	if err == nil {
+		if refund > 0 {
+			_ = invalidateUserCache(ownerId)
+		}
+		invalidateOrganizationCache(id)
+		invalidateTokenCache(keys)
+	}
+	return err
+}
+
+// AdjustOrganizationQuota 管理员调整组织额度，delta 为负时扣减，扣减后不能为负
+func AdjustOrganizationQuota(id int, delta int) error {
+	tx := DB.Model(&Organization{}).Where("id = ?", id)
+	if delta < 0 {
+		tx = tx.Where("quota >= ?", -delta)
+	}
+	result := tx.Updates(map[string]any{
+		"quota":      gorm.Expr("quota + ?", delta),
+		"updated_at": common.GetTimestamp(),
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrOrganizationQuotaInsufficient
+	}
+	invalidateOrganizationCache(id)
+	return nil
+}
+
+// DepositOrganizationQuota 成员将个人钱包额度转入组织钱包
+func DepositOrganizationQuota(orgId int, userId int, quota int) error {
+	if quota <= 0 {
+		return errors.New("转入额度必须大于 0")
+	}
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&User{}).Where("id = ? AND quota >= ?", userId, quota).
+			Update("quota", gorm.Expr("quota - ?", quota))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("用户额度不足")
+		}
+		return tx.Model(&Organization{}).Where("id = ?", orgId).Updates(map[string]any{
+			"quota":      gorm.Expr("quota + ?", quota),
+			"updated_at": common.GetTimestamp(),
+		}).Error
+	})
+	if err == nil {
+		_ = invalidateUserCache(userId)
+		invalidateOrganizationCache(orgId)
+	}
+	return err
+}
+
+func GetOrganizationMember(orgId int, userId int) (*OrganizationMember, error) {
+	member := OrganizationMember{}
+	err := DB.First(&member, "organization_id = ? AND user_id = ?", orgId, userId).Error
+	return &member, err
+}
+
+func GetOrganizationMembers(orgId int) ([]*OrganizationMember, error) {
+	var members []*OrganizationMember
+	if err := DB.Where("organization_id = ?", orgId).Order("id asc").Find(&members).Error; err != nil {
+		return nil, err
+	}
+	for _, member := range members {
+		member.Username, _ = GetUsernameById(member.UserId, false)
+	}
+	return members, nil
+}
+
+func (member *OrganizationMember) Insert() error {
+	member.CreatedAt = common.GetTimestamp()
+	return DB.Create(member).Error
+}
+
+func (member *OrganizationMember) Update() error {
+	err := DB.Model(member).Select("role", "spend_limit", "used_quota").Updates(member).Error
+	if err == nil {
+		invalidateOrganizationMemberCache(member.OrganizationId, member.UserId)
+	}
+	return err
+}
+
+// DeleteOrganizationMember 移除成员，并禁用该成员创建的组织令牌
+func DeleteOrganizationMember(orgId int, userId int) error {
+	var keys []string
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("organization_id = ? AND user_id = ?", orgId, userId).Delete(&OrganizationMember{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&Token{}).Where("organization_id = ? AND user_id = ?", orgId, userId).Pluck(commonKeyCol, &keys).Error; err != nil {
+			return err
+		}
+		return tx.Model(&Token{}).Where("organization_id = ? AND user_id = ?", orgId, userId).
+			Update("status", common.TokenStatusDisabled).Error
+	})
+	if err == nil {
+		invalidateOrganizationMemberCache(orgId, userId)
+		invalidateTokenCache(keys)
+	}
+	return err
+}
+
+func invalidateTokenCache(keys []string) {
+	if !shouldUpdateRedis(true, nil) {
+		return
+	}
+	for _, key := range keys {
+		if err := cacheDeleteToken(key); err != nil {
+			common.SysLog("failed to delete token cache: " + err.Error())
+		}
+	}
+}
+
+// PreConsumeOrganizationQuota 从组织钱包预扣额度，同时检查成员消费上限。
+// 与用户钱包一致，按缓存中的余额校验后扣减，不对组织行加锁
+func PreConsumeOrganizationQuota(orgId int, userId int, quota int) error {
+	if quota <= 0 {
+		return nil
+	}
+	spend, err := getOrganizationMemberSpend(orgId, userId)
+	if err != nil {
+		return err
+	}
+	if spend.SpendLimit > 0 && spend.UsedQuota+quota > spend.SpendLimit {
+		return ErrOrganizationSpendLimitReached
+	}
+	wallet, err := getOrganizationWallet(orgId)
+	if err != nil {
+		return err
+	}
+	if wallet.Status != OrganizationStatusEnabled || wallet.Quota < quota {
+		return ErrOrganizationQuotaInsufficient
+	}
+	return changeOrganizationQuota(orgId, userId, spend.Id, quota)
+}
+
+// PostConsumeOrganizationQuota 按差额调整组织钱包与成员已用额度（正数补扣，负数退还），不做余额检查
+func PostConsumeOrganizationQuota(orgId int, userId int, delta int) error {
+	if delta == 0 {
+		return nil
+	}
+	memberId := 0
+	if spend, err := getOrganizationMemberSpend(orgId, userId); err == nil {
+		memberId = spend.Id
+	} else if !errors.Is(err, ErrOrganizationNotMember) {
+		return err
+	}
+	return changeOrganizationQuota(orgId, userId, memberId, delta)
+}
+
+// changeOrganizationQuota 组织钱包扣减 delta 并累计成员已用额度（负数为退还）。
+// 同步调整缓存，开启批量更新时合并写库，否则直接写库；memberId 为 0 时（成员已移除）只调整组织钱包
+func changeOrganizationQuota(orgId int, userId int, memberId int, delta int) error {
+	gopool.Go(func() {
+		if err := cacheIncrOrganizationQuota(orgId, int64(-delta)); err != nil {
+			common.SysLog("failed to change organization quota cache: " + err.Error())
+		}
+		if memberId == 0 {
+			return
+		}
+		if err := cacheIncrOrganizationMemberUsedQuota(orgId, userId, int64(delta)); err != nil {
+			common.SysLog("failed to change organization member used quota cache: " + err.Error())
+		}
+	})
+	if common.BatchUpdateEnabled {
+		addNewRecord(BatchUpdateTypeOrganizationQuota, orgId, delta)
+		if memberId != 0 {
+			addNewRecord(BatchUpdateTypeOrganizationMemberUsedQuota, memberId, delta)
+		}
+		return nil
+	}
+	if memberId != 0 {
+		if err := increaseOrganizationMemberUsedQuota(memberId, delta); err != nil {
+			return err
+		}
+	}
+	return consumeOrganizationQuota(orgId, delta)
+}
+
+func consumeOrganizationQuota(orgId int, delta int) error {
+	return DB.Model(&Organization{}).Where("id = ?", orgId).Updates(map[string]any{
+		"quota":      gorm.Expr("quota - ?", delta),
+		"used_quota": gorm.Expr("used_quota + ?", delta),
+	}).Error
+}
+
+func increaseOrganizationMemberUsedQuota(memberId int, delta int) error {
+	return DB.Model(&OrganizationMember{}).Where("id = ?", memberId).
+		Update("used_quota", gorm.Expr("used_quota + ?", delta)).Error
+}
+
+// IncreaseOrganizationRequestCount 异步累加组织请求数
+func IncreaseOrganizationRequestCount(orgId int) {
+	gopool.Go(func() {
+		err := DB.Model(&Organization{}).Where("id = ?", orgId).
+			Update("request_count", gorm.Expr("request_count + ?", 1)).Error
+		if err != nil {
+			common.SysLog(fmt.Sprintf("failed to increase organization %d request count: %s", orgId, err.Error()))
+		}
+	})
+}
+
+func GetOrganizationTokens(orgId int) ([]*Token, error) {
+	var tokens []*Token
+	err := DB.Where("organization_id = ?", orgId).Order("id desc").Find(&tokens).Error
+	return tokens, err
+}
+
+// OrganizationUsage 按成员与模型汇总的组织消费
+type OrganizationUsage struct {
+	UserId           int    `json:"user_id"`
+	Username         string `json:"username"`
+	ModelName        string `json:"model_name"`
+	Quota            int    `json:"quota"`
+	PromptTokens     int    `json:"prompt_tokens"`
+	CompletionTokens int    `json:"completion_tokens"`
+	Count            int    `json:"count"`
+}
+
+// GetOrganizationLogs 分页查询组织令牌产生的日志
+func GetOrganizationLogs(orgId int, logType int, startTimestamp int64, endTimestamp int64, userId int, modelName string, startIdx int, num int) (logs []*Log, total int64, err error) {
+	tx := LOG_DB.Model(&Log{}).Where("organization_id = ?", orgId)
+	if logType != LogTypeUnknown {
+		tx = tx.Where("type = ?", logType)
+	}
+	if startTimestamp != 0 {
+		tx = tx.Where("created_at >= ?", startTimestamp)
+	}
+	if endTimestamp != 0 {
+		tx = tx.Where("created_at <= ?", endTimestamp)
+	}
+	if userId != 0 {
+		tx = tx.Where("user_id = ?", userId)
+	}
+	if modelName != "" {
+		tx = tx.Where("model_name = ?", modelName)
+	}
+	if err = tx.Limit(logSearchCountLimit).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if err = tx.Order("id desc").Limit(num).Offset(startIdx).Find(&logs).Error; err != nil {
+		return nil, 0, err
+	}
+	formatUserLogs(logs, startIdx)
+	return logs, total, nil
+}
+
+// GetOrganizationUsage 汇总组织在时间范围内的消费
+func GetOrganizationUsage(orgId int, startTimestamp int64, endTimestamp int64) ([]*OrganizationUsage, error) {
+	tx := LOG_DB.Table("logs").
+		Select("user_id, username, model_name, sum(quota) quota, sum(prompt_tokens) prompt_tokens, sum(completion_tokens) completion_tokens, count(*) count").
+		Where("organization_id = ? AND type = ?", orgId, LogTypeConsume)
+	if startTimestamp != 0 {
+		tx = tx.Where("created_at >= ?", startTimestamp)
+	}
+	if endTimestamp != 0 {
+		tx = tx.Where("created_at <= ?", endTimestamp)
+	}
+	var usage []*OrganizationUsage
+	err := tx.Group("user_id, username, model_name").Order("quota desc").Scan(&usage).Error
+	return usage, err
+}
@@ -0,0 +1,125 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+
+	"github.com/bytedance/gopkg/util/gopool"
+	"gorm.io/gorm"
+)
+
+// organizationWallet 组织钱包的缓存字段，预扣时据此校验余额
+type organizationWallet struct {
+	Quota  int
+	Status int
+}
+
+// organizationMemberSpend 成员消费上限与已用额度的缓存字段
+type organizationMemberSpend struct {
+	Id         int
+	SpendLimit int
+	UsedQuota  int
+}
+
+func getOrganizationCacheKey(orgId int) string {
+	return fmt.Sprintf("organization:%d", orgId)
+}
+
+func getOrganizationMemberCacheKey(orgId int, userId int) string {
+	return fmt.Sprintf("organization_member:%d:%d", orgId, userId)
+}
+
+func invalidateOrganizationCache(orgId int) {
+	if !common.RedisEnabled {
+		return
+	}
+	if err := common.RedisDelKey(getOrganizationCacheKey(orgId)); err != nil {
+		common.SysLog("failed to delete organization cache: " + err.Error())
+	}
+}
+
+func invalidateOrganizationMemberCache(orgId int, userId int) {
+	if !common.RedisEnabled {
+		return
+	}
+	if err := common.RedisDelKey(getOrganizationMemberCacheKey(orgId, userId)); err != nil {
+		common.SysLog("failed to delete organization member cache: " + err.Error())
+	}
+}
+
+// getOrganizationWallet 优先读取缓存，未命中时从数据库读取并异步回填
+func getOrganizationWallet(orgId int) (wallet *organizationWallet, err error) {
+	var fromDB bool
+	defer func() {
+		if shouldUpdateRedis(fromDB, err) {
+			cached := *wallet
+			gopool.Go(func() {
+				if err := common.RedisHSetObj(getOrganizationCacheKey(orgId), &cached, time.Duration(common.RedisKeyCacheSeconds())*time.Second); err != nil {
+					common.SysLog("failed to update organization cache: " + err.Error())
+				}
+			})
+		}
+	}()
+	if common.RedisEnabled {
+		var cached organizationWallet
+		if err := common.RedisHGetObj(getOrganizationCacheKey(orgId), &cached); err == nil {
+			return &cached, nil
+		}
+	}
+	fromDB = true
+	org := Organization{}
+	if err = DB.Select("quota", "status").First(&org, "id = ?", orgId).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrOrganizationQuotaInsufficient
+		}
+		return nil, err
+	}
+	return &organizationWallet{Quota: org.Quota, Status: org.Status}, nil
+}
+
+// getOrganizationMemberSpend 优先读取缓存，未命中时从数据库读取并异步回填
+func getOrganizationMemberSpend(orgId int, userId int) (spend *organizationMemberSpend, err error) {
+	var fromDB bool
+	defer func() {
+		if shouldUpdateRedis(fromDB, err) {
+			cached := *spend
+			gopool.Go(func() {
+				if err := common.RedisHSetObj(getOrganizationMemberCacheKey(orgId, userId), &cached, time.Duration(common.RedisKeyCacheSeconds())*time.Second); err != nil {
+					common.SysLog("failed to update organization member cache: " + err.Error())
+				}
+			})
+		}
+	}()
+	if common.RedisEnabled {
+		var cached organizationMemberSpend
+		if err := common.RedisHGetObj(getOrganizationMemberCacheKey(orgId, userId), &cached); err == nil {
+			return &cached, nil
+		}
+	}
+	fromDB = true
+	member, err := GetOrganizationMember(orgId, userId)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrOrganizationNotMember
+		}
+		return nil, err
+	}
+	return &organizationMemberSpend{Id: member.Id, SpendLimit: member.SpendLimit, UsedQuota: member.UsedQuota}, nil
+}
+
+func cacheIncrOrganizationQuota(orgId int, delta int64) error {
+	if !common.RedisEnabled {
+		return nil
+	}
+	return common.RedisHIncrBy(getOrganizationCacheKey(orgId), "Quota", delta)
+}
+
+func cacheIncrOrganizationMemberUsedQuota(orgId int, userId int, delta int64) error {
+	if !common.RedisEnabled {
+		return nil
+	}
+	return common.RedisHIncrBy(getOrganizationMemberCacheKey(orgId, userId), "UsedQuota", delta)
+}
@@ -0,0 +1,82 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOrganizationRoleAtLeast(t *testing.T) {
+	require.True(t, OrganizationRoleAtLeast(OrganizationRoleOwner, OrganizationRoleAdmin))
+	require.True(t, OrganizationRoleAtLeast(OrganizationRoleDeveloper, OrganizationRoleDeveloper))
+	require.False(t, OrganizationRoleAtLeast(OrganizationRoleViewer, OrganizationRoleDeveloper))
+	require.False(t, OrganizationRoleAtLeast("unknown", OrganizationRoleViewer))
+}
+
+func TestPreConsumeOrganizationQuota(t *testing.T) {
+	require.NoError(t, DB.AutoMigrate(&Organization{}, &OrganizationMember{}))
+	t.Cleanup(func() {
+		DB.Exec("DELETE FROM organizations")
+		DB.Exec("DELETE FROM organization_members")
+	})
+
+	org := &Organization{Name: "team", OwnerId: 1}
+	require.NoError(t, CreateOrganization(org))
+	require.NoError(t, AdjustOrganizationQuota(org.Id, 1000))
+	member := &OrganizationMember{OrganizationId: org.Id, UserId: 2, Role: OrganizationRoleDeveloper, SpendLimit: 300}
+	require.NoError(t, member.Insert())
+
+	require.NoError(t, PreConsumeOrganizationQuota(org.Id, 2, 200))
+	require.ErrorIs(t, PreConsumeOrganizationQuota(org.Id, 2, 200), ErrOrganizationSpendLimitReached)
+	require.ErrorIs(t, PreConsumeOrganizationQuota(org.Id, 3, 10), ErrOrganizationNotMember)
+	require.ErrorIs(t, PreConsumeOrganizationQuota(org.Id, 1, 900), ErrOrganizationQuotaInsufficient)
+
+	// 校验失败时不写入成员已用额度
+	owner, err := GetOrganizationMember(org.Id, 1)
+	require.NoError(t, err)
+	require.Equal(t, 0, owner.UsedQuota)
+
+	require.NoError(t, PostConsumeOrganizationQuota(org.Id, 2, -50))
+	got, err := GetOrganizationById(org.Id)
+	require.NoError(t, err)
+	require.Equal(t, 850, got.Quota)
+	require.Equal(t, 150, got.UsedQuota)
+	member, err = GetOrganizationMember(org.Id, 2)
+	require.NoError(t, err)
+	require.Equal(t, 150, member.UsedQuota)
+
+	require.ErrorIs(t, AdjustOrganizationQuota(org.Id, -1000), ErrOrganizationQuotaInsufficient)
+}
+
+func TestPreConsumeOrganizationQuotaBatchUpdate(t *testing.T) {
+	require.NoError(t, DB.AutoMigrate(&Organization{}, &OrganizationMember{}))
+	t.Cleanup(func() {
+		DB.Exec("DELETE FROM organizations")
+		DB.Exec("DELETE FROM organization_members")
+	})
+	batchUpdateEnabled := common.BatchUpdateEnabled
+	common.BatchUpdateEnabled = true
+	t.Cleanup(func() { common.BatchUpdateEnabled = batchUpdateEnabled })
+
+	org := &Organization{Name: "batch", OwnerId: 1}
+	require.NoError(t, CreateOrganization(org))
+	require.NoError(t, AdjustOrganizationQuota(org.Id, 1000))
+
+	// 开启批量更新时预扣只记入批量队列，由 batchUpdate 合并写库
+	require.NoError(t, PreConsumeOrganizationQuota(org.Id, 1, 100))
+	require.NoError(t, PostConsumeOrganizationQuota(org.Id, 1, 20))
+	got, err := GetOrganizationById(org.Id)
+	require.NoError(t, err)
+	require.Equal(t, 1000, got.Quota)
+
+	batchUpdate()
+	got, err = GetOrganizationById(org.Id)
+	require.NoError(t, err)
+	require.Equal(t, 880, got.Quota)
+	require.Equal(t, 120, got.UsedQuota)
+	owner, err := GetOrganizationMember(org.Id, 1)
+	require.NoError(t, err)
+	require.Equal(t, 120, owner.UsedQuota)
+}
@@ -0,0 +1,135 @@
+package model
+
+import (
+	"errors"
+
+	"github.com/QuantumNous/new-api/common"
+)
+
+// 抓包规则的目标类型
+const (
+	PayloadCaptureTargetToken   = "token"
+	PayloadCaptureTargetUser    = "user"
+	PayloadCaptureTargetChannel = "channel"
+)
+
+// PayloadCaptureRule 请求/响应内容抓取规则，命中令牌、用户或渠道的请求在到期前会被记录
+type PayloadCaptureRule struct {
+	Id         int    `json:"id"`
+	TargetType string `json:"target_type" gorm:"type:varchar(16);index:idx_capture_rule_target"`
+	TargetId   int    `json:"target_id" gorm:"index:idx_capture_rule_target"`
+	Remark     string `json:"remark" gorm:"type:varchar(255)"`
+	CreatedBy  int    `json:"created_by"`
+	CreatedAt  int64  `json:"created_at" gorm:"bigint"`
+	ExpiresAt  int64  `json:"expires_at" gorm:"bigint;index"`
+}
+
+// PayloadCapture 一次上游尝试的抓包记录，内容均已脱敏
+type PayloadCapture struct {
+	Id              int    `json:"id"`
+	CreatedAt       int64  `json:"created_at" gorm:"bigint;index"`
+	RuleId          int    `json:"rule_id" gorm:"index"`
+	RequestId       string `json:"request_id" gorm:"type:varchar(64);index"`
+	UserId          int    `json:"user_id" gorm:"index"`
+	TokenId         int    `json:"token_id" gorm:"index"`
+	ChannelId       int    `json:"channel_id" gorm:"index"`
+	ModelName       string `json:"model_name" gorm:"type:varchar(128)"`
+	Path            string `json:"path" gorm:"type:varchar(255)"`
+	IsStream        bool   `json:"is_stream"`
+	StatusCode      int    `json:"status_code"`
+	ErrorMessage    string `json:"error_message" gorm:"type:text"`
+	ClientRequest   string `json:"client_request" gorm:"type:text"`
+	UpstreamRequest string `json:"upstream_request" gorm:"type:text"`
+	Response        string `json:"response" gorm:"type:text"`
+	Truncated       bool   `json:"truncated"`
+}
+
+type PayloadCaptureFilter struct {
+	RequestId string
+	UserId    int
+	TokenId   int
+	ChannelId int
+}
+
+func NormalizePayloadCaptureTarget(targetType string) (string, error) {
+	switch targetType {
+	case PayloadCaptureTargetToken, PayloadCaptureTargetUser, PayloadCaptureTargetChannel:
+		return targetType, nil
+	default:
+		return "", errors.New("无效的抓包目标类型: " + targetType)
+	}
+}
+
+func (rule *PayloadCaptureRule) Insert() error {
+	rule.CreatedAt = common.GetTimestamp()
+	return DB.Create(rule).Error
+}
+
+func DeletePayloadCaptureRule(id int) (*PayloadCaptureRule, error) {
+	rule := PayloadCaptureRule{}
+	if err := DB.First(&rule, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &rule, DB.Delete(&rule).Error
+}
+
+func GetPayloadCaptureRules() ([]*PayloadCaptureRule, error) {
+	var rules []*PayloadCaptureRule
+	err := DB.Order("id desc").Find(&rules).Error
+	return rules, err
+}
+
+// GetActivePayloadCaptureRules 返回尚未到期的规则
+func GetActivePayloadCaptureRules() ([]*PayloadCaptureRule, error) {
+	var rules []*PayloadCaptureRule
+	err := DB.Where("expires_at > ?", common.GetTimestamp()).Find(&rules).Error
+	return rules, err
+}
+
+func (capture *PayloadCapture) Insert() error {
+	if capture.CreatedAt == 0 {
+		capture.CreatedAt = common.GetTimestamp()
+	}
+	return LOG_DB.Create(capture).Error
+}
+
+// GetPayloadCaptures 分页查询抓包记录，列表不返回内容字段
+func GetPayloadCaptures(filter PayloadCaptureFilter, startIdx int, num int) (captures []*PayloadCapture, total int64, err error) {
+	tx := LOG_DB.Model(&PayloadCapture{})
+	if filter.RequestId != "" {
+		tx = tx.Where("request_id = ?", filter.RequestId)
+	}
+	if filter.UserId != 0 {
+		tx = tx.Where("user_id = ?", filter.UserId)
+	}
+	if filter.TokenId != 0 {
+		tx = tx.Where("token_id = ?", filter.TokenId)
+	}
+	if filter.ChannelId != 0 {
+		tx = tx.Where("channel_id = ?", filter.ChannelId)
+	}
+	if err = tx.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err = tx.Omit("client_request", "upstream_request", "response").
+		Order("id desc").Limit(num).Offset(startIdx).Find(&captures).Error
+	return captures, total, err
+}
+
+func GetPayloadCaptureById(id int) (*PayloadCapture, error) {
+	capture := PayloadCapture{}
+	err := LOG_DB.First(&capture, "id = ?", id).Error
+	return &capture, err
+}
+
+// PurgePayloadCaptures 删除 before 之前的抓包记录与已到期的规则
+func PurgePayloadCaptures(before int64) (int64, error) {
+	result := LOG_DB.Where("created_at < ?", before).Delete(&PayloadCapture{})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	if err := DB.Where("expires_at <= ?", common.GetTimestamp()).Delete(&PayloadCaptureRule{}).Error; err != nil {
+		return result.RowsAffected, err
+	}
+	return result.RowsAffected, nil
+}
@@ -309,14 +309,21 @@ func calcNextResetTime(base time.Time, plan *SubscriptionPlan, endUnix int64) in
 	if plan == nil {
 		return 0
 	}
-	period := NormalizeResetPeriod(plan.QuotaResetPeriod)
-	if period == SubscriptionResetNever {
+	next := nextResetTimeByPeriod(base, NormalizeResetPeriod(plan.QuotaResetPeriod), plan.QuotaResetCustomSeconds)
+	if next.IsZero() {
 		return 0
 	}
-	var next time.Time
+	if endUnix > 0 && next.Unix() > endUnix {
+		return 0
+	}
+	return next.Unix()
+}
+
+// nextResetTimeByPeriod 计算 base 之后的下一个重置时间点，不重置时返回零值
+func nextResetTimeByPeriod(base time.Time, period string, customSeconds int64) time.Time {
 	switch period {
 	case SubscriptionResetDaily:
-		next = time.Date(base.Year(), base.Month(), base.Day(), 0, 0, 0, 0, base.Location()).
+		return time.Date(base.Year(), base.Month(), base.Day(), 0, 0, 0, 0, base.Location()).
 			AddDate(0, 0, 1)
 	case SubscriptionResetWeekly:
 		// Align to next Monday 00:00
@@ -326,24 +333,20 @@ func calcNextResetTime(base time.Time, plan *SubscriptionPlan, endUnix int64) in
 			weekday = 7
 		}
 		daysUntil := 8 - weekday
-		next = time.Date(base.Year(), base.Month(), base.Day(), 0, 0, 0, 0, base.Location()).
+		return time.Date(base.Year(), base.Month(), base.Day(), 0, 0, 0, 0, base.Location()).
 			AddDate(0, 0, daysUntil)
 	case SubscriptionResetMonthly:
 		// Align to first day of next month 00:00
-		next = time.Date(base.Year(), base.Month(), 1, 0, 0, 0, 0, base.Location()).
+		return time.Date(base.Year(), base.Month(), 1, 0, 0, 0, 0, base.Location()).
 			AddDate(0, 1, 0)
 	case SubscriptionResetCustom:
-		if plan.QuotaResetCustomSeconds <= 0 {
-			return 0
+		if customSeconds <= 0 {
+			return time.Time{}
 		}
-		next = base.Add(time.Duration(plan.QuotaResetCustomSeconds) * time.Second)
+		return base.Add(time.Duration(customSeconds) * time.Second)
 	default:
-		return 0
+		return time.Time{}
 	}
-	if endUnix > 0 && next.Unix() > endUnix {
-		return 0
-	}
-	return next.Unix()
 }
 
 func GetSubscriptionPlanById(id int) (*SubscriptionPlan, error) {
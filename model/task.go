@@ -101,9 +101,11 @@ type TaskPrivateData struct {
 	UpstreamTaskID string `json:"upstream_task_id,omitempty"` // 上游真实 task ID
 	ResultURL      string `json:"result_url,omitempty"`       // 任务成功后的结果 URL（视频地址等）
 	// 计费上下文：用于异步退款/差额结算（轮询阶段读取）
-	BillingSource  string              `json:"billing_source,omitempty"`  // "wallet" 或 "subscription"
+	BillingSource  string              `json:"billing_source,omitempty"`  // "wallet"、"subscription" 或 "organization"
 	SubscriptionId int                 `json:"subscription_id,omitempty"` // 订阅 ID，用于订阅退款
+	OrganizationId int                 `json:"organization_id,omitempty"` // 组织 ID，用于组织钱包退款
 	TokenId        int                 `json:"token_id,omitempty"`        // 令牌 ID，用于令牌额度退款
+	CallbackURL    string              `json:"callback_url,omitempty"`    // 任务到达终态时的回调地址
 	BillingContext *TaskBillingContext `json:"billing_context,omitempty"` // 计费参数快照（用于轮询阶段重新计算）
 }
 
@@ -173,8 +175,10 @@ func InitTask(platform constant.TaskPlatform, relayInfo *commonRelay.RelayInfo)
 	properties := Properties{}
 	privateData := TaskPrivateData{}
 	if relayInfo != nil && relayInfo.ChannelMeta != nil {
+		// 微调任务依赖训练文件所在的 key，轮询时必须使用同一个 key
 		if relayInfo.ChannelMeta.ChannelType == constant.ChannelTypeGemini ||
-			relayInfo.ChannelMeta.ChannelType == constant.ChannelTypeVertexAi {
+			relayInfo.ChannelMeta.ChannelType == constant.ChannelTypeVertexAi ||
+			platform == constant.TaskPlatformFineTuning {
 			privateData.Key = relayInfo.ChannelMeta.ApiKey
 		}
 		if relayInfo.UpstreamModelName != "" {
@@ -291,8 +295,10 @@ func TaskGetAllTasks(startIdx int, num int, queryParams SyncTaskQueryParams) []*
 
 func GetTimedOutUnfinishedTasks(cutoffUnix int64, limit int) []*Task {
 	var tasks []*Task
+	// 微调任务耗时可能远超视频任务，状态以上游为准，不参与超时清理
 	err := DB.Where("progress != ?", "100%").
 		Where("status NOT IN ?", []string{TaskStatusFailure, TaskStatusSuccess}).
+		Where("platform != ?", constant.TaskPlatformFineTuning).
 		Where("submit_time < ?", cutoffUnix).
 		Order("submit_time").
 		Limit(limit).
@@ -343,6 +349,24 @@ func GetByTaskId(userId int, taskId string) (*Task, bool, error) {
 	return task, exist, err
 }
 
+// GetUserTasksByPlatform 按 id 倒序分页查询用户在某平台的任务，afterId > 0 时只返回更早的任务。
+// 多取一条用于判断 hasMore。
+func GetUserTasksByPlatform(userId int, platform constant.TaskPlatform, afterId int64, limit int) ([]*Task, bool, error) {
+	var tasks []*Task
+	query := DB.Where("user_id = ? and platform = ?", userId, platform)
+	if afterId > 0 {
+		query = query.Where("id < ?", afterId)
+	}
+	if err := query.Order("id desc").Limit(limit + 1).Find(&tasks).Error; err != nil {
+		return nil, false, err
+	}
+	hasMore := len(tasks) > limit
+	if hasMore {
+		tasks = tasks[:limit]
+	}
+	return tasks, hasMore, nil
+}
+
 func GetByTaskIds(userId int, taskIds []any) ([]*Task, error) {
 	if len(taskIds) == 0 {
 		return nil, nil
@@ -401,6 +425,11 @@ func (Task *Task) Update() error {
 	return err
 }
 
+// UpdatePrivateData 只更新 private_data 列，用于任务终态之后改写结果地址
+func (t *Task) UpdatePrivateData() error {
+	return DB.Model(t).Select("private_data").Updates(t).Error
+}
+
 // UpdateWithStatus performs a conditional UPDATE guarded by fromStatus (CAS).
 // Returns (true, nil) if this caller won the update, (false, nil) if
 // another process already moved the task out of fromStatus.
@@ -0,0 +1,142 @@
+package model
+
+import (
+	"errors"
+
+	"github.com/QuantumNous/new-api/common"
+)
+
+// 回调投递状态
+const (
+	TaskCallbackStatusPending   = "pending"
+	TaskCallbackStatusSucceeded = "succeeded"
+	TaskCallbackStatusFailed    = "failed"
+)
+
+// TaskCallback 异步任务回调的投递记录，失败后按退避时间重试，超过最大次数后标记为 failed
+type TaskCallback struct {
+	Id             int    `json:"id"`
+	CreatedAt      int64  `json:"created_at" gorm:"bigint;index"`
+	UpdatedAt      int64  `json:"updated_at" gorm:"bigint"`
+	TaskId         string `json:"task_id" gorm:"type:varchar(191);index"`
+	UserId         int    `json:"user_id" gorm:"index"`
+	Url            string `json:"url" gorm:"type:varchar(512)"`
+	Event          string `json:"event" gorm:"type:varchar(32)"`
+	Payload        string `json:"payload" gorm:"type:text"`
+	Status         string `json:"status" gorm:"type:varchar(16);index:idx_task_callback_due"`
+	Attempts       int    `json:"attempts" gorm:"default:0"`
+	NextAttemptAt  int64  `json:"next_attempt_at" gorm:"bigint;index:idx_task_callback_due"`
+	LastStatusCode int    `json:"last_status_code"`
+	LastError      string `json:"last_error" gorm:"type:varchar(512)"`
+}
+
+type TaskCallbackFilter struct {
+	UserId int
+	TaskId string
+	Status string
+}
+
+func (callback *TaskCallback) Insert() error {
+	now := common.GetTimestamp()
+	callback.CreatedAt = now
+	callback.UpdatedAt = now
+	if callback.Status == "" {
+		callback.Status = TaskCallbackStatusPending
+	}
+	if callback.NextAttemptAt == 0 {
+		callback.NextAttemptAt = now
+	}
+	return DB.Create(callback).Error
+}
+
+// SaveAttempt 保存一次投递尝试的结果
+func (callback *TaskCallback) SaveAttempt() error {
+	callback.UpdatedAt = common.GetTimestamp()
+	return DB.Model(callback).Select("updated_at", "status", "attempts", "next_attempt_at", "last_status_code", "last_error").
+		Updates(callback).Error
+}
+
+// GetDueTaskCallbacks 返回到达重试时间的待投递回调
+func GetDueTaskCallbacks(now int64, limit int) ([]*TaskCallback, error) {
+	var callbacks []*TaskCallback
+	err := DB.Where("status = ? AND next_attempt_at <= ?", TaskCallbackStatusPending, now).
+		Order("next_attempt_at asc").Limit(limit).Find(&callbacks).Error
+	return callbacks, err
+}
+
+// GetTaskCallbacks 分页查询投递记录，UserId 为 0 时查询全部用户
+func GetTaskCallbacks(filter TaskCallbackFilter, startIdx int, num int) (callbacks []*TaskCallback, total int64, err error) {
+	tx := DB.Model(&TaskCallback{})
+	if filter.UserId != 0 {
+		tx = tx.Where("user_id = ?", filter.UserId)
+	}
+	if filter.TaskId != "" {
+		tx = tx.Where("task_id = ?", filter.TaskId)
+	}
+	if filter.Status != "" {
+		tx = tx.Where("status = ?", filter.Status)
+	}
+	if err = tx.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err = tx.Order("id desc").Limit(num).Offset(startIdx).Find(&callbacks).Error
+	return callbacks, total, err
+}
+
+// GetTaskCallbackById userId 为 0 时不校验归属
+func GetTaskCallbackById(id int, userId int) (*TaskCallback, error) {
+	callback := TaskCallback{}
+	tx := DB.Where("id = ?", id)
+	if userId != 0 {
+		tx = tx.Where("user_id = ?", userId)
+	}
+	if err := tx.First(&callback).Error; err != nil {
+		return nil, err
+	}
+	return &callback, nil
+}
+
+// ResetTaskCallback 重新投递：清零尝试次数并立即进入待投递队列
+func ResetTaskCallback(callback *TaskCallback) error {
+	callback.Status = TaskCallbackStatusPending
+	callback.Attempts = 0
+	callback.NextAttemptAt = common.GetTimestamp()
+	callback.LastError = ""
+	return callback.SaveAttempt()
+}
+
+// EnsureUserTaskCallbackSecret 返回用户的任务回调签名密钥，首次使用时生成并写入用户设置。
+// 以原设置作为条件更新，并发生成时以先写入的密钥为准
+func EnsureUserTaskCallbackSecret(userId int) (string, error) {
+	for i := 0; i < 3; i++ {
+		user, err := GetUserById(userId, true)
+		if err != nil {
+			return "", err
+		}
+		setting := user.GetSetting()
+		if setting.TaskCallbackSecret != "" {
+			return setting.TaskCallbackSecret, nil
+		}
+		secret, err := common.GenerateRandomCharsKey(32)
+		if err != nil {
+			return "", err
+		}
+		oldSetting := user.Setting
+		setting.TaskCallbackSecret = "whsec_" + secret
+		user.SetSetting(setting)
+		result := DB.Model(&User{}).Where("id = ? AND setting = ?", userId, oldSetting).Update("setting", user.Setting)
+		if result.Error != nil {
+			return "", result.Error
+		}
+		if result.RowsAffected == 0 {
+			continue
+		}
+		if common.RedisEnabled {
+			if err := updateUserSettingCache(userId, user.Setting); err != nil {
+				common.SysLog("failed to update user setting cache: " + err.Error())
+			}
+		}
+		return setting.TaskCallbackSecret, nil
+	}
+	return "", errors.New("failed to generate task callback secret")
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/QuantumNous/new-api/common"
 	"github.com/QuantumNous/new-api/setting/operation_setting"
@@ -27,7 +28,17 @@ type Token struct {
 	AllowIps           *string        `json:"allow_ips" gorm:"default:''"`
 	UsedQuota          int            `json:"used_quota" gorm:"default:0"` // used quota
 	Group              string         `json:"group" gorm:"default:''"`
-	CrossGroupRetry    bool           `json:"cross_group_retry"` // 跨分组重试，仅auto分组有效
+	CrossGroupRetry    bool           `json:"cross_group_retry"`                                     // 跨分组重试，仅auto分组有效
+	ModelFallback      int            `json:"model_fallback" gorm:"default:0"`                       // 模型降级策略：0 跟随系统，1 启用，2 禁用
+	RpmLimit           int            `json:"rpm_limit" gorm:"default:0"`                            // 每分钟请求数限制，0 表示不限制
+	TpmLimit           int            `json:"tpm_limit" gorm:"default:0"`                            // 每分钟 token 数限制，按结算后的实际用量计算
+	ConcurrencyLimit   int            `json:"concurrency_limit" gorm:"default:0"`                    // 最大并发请求数，WebSocket 连接不计入
+	BudgetPeriod       string         `json:"budget_period" gorm:"type:varchar(16);default:'never'"` // 周期预算：never/daily/weekly/monthly
+	BudgetQuota        int            `json:"budget_quota" gorm:"default:0"`                         // 每个周期的消费上限
+	BudgetUsed         int            `json:"budget_used" gorm:"default:0"`                          // 本周期已消费额度
+	BudgetResetTime    int64          `json:"budget_reset_time" gorm:"bigint;index;default:0"`       // 下次重置时间
+	OrganizationId     int            `json:"organization_id" gorm:"index;default:0"`                // 组织令牌，消费从组织钱包扣除
+	CallbackUrl        string         `json:"callback_url" gorm:"type:varchar(512);default:''"`      // 异步任务完成回调的默认地址
 	DeletedAt          gorm.DeletedAt `gorm:"index"`
 }
 
@@ -224,6 +235,14 @@ func ValidateUserToken(key string) (token *Token, err error) {
 			keySuffix := key[len(key)-3:]
 			return token, fmt.Errorf("[sk-%s***%s] 该令牌额度已用尽 !token.UnlimitedQuota && token.RemainQuota = %d", keyPrefix, keySuffix, token.RemainQuota)
 		}
+		if token.IsBudgetEnabled() {
+			if _, err := token.RollBudgetPeriod(common.GetTimestamp()); err != nil {
+				common.SysLog("failed to roll token budget period: " + err.Error())
+			}
+		}
+		if token.IsBudgetEnabled() && token.GetBudgetRemain(common.GetTimestamp()) <= 0 {
+			return token, fmt.Errorf("该令牌本周期额度已用尽，将于 %s 重置", time.Unix(token.BudgetResetTime, 0).Format("2006-01-02 15:04:05"))
+		}
 		return token, nil
 	}
 	common.SysLog("ValidateUserToken: failed to get token: " + err.Error())
@@ -304,7 +323,9 @@ func (token *Token) Update() (err error) {
 		}
 	}()
 	err = DB.Model(token).Select("name", "status", "expired_time", "remain_quota", "unlimited_quota",
-		"model_limits_enabled", "model_limits", "allow_ips", "group", "cross_group_retry").Updates(token).Error
+		"model_limits_enabled", "model_limits", "allow_ips", "group", "cross_group_retry", "model_fallback",
+		"rpm_limit", "tpm_limit", "concurrency_limit",
+		"budget_period", "budget_quota", "budget_reset_time", "callback_url").Updates(token).Error
 	return err
 }
 
@@ -405,6 +426,7 @@ func increaseTokenQuota(id int, quota int) (err error) {
 		map[string]interface{}{
 			"remain_quota":  gorm.Expr("remain_quota + ?", quota),
 			"used_quota":    gorm.Expr("used_quota - ?", quota),
+			"budget_used":   gorm.Expr("CASE WHEN budget_used > ? THEN budget_used - ? ELSE 0 END", quota, quota),
 			"accessed_time": common.GetTimestamp(),
 		},
 	).Error
@@ -435,6 +457,7 @@ func decreaseTokenQuota(id int, quota int) (err error) {
 		map[string]interface{}{
 			"remain_quota":  gorm.Expr("remain_quota - ?", quota),
 			"used_quota":    gorm.Expr("used_quota + ?", quota),
+			"budget_used":   gorm.Expr("budget_used + ?", quota),
 			"accessed_time": common.GetTimestamp(),
 		},
 	).Error
@@ -0,0 +1,142 @@
+package model
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+)
+
+// NormalizeTokenBudgetPeriod 令牌周期预算仅支持按天、周、月重置，其余取值视为不启用
+func NormalizeTokenBudgetPeriod(period string) string {
+	switch strings.TrimSpace(period) {
+	case SubscriptionResetDaily, SubscriptionResetWeekly, SubscriptionResetMonthly:
+		return strings.TrimSpace(period)
+	default:
+		return SubscriptionResetNever
+	}
+}
+
+func calcNextTokenBudgetResetTime(base time.Time, period string) int64 {
+	next := nextResetTimeByPeriod(base, NormalizeTokenBudgetPeriod(period), 0)
+	if next.IsZero() {
+		return 0
+	}
+	return next.Unix()
+}
+
+func (token *Token) IsBudgetEnabled() bool {
+	return NormalizeTokenBudgetPeriod(token.BudgetPeriod) != SubscriptionResetNever && token.BudgetQuota > 0
+}
+
+// GetBudgetRemain 返回本周期剩余可用额度；已到重置时间的周期需先经 RollBudgetPeriod 推进，
+// 否则仍按上一周期的用量计算，不会在重置前放行超出预算的请求
+func (token *Token) GetBudgetRemain(now int64) int {
+	return max(token.BudgetQuota-max(token.BudgetUsed, 0), 0)
+}
+
+// nextTokenBudgetResetTime 从已到期的重置时间推进到 now 之后的第一个重置时间
+func nextTokenBudgetResetTime(resetTime int64, period string, now int64) int64 {
+	next := calcNextTokenBudgetResetTime(time.Unix(resetTime, 0), period)
+	for next > 0 && next <= now {
+		next = calcNextTokenBudgetResetTime(time.Unix(next, 0), period)
+	}
+	return next
+}
+
+// RollBudgetPeriod 令牌已到重置时间时清零本周期用量并推进重置时间，返回本次调用是否完成了重置。
+// 以 budget_reset_time 作为条件更新，并发请求、多节点与定时任务同时到期时只有一方生效，
+// 其余方重新读取最新的周期状态
+func (token *Token) RollBudgetPeriod(now int64) (bool, error) {
+	if token.BudgetResetTime <= 0 || token.BudgetResetTime > now {
+		return false, nil
+	}
+	next := nextTokenBudgetResetTime(token.BudgetResetTime, token.BudgetPeriod, now)
+	result := DB.Model(&Token{}).
+		Where("id = ? AND budget_reset_time = ?", token.Id, token.BudgetResetTime).
+		Updates(map[string]interface{}{
+			"budget_used":       0,
+			"budget_reset_time": next,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	rolled := result.RowsAffected > 0
+	if rolled {
+		token.BudgetUsed = 0
+		token.BudgetResetTime = next
+	} else {
+		var latest Token
+		if err := DB.Select("budget_used", "budget_reset_time").Where("id = ?", token.Id).First(&latest).Error; err != nil {
+			return false, err
+		}
+		token.BudgetUsed = latest.BudgetUsed
+		token.BudgetResetTime = latest.BudgetResetTime
+	}
+	if common.RedisEnabled && token.Key != "" {
+		if err := cacheSetTokenField(token.Key, constant.TokenFiledBudgetUsed, strconv.Itoa(token.BudgetUsed)); err != nil {
+			common.SysLog("failed to update token cache: " + err.Error())
+		}
+		if err := cacheSetTokenField(token.Key, constant.TokenFiledBudgetResetTime, strconv.FormatInt(token.BudgetResetTime, 10)); err != nil {
+			common.SysLog("failed to update token cache: " + err.Error())
+		}
+	}
+	return rolled, nil
+}
+
+// SetBudget 设置令牌的周期预算，周期变化时从当前时间重新开始计算。
+// 返回本周期用量是否被清零，清零需通过 ResetTokenBudgetUsed 单独写入
+func (token *Token) SetBudget(period string, quota int, now int64) bool {
+	period = NormalizeTokenBudgetPeriod(period)
+	if period == SubscriptionResetNever {
+		reset := token.BudgetUsed != 0
+		token.BudgetPeriod = SubscriptionResetNever
+		token.BudgetQuota = 0
+		token.BudgetUsed = 0
+		token.BudgetResetTime = 0
+		return reset
+	}
+	reset := false
+	if period != token.BudgetPeriod || token.BudgetResetTime == 0 {
+		reset = token.BudgetUsed != 0
+		token.BudgetUsed = 0
+		token.BudgetResetTime = calcNextTokenBudgetResetTime(time.Unix(now, 0), period)
+	}
+	token.BudgetPeriod = period
+	token.BudgetQuota = quota
+	return reset
+}
+
+// ResetTokenBudgetUsed 清零本周期用量。Token.Update 不写 budget_used，避免覆盖并发请求的累加
+func ResetTokenBudgetUsed(id int) error {
+	return DB.Model(&Token{}).Where("id = ?", id).Update("budget_used", 0).Error
+}
+
+// ResetDueTokenBudgets 重置已到期的令牌周期预算：只清零本周期用量并推进重置时间，不影响令牌的剩余额度。
+// 请求路径会在校验预算前通过 RollBudgetPeriod 惰性推进，这里负责长期没有请求的令牌
+func ResetDueTokenBudgets(limit int) (int, error) {
+	if limit <= 0 {
+		limit = 200
+	}
+	now := GetDBTimestamp()
+	var tokens []Token
+	if err := DB.Where("budget_reset_time > 0 AND budget_reset_time <= ?", now).
+		Order("budget_reset_time asc").
+		Limit(limit).
+		Find(&tokens).Error; err != nil {
+		return 0, err
+	}
+	resetCount := 0
+	for i := range tokens {
+		rolled, err := tokens[i].RollBudgetPeriod(now)
+		if err != nil {
+			return resetCount, err
+		}
+		if rolled {
+			resetCount++
+		}
+	}
+	return resetCount, nil
+}
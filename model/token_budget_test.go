@@ -0,0 +1,89 @@
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenBudget(t *testing.T) {
+	now := time.Date(2026, 3, 4, 15, 30, 0, 0, time.Local).Unix()
+	token := &Token{}
+
+	token.SetBudget("daily", 500, now)
+	require.True(t, token.IsBudgetEnabled())
+	require.Equal(t, time.Date(2026, 3, 5, 0, 0, 0, 0, time.Local).Unix(), token.BudgetResetTime)
+
+	token.BudgetUsed = 300
+	require.Equal(t, 200, token.GetBudgetRemain(now))
+	// 周期未变化时保留本周期用量
+	token.SetBudget("daily", 1000, now)
+	require.Equal(t, 300, token.BudgetUsed)
+	// 已过重置时间但周期尚未推进时仍按本周期用量计算
+	require.Equal(t, 700, token.GetBudgetRemain(token.BudgetResetTime))
+
+	token.SetBudget("monthly", 1000, now)
+	require.Equal(t, 0, token.BudgetUsed)
+	require.Equal(t, time.Date(2026, 4, 1, 0, 0, 0, 0, time.Local).Unix(), token.BudgetResetTime)
+
+	token.SetBudget("custom", 1000, now)
+	require.False(t, token.IsBudgetEnabled())
+	require.Equal(t, int64(0), token.BudgetResetTime)
+}
+
+func TestResetDueTokenBudgetsKeepsRemainQuota(t *testing.T) {
+	t.Cleanup(func() { DB.Exec("DELETE FROM tokens") })
+	token := &Token{
+		Id:              901,
+		UserId:          1,
+		Key:             "budget-reset-key",
+		Status:          1,
+		RemainQuota:     700,
+		BudgetPeriod:    "daily",
+		BudgetQuota:     500,
+		BudgetUsed:      400,
+		BudgetResetTime: time.Now().Add(-time.Minute).Unix(),
+	}
+	require.NoError(t, DB.Create(token).Error)
+
+	count, err := ResetDueTokenBudgets(10)
+	require.NoError(t, err)
+	require.Equal(t, 1, count)
+
+	got, err := GetTokenById(token.Id)
+	require.NoError(t, err)
+	require.Equal(t, 0, got.BudgetUsed)
+	require.Equal(t, 700, got.RemainQuota)
+	require.Greater(t, got.BudgetResetTime, time.Now().Unix())
+}
+
+func TestRollBudgetPeriodOnlyOnce(t *testing.T) {
+	t.Cleanup(func() { DB.Exec("DELETE FROM tokens") })
+	token := &Token{
+		Id:              902,
+		UserId:          1,
+		Key:             "budget-roll-key",
+		Status:          1,
+		BudgetPeriod:    "daily",
+		BudgetQuota:     500,
+		BudgetUsed:      500,
+		BudgetResetTime: time.Now().Add(-time.Minute).Unix(),
+	}
+	require.NoError(t, DB.Create(token).Error)
+	stale := *token
+
+	now := time.Now().Unix()
+	rolled, err := token.RollBudgetPeriod(now)
+	require.NoError(t, err)
+	require.True(t, rolled)
+	require.Equal(t, 500, token.GetBudgetRemain(now))
+	require.NoError(t, DB.Model(&Token{}).Where("id = ?", token.Id).Update("budget_used", 200).Error)
+
+	// 同一周期的并发请求不会再次清零，而是读取最新用量
+	rolled, err = stale.RollBudgetPeriod(now)
+	require.NoError(t, err)
+	require.False(t, rolled)
+	require.Equal(t, 200, stale.BudgetUsed)
+	require.Equal(t, token.BudgetResetTime, stale.BudgetResetTime)
+}
@@ -17,6 +17,8 @@ const (
 	BatchUpdateTypeUsedQuota
 	BatchUpdateTypeChannelUsedQuota
 	BatchUpdateTypeRequestCount
+	BatchUpdateTypeOrganizationQuota
+	BatchUpdateTypeOrganizationMemberUsedQuota
 	BatchUpdateTypeCount // if you add a new type, you need to add a new map and a new lock
 )
 
@@ -91,6 +93,14 @@ func batchUpdate() {
 				updateUserRequestCount(key, value)
 			case BatchUpdateTypeChannelUsedQuota:
 				updateChannelUsedQuota(key, value)
+			case BatchUpdateTypeOrganizationQuota:
+				if err := consumeOrganizationQuota(key, value); err != nil {
+					common.SysLog("failed to batch update organization quota: " + err.Error())
+				}
+			case BatchUpdateTypeOrganizationMemberUsedQuota:
+				if err := increaseOrganizationMemberUsedQuota(key, value); err != nil {
+					common.SysLog("failed to batch update organization member used quota: " + err.Error())
+				}
 			}
 		}
 	}
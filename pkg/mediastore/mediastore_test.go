@@ -0,0 +1,96 @@
+package mediastore
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidKey(t *testing.T) {
+	require.True(t, ValidKey("task_abc.mp4"))
+	require.True(t, ValidKey("2026/10/task_abc.png"))
+	require.False(t, ValidKey(""))
+	require.False(t, ValidKey("/etc/passwd"))
+	require.False(t, ValidKey("../secret"))
+	require.False(t, ValidKey("a//b"))
+	require.False(t, ValidKey("a b"))
+}
+
+func testStoreRoundTrip(t *testing.T, store Store) {
+	ctx := context.Background()
+	require.NoError(t, store.Put(ctx, "tasks/task_1.mp4", strings.NewReader("video"), 5, "video/mp4"))
+
+	body, size, err := store.Get(ctx, "tasks/task_1.mp4")
+	require.NoError(t, err)
+	data, _ := io.ReadAll(body)
+	body.Close()
+	require.Equal(t, "video", string(data))
+	require.EqualValues(t, 5, size)
+
+	require.NoError(t, store.Delete(ctx, "tasks/task_1.mp4"))
+	require.NoError(t, store.Delete(ctx, "tasks/task_1.mp4"))
+	_, _, err = store.Get(ctx, "tasks/task_1.mp4")
+	require.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestLocalStore(t *testing.T) {
+	testStoreRoundTrip(t, NewLocalStore(t.TempDir()))
+}
+
+// fakeS3 MinIO 风格的内存对象服务，只校验请求带有 SigV4 签名
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=minio/") ||
+		r.Header.Get("X-Amz-Content-Sha256") != s3UnsignedPayload {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch r.Method {
+	case http.MethodPut:
+		data, _ := io.ReadAll(r.Body)
+		f.objects[r.URL.Path] = data
+	case http.MethodGet:
+		data, ok := f.objects[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write(data)
+	case http.MethodDelete:
+		delete(f.objects, r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func TestS3Store(t *testing.T) {
+	fake := &fakeS3{objects: map[string][]byte{}}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	store, err := NewS3Store(S3Config{
+		Endpoint:        server.URL,
+		Bucket:          "media",
+		AccessKeyId:     "minio",
+		SecretAccessKey: "minio123",
+		PathStyle:       true,
+	}, server.Client())
+	require.NoError(t, err)
+
+	require.NoError(t, store.Put(context.Background(), "task_2.png", strings.NewReader("png"), 3, "image/png"))
+	require.Contains(t, fake.objects, "/media/task_2.png")
+	require.NoError(t, store.Delete(context.Background(), "task_2.png"))
+
+	testStoreRoundTrip(t, store)
+}
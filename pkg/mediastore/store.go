@@ -0,0 +1,45 @@
+// Package mediastore 提供任务产物（视频、图片等）的对象存储抽象，支持本地文件系统与 S3 兼容服务
+package mediastore
+
+import (
+	"context"
+	"errors"
+	"io"
+	"path"
+	"strings"
+)
+
+var (
+	ErrNotFound   = errors.New("media object not found")
+	errInvalidKey = errors.New("invalid media object key")
+)
+
+// Store 以 key 存取对象，key 由调用方生成，只包含字母、数字、'-'、'_'、'.' 和 '/'
+type Store interface {
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Get 返回对象内容与大小，对象不存在时返回 ErrNotFound
+	Get(ctx context.Context, key string) (io.ReadCloser, int64, error)
+	// Delete 删除对象，对象不存在时不报错
+	Delete(ctx context.Context, key string) error
+}
+
+// ValidKey 拒绝空 key、绝对路径与 ".." 等可能越出存储根目录的 key
+func ValidKey(key string) bool {
+	if key == "" || strings.HasPrefix(key, "/") || path.Clean(key) != key {
+		return false
+	}
+	for _, r := range key {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '-' || r == '_' || r == '.' || r == '/':
+		default:
+			return false
+		}
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return false
+		}
+	}
+	return true
+}
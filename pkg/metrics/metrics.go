@@ -0,0 +1,170 @@
+// Package metrics 提供 Prometheus 指标的注册与采集，通过 /metrics 端点导出
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "newapi"
+
+// 覆盖从毫秒级到长时间流式输出的请求
+var latencyBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2, 5, 10, 20, 30, 60, 120, 300}
+
+var (
+	registry = prometheus.NewRegistry()
+
+	relayRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "relay_requests_total",
+		Help:      "Relay requests by model, channel, group and response status.",
+	}, []string{"model", "channel", "group", "status"})
+
+	relayDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "relay_request_duration_seconds",
+		Help:      "Relay request latency including retries.",
+		Buckets:   latencyBuckets,
+	}, []string{"model", "channel", "group"})
+
+	upstreamAttempts = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "upstream_attempts_total",
+		Help:      "Upstream attempts by model, channel and result.",
+	}, []string{"model", "channel", "result"})
+
+	upstreamErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "upstream_errors_total",
+		Help:      "Upstream errors by channel, HTTP status code and error code.",
+	}, []string{"channel", "status_code", "error_code"})
+
+	upstreamDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "upstream_duration_seconds",
+		Help:      "Latency of a single upstream attempt.",
+		Buckets:   latencyBuckets,
+	}, []string{"model", "channel"})
+
+	firstTokenDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "time_to_first_token_seconds",
+		Help:      "Time to first token of streaming responses.",
+		Buckets:   []float64{0.1, 0.25, 0.5, 1, 2, 3, 5, 10, 20, 30, 60},
+	}, []string{"model", "channel"})
+
+	billingOperations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "billing_operations_total",
+		Help:      "Billing session operations (pre_consume, settle, refund) by funding source and result.",
+	}, []string{"operation", "source", "result"})
+
+	billingQuota = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "billing_quota_total",
+		Help:      "Quota moved by billing session operations.",
+	}, []string{"operation", "source"})
+
+	channelStatusEvents = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "channel_status_events_total",
+		Help:      "Channel enable / disable events.",
+	}, []string{"channel", "event"})
+
+	taskBacklog = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "task_polling_backlog",
+		Help:      "Unfinished async tasks waiting to be polled, by platform.",
+	}, []string{"platform"})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		relayRequests,
+		relayDuration,
+		upstreamAttempts,
+		upstreamErrors,
+		upstreamDuration,
+		firstTokenDuration,
+		billingOperations,
+		billingQuota,
+		channelStatusEvents,
+		taskBacklog,
+	)
+}
+
+// Handler 返回 /metrics 的 HTTP 处理器
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{Registry: registry})
+}
+
+// Register 注册额外的采集器，供其他模块导出自定义指标
+func Register(cs ...prometheus.Collector) {
+	registry.MustRegister(cs...)
+}
+
+func channelLabel(channelId int) string {
+	if channelId == 0 {
+		return ""
+	}
+	return strconv.Itoa(channelId)
+}
+
+// ObserveRelayRequest 记录一次中继请求的最终结果
+func ObserveRelayRequest(model string, channelId int, group string, status int, duration time.Duration) {
+	channel := channelLabel(channelId)
+	relayRequests.WithLabelValues(model, channel, group, strconv.Itoa(status)).Inc()
+	relayDuration.WithLabelValues(model, channel, group).Observe(duration.Seconds())
+}
+
+// ObserveUpstreamAttempt 记录一次上游请求，statusCode 为 0 表示成功；ttft 为 0 时不记录首字时间
+func ObserveUpstreamAttempt(model string, channelId int, statusCode int, errorCode string, duration time.Duration, ttft time.Duration) {
+	channel := channelLabel(channelId)
+	upstreamDuration.WithLabelValues(model, channel).Observe(duration.Seconds())
+	if errorCode == "" && statusCode == 0 {
+		upstreamAttempts.WithLabelValues(model, channel, "success").Inc()
+		if ttft > 0 {
+			firstTokenDuration.WithLabelValues(model, channel).Observe(ttft.Seconds())
+		}
+		return
+	}
+	upstreamAttempts.WithLabelValues(model, channel, "error").Inc()
+	upstreamErrors.WithLabelValues(channel, strconv.Itoa(statusCode), errorCode).Inc()
+}
+
+// ObserveBilling 记录计费会话的预扣、结算与退款
+func ObserveBilling(operation string, source string, quota int, err error) {
+	result := "success"
+	if err != nil {
+		result = "error"
+	}
+	billingOperations.WithLabelValues(operation, source, result).Inc()
+	if err == nil && quota != 0 {
+		if quota < 0 {
+			quota = -quota
+		}
+		billingQuota.WithLabelValues(operation, source).Add(float64(quota))
+	}
+}
+
+// ObserveChannelStatus 记录渠道启用 / 禁用事件
+func ObserveChannelStatus(channelId int, event string) {
+	channelStatusEvents.WithLabelValues(channelLabel(channelId), event).Inc()
+}
+
+// SetTaskBacklog 设置某个平台待轮询的任务数
+func SetTaskBacklog(platform string, count int) {
+	taskBacklog.WithLabelValues(platform).Set(float64(count))
+}
+
+// ResetTaskBacklog 清空任务积压指标，每轮轮询开始前调用，避免已清空的平台保留旧值
+func ResetTaskBacklog() {
+	taskBacklog.Reset()
+}
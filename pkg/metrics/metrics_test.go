@@ -0,0 +1,33 @@
+package metrics
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetricsHandler(t *testing.T) {
+	ObserveRelayRequest("gpt-4o", 3, "default", http.StatusOK, 120*time.Millisecond)
+	ObserveUpstreamAttempt("gpt-4o", 3, 0, "", 100*time.Millisecond, 30*time.Millisecond)
+	ObserveUpstreamAttempt("gpt-4o", 3, http.StatusTooManyRequests, "bad_response_status_code", time.Second, 0)
+	ObserveBilling("settle", "wallet", -200, nil)
+	ObserveBilling("pre_consume", "wallet", 500, errors.New("insufficient"))
+	ObserveChannelStatus(3, "disabled")
+	SetTaskBacklog("suno", 7)
+
+	recorder := httptest.NewRecorder()
+	Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, recorder.Code)
+	body := recorder.Body.String()
+	require.Contains(t, body, `newapi_relay_requests_total{channel="3",group="default",model="gpt-4o",status="200"} 1`)
+	require.Contains(t, body, `newapi_upstream_errors_total{channel="3",error_code="bad_response_status_code",status_code="429"} 1`)
+	require.Contains(t, body, `newapi_time_to_first_token_seconds_count{channel="3",model="gpt-4o"} 1`)
+	require.Contains(t, body, `newapi_billing_quota_total{operation="settle",source="wallet"} 200`)
+	require.Contains(t, body, `newapi_billing_operations_total{operation="pre_consume",result="error",source="wallet"} 1`)
+	require.Contains(t, body, `newapi_channel_status_events_total{channel="3",event="disabled"} 1`)
+	require.Contains(t, body, `newapi_task_polling_backlog{platform="suno"} 7`)
+}
@@ -0,0 +1,144 @@
+// Package tracing 提供可选的 OpenTelemetry 链路追踪，未启用时所有 span 均为空操作
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"sync/atomic"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/QuantumNous/new-api"
+
+const (
+	ExporterOTLP   = "otlp"
+	ExporterStdout = "stdout"
+)
+
+type Config struct {
+	// Exporter 为 otlp（OTLP/HTTP）或 stdout（输出到标准输出，便于本地调试）
+	Exporter string
+	// Endpoint OTLP/HTTP 地址，如 http://localhost:4318；为空时使用 OTEL_EXPORTER_OTLP_ENDPOINT 等标准环境变量
+	Endpoint       string
+	ServiceName    string
+	ServiceVersion string
+	// SampleRatio 采样比例，取值 (0, 1]，上游传入的 traceparent 的采样标记优先
+	SampleRatio float64
+}
+
+var (
+	enabled    atomic.Bool
+	provider   *sdktrace.TracerProvider
+	propagator = propagation.TraceContext{}
+)
+
+// Init 初始化全局 TracerProvider，应在服务启动时调用一次
+func Init(ctx context.Context, cfg Config) error {
+	exporter, err := newExporter(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	if cfg.ServiceName == "" {
+		cfg.ServiceName = "new-api"
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(
+		attribute.String("service.name", cfg.ServiceName),
+		attribute.String("service.version", cfg.ServiceVersion),
+	))
+	if err != nil {
+		return err
+	}
+	ratio := cfg.SampleRatio
+	if ratio <= 0 || ratio > 1 {
+		ratio = 1
+	}
+	setProvider(sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))),
+	))
+	return nil
+}
+
+func newExporter(ctx context.Context, cfg Config) (sdktrace.SpanExporter, error) {
+	switch cfg.Exporter {
+	case ExporterStdout:
+		return stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	case "", ExporterOTLP:
+		var opts []otlptracehttp.Option
+		if cfg.Endpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpointURL(cfg.Endpoint))
+		}
+		return otlptracehttp.New(ctx, opts...)
+	default:
+		return nil, errors.New("unknown tracing exporter: " + cfg.Exporter)
+	}
+}
+
+func setProvider(tp *sdktrace.TracerProvider) {
+	provider = tp
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagator)
+	enabled.Store(true)
+}
+
+// Shutdown 刷新并关闭导出器，未启用时直接返回
+func Shutdown(ctx context.Context) error {
+	if !enabled.Load() || provider == nil {
+		return nil
+	}
+	enabled.Store(false)
+	return provider.Shutdown(ctx)
+}
+
+func Enabled() bool {
+	return enabled.Load()
+}
+
+// Start 创建子 span，未启用时返回原 context 与空 span
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return StartWithKind(ctx, name, trace.SpanKindInternal, attrs...)
+}
+
+func StartWithKind(ctx context.Context, name string, kind trace.SpanKind, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	if !enabled.Load() {
+		return ctx, trace.SpanFromContext(context.Background())
+	}
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithSpanKind(kind), trace.WithAttributes(attrs...))
+}
+
+// End 结束 span，err 非空时记录错误并标记状态
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// Extract 从请求头中解析上游传入的 traceparent
+func Extract(ctx context.Context, header http.Header) context.Context {
+	if !enabled.Load() {
+		return ctx
+	}
+	return propagator.Extract(ctx, propagation.HeaderCarrier(header))
+}
+
+// Inject 将当前 span 的 traceparent 写入请求头
+func Inject(ctx context.Context, header http.Header) {
+	if !enabled.Load() {
+		return
+	}
+	propagator.Inject(ctx, propagation.HeaderCarrier(header))
+}
@@ -0,0 +1,50 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestDisabledSpansAreNoop(t *testing.T) {
+	ctx, span := Start(context.Background(), "noop")
+	require.False(t, span.SpanContext().IsValid())
+	header := http.Header{}
+	Inject(ctx, header)
+	require.Empty(t, header.Get("traceparent"))
+}
+
+func TestPropagationAndErrorStatus(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	setProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() {
+		require.NoError(t, Shutdown(context.Background()))
+	})
+
+	incoming := http.Header{}
+	incoming.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	ctx := Extract(context.Background(), incoming)
+
+	ctx, root := StartWithKind(ctx, "relay", trace.SpanKindServer)
+	_, attempt := Start(ctx, "relay.attempt")
+	End(attempt, errors.New("upstream 500"))
+	root.End()
+
+	outgoing := http.Header{}
+	Inject(ctx, outgoing)
+	require.Contains(t, outgoing.Get("traceparent"), "4bf92f3577b34da6a3ce929d0e0e4736")
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	require.Equal(t, "relay.attempt", spans[0].Name())
+	require.Equal(t, codes.Error, spans[0].Status().Code)
+	require.Equal(t, root.SpanContext().SpanID(), spans[0].Parent().SpanID())
+	require.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", spans[1].SpanContext().TraceID().String())
+}
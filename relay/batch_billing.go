@@ -0,0 +1,140 @@
+package relay
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/model"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/relay/helper"
+	"github.com/QuantumNous/new-api/service"
+	"github.com/QuantumNous/new-api/setting/ratio_setting"
+	"github.com/QuantumNous/new-api/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+// batchLineSettler 记录后结算时的实际额度，作为该行的计费结果返回
+type batchLineSettler struct {
+	relaycommon.BillingSettler
+	quota int
+}
+
+func (s *batchLineSettler) Settle(actualQuota int) error {
+	s.quota = actualQuota
+	return s.BillingSettler.Settle(actualQuota)
+}
+
+// batchLineUsage 将 chat/embeddings/responses 三种结果行的用量统一为 chat 格式
+func batchLineUsage(usage *dto.Usage) *dto.Usage {
+	normalized := *usage
+	if normalized.PromptTokens == 0 {
+		normalized.PromptTokens = usage.InputTokens
+	}
+	if normalized.CompletionTokens == 0 {
+		normalized.CompletionTokens = usage.OutputTokens
+	}
+	if normalized.PromptTokensDetails.CachedTokens == 0 && usage.InputTokensDetails != nil {
+		normalized.PromptTokensDetails.CachedTokens = usage.InputTokensDetails.CachedTokens
+	}
+	if normalized.TotalTokens == 0 {
+		normalized.TotalTokens = normalized.PromptTokens + normalized.CompletionTokens
+	}
+	return &normalized
+}
+
+// applyBatchLineResponsesTools 与 OaiResponsesHandler 一致，按结果中的内置工具与图片生成调用计费
+func applyBatchLineResponsesTools(c *gin.Context, info *relaycommon.RelayInfo, body []byte) {
+	var responsesResponse dto.OpenAIResponsesResponse
+	if err := common.Unmarshal(body, &responsesResponse); err != nil {
+		return
+	}
+	if responsesResponse.HasImageGenerationCall() {
+		c.Set("image_generation_call", true)
+		c.Set("image_generation_call_quality", responsesResponse.GetQuality())
+		c.Set("image_generation_call_size", responsesResponse.GetSize())
+	}
+	info.ResponsesUsageInfo = &relaycommon.ResponsesUsageInfo{
+		BuiltInTools: make(map[string]*relaycommon.BuildInToolInfo),
+	}
+	for _, tool := range responsesResponse.Tools {
+		toolType := common.Interface2String(tool["type"])
+		toolInfo, ok := info.ResponsesUsageInfo.BuiltInTools[toolType]
+		if !ok {
+			toolInfo = &relaycommon.BuildInToolInfo{ToolName: toolType}
+			if toolType == dto.BuildInToolWebSearchPreview {
+				toolInfo.SearchContextSize = common.GetStringIfEmpty(common.Interface2String(tool["search_context_size"]), "medium")
+			}
+			info.ResponsesUsageInfo.BuiltInTools[toolType] = toolInfo
+		}
+		toolInfo.CallCount++
+	}
+}
+
+// SettleBatchLine 结算上游批处理结果中的一行，返回实际扣除的额度。
+// 价格由 ModelPriceHelper 计算（批处理倍率通过 ContextKeyBatchId 生效），按该行用量预扣后
+// 走与实时请求相同的后结算流程；预扣被拒绝（余额不足等）时该行不计费并返回错误，不会产生欠费。
+// 失败行（非 200）不计费。c 只能用于一行，工具调用等标记会写入其中。
+func SettleBatchLine(c *gin.Context, user *model.UserBase, batch *model.Batch, line *dto.BatchResponseLine) (int, error) {
+	if line == nil || line.Response == nil || line.Response.StatusCode != http.StatusOK {
+		return 0, nil
+	}
+	var body dto.BatchResponseUsageBody
+	if err := common.Unmarshal(line.Response.Body, &body); err != nil || body.Usage == nil {
+		return 0, nil
+	}
+	usage := batchLineUsage(body.Usage)
+
+	common.SetContextKey(c, constant.ContextKeyBatchId, batch.BatchId)
+	relayInfo := &relaycommon.RelayInfo{
+		TokenId:         batch.TokenId,
+		TokenKey:        c.GetString("token_key"),
+		TokenUnlimited:  c.GetBool("token_unlimited_quota"),
+		UserId:          batch.UserId,
+		UserGroup:       user.Group,
+		UsingGroup:      batch.Group,
+		UserSetting:     user.GetSetting(),
+		OrganizationId:  common.GetContextKeyInt(c, constant.ContextKeyTokenOrganizationId),
+		OriginModelName: body.Model,
+		RequestId:       fmt.Sprintf("%s-%s", batch.BatchId, line.CustomId),
+		RequestURLPath:  batch.Endpoint,
+		StartTime:       time.Now(),
+		ForcePreConsume: true,
+		ChannelMeta:     &relaycommon.ChannelMeta{ChannelId: batch.ChannelId},
+	}
+	relayInfo.FirstResponseTime = relayInfo.StartTime
+	if batch.Endpoint == "/v1/responses" {
+		relayInfo.RelayFormat = types.RelayFormatOpenAIResponses
+		applyBatchLineResponsesTools(c, relayInfo, line.Response.Body)
+	} else {
+		relayInfo.RelayFormat = types.RelayFormatOpenAI
+	}
+
+	// 按补全倍率折算输出 token，使预扣额度覆盖该行的实际消耗
+	completionTokens := int(math.Ceil(float64(usage.CompletionTokens) * ratio_setting.GetCompletionRatio(body.Model)))
+	priceData, err := helper.ModelPriceHelper(c, relayInfo, usage.PromptTokens, &types.TokenCountMeta{MaxTokens: completionTokens})
+	if err != nil {
+		return 0, err
+	}
+	session, apiErr := service.NewBillingSession(c, relayInfo, priceData.QuotaToPreConsume)
+	if apiErr != nil {
+		return 0, apiErr
+	}
+	settler := &batchLineSettler{BillingSettler: session}
+	relayInfo.Billing = settler
+
+	extraContent := fmt.Sprintf("批处理 %s 行 %s", batch.BatchId, line.CustomId)
+	containAudioTokens := usage.CompletionTokenDetails.AudioTokens > 0 || usage.PromptTokensDetails.AudioTokens > 0
+	containsAudioRatios := ratio_setting.ContainsAudioRatio(body.Model) || ratio_setting.ContainsAudioCompletionRatio(body.Model)
+	if containAudioTokens && containsAudioRatios {
+		service.PostAudioConsumeQuota(c, relayInfo, usage, extraContent)
+	} else {
+		postConsumeQuota(c, relayInfo, usage, extraContent)
+	}
+	return settler.quota, nil
+}
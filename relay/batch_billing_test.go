@@ -0,0 +1,77 @@
+package relay
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/service"
+	"github.com/QuantumNous/new-api/setting/ratio_setting"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSettleBatchLine(t *testing.T) {
+	t.Setenv("SQL_DSN", "")
+	t.Setenv("LOG_SQL_DSN", "")
+	sqlitePath := common.SQLitePath
+	common.SQLitePath = "file:relay_batch_billing?mode=memory&cache=shared"
+	t.Cleanup(func() { common.SQLitePath = sqlitePath })
+	common.RedisEnabled = false
+	require.NoError(t, model.InitDB())
+	require.NoError(t, model.InitLogDB())
+	db := model.DB
+	require.NoError(t, db.AutoMigrate(&model.User{}, &model.Token{}, &model.Log{}, &model.UserSubscription{}))
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			_ = sqlDB.Close()
+		}
+	})
+
+	originPrice := ratio_setting.ModelPrice2JSONString()
+	originBatch := ratio_setting.BatchRatio2JSONString()
+	t.Cleanup(func() {
+		require.NoError(t, ratio_setting.UpdateModelPriceByJSONString(originPrice))
+		require.NoError(t, ratio_setting.UpdateBatchRatioByJSONString(originBatch))
+	})
+	require.NoError(t, ratio_setting.UpdateModelPriceByJSONString(`{"batch-test-model":0.02}`))
+	require.NoError(t, ratio_setting.UpdateBatchRatioByJSONString(`{"batch-test-model":0.25}`))
+	expected := int(0.02 * common.QuotaPerUnit * ratio_setting.GetGroupRatio("default") * 0.25)
+
+	user := &model.User{Username: "batch", Password: "password123", Status: common.UserStatusEnabled, Group: "default", Quota: expected + 10}
+	require.NoError(t, db.Create(user).Error)
+	token := &model.Token{UserId: user.Id, Key: "batchbillingkey", Name: "t", Status: common.TokenStatusEnabled, ExpiredTime: -1, UnlimitedQuota: true}
+	require.NoError(t, db.Create(token).Error)
+
+	batch := &model.Batch{BatchId: "batch_billing", UserId: user.Id, TokenId: token.Id, Group: "default", Endpoint: "/v1/chat/completions"}
+	line := func(customId string) *dto.BatchResponseLine {
+		return &dto.BatchResponseLine{
+			CustomId: customId,
+			Response: &dto.BatchResponseBody{
+				StatusCode: http.StatusOK,
+				Body:       []byte(`{"model":"batch-test-model","usage":{"prompt_tokens":10,"completion_tokens":5,"total_tokens":15}}`),
+			},
+		}
+	}
+
+	c, userCache, err := service.NewBackgroundBillingContext(user.Id, token, batch.Endpoint)
+	require.NoError(t, err)
+	quota, err := SettleBatchLine(c, userCache, batch, line("a"))
+	require.NoError(t, err)
+	require.Equal(t, expected, quota)
+	remain, err := model.GetUserQuota(user.Id, true)
+	require.NoError(t, err)
+	require.Equal(t, 10, remain)
+
+	// 余额不足时拒绝该行，不产生欠费
+	c, userCache, err = service.NewBackgroundBillingContext(user.Id, token, batch.Endpoint)
+	require.NoError(t, err)
+	quota, err = SettleBatchLine(c, userCache, batch, line("b"))
+	require.Error(t, err)
+	require.Zero(t, quota)
+	remain, err = model.GetUserQuota(user.Id, true)
+	require.NoError(t, err)
+	require.Equal(t, 10, remain)
+}
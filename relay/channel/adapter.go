@@ -31,6 +31,21 @@ type Adaptor interface {
 	ConvertGeminiRequest(c *gin.Context, info *relaycommon.RelayInfo, request *dto.GeminiChatRequest) (any, error)
 }
 
+// ClaudeTokenCounter 支持上游 /v1/messages/count_tokens 的适配器（Anthropic / Bedrock / Vertex Claude）
+type ClaudeTokenCounter interface {
+	CountClaudeTokens(c *gin.Context, info *relaycommon.RelayInfo, request *dto.ClaudeRequest) (*dto.ClaudeCountTokensResponse, error)
+}
+
+// GeminiTokenCounter 支持上游 models/{model}:countTokens 的适配器（Gemini / Vertex Gemini）
+type GeminiTokenCounter interface {
+	CountGeminiTokens(c *gin.Context, info *relaycommon.RelayInfo, request *dto.GeminiCountTokensRequest) (*dto.GeminiCountTokensResponse, error)
+}
+
+// ResponsesCapability 按 Init 后的模型判断能否直接转换 /v1/responses 请求的适配器（Vertex）
+type ResponsesCapability interface {
+	SupportsNativeResponses() bool
+}
+
 type TaskAdaptor interface {
 	Init(info *relaycommon.RelayInfo)
 
@@ -1,6 +1,7 @@
 package aws
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -293,6 +294,47 @@ func awsStreamHandler(c *gin.Context, info *relaycommon.RelayInfo, a *Adaptor) (
 	return nil, claudeInfo.Usage
 }
 
+// countClaudeTokens 通过 Bedrock CountTokens 接口统计 Claude InvokeModel 请求体的输入 token
+func countClaudeTokens(c *gin.Context, info *relaycommon.RelayInfo, request *dto.ClaudeRequest) (*dto.ClaudeCountTokensResponse, error) {
+	awsModelId := getAwsModelID(info.UpstreamModelName)
+	if isNovaModel(awsModelId) {
+		return nil, errors.New("count_tokens is not supported for nova models")
+	}
+	awsCli, err := newAwsClient(c, info)
+	if err != nil {
+		return nil, err
+	}
+
+	requestHeader := http.Header{}
+	claude.CommonClaudeHeadersOperation(c, &requestHeader, info)
+	requestBody, err := common.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+	awsClaudeReq, err := formatRequest(bytes.NewReader(requestBody), requestHeader)
+	if err != nil {
+		return nil, errors.Wrap(err, "format aws request fail")
+	}
+	body, err := common.Marshal(awsClaudeReq)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := newAwsInvokeContext()
+	defer cancel()
+	// CountTokens 只接受基础模型 ID，不使用跨区域推理配置
+	awsResp, err := awsCli.CountTokens(ctx, &bedrockruntime.CountTokensInput{
+		ModelId: aws.String(awsModelId),
+		Input: &bedrockruntimeTypes.CountTokensInputMemberInvokeModel{
+			Value: bedrockruntimeTypes.InvokeModelTokensRequest{Body: body},
+		},
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "CountTokens")
+	}
+	return &dto.ClaudeCountTokensResponse{InputTokens: int(aws.ToInt32(awsResp.InputTokens))}, nil
+}
+
 // Nova模型处理函数
 func handleNovaRequest(c *gin.Context, info *relaycommon.RelayInfo, a *Adaptor) (*types.NewAPIError, *dto.Usage) {
 
@@ -6,9 +6,12 @@ import (
 	"io"
 	"net/http"
 
+	"github.com/QuantumNous/new-api/common"
 	"github.com/QuantumNous/new-api/dto"
 	"github.com/QuantumNous/new-api/relay/channel"
 	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/relay/constant"
+	"github.com/QuantumNous/new-api/service"
 	"github.com/QuantumNous/new-api/setting/model_setting"
 	"github.com/QuantumNous/new-api/types"
 
@@ -42,6 +45,9 @@ func (a *Adaptor) Init(info *relaycommon.RelayInfo) {
 
 func (a *Adaptor) GetRequestURL(info *relaycommon.RelayInfo) (string, error) {
 	baseURL := fmt.Sprintf("%s/v1/messages", info.ChannelBaseUrl)
+	if info.RelayMode == constant.RelayModeMessagesCountTokens {
+		baseURL = baseURL + "/count_tokens"
+	}
 	if info.IsClaudeBetaQuery {
 		baseURL = baseURL + "?beta=true"
 	}
@@ -86,8 +92,24 @@ func (a *Adaptor) ConvertEmbeddingRequest(c *gin.Context, info *relaycommon.Rela
 }
 
 func (a *Adaptor) ConvertOpenAIResponsesRequest(c *gin.Context, info *relaycommon.RelayInfo, request dto.OpenAIResponsesRequest) (any, error) {
-	// TODO implement me
-	return nil, errors.New("not implemented")
+	if info.RelayMode == constant.RelayModeResponsesCompact {
+		return nil, errors.New("responses compact is not supported by claude channel")
+	}
+	claudeRequest, err := service.ResponsesRequestToClaudeRequest(&request)
+	if err != nil {
+		return nil, err
+	}
+	if claudeRequest.MaxTokens == nil || *claudeRequest.MaxTokens == 0 {
+		defaultMaxTokens := uint(model_setting.GetClaudeSettings().GetDefaultMaxTokens(request.Model))
+		claudeRequest.MaxTokens = &defaultMaxTokens
+	}
+	// max_tokens 必须大于 thinking 预算
+	if claudeRequest.Thinking != nil {
+		if budget := uint(claudeRequest.Thinking.GetBudgetTokens()); budget >= *claudeRequest.MaxTokens {
+			claudeRequest.MaxTokens = common.GetPointer(budget + *claudeRequest.MaxTokens)
+		}
+	}
+	return claudeRequest, nil
 }
 
 func (a *Adaptor) DoRequest(c *gin.Context, info *relaycommon.RelayInfo, requestBody io.Reader) (any, error) {
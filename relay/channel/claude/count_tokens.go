@@ -0,0 +1,45 @@
+package claude
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/relay/channel"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (a *Adaptor) CountClaudeTokens(c *gin.Context, info *relaycommon.RelayInfo, request *dto.ClaudeRequest) (*dto.ClaudeCountTokensResponse, error) {
+	return DoCountTokensRequest(a, c, info, request.ToCountTokensRequest())
+}
+
+// DoCountTokensRequest 发送 count_tokens 请求，请求地址由适配器根据 RelayModeMessagesCountTokens 生成
+func DoCountTokensRequest(a channel.Adaptor, c *gin.Context, info *relaycommon.RelayInfo, body any) (*dto.ClaudeCountTokensResponse, error) {
+	jsonData, err := common.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := channel.DoApiRequest(a, c, info, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, service.RelayErrorHandler(c.Request.Context(), resp, false)
+	}
+	defer service.CloseResponseBodyGracefully(resp)
+	responseBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	var countResponse dto.ClaudeCountTokensResponse
+	if err := common.Unmarshal(responseBody, &countResponse); err != nil {
+		return nil, fmt.Errorf("unmarshal count_tokens response failed: %w", err)
+	}
+	return &countResponse, nil
+}
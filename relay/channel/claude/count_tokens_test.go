@@ -0,0 +1,54 @@
+package claude
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/dto"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/relay/constant"
+	"github.com/QuantumNous/new-api/service"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCountClaudeTokens(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	service.InitHttpClient()
+
+	var gotPath string
+	var gotBody map[string]any
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		body, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(body, &gotBody)
+		_, _ = w.Write([]byte(`{"input_tokens":42}`))
+	}))
+	defer upstream.Close()
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/messages/count_tokens", nil)
+	info := &relaycommon.RelayInfo{
+		RelayMode: constant.RelayModeMessagesCountTokens,
+		ChannelMeta: &relaycommon.ChannelMeta{
+			ChannelBaseUrl: upstream.URL,
+			ApiKey:         "sk-test",
+		},
+	}
+	request := &dto.ClaudeRequest{
+		Model:     "claude-sonnet-4-5",
+		MaxTokens: common.GetPointer[uint](1024),
+		Messages:  []dto.ClaudeMessage{{Role: "user", Content: "hello"}},
+	}
+
+	resp, err := (&Adaptor{}).CountClaudeTokens(c, info, request)
+	require.NoError(t, err)
+	require.Equal(t, 42, resp.InputTokens)
+	require.Equal(t, "/v1/messages/count_tokens", gotPath)
+	require.Equal(t, "claude-sonnet-4-5", gotBody["model"])
+	require.NotContains(t, gotBody, "max_tokens")
+}
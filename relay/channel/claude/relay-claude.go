@@ -553,6 +553,8 @@ type ClaudeResponseInfo struct {
 	ResponseText strings.Builder
 	Usage        *dto.Usage
 	Done         bool
+	// ResponsesStream 客户端请求 /v1/responses 时用于转换流事件
+	ResponsesStream *service.ClaudeResponsesStreamConverter
 }
 
 func buildMessageDeltaPatchUsage(claudeResponse *dto.ClaudeResponse, claudeInfo *ClaudeResponseInfo) *dto.ClaudeUsage {
@@ -740,6 +742,11 @@ func HandleStreamResponseData(c *gin.Context, info *relaycommon.RelayInfo, claud
 		if err != nil {
 			logger.LogError(c, "send_stream_response_failed: "+err.Error())
 		}
+	} else if info.RelayFormat == types.RelayFormatOpenAIResponses {
+		FormatClaudeResponseInfo(&claudeResponse, nil, claudeInfo)
+		if err = helper.ResponsesEventsData(c, claudeInfo.ResponsesStream.Convert(&claudeResponse)); err != nil {
+			logger.LogError(c, "send_stream_response_failed: "+err.Error())
+		}
 	}
 	return nil
 }
@@ -766,6 +773,11 @@ func HandleStreamFinalResponse(c *gin.Context, info *relaycommon.RelayInfo, clau
 			}
 		}
 		helper.Done(c)
+	} else if info.RelayFormat == types.RelayFormatOpenAIResponses {
+		// 上游未发送 message_stop 时补发 response.incomplete
+		if err := helper.ResponsesEventsData(c, claudeInfo.ResponsesStream.Finish()); err != nil {
+			common.SysLog("send final response failed: " + err.Error())
+		}
 	}
 }
 
@@ -777,6 +789,9 @@ func ClaudeStreamHandler(c *gin.Context, resp *http.Response, info *relaycommon.
 		ResponseText: strings.Builder{},
 		Usage:        &dto.Usage{},
 	}
+	if info.RelayFormat == types.RelayFormatOpenAIResponses {
+		claudeInfo.ResponsesStream = service.NewClaudeResponsesStreamConverter(helper.GetResponsesID(c), claudeInfo.Created, info.UpstreamModelName)
+	}
 	var err *types.NewAPIError
 	helper.StreamScannerHandler(c, resp, info, func(data string) bool {
 		err = HandleStreamResponseData(c, info, claudeInfo, data)
@@ -826,6 +841,12 @@ func HandleClaudeResponseData(c *gin.Context, info *relaycommon.RelayInfo, claud
 		}
 	case types.RelayFormatClaude:
 		responseData = data
+	case types.RelayFormatOpenAIResponses:
+		responsesResponse := service.ClaudeResponseToResponsesResponse(&claudeResponse, helper.GetResponsesID(c), claudeInfo.Created)
+		responseData, err = common.Marshal(responsesResponse)
+		if err != nil {
+			return types.NewError(err, types.ErrorCodeBadResponseBody)
+		}
 	}
 
 	if claudeResponse.Usage != nil && claudeResponse.Usage.ServerToolUse != nil && claudeResponse.Usage.ServerToolUse.WebSearchRequests > 0 {
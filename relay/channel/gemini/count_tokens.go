@@ -0,0 +1,64 @@
+package gemini
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/relay/channel"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// geminiCountTokensRequest generateContentRequest 需要携带 models/{model}
+type geminiCountTokensRequest struct {
+	Contents               []dto.GeminiChatContent         `json:"contents,omitempty"`
+	GenerateContentRequest *geminiGenerateContentWithModel `json:"generateContentRequest,omitempty"`
+}
+
+type geminiGenerateContentWithModel struct {
+	Model string `json:"model"`
+	*dto.GeminiChatRequest
+}
+
+func (a *Adaptor) CountGeminiTokens(c *gin.Context, info *relaycommon.RelayInfo, request *dto.GeminiCountTokensRequest) (*dto.GeminiCountTokensResponse, error) {
+	countRequest := geminiCountTokensRequest{Contents: request.Contents}
+	if request.GenerateContentRequest != nil {
+		countRequest.Contents = nil
+		countRequest.GenerateContentRequest = &geminiGenerateContentWithModel{
+			Model:             "models/" + info.UpstreamModelName,
+			GeminiChatRequest: request.GenerateContentRequest,
+		}
+	}
+	return DoCountTokensRequest(a, c, info, countRequest)
+}
+
+// DoCountTokensRequest 发送 countTokens 请求，请求地址由适配器根据请求路径中的 action 生成
+func DoCountTokensRequest(a channel.Adaptor, c *gin.Context, info *relaycommon.RelayInfo, body any) (*dto.GeminiCountTokensResponse, error) {
+	jsonData, err := common.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := channel.DoApiRequest(a, c, info, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, service.RelayErrorHandler(c.Request.Context(), resp, false)
+	}
+	defer service.CloseResponseBodyGracefully(resp)
+	responseBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	var countResponse dto.GeminiCountTokensResponse
+	if err := common.Unmarshal(responseBody, &countResponse); err != nil {
+		return nil, fmt.Errorf("unmarshal countTokens response failed: %w", err)
+	}
+	return &countResponse, nil
+}
@@ -0,0 +1,62 @@
+package gemini
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/QuantumNous/new-api/dto"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/relay/constant"
+	"github.com/QuantumNous/new-api/service"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCountGeminiTokens(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	service.InitHttpClient()
+
+	var gotPath string
+	var gotBody map[string]any
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		body, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(body, &gotBody)
+		_, _ = w.Write([]byte(`{"totalTokens":12}`))
+	}))
+	defer upstream.Close()
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1beta/models/gemini-2.5-flash:countTokens", nil)
+	info := &relaycommon.RelayInfo{
+		RelayMode:      constant.RelayModeGemini,
+		RequestURLPath: "/v1beta/models/gemini-2.5-flash:countTokens",
+		ChannelMeta: &relaycommon.ChannelMeta{
+			ChannelBaseUrl:    upstream.URL,
+			ApiKey:            "test-key",
+			UpstreamModelName: "gemini-2.5-flash",
+		},
+	}
+	request := &dto.GeminiCountTokensRequest{
+		GenerateContentRequest: &dto.GeminiChatRequest{
+			Contents: []dto.GeminiChatContent{{Role: "user", Parts: []dto.GeminiPart{{Text: "hello"}}}},
+			SystemInstructions: &dto.GeminiChatContent{
+				Parts: []dto.GeminiPart{{Text: "be brief"}},
+			},
+		},
+	}
+
+	adaptor := &Adaptor{}
+	adaptor.Init(info)
+	resp, err := adaptor.CountGeminiTokens(c, info, request)
+	require.NoError(t, err)
+	require.Equal(t, 12, resp.TotalTokens)
+	require.Equal(t, "/v1beta/models/gemini-2.5-flash:countTokens", gotPath)
+	require.NotContains(t, gotBody, "contents")
+	generateContentRequest := gotBody["generateContentRequest"].(map[string]any)
+	require.Equal(t, "models/gemini-2.5-flash", generateContentRequest["model"])
+	require.Contains(t, generateContentRequest, "systemInstruction")
+}
@@ -0,0 +1,655 @@
+package gemini
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/logger"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/relay/helper"
+	"github.com/QuantumNous/new-api/service"
+	"github.com/QuantumNous/new-api/types"
+
+	"github.com/bytedance/gopkg/util/gopool"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+// Gemini Live 输入输出均为 24kHz 单声道 PCM16，与 OpenAI realtime 的 pcm16 一致，音频数据无需转码
+const geminiLiveAudioMimeType = "audio/pcm;rate=24000"
+
+// OpenAI realtime 内置音色，Gemini 不支持，转换时忽略
+var openAIRealtimeVoices = map[string]struct{}{
+	"alloy": {}, "ash": {}, "ballad": {}, "coral": {}, "echo": {},
+	"sage": {}, "shimmer": {}, "verse": {}, "marin": {}, "cedar": {},
+}
+
+type geminiLiveClientMessage struct {
+	Setup         *geminiLiveSetup         `json:"setup,omitempty"`
+	ClientContent *geminiLiveClientContent `json:"clientContent,omitempty"`
+	RealtimeInput *geminiLiveRealtimeInput `json:"realtimeInput,omitempty"`
+	ToolResponse  *geminiLiveToolResponse  `json:"toolResponse,omitempty"`
+}
+
+type geminiLiveSetup struct {
+	Model                    string                     `json:"model"`
+	GenerationConfig         geminiLiveGenerationConfig `json:"generationConfig"`
+	SystemInstruction        *dto.GeminiChatContent     `json:"systemInstruction,omitempty"`
+	Tools                    []dto.GeminiChatTool       `json:"tools,omitempty"`
+	InputAudioTranscription  *struct{}                  `json:"inputAudioTranscription,omitempty"`
+	OutputAudioTranscription *struct{}                  `json:"outputAudioTranscription,omitempty"`
+}
+
+type geminiLiveGenerationConfig struct {
+	ResponseModalities []string                `json:"responseModalities"`
+	Temperature        *float64                `json:"temperature,omitempty"`
+	SpeechConfig       *geminiLiveSpeechConfig `json:"speechConfig,omitempty"`
+}
+
+type geminiLiveSpeechConfig struct {
+	VoiceConfig geminiLiveVoiceConfig `json:"voiceConfig"`
+}
+
+type geminiLiveVoiceConfig struct {
+	PrebuiltVoiceConfig struct {
+		VoiceName string `json:"voiceName"`
+	} `json:"prebuiltVoiceConfig"`
+}
+
+type geminiLiveClientContent struct {
+	Turns        []dto.GeminiChatContent `json:"turns,omitempty"`
+	TurnComplete bool                    `json:"turnComplete"`
+}
+
+type geminiLiveRealtimeInput struct {
+	Audio          *dto.GeminiInlineData `json:"audio,omitempty"`
+	AudioStreamEnd bool                  `json:"audioStreamEnd,omitempty"`
+}
+
+type geminiLiveToolResponse struct {
+	FunctionResponses []geminiLiveFunctionResponse `json:"functionResponses"`
+}
+
+type geminiLiveFunctionResponse struct {
+	Id       string         `json:"id,omitempty"`
+	Name     string         `json:"name,omitempty"`
+	Response map[string]any `json:"response"`
+}
+
+type geminiLiveServerMessage struct {
+	SetupComplete json.RawMessage          `json:"setupComplete,omitempty"`
+	ServerContent *geminiLiveServerContent `json:"serverContent,omitempty"`
+	ToolCall      *geminiLiveToolCall      `json:"toolCall,omitempty"`
+	UsageMetadata *geminiLiveUsageMetadata `json:"usageMetadata,omitempty"`
+	GoAway        json.RawMessage          `json:"goAway,omitempty"`
+}
+
+type geminiLiveServerContent struct {
+	ModelTurn           *dto.GeminiChatContent   `json:"modelTurn,omitempty"`
+	TurnComplete        bool                     `json:"turnComplete"`
+	Interrupted         bool                     `json:"interrupted"`
+	InputTranscription  *geminiLiveTranscription `json:"inputTranscription,omitempty"`
+	OutputTranscription *geminiLiveTranscription `json:"outputTranscription,omitempty"`
+}
+
+type geminiLiveTranscription struct {
+	Text string `json:"text"`
+}
+
+type geminiLiveToolCall struct {
+	FunctionCalls []geminiLiveFunctionCall `json:"functionCalls"`
+}
+
+type geminiLiveFunctionCall struct {
+	Id   string `json:"id"`
+	Name string `json:"name"`
+	Args any    `json:"args"`
+}
+
+type geminiLiveUsageMetadata struct {
+	PromptTokenCount        int                             `json:"promptTokenCount"`
+	CachedContentTokenCount int                             `json:"cachedContentTokenCount"`
+	ResponseTokenCount      int                             `json:"responseTokenCount"`
+	ToolUsePromptTokenCount int                             `json:"toolUsePromptTokenCount"`
+	ThoughtsTokenCount      int                             `json:"thoughtsTokenCount"`
+	TotalTokenCount         int                             `json:"totalTokenCount"`
+	PromptTokensDetails     []dto.GeminiPromptTokensDetails `json:"promptTokensDetails"`
+	ResponseTokensDetails   []dto.GeminiPromptTokensDetails `json:"responseTokensDetails"`
+}
+
+// geminiLiveURL 返回 BidiGenerateContent 的地址，http(s) 到 ws(s) 的转换由 DoWssRequest 统一处理
+func geminiLiveURL(baseUrl string, version string) string {
+	return fmt.Sprintf("%s/ws/google.ai.generativelanguage.%s.GenerativeService.BidiGenerateContent", baseUrl, version)
+}
+
+// buildGeminiLiveSetup 由 OpenAI realtime 会话配置生成 setup 消息，Gemini 每次只支持一种输出模态，包含 audio 时输出音频
+func buildGeminiLiveSetup(modelName string, session *dto.RealtimeSession) *geminiLiveSetup {
+	setup := &geminiLiveSetup{
+		Model: "models/" + modelName,
+		GenerationConfig: geminiLiveGenerationConfig{
+			ResponseModalities: []string{"AUDIO"},
+		},
+		InputAudioTranscription:  &struct{}{},
+		OutputAudioTranscription: &struct{}{},
+	}
+	if len(session.Modalities) > 0 && !common.StringsContains(session.Modalities, "audio") {
+		setup.GenerationConfig.ResponseModalities = []string{"TEXT"}
+		setup.OutputAudioTranscription = nil
+	}
+	if session.Temperature > 0 {
+		temperature := session.Temperature
+		setup.GenerationConfig.Temperature = &temperature
+	}
+	if _, ok := openAIRealtimeVoices[session.Voice]; !ok && session.Voice != "" {
+		setup.GenerationConfig.SpeechConfig = &geminiLiveSpeechConfig{}
+		setup.GenerationConfig.SpeechConfig.VoiceConfig.PrebuiltVoiceConfig.VoiceName = session.Voice
+	}
+	if session.Instructions != "" {
+		setup.SystemInstruction = &dto.GeminiChatContent{
+			Parts: []dto.GeminiPart{{Text: session.Instructions}},
+		}
+	}
+	var functions []dto.FunctionRequest
+	for _, tool := range session.Tools {
+		if tool.Type != "" && tool.Type != "function" {
+			continue
+		}
+		functions = append(functions, dto.FunctionRequest{
+			Name:        tool.Name,
+			Description: tool.Description,
+			Parameters:  cleanFunctionParameters(tool.Parameters),
+		})
+	}
+	if len(functions) > 0 {
+		setup.Tools = []dto.GeminiChatTool{{FunctionDeclarations: functions}}
+	}
+	return setup
+}
+
+// geminiLiveUsageToRealtime 转换 Gemini Live 的用量，非音频模态计入文本
+func geminiLiveUsageToRealtime(meta *geminiLiveUsageMetadata) *dto.RealtimeUsage {
+	usage := &dto.RealtimeUsage{
+		InputTokens:  meta.PromptTokenCount + meta.ToolUsePromptTokenCount,
+		OutputTokens: meta.ResponseTokenCount + meta.ThoughtsTokenCount,
+	}
+	usage.TotalTokens = usage.InputTokens + usage.OutputTokens
+	usage.InputTokenDetails.CachedTokens = meta.CachedContentTokenCount
+	for _, detail := range meta.PromptTokensDetails {
+		if detail.Modality == "AUDIO" {
+			usage.InputTokenDetails.AudioTokens += detail.TokenCount
+		}
+	}
+	for _, detail := range meta.ResponseTokensDetails {
+		if detail.Modality == "AUDIO" {
+			usage.OutputTokenDetails.AudioTokens += detail.TokenCount
+		}
+	}
+	usage.InputTokenDetails.TextTokens = usage.InputTokens - usage.InputTokenDetails.AudioTokens
+	usage.OutputTokenDetails.TextTokens = usage.OutputTokens - usage.OutputTokenDetails.AudioTokens
+	return usage
+}
+
+// geminiLiveSession 在 OpenAI realtime 协议与 Gemini Live 之间转换事件
+type geminiLiveSession struct {
+	mu      sync.Mutex
+	session dto.RealtimeSession
+	// callNames 记录函数调用 id 对应的函数名，Gemini 的 toolResponse 需要带上函数名
+	callNames map[string]string
+	// awaitingToolResult 已提交函数结果，Gemini 会自动继续生成，忽略客户端随后的 response.create
+	awaitingToolResult bool
+
+	responseSeq int
+	responseId  string
+	itemId      string
+}
+
+func newGeminiLiveSession() *geminiLiveSession {
+	return &geminiLiveSession{
+		session: dto.RealtimeSession{
+			Modalities:        []string{"text", "audio"},
+			InputAudioFormat:  "pcm16",
+			OutputAudioFormat: "pcm16",
+		},
+		callNames: make(map[string]string),
+	}
+}
+
+// applySessionUpdate 合并 session.update 中的配置，返回合并后的会话
+func (s *geminiLiveSession) applySessionUpdate(update *dto.RealtimeSession) (dto.RealtimeSession, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if update == nil {
+		return s.session, nil
+	}
+	for _, format := range []string{update.InputAudioFormat, update.OutputAudioFormat} {
+		if format != "" && format != "pcm16" {
+			return s.session, fmt.Errorf("audio format %s is not supported by gemini live, only pcm16 is supported", format)
+		}
+	}
+	if len(update.Modalities) > 0 {
+		s.session.Modalities = update.Modalities
+	}
+	if update.Instructions != "" {
+		s.session.Instructions = update.Instructions
+	}
+	if update.Voice != "" {
+		s.session.Voice = update.Voice
+	}
+	if update.Tools != nil {
+		s.session.Tools = update.Tools
+	}
+	if update.ToolChoice != "" {
+		s.session.ToolChoice = update.ToolChoice
+	}
+	if update.Temperature > 0 {
+		s.session.Temperature = update.Temperature
+	}
+	if update.TurnDetection != nil {
+		s.session.TurnDetection = update.TurnDetection
+	}
+	return s.session, nil
+}
+
+func (s *geminiLiveSession) currentSession() dto.RealtimeSession {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.session
+}
+
+// convertClientEvent 将客户端事件转换为 Gemini Live 消息，返回 nil 表示无需转发
+func (s *geminiLiveSession) convertClientEvent(event *dto.RealtimeEvent) *geminiLiveClientMessage {
+	switch event.Type {
+	case dto.RealtimeEventInputAudioBufferAppend:
+		if event.Audio == "" {
+			return nil
+		}
+		return &geminiLiveClientMessage{RealtimeInput: &geminiLiveRealtimeInput{
+			Audio: &dto.GeminiInlineData{MimeType: geminiLiveAudioMimeType, Data: event.Audio},
+		}}
+	case dto.RealtimeEventInputAudioBufferCommit:
+		return &geminiLiveClientMessage{RealtimeInput: &geminiLiveRealtimeInput{AudioStreamEnd: true}}
+	case dto.RealtimeEventTypeConversationCreate:
+		if event.Item == nil {
+			return nil
+		}
+		switch event.Item.Type {
+		case "message":
+			role := "user"
+			if event.Item.Role == "assistant" {
+				role = "model"
+			}
+			content := dto.GeminiChatContent{Role: role}
+			for _, part := range event.Item.Content {
+				switch part.Type {
+				case "input_text", "text":
+					content.Parts = append(content.Parts, dto.GeminiPart{Text: part.Text})
+				case "input_audio":
+					content.Parts = append(content.Parts, dto.GeminiPart{InlineData: &dto.GeminiInlineData{
+						MimeType: geminiLiveAudioMimeType,
+						Data:     part.Audio,
+					}})
+				}
+			}
+			if len(content.Parts) == 0 {
+				return nil
+			}
+			return &geminiLiveClientMessage{ClientContent: &geminiLiveClientContent{
+				Turns: []dto.GeminiChatContent{content},
+			}}
+		case "function_call_output":
+			s.mu.Lock()
+			name := s.callNames[event.Item.CallId]
+			delete(s.callNames, event.Item.CallId)
+			s.awaitingToolResult = true
+			s.mu.Unlock()
+			return &geminiLiveClientMessage{ToolResponse: &geminiLiveToolResponse{
+				FunctionResponses: []geminiLiveFunctionResponse{{
+					Id:       event.Item.CallId,
+					Name:     name,
+					Response: map[string]any{"output": event.Item.Output},
+				}},
+			}}
+		}
+	case dto.RealtimeEventTypeResponseCreate:
+		s.mu.Lock()
+		awaiting := s.awaitingToolResult
+		s.awaitingToolResult = false
+		s.mu.Unlock()
+		if awaiting {
+			return nil
+		}
+		return &geminiLiveClientMessage{ClientContent: &geminiLiveClientContent{TurnComplete: true}}
+	}
+	return nil
+}
+
+func (s *geminiLiveSession) ensureResponse(events []dto.RealtimeEvent) []dto.RealtimeEvent {
+	if s.responseId != "" {
+		return events
+	}
+	s.responseSeq++
+	s.responseId = fmt.Sprintf("resp_%d", s.responseSeq)
+	s.itemId = fmt.Sprintf("item_%d", s.responseSeq)
+	return append(events, dto.RealtimeEvent{
+		Type:     dto.RealtimeEventResponseCreated,
+		Response: &dto.RealtimeResponse{Id: s.responseId, Status: "in_progress"},
+	})
+}
+
+// convertServerMessage 将 Gemini Live 消息转换为客户端事件，轮次结束或发起函数调用时生成 response.done
+func (s *geminiLiveSession) convertServerMessage(msg *geminiLiveServerMessage) []dto.RealtimeEvent {
+	var events []dto.RealtimeEvent
+	if len(msg.SetupComplete) > 0 {
+		session := s.currentSession()
+		events = append(events, dto.RealtimeEvent{Type: dto.RealtimeEventTypeSessionUpdated, Session: &session})
+	}
+	if content := msg.ServerContent; content != nil {
+		if content.Interrupted {
+			events = append(events, dto.RealtimeEvent{Type: dto.RealtimeEventInputAudioBufferSpeechStarted})
+		}
+		if content.InputTranscription != nil && content.InputTranscription.Text != "" {
+			events = append(events, dto.RealtimeEvent{
+				Type:  dto.RealtimeEventInputAudioTranscriptionDelta,
+				Delta: content.InputTranscription.Text,
+			})
+		}
+		if content.ModelTurn != nil {
+			for _, part := range content.ModelTurn.Parts {
+				if part.Thought {
+					continue
+				}
+				if part.InlineData != nil && strings.HasPrefix(part.InlineData.MimeType, "audio/") {
+					events = s.ensureResponse(events)
+					events = append(events, dto.RealtimeEvent{
+						Type:       dto.RealtimeEventResponseAudioDelta,
+						ResponseId: s.responseId,
+						ItemId:     s.itemId,
+						Delta:      part.InlineData.Data,
+					})
+				} else if part.Text != "" {
+					events = s.ensureResponse(events)
+					events = append(events, dto.RealtimeEvent{
+						Type:       dto.RealtimeEventResponseTextDelta,
+						ResponseId: s.responseId,
+						ItemId:     s.itemId,
+						Delta:      part.Text,
+					})
+				}
+			}
+		}
+		if content.OutputTranscription != nil && content.OutputTranscription.Text != "" {
+			events = s.ensureResponse(events)
+			events = append(events, dto.RealtimeEvent{
+				Type:       dto.RealtimeEventResponseAudioTranscriptionDelta,
+				ResponseId: s.responseId,
+				ItemId:     s.itemId,
+				Delta:      content.OutputTranscription.Text,
+			})
+		}
+	}
+	if msg.ToolCall != nil && len(msg.ToolCall.FunctionCalls) > 0 {
+		events = s.ensureResponse(events)
+		s.mu.Lock()
+		for _, call := range msg.ToolCall.FunctionCalls {
+			s.callNames[call.Id] = call.Name
+		}
+		s.mu.Unlock()
+		for _, call := range msg.ToolCall.FunctionCalls {
+			arguments := "{}"
+			if call.Args != nil {
+				if data, err := common.Marshal(call.Args); err == nil {
+					arguments = string(data)
+				}
+			}
+			events = append(events, dto.RealtimeEvent{
+				Type:       dto.RealtimeEventResponseFunctionCallArgumentsDone,
+				ResponseId: s.responseId,
+				CallId:     call.Id,
+				Name:       call.Name,
+				Arguments:  arguments,
+			})
+		}
+		events = append(events, s.finishResponse())
+	} else if msg.ServerContent != nil && (msg.ServerContent.TurnComplete || msg.ServerContent.Interrupted) && s.responseId != "" {
+		events = append(events, s.finishResponse())
+	}
+	return events
+}
+
+func (s *geminiLiveSession) finishResponse() dto.RealtimeEvent {
+	event := dto.RealtimeEvent{
+		Type:     dto.RealtimeEventTypeResponseDone,
+		Response: &dto.RealtimeResponse{Id: s.responseId, Status: "completed"},
+	}
+	s.responseId = ""
+	s.itemId = ""
+	return event
+}
+
+// GeminiLiveRealtimeHandler 将 OpenAI realtime 客户端桥接到 Gemini Live，计费方式与 OpenaiRealtimeHandler 一致：
+// 每轮结束时优先使用 Gemini 返回的用量，没有用量时使用本地估算
+func GeminiLiveRealtimeHandler(c *gin.Context, info *relaycommon.RelayInfo) (*types.NewAPIError, *dto.RealtimeUsage) {
+	if info == nil || info.ClientWs == nil || info.TargetWs == nil {
+		return types.NewError(fmt.Errorf("invalid websocket connection"), types.ErrorCodeBadResponse), nil
+	}
+
+	info.IsStream = true
+	clientConn := info.ClientWs
+	targetConn := info.TargetWs
+	session := newGeminiLiveSession()
+
+	var clientMu sync.Mutex
+	writeClient := func(event *dto.RealtimeEvent) error {
+		if event.EventId == "" {
+			event.EventId = helper.GetLocalRealtimeID(c)
+		}
+		clientMu.Lock()
+		defer clientMu.Unlock()
+		return helper.WssObject(c, clientConn, event)
+	}
+	sendError := func(err error) error {
+		return writeClient(&dto.RealtimeEvent{
+			Type:  dto.RealtimeEventTypeError,
+			Error: &types.OpenAIError{Message: err.Error(), Type: "invalid_request_error"},
+		})
+	}
+
+	clientClosed := make(chan struct{})
+	targetClosed := make(chan struct{})
+	errChan := make(chan error, 2)
+
+	// finished 置位后不再计费，避免连接关闭后读协程仍在累加 sumUsage
+	var usageMu sync.Mutex
+	var finished bool
+	var upstreamUsage *geminiLiveUsageMetadata
+	localUsage := &dto.RealtimeUsage{}
+	sumUsage := &dto.RealtimeUsage{}
+	consume := func(usage *dto.RealtimeUsage) error {
+		sumUsage.Add(usage)
+		return service.PreWssConsumeQuota(c, info, usage)
+	}
+
+	initial := session.currentSession()
+	if err := writeClient(&dto.RealtimeEvent{Type: dto.RealtimeEventTypeSessionCreated, Session: &initial}); err != nil {
+		return types.NewError(err, types.ErrorCodeBadResponse), nil
+	}
+
+	gopool.Go(func() {
+		defer func() {
+			if r := recover(); r != nil {
+				errChan <- fmt.Errorf("panic in client reader: %v", r)
+			}
+		}()
+		setupSent := false
+		for {
+			select {
+			case <-c.Done():
+				return
+			default:
+				_, message, err := clientConn.ReadMessage()
+				if err != nil {
+					if !websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+						errChan <- fmt.Errorf("error reading from client: %v", err)
+					}
+					close(clientClosed)
+					return
+				}
+
+				realtimeEvent := &dto.RealtimeEvent{}
+				if err := common.Unmarshal(message, realtimeEvent); err != nil {
+					errChan <- fmt.Errorf("error unmarshalling message: %v", err)
+					return
+				}
+
+				if realtimeEvent.Type == dto.RealtimeEventTypeSessionUpdate {
+					current, err := session.applySessionUpdate(realtimeEvent.Session)
+					if err != nil {
+						_ = sendError(err)
+						continue
+					}
+					info.RealtimeTools = current.Tools
+					if setupSent {
+						// Gemini Live 不支持会话中途修改配置，仅回显当前配置
+						logger.LogWarn(c, "gemini live does not support updating session after setup, session.update ignored")
+						if err := writeClient(&dto.RealtimeEvent{Type: dto.RealtimeEventTypeSessionUpdated, Session: &current}); err != nil {
+							errChan <- fmt.Errorf("error writing to client: %v", err)
+							return
+						}
+						continue
+					}
+				}
+				if !setupSent {
+					current := session.currentSession()
+					setup := buildGeminiLiveSetup(info.UpstreamModelName, &current)
+					if err := helper.WssObject(c, targetConn, &geminiLiveClientMessage{Setup: setup}); err != nil {
+						errChan <- fmt.Errorf("error writing to target: %v", err)
+						return
+					}
+					setupSent = true
+				}
+
+				textToken, audioToken, err := service.CountTokenRealtime(info, *realtimeEvent, info.UpstreamModelName)
+				if err != nil {
+					errChan <- fmt.Errorf("error counting text token: %v", err)
+					return
+				}
+				usageMu.Lock()
+				localUsage.TotalTokens += textToken + audioToken
+				localUsage.InputTokens += textToken + audioToken
+				localUsage.InputTokenDetails.TextTokens += textToken
+				localUsage.InputTokenDetails.AudioTokens += audioToken
+				usageMu.Unlock()
+
+				if msg := session.convertClientEvent(realtimeEvent); msg != nil {
+					if err := helper.WssObject(c, targetConn, msg); err != nil {
+						errChan <- fmt.Errorf("error writing to target: %v", err)
+						return
+					}
+				}
+			}
+		}
+	})
+
+	gopool.Go(func() {
+		defer func() {
+			if r := recover(); r != nil {
+				errChan <- fmt.Errorf("panic in target reader: %v", r)
+			}
+		}()
+		for {
+			select {
+			case <-c.Done():
+				return
+			default:
+				_, message, err := targetConn.ReadMessage()
+				if err != nil {
+					if !websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+						errChan <- fmt.Errorf("error reading from target: %v", err)
+					}
+					close(targetClosed)
+					return
+				}
+				info.SetFirstResponseTime()
+				serverMessage := &geminiLiveServerMessage{}
+				if err := common.Unmarshal(message, serverMessage); err != nil {
+					errChan <- fmt.Errorf("error unmarshalling message: %v", err)
+					return
+				}
+				if len(serverMessage.GoAway) > 0 {
+					logger.LogWarn(c, "gemini live session is going away: "+string(serverMessage.GoAway))
+				}
+				usageMu.Lock()
+				if serverMessage.UsageMetadata != nil {
+					upstreamUsage = serverMessage.UsageMetadata
+				}
+				usageMu.Unlock()
+
+				for _, event := range session.convertServerMessage(serverMessage) {
+					textToken, audioToken, err := service.CountTokenRealtime(info, event, info.UpstreamModelName)
+					if err != nil {
+						errChan <- fmt.Errorf("error counting text token: %v", err)
+						return
+					}
+					usageMu.Lock()
+					if finished {
+						usageMu.Unlock()
+						return
+					}
+					if event.Type == dto.RealtimeEventTypeResponseDone {
+						var turnUsage *dto.RealtimeUsage
+						if upstreamUsage != nil {
+							turnUsage = geminiLiveUsageToRealtime(upstreamUsage)
+						} else {
+							info.IsFirstRequest = false
+							localUsage.TotalTokens += textToken + audioToken
+							localUsage.InputTokens += textToken + audioToken
+							localUsage.InputTokenDetails.TextTokens += textToken
+							localUsage.InputTokenDetails.AudioTokens += audioToken
+							turnUsage = localUsage
+						}
+						upstreamUsage = nil
+						localUsage = &dto.RealtimeUsage{}
+						event.Response.Usage = turnUsage
+						err = consume(turnUsage)
+					} else {
+						localUsage.TotalTokens += textToken + audioToken
+						localUsage.OutputTokens += textToken + audioToken
+						localUsage.OutputTokenDetails.TextTokens += textToken
+						localUsage.OutputTokenDetails.AudioTokens += audioToken
+					}
+					usageMu.Unlock()
+					if err != nil {
+						errChan <- fmt.Errorf("error consume usage: %v", err)
+						return
+					}
+					if err := writeClient(&event); err != nil {
+						errChan <- fmt.Errorf("error writing to client: %v", err)
+						return
+					}
+				}
+			}
+		}
+	})
+
+	select {
+	case <-clientClosed:
+	case <-targetClosed:
+	case err := <-errChan:
+		logger.LogError(c, "gemini live realtime error: "+err.Error())
+	case <-c.Done():
+	}
+
+	usageMu.Lock()
+	defer usageMu.Unlock()
+	finished = true
+	if upstreamUsage != nil {
+		_ = consume(geminiLiveUsageToRealtime(upstreamUsage))
+	} else if localUsage.TotalTokens != 0 {
+		_ = consume(localUsage)
+	}
+	return nil, sumUsage
+}
@@ -0,0 +1,514 @@
+package gemini
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/logger"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/relay/helper"
+	"github.com/QuantumNous/new-api/service"
+	"github.com/QuantumNous/new-api/setting/model_setting"
+	"github.com/QuantumNous/new-api/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+// geminiContentBuilder 按顺序累积 Gemini contents，相同角色的连续 part 合并为一条
+type geminiContentBuilder struct {
+	contents []dto.GeminiChatContent
+}
+
+func (b *geminiContentBuilder) append(role string, parts ...dto.GeminiPart) {
+	if len(parts) == 0 {
+		return
+	}
+	if n := len(b.contents); n > 0 && b.contents[n-1].Role == role {
+		b.contents[n-1].Parts = append(b.contents[n-1].Parts, parts...)
+		return
+	}
+	b.contents = append(b.contents, dto.GeminiChatContent{Role: role, Parts: parts})
+}
+
+func responsesFileToGeminiPart(c *gin.Context, url string, reason string) (*dto.GeminiPart, error) {
+	var source *types.FileSource
+	if strings.HasPrefix(url, "http") {
+		source = types.NewURLFileSource(url)
+	} else {
+		source = types.NewBase64FileSource(url, "")
+	}
+	base64Data, mimeType, err := service.GetBase64Data(c, source, reason)
+	if err != nil {
+		return nil, fmt.Errorf("get file data from '%s' failed: %w", source.GetIdentifier(), err)
+	}
+	if _, ok := geminiSupportedMimeTypes[strings.ToLower(mimeType)]; !ok {
+		return nil, fmt.Errorf("mime type is not supported by Gemini: '%s', url: '%s', supported types are: %v", mimeType, source.GetIdentifier(), getSupportedMimeTypesList())
+	}
+	return &dto.GeminiPart{
+		InlineData: &dto.GeminiInlineData{
+			MimeType: mimeType,
+			Data:     base64Data,
+		},
+	}, nil
+}
+
+// responsesContentToGeminiParts 转换 message 中的内容，支持字符串或内容数组
+func responsesContentToGeminiParts(c *gin.Context, content any) ([]dto.GeminiPart, error) {
+	switch v := content.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		if v == "" {
+			return nil, nil
+		}
+		return []dto.GeminiPart{{Text: v}}, nil
+	case []any:
+		parts := make([]dto.GeminiPart, 0, len(v))
+		for _, partAny := range v {
+			part, ok := partAny.(map[string]any)
+			if !ok {
+				continue
+			}
+			switch partType := common.Interface2String(part["type"]); partType {
+			case "input_text", "output_text", "text":
+				if text := common.Interface2String(part["text"]); text != "" {
+					parts = append(parts, dto.GeminiPart{Text: text})
+				}
+			case "refusal":
+				if text := common.Interface2String(part["refusal"]); text != "" {
+					parts = append(parts, dto.GeminiPart{Text: text})
+				}
+			case "input_image":
+				var url string
+				switch imageUrl := part["image_url"].(type) {
+				case string:
+					url = imageUrl
+				case map[string]any:
+					url = common.Interface2String(imageUrl["url"])
+				}
+				if url == "" {
+					return nil, errors.New("input_image without image_url is not supported in gemini")
+				}
+				geminiPart, err := responsesFileToGeminiPart(c, url, "formatting image for Gemini")
+				if err != nil {
+					return nil, err
+				}
+				parts = append(parts, *geminiPart)
+			case "input_file":
+				url := common.Interface2String(part["file_data"])
+				if url == "" {
+					url = common.Interface2String(part["file_url"])
+				}
+				if url == "" {
+					return nil, errors.New("only file_data or file_url is supported for input_file in gemini")
+				}
+				geminiPart, err := responsesFileToGeminiPart(c, url, "formatting file for Gemini")
+				if err != nil {
+					return nil, err
+				}
+				parts = append(parts, *geminiPart)
+			default:
+				return nil, fmt.Errorf("unsupported content type %q", partType)
+			}
+		}
+		return parts, nil
+	default:
+		return nil, fmt.Errorf("unsupported content format %T", content)
+	}
+}
+
+func responsesToolsToGeminiTools(raw []byte) ([]dto.GeminiChatTool, error) {
+	var tools []map[string]any
+	if err := common.Unmarshal(raw, &tools); err != nil {
+		return nil, err
+	}
+	var geminiTools []dto.GeminiChatTool
+	functions := make([]dto.FunctionRequest, 0, len(tools))
+	googleSearch := false
+	for _, tool := range tools {
+		switch toolType := common.Interface2String(tool["type"]); toolType {
+		case "function":
+			functions = append(functions, dto.FunctionRequest{
+				Name:        common.Interface2String(tool["name"]),
+				Description: common.Interface2String(tool["description"]),
+				Parameters:  cleanFunctionParameters(tool["parameters"]),
+			})
+		case dto.BuildInToolWebSearchPreview, "web_search":
+			googleSearch = true
+		default:
+			return nil, fmt.Errorf("unsupported tool type %q", toolType)
+		}
+	}
+	if googleSearch {
+		geminiTools = append(geminiTools, dto.GeminiChatTool{
+			GoogleSearch: make(map[string]string),
+		})
+	}
+	if len(functions) > 0 {
+		geminiTools = append(geminiTools, dto.GeminiChatTool{
+			FunctionDeclarations: functions,
+		})
+	}
+	return geminiTools, nil
+}
+
+// responsesToolChoiceToGemini Responses 的指定函数格式为 {"type":"function","name":"xxx"}，
+// 先转换为 chat 格式再复用 convertToolChoiceToGeminiConfig
+func responsesToolChoiceToGemini(raw []byte) *dto.ToolConfig {
+	switch common.GetJsonType(raw) {
+	case "string":
+		var mode string
+		_ = common.Unmarshal(raw, &mode)
+		return convertToolChoiceToGeminiConfig(mode)
+	case "object":
+		var obj map[string]any
+		_ = common.Unmarshal(raw, &obj)
+		if common.Interface2String(obj["type"]) != "function" {
+			return nil
+		}
+		return convertToolChoiceToGeminiConfig(map[string]any{
+			"type":     "function",
+			"function": map[string]any{"name": obj["name"]},
+		})
+	}
+	return nil
+}
+
+// CovertOpenAIResponses2Gemini 将 /v1/responses 请求转换为 Gemini generateContent 请求
+func CovertOpenAIResponses2Gemini(c *gin.Context, request dto.OpenAIResponsesRequest, info *relaycommon.RelayInfo) (*dto.GeminiChatRequest, error) {
+	if request.PreviousResponseID != "" {
+		return nil, errors.New("previous_response_id is not supported for this model")
+	}
+
+	geminiRequest := dto.GeminiChatRequest{
+		GenerationConfig: dto.GeminiChatGenerationConfig{
+			Temperature: request.Temperature,
+		},
+		SafetySettings: buildSafetySettings(),
+	}
+	if request.TopP != nil && *request.TopP > 0 {
+		geminiRequest.GenerationConfig.TopP = common.GetPointer(*request.TopP)
+	}
+	if request.MaxOutputTokens != nil && *request.MaxOutputTokens > 0 {
+		geminiRequest.GenerationConfig.MaxOutputTokens = common.GetPointer(*request.MaxOutputTokens)
+	}
+	if model_setting.IsGeminiModelSupportImagine(info.UpstreamModelName) {
+		geminiRequest.GenerationConfig.ResponseModalities = []string{
+			"TEXT",
+			"IMAGE",
+		}
+	}
+
+	ThinkingAdaptor(&geminiRequest, info)
+	if request.Reasoning != nil && request.Reasoning.Effort != "" && geminiRequest.GenerationConfig.ThinkingConfig == nil {
+		if request.Reasoning.Effort == "none" {
+			geminiRequest.GenerationConfig.ThinkingConfig = &dto.GeminiThinkingConfig{
+				ThinkingBudget: common.GetPointer(0),
+			}
+		} else {
+			geminiRequest.GenerationConfig.ThinkingConfig = &dto.GeminiThinkingConfig{
+				IncludeThoughts: true,
+				ThinkingBudget:  common.GetPointer(clampThinkingBudgetByEffort(info.UpstreamModelName, request.Reasoning.Effort)),
+			}
+		}
+	}
+
+	if len(request.Text) > 0 {
+		var text struct {
+			Format struct {
+				Type   string `json:"type"`
+				Schema any    `json:"schema"`
+			} `json:"format"`
+		}
+		if err := common.Unmarshal(request.Text, &text); err == nil &&
+			(text.Format.Type == "json_schema" || text.Format.Type == "json_object") {
+			geminiRequest.GenerationConfig.ResponseMimeType = "application/json"
+			if text.Format.Schema != nil {
+				geminiRequest.GenerationConfig.ResponseSchema = removeAdditionalPropertiesWithDepth(text.Format.Schema, 0)
+			}
+		}
+	}
+
+	if len(request.Tools) > 0 && common.GetJsonType(request.Tools) == "array" {
+		tools, err := responsesToolsToGeminiTools(request.Tools)
+		if err != nil {
+			return nil, err
+		}
+		if len(tools) > 0 {
+			geminiRequest.SetTools(tools)
+		}
+	}
+	if len(request.ToolChoice) > 0 {
+		geminiRequest.ToolConfig = responsesToolChoiceToGemini(request.ToolChoice)
+	}
+
+	var systemContent []string
+	if common.GetJsonType(request.Instructions) == "string" {
+		var instructions string
+		if err := common.Unmarshal(request.Instructions, &instructions); err != nil {
+			return nil, err
+		}
+		if instructions != "" {
+			systemContent = append(systemContent, instructions)
+		}
+	}
+
+	attachThoughtSignature := (info.ChannelType == constant.ChannelTypeGemini ||
+		info.ChannelType == constant.ChannelTypeVertexAi) &&
+		model_setting.GetGeminiSettings().FunctionCallThoughtSignatureEnabled
+
+	builder := &geminiContentBuilder{}
+	switch common.GetJsonType(request.Input) {
+	case "string":
+		var input string
+		if err := common.Unmarshal(request.Input, &input); err != nil {
+			return nil, err
+		}
+		builder.append("user", dto.GeminiPart{Text: input})
+	case "array":
+		var items []map[string]any
+		if err := common.Unmarshal(request.Input, &items); err != nil {
+			return nil, err
+		}
+		callNames := make(map[string]string)
+		signatureAttached := false
+		for _, item := range items {
+			itemType := common.Interface2String(item["type"])
+			if itemType == "" && item["role"] != nil {
+				itemType = "message"
+			}
+			switch itemType {
+			case "message":
+				role := common.Interface2String(item["role"])
+				if role == "system" || role == "developer" {
+					parts, err := responsesContentToGeminiParts(c, item["content"])
+					if err != nil {
+						return nil, err
+					}
+					for _, part := range parts {
+						if part.Text != "" {
+							systemContent = append(systemContent, part.Text)
+						}
+					}
+					continue
+				}
+				parts, err := responsesContentToGeminiParts(c, item["content"])
+				if err != nil {
+					return nil, err
+				}
+				if role == "assistant" {
+					builder.append("model", parts...)
+				} else {
+					signatureAttached = false
+					builder.append("user", parts...)
+				}
+			case "function_call":
+				callId := common.Interface2String(item["call_id"])
+				name := common.Interface2String(item["name"])
+				args := map[string]interface{}{}
+				if arguments := strings.TrimSpace(common.Interface2String(item["arguments"])); arguments != "" {
+					if err := common.UnmarshalJsonStr(arguments, &args); err != nil {
+						return nil, fmt.Errorf("invalid arguments for function %s, args: %s", name, arguments)
+					}
+				}
+				part := dto.GeminiPart{
+					FunctionCall: &dto.FunctionCall{
+						FunctionName: name,
+						Arguments:    args,
+					},
+				}
+				// 每轮模型输出的首个函数调用需要 thoughtSignature
+				if attachThoughtSignature && !signatureAttached && hasFunctionCallContent(part.FunctionCall) {
+					part.ThoughtSignature = json.RawMessage(strconv.Quote(thoughtSignatureBypassValue))
+					signatureAttached = true
+				}
+				callNames[callId] = name
+				builder.append("model", part)
+			case "function_call_output":
+				var output string
+				if s, ok := item["output"].(string); ok {
+					output = s
+				} else if item["output"] != nil {
+					outputBytes, err := common.Marshal(item["output"])
+					if err != nil {
+						return nil, err
+					}
+					output = string(outputBytes)
+				}
+				signatureAttached = false
+				builder.append("user", dto.GeminiPart{
+					FunctionResponse: &dto.GeminiFunctionResponse{
+						Name:     callNames[common.Interface2String(item["call_id"])],
+						Response: toolResultToGeminiResponse(output),
+					},
+				})
+			case "reasoning":
+				// Gemini 的思考内容无法回传
+				continue
+			default:
+				return nil, fmt.Errorf("unsupported input item type %q", itemType)
+			}
+		}
+	}
+	if len(builder.contents) == 0 {
+		return nil, errors.New("input is required")
+	}
+	geminiRequest.Contents = builder.contents
+
+	if len(systemContent) > 0 {
+		geminiRequest.SystemInstructions = &dto.GeminiChatContent{
+			Parts: []dto.GeminiPart{
+				{
+					Text: strings.Join(systemContent, "\n"),
+				},
+			},
+		}
+	}
+	return &geminiRequest, nil
+}
+
+// geminiFinishReasonToResponsesStatus 未收到 finishReason 视为上游中断
+func geminiFinishReasonToResponsesStatus(finishReason *string) (string, *dto.IncompleteDetails) {
+	if finishReason == nil {
+		return "incomplete", &dto.IncompleteDetails{Reason: "interrupted"}
+	}
+	switch *finishReason {
+	case "STOP":
+		return "completed", nil
+	case "MAX_TOKENS":
+		return "incomplete", &dto.IncompleteDetails{Reason: "max_output_tokens"}
+	default:
+		return "incomplete", &dto.IncompleteDetails{Reason: "content_filter"}
+	}
+}
+
+const (
+	geminiResponsesMessageKey   = "message"
+	geminiResponsesReasoningKey = "reasoning"
+)
+
+// geminiResponsesConverter 将 Gemini 候选内容（仅首个候选）转换为 Responses 输出项：
+// 连续的文本 / 思考内容各自合并为一个 message / reasoning 项，每个函数调用对应一个 function_call 项
+type geminiResponsesConverter struct {
+	builder      *service.ResponsesStreamBuilder
+	finishReason *string
+	callCount    int
+}
+
+func newGeminiResponsesConverter(c *gin.Context, info *relaycommon.RelayInfo) *geminiResponsesConverter {
+	return &geminiResponsesConverter{
+		builder: service.NewResponsesStreamBuilder(helper.GetResponsesID(c), common.GetTimestamp(), info.UpstreamModelName),
+	}
+}
+
+func (s *geminiResponsesConverter) appendText(key string, text string) []dto.ResponsesStreamResponse {
+	var events []dto.ResponsesStreamResponse
+	if !s.builder.IsOpen(key) {
+		if key == geminiResponsesMessageKey {
+			events = append(events, s.builder.Close(geminiResponsesReasoningKey)...)
+			events = append(events, s.builder.OpenMessage(key)...)
+		} else {
+			events = append(events, s.builder.Close(geminiResponsesMessageKey)...)
+			events = append(events, s.builder.OpenReasoning(key)...)
+		}
+	}
+	return append(events, s.builder.AppendDelta(key, text)...)
+}
+
+func (s *geminiResponsesConverter) Convert(geminiResponse *dto.GeminiChatResponse) []dto.ResponsesStreamResponse {
+	events := s.builder.Start()
+	if len(geminiResponse.Candidates) == 0 {
+		return events
+	}
+	candidate := geminiResponse.Candidates[0]
+	for i := range candidate.Content.Parts {
+		part := &candidate.Content.Parts[i]
+		switch {
+		case part.FunctionCall != nil:
+			call := getResponseToolCall(part)
+			if call == nil {
+				continue
+			}
+			events = append(events, s.builder.Close(geminiResponsesReasoningKey)...)
+			events = append(events, s.builder.Close(geminiResponsesMessageKey)...)
+			key := "call_" + strconv.Itoa(s.callCount)
+			s.callCount++
+			events = append(events, s.builder.OpenFunctionCall(key, call.ID, call.Function.Name)...)
+			events = append(events, s.builder.AppendDelta(key, call.Function.Arguments)...)
+			events = append(events, s.builder.Close(key)...)
+		case part.Thought:
+			events = append(events, s.appendText(geminiResponsesReasoningKey, part.Text)...)
+		case part.Text != "":
+			events = append(events, s.appendText(geminiResponsesMessageKey, part.Text)...)
+		case part.InlineData != nil && strings.HasPrefix(part.InlineData.MimeType, "image"):
+			imgText := "![image](data:" + part.InlineData.MimeType + ";base64," + part.InlineData.Data + ")"
+			events = append(events, s.appendText(geminiResponsesMessageKey, imgText)...)
+		case part.ExecutableCode != nil:
+			code := "```" + part.ExecutableCode.Language + "\n" + part.ExecutableCode.Code + "\n```\n"
+			events = append(events, s.appendText(geminiResponsesMessageKey, code)...)
+		case part.CodeExecutionResult != nil:
+			output := "```output\n" + part.CodeExecutionResult.Output + "\n```\n"
+			events = append(events, s.appendText(geminiResponsesMessageKey, output)...)
+		}
+	}
+	if candidate.FinishReason != nil {
+		s.finishReason = candidate.FinishReason
+	}
+	return events
+}
+
+func (s *geminiResponsesConverter) Finish(usage *dto.Usage) []dto.ResponsesStreamResponse {
+	status, incomplete := geminiFinishReasonToResponsesStatus(s.finishReason)
+	return s.builder.Finish(status, incomplete, service.ChatUsageToResponsesUsage(usage))
+}
+
+func GeminiResponsesStreamHandler(c *gin.Context, info *relaycommon.RelayInfo, resp *http.Response) (*dto.Usage, *types.NewAPIError) {
+	converter := newGeminiResponsesConverter(c, info)
+	usage, err := geminiStreamHandler(c, info, resp, func(data string, geminiResponse *dto.GeminiChatResponse) bool {
+		if sendErr := helper.ResponsesEventsData(c, converter.Convert(geminiResponse)); sendErr != nil {
+			logger.LogError(c, sendErr.Error())
+		}
+		return true
+	})
+	if err != nil {
+		return usage, err
+	}
+	if sendErr := helper.ResponsesEventsData(c, converter.Finish(usage)); sendErr != nil {
+		logger.LogError(c, "send final response failed: "+sendErr.Error())
+	}
+	return usage, nil
+}
+
+func GeminiResponsesHandler(c *gin.Context, info *relaycommon.RelayInfo, resp *http.Response) (*dto.Usage, *types.NewAPIError) {
+	responseBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, types.NewOpenAIError(err, types.ErrorCodeBadResponseBody, http.StatusInternalServerError)
+	}
+	service.CloseResponseBodyGracefully(resp)
+	var geminiResponse dto.GeminiChatResponse
+	if err = common.Unmarshal(responseBody, &geminiResponse); err != nil {
+		return nil, types.NewOpenAIError(err, types.ErrorCodeBadResponseBody, http.StatusInternalServerError)
+	}
+	if len(geminiResponse.Candidates) == 0 {
+		return handleGeminiEmptyCandidates(c, info, &geminiResponse), nil
+	}
+	usage := buildUsageFromGeminiMetadata(geminiResponse.UsageMetadata, info.GetEstimatePromptTokens())
+
+	converter := newGeminiResponsesConverter(c, info)
+	converter.Convert(&geminiResponse)
+	converter.Finish(&usage)
+	responseBody, err = common.Marshal(converter.builder.Response())
+	if err != nil {
+		return nil, types.NewError(err, types.ErrorCodeBadResponseBody)
+	}
+	service.IOCopyBytesGracefully(c, resp, responseBody)
+	return &usage, nil
+}
@@ -0,0 +1,72 @@
+package gemini
+
+import (
+	"testing"
+
+	"github.com/QuantumNous/new-api/dto"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildGeminiLiveSetup(t *testing.T) {
+	setup := buildGeminiLiveSetup("gemini-live-2.5-flash", &dto.RealtimeSession{
+		Modalities:   []string{"text"},
+		Instructions: "be brief",
+		Voice:        "alloy",
+		Tools:        []dto.RealTimeTool{{Type: "function", Name: "lookup", Parameters: map[string]any{"type": "object"}}},
+	})
+	require.Equal(t, "models/gemini-live-2.5-flash", setup.Model)
+	require.Equal(t, []string{"TEXT"}, setup.GenerationConfig.ResponseModalities)
+	require.Nil(t, setup.GenerationConfig.SpeechConfig)
+	require.Nil(t, setup.OutputAudioTranscription)
+	require.Equal(t, "be brief", setup.SystemInstruction.Parts[0].Text)
+	require.Len(t, setup.Tools, 1)
+
+	setup = buildGeminiLiveSetup("gemini-live-2.5-flash", &dto.RealtimeSession{Modalities: []string{"text", "audio"}, Voice: "Puck"})
+	require.Equal(t, []string{"AUDIO"}, setup.GenerationConfig.ResponseModalities)
+	require.Equal(t, "Puck", setup.GenerationConfig.SpeechConfig.VoiceConfig.PrebuiltVoiceConfig.VoiceName)
+}
+
+func TestGeminiLiveSessionConvert(t *testing.T) {
+	s := newGeminiLiveSession()
+
+	msg := s.convertClientEvent(&dto.RealtimeEvent{Type: dto.RealtimeEventInputAudioBufferAppend, Audio: "AAAA"})
+	require.Equal(t, geminiLiveAudioMimeType, msg.RealtimeInput.Audio.MimeType)
+
+	events := s.convertServerMessage(&geminiLiveServerMessage{ServerContent: &geminiLiveServerContent{
+		ModelTurn: &dto.GeminiChatContent{Parts: []dto.GeminiPart{{InlineData: &dto.GeminiInlineData{MimeType: geminiLiveAudioMimeType, Data: "BBBB"}}}},
+	}})
+	require.Len(t, events, 2)
+	require.Equal(t, dto.RealtimeEventResponseCreated, events[0].Type)
+	require.Equal(t, dto.RealtimeEventResponseAudioDelta, events[1].Type)
+	require.Equal(t, "BBBB", events[1].Delta)
+
+	// 函数调用结束本轮响应，函数结果带上函数名回传，随后的 response.create 不再转发
+	toolCall := &geminiLiveToolCall{FunctionCalls: []geminiLiveFunctionCall{{Id: "call_1", Name: "lookup", Args: map[string]any{"q": "x"}}}}
+	events = s.convertServerMessage(&geminiLiveServerMessage{ToolCall: toolCall})
+	require.Len(t, events, 2)
+	require.Equal(t, `{"q":"x"}`, events[0].Arguments)
+	require.Equal(t, dto.RealtimeEventTypeResponseDone, events[1].Type)
+
+	msg = s.convertClientEvent(&dto.RealtimeEvent{
+		Type: dto.RealtimeEventTypeConversationCreate,
+		Item: &dto.RealtimeItem{Type: "function_call_output", CallId: "call_1", Output: "ok"},
+	})
+	require.Equal(t, "lookup", msg.ToolResponse.FunctionResponses[0].Name)
+	require.Nil(t, s.convertClientEvent(&dto.RealtimeEvent{Type: dto.RealtimeEventTypeResponseCreate}))
+	require.True(t, s.convertClientEvent(&dto.RealtimeEvent{Type: dto.RealtimeEventTypeResponseCreate}).ClientContent.TurnComplete)
+}
+
+func TestGeminiLiveUsageToRealtime(t *testing.T) {
+	usage := geminiLiveUsageToRealtime(&geminiLiveUsageMetadata{
+		PromptTokenCount:      120,
+		ResponseTokenCount:    80,
+		PromptTokensDetails:   []dto.GeminiPromptTokensDetails{{Modality: "AUDIO", TokenCount: 100}, {Modality: "TEXT", TokenCount: 20}},
+		ResponseTokensDetails: []dto.GeminiPromptTokensDetails{{Modality: "AUDIO", TokenCount: 80}},
+	})
+	require.Equal(t, 200, usage.TotalTokens)
+	require.Equal(t, 100, usage.InputTokenDetails.AudioTokens)
+	require.Equal(t, 20, usage.InputTokenDetails.TextTokens)
+	require.Equal(t, 80, usage.OutputTokenDetails.AudioTokens)
+	require.Equal(t, 0, usage.OutputTokenDetails.TextTokens)
+}
@@ -0,0 +1,125 @@
+package gemini
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/dto"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/types"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCovertOpenAIResponses2Gemini(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/responses", nil)
+
+	info := &relaycommon.RelayInfo{
+		RelayFormat: types.RelayFormatOpenAIResponses,
+		ChannelMeta: &relaycommon.ChannelMeta{
+			ChannelType:       constant.ChannelTypeOpenAI,
+			UpstreamModelName: "gemini-2.5-flash",
+		},
+	}
+	request := dto.OpenAIResponsesRequest{
+		Model:        "gemini-2.5-flash",
+		Instructions: json.RawMessage(`"be brief"`),
+		Input: json.RawMessage(`[
+			{"role":"user","content":[{"type":"input_text","text":"weather?"},{"type":"input_image","image_url":"data:image/png;base64,AAAA"}]},
+			{"type":"function_call","call_id":"call_1","name":"get_weather","arguments":"{\"city\":\"Paris\"}"},
+			{"type":"function_call_output","call_id":"call_1","output":"sunny"}
+		]`),
+		Tools:      json.RawMessage(`[{"type":"function","name":"get_weather","parameters":{"type":"object","properties":{"city":{"type":"string"}},"additionalProperties":false}}]`),
+		ToolChoice: json.RawMessage(`{"type":"function","name":"get_weather"}`),
+		Reasoning:  &dto.Reasoning{Effort: "low"},
+	}
+
+	geminiRequest, err := CovertOpenAIResponses2Gemini(c, request, info)
+	require.NoError(t, err)
+
+	require.Equal(t, "be brief", geminiRequest.SystemInstructions.Parts[0].Text)
+	require.Len(t, geminiRequest.Contents, 3)
+	require.Equal(t, "user", geminiRequest.Contents[0].Role)
+	require.Equal(t, "image/png", geminiRequest.Contents[0].Parts[1].InlineData.MimeType)
+	require.Equal(t, "model", geminiRequest.Contents[1].Role)
+	require.Equal(t, "get_weather", geminiRequest.Contents[1].Parts[0].FunctionCall.FunctionName)
+	require.Equal(t, "get_weather", geminiRequest.Contents[2].Parts[0].FunctionResponse.Name)
+	require.Equal(t, map[string]interface{}{"content": "sunny"}, geminiRequest.Contents[2].Parts[0].FunctionResponse.Response)
+
+	tools, err := common.Marshal(geminiRequest.GetTools())
+	require.NoError(t, err)
+	require.JSONEq(t, `[{"functionDeclarations":[{"name":"get_weather","parameters":{"type":"OBJECT","properties":{"city":{"type":"STRING"}}}}]}]`, string(tools))
+	require.Equal(t, []string{"get_weather"}, geminiRequest.ToolConfig.FunctionCallingConfig.AllowedFunctionNames)
+
+	thinkingConfig := geminiRequest.GenerationConfig.ThinkingConfig
+	require.NotNil(t, thinkingConfig)
+	require.True(t, thinkingConfig.IncludeThoughts)
+	require.Equal(t, clampThinkingBudgetByEffort("gemini-2.5-flash", "low"), *thinkingConfig.ThinkingBudget)
+}
+
+func TestGeminiResponsesStreamHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/responses", nil)
+
+	oldStreamingTimeout := constant.StreamingTimeout
+	constant.StreamingTimeout = 300
+	t.Cleanup(func() {
+		constant.StreamingTimeout = oldStreamingTimeout
+	})
+
+	info := &relaycommon.RelayInfo{
+		RelayFormat: types.RelayFormatOpenAIResponses,
+		ChannelMeta: &relaycommon.ChannelMeta{
+			UpstreamModelName: "gemini-2.5-flash",
+		},
+	}
+
+	var streamBody bytes.Buffer
+	for _, data := range []string{
+		`{"candidates":[{"content":{"role":"model","parts":[{"text":"thinking","thought":true}]}}]}`,
+		`{"candidates":[{"content":{"role":"model","parts":[{"text":"Hel"}]}}]}`,
+		`{"candidates":[{"content":{"role":"model","parts":[{"text":"lo"},{"functionCall":{"name":"f","args":{"a":1}}}]},"finishReason":"STOP"}],"usageMetadata":{"promptTokenCount":10,"candidatesTokenCount":5,"thoughtsTokenCount":2,"totalTokenCount":17}}`,
+	} {
+		streamBody.WriteString("data: " + data + "\n")
+	}
+	streamBody.WriteString("data: [DONE]\n")
+	resp := &http.Response{
+		Body: io.NopCloser(&streamBody),
+	}
+
+	usage, newAPIError := GeminiChatStreamHandler(c, info, resp)
+	require.Nil(t, newAPIError)
+	require.Equal(t, 10, usage.PromptTokens)
+	require.Equal(t, 7, usage.CompletionTokens)
+
+	var events []dto.ResponsesStreamResponse
+	for _, line := range strings.Split(recorder.Body.String(), "\n") {
+		if data, ok := strings.CutPrefix(line, "data: "); ok {
+			var ev dto.ResponsesStreamResponse
+			require.NoError(t, common.UnmarshalJsonStr(data, &ev))
+			events = append(events, ev)
+		}
+	}
+	require.NotEmpty(t, events)
+	completed := events[len(events)-1]
+	require.Equal(t, "response.completed", completed.Type)
+	require.Len(t, completed.Response.Output, 3)
+	require.Equal(t, "reasoning", completed.Response.Output[0].Type)
+	require.Equal(t, "thinking", completed.Response.Output[0].Summary[0].Text)
+	require.Equal(t, "Hello", completed.Response.Output[1].Content[0].Text)
+	require.Equal(t, "function_call", completed.Response.Output[2].Type)
+	require.JSONEq(t, `{"a":1}`, completed.Response.Output[2].Arguments)
+	require.Equal(t, 10, completed.Response.Usage.InputTokens)
+	require.Equal(t, 7, completed.Response.Usage.OutputTokens)
+}
@@ -163,6 +163,10 @@ func (a *Adaptor) GetRequestURL(info *relaycommon.RelayInfo) (string, error) {
 		requestURL = fmt.Sprintf("/openai/deployments/%s/%s", model_, task)
 		if info.RelayMode == relayconstant.RelayModeRealtime {
 			requestURL = fmt.Sprintf("/openai/realtime?deployment=%s&api-version=%s", model_, apiVersion)
+			// GA 版本的 realtime 接口不带 api-version，渠道 API 版本填写 v1 时使用
+			if apiVersion == "v1" {
+				requestURL = fmt.Sprintf("/openai/v1/realtime?model=%s", model_)
+			}
 		}
 		return relaycommon.GetFullRequestURL(info.ChannelBaseUrl, requestURL, info.ChannelType), nil
 	//case constant.ChannelTypeMiniMax:
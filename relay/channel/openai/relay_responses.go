@@ -76,8 +76,7 @@ func OaiResponsesStreamHandler(c *gin.Context, info *relaycommon.RelayInfo, resp
 
 	defer service.CloseResponseBodyGracefully(resp)
 
-	var usage = &dto.Usage{}
-	var responseTextBuilder strings.Builder
+	usage := &ResponsesStreamUsage{}
 
 	helper.StreamScannerHandler(c, resp, info, func(data string) bool {
 
@@ -85,54 +84,72 @@ func OaiResponsesStreamHandler(c *gin.Context, info *relaycommon.RelayInfo, resp
 		var streamResponse dto.ResponsesStreamResponse
 		if err := common.UnmarshalJsonStr(data, &streamResponse); err == nil {
 			sendResponsesStreamData(c, streamResponse, data)
-			switch streamResponse.Type {
-			case "response.completed":
-				if streamResponse.Response != nil {
-					if streamResponse.Response.Usage != nil {
-						if streamResponse.Response.Usage.InputTokens != 0 {
-							usage.PromptTokens = streamResponse.Response.Usage.InputTokens
-						}
-						if streamResponse.Response.Usage.OutputTokens != 0 {
-							usage.CompletionTokens = streamResponse.Response.Usage.OutputTokens
-						}
-						if streamResponse.Response.Usage.TotalTokens != 0 {
-							usage.TotalTokens = streamResponse.Response.Usage.TotalTokens
-						}
-						if streamResponse.Response.Usage.InputTokensDetails != nil {
-							usage.PromptTokensDetails.CachedTokens = streamResponse.Response.Usage.InputTokensDetails.CachedTokens
-						}
-					}
-					if streamResponse.Response.HasImageGenerationCall() {
-						c.Set("image_generation_call", true)
-						c.Set("image_generation_call_quality", streamResponse.Response.GetQuality())
-						c.Set("image_generation_call_size", streamResponse.Response.GetSize())
-					}
-				}
-			case "response.output_text.delta":
-				// 处理输出文本
-				responseTextBuilder.WriteString(streamResponse.Delta)
-			case dto.ResponsesOutputTypeItemDone:
-				// 函数调用处理
-				if streamResponse.Item != nil {
-					switch streamResponse.Item.Type {
-					case dto.BuildInCallWebSearchCall:
-						if info != nil && info.ResponsesUsageInfo != nil && info.ResponsesUsageInfo.BuiltInTools != nil {
-							if webSearchTool, exists := info.ResponsesUsageInfo.BuiltInTools[dto.BuildInToolWebSearchPreview]; exists && webSearchTool != nil {
-								webSearchTool.CallCount++
-							}
-						}
-					}
-				}
-			}
+			usage.Handle(c, info, &streamResponse)
 		} else {
 			logger.LogError(c, "failed to unmarshal stream response: "+err.Error())
 		}
 		return true
 	})
 
+	return usage.Usage(info), nil
+}
+
+// ResponsesStreamUsage 从 Responses 流式事件中汇总用量，HTTP 流式与 WebSocket 模式共用
+type ResponsesStreamUsage struct {
+	usage               dto.Usage
+	responseTextBuilder strings.Builder
+}
+
+// Handle 处理一条流式事件
+func (u *ResponsesStreamUsage) Handle(c *gin.Context, info *relaycommon.RelayInfo, streamResponse *dto.ResponsesStreamResponse) {
+	usage := &u.usage
+	switch streamResponse.Type {
+	case "response.completed", "response.incomplete", "response.failed":
+		if streamResponse.Response != nil {
+			if streamResponse.Response.Usage != nil {
+				if streamResponse.Response.Usage.InputTokens != 0 {
+					usage.PromptTokens = streamResponse.Response.Usage.InputTokens
+				}
+				if streamResponse.Response.Usage.OutputTokens != 0 {
+					usage.CompletionTokens = streamResponse.Response.Usage.OutputTokens
+				}
+				if streamResponse.Response.Usage.TotalTokens != 0 {
+					usage.TotalTokens = streamResponse.Response.Usage.TotalTokens
+				}
+				if streamResponse.Response.Usage.InputTokensDetails != nil {
+					usage.PromptTokensDetails.CachedTokens = streamResponse.Response.Usage.InputTokensDetails.CachedTokens
+				}
+			}
+			if streamResponse.Response.HasImageGenerationCall() {
+				c.Set("image_generation_call", true)
+				c.Set("image_generation_call_quality", streamResponse.Response.GetQuality())
+				c.Set("image_generation_call_size", streamResponse.Response.GetSize())
+			}
+		}
+	case "response.output_text.delta":
+		// 处理输出文本
+		u.responseTextBuilder.WriteString(streamResponse.Delta)
+	case dto.ResponsesOutputTypeItemDone:
+		// 函数调用处理
+		if streamResponse.Item != nil {
+			switch streamResponse.Item.Type {
+			case dto.BuildInCallWebSearchCall:
+				if info != nil && info.ResponsesUsageInfo != nil && info.ResponsesUsageInfo.BuiltInTools != nil {
+					if webSearchTool, exists := info.ResponsesUsageInfo.BuiltInTools[dto.BuildInToolWebSearchPreview]; exists && webSearchTool != nil {
+						webSearchTool.CallCount++
+					}
+				}
+			}
+		}
+	}
+}
+
+// Usage 返回最终用量，上游未返回用量时按输出文本估算
+func (u *ResponsesStreamUsage) Usage(info *relaycommon.RelayInfo) *dto.Usage {
+	usage := u.usage
 	if usage.CompletionTokens == 0 {
 		// 计算输出文本的 token 数量
-		tempStr := responseTextBuilder.String()
+		tempStr := u.responseTextBuilder.String()
 		if len(tempStr) > 0 {
 			// 非正常结束，使用输出文本的 token 数量
 			completionTokens := service.CountTextToken(tempStr, info.UpstreamModelName)
@@ -146,5 +163,5 @@ func OaiResponsesStreamHandler(c *gin.Context, info *relaycommon.RelayInfo, resp
 
 	usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
 
-	return usage, nil
+	return &usage
 }
@@ -0,0 +1,221 @@
+package finetune
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/relay/channel"
+	taskcommon "github.com/QuantumNous/new-api/relay/channel/task/taskcommon"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/service"
+	"github.com/QuantumNous/new-api/setting/ratio_setting"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	"github.com/tidwall/sjson"
+)
+
+// TaskAdaptor OpenAI fine_tuning jobs。
+// 提交时预扣基础额度，任务成功后按 trained_tokens 重新结算，并把生成的模型登记到提交者名下。
+type TaskAdaptor struct {
+	taskcommon.BaseBilling
+	ChannelType int
+	apiKey      string
+	baseURL     string
+}
+
+func (a *TaskAdaptor) Init(info *relaycommon.RelayInfo) {
+	a.ChannelType = info.ChannelType
+	a.baseURL = info.ChannelBaseUrl
+	a.apiKey = info.ApiKey
+}
+
+func (a *TaskAdaptor) ValidateRequestAndSetAction(c *gin.Context, info *relaycommon.RelayInfo) *dto.TaskError {
+	var req dto.FineTuningJobRequest
+	if err := common.UnmarshalBodyReusable(c, &req); err != nil {
+		return service.TaskErrorWrapperLocal(err, "invalid_request", http.StatusBadRequest)
+	}
+	if strings.TrimSpace(req.Model) == "" {
+		return service.TaskErrorWrapperLocal(fmt.Errorf("field model is required"), "invalid_request", http.StatusBadRequest)
+	}
+	if strings.TrimSpace(req.TrainingFile) == "" {
+		return service.TaskErrorWrapperLocal(fmt.Errorf("field training_file is required"), "invalid_request", http.StatusBadRequest)
+	}
+	info.Action = constant.TaskActionFineTune
+	return nil
+}
+
+// AdjustBillingOnComplete 按训练 token 计费：trained_tokens × 模型倍率 × 微调倍率 × 分组倍率
+func (a *TaskAdaptor) AdjustBillingOnComplete(task *model.Task, taskResult *relaycommon.TaskInfo) int {
+	if taskResult.TotalTokens <= 0 {
+		return 0
+	}
+	modelName := task.Properties.OriginModelName
+	groupRatio := 1.0
+	if bc := task.PrivateData.BillingContext; bc != nil {
+		modelName = taskcommon.DefaultString(bc.OriginModelName, modelName)
+		groupRatio = bc.GroupRatio
+	}
+	modelRatio, ok, _ := ratio_setting.GetModelRatio(modelName)
+	if !ok || modelRatio <= 0 {
+		return 0
+	}
+	return int(float64(taskResult.TotalTokens) * modelRatio * ratio_setting.GetFineTuningRatio(modelName) * groupRatio)
+}
+
+func (a *TaskAdaptor) BuildRequestURL(info *relaycommon.RelayInfo) (string, error) {
+	return fmt.Sprintf("%s/v1/fine_tuning/jobs", a.baseURL), nil
+}
+
+func (a *TaskAdaptor) BuildRequestHeader(c *gin.Context, req *http.Request, info *relaycommon.RelayInfo) error {
+	req.Header.Set("Authorization", "Bearer "+a.apiKey)
+	req.Header.Set("Content-Type", "application/json")
+	if info.Organization != "" {
+		req.Header.Set("OpenAI-Organization", info.Organization)
+	}
+	return nil
+}
+
+func (a *TaskAdaptor) BuildRequestBody(c *gin.Context, info *relaycommon.RelayInfo) (io.Reader, error) {
+	storage, err := common.GetBodyStorage(c)
+	if err != nil {
+		return nil, errors.Wrap(err, "get_request_body_failed")
+	}
+	body, err := storage.Bytes()
+	if err != nil {
+		return nil, errors.Wrap(err, "read_body_bytes_failed")
+	}
+	// 应用渠道模型映射
+	if body, err = sjson.SetBytes(body, "model", info.UpstreamModelName); err != nil {
+		return nil, errors.Wrap(err, "set_model_failed")
+	}
+	return bytes.NewReader(body), nil
+}
+
+func (a *TaskAdaptor) DoRequest(c *gin.Context, info *relaycommon.RelayInfo, requestBody io.Reader) (*http.Response, error) {
+	return channel.DoTaskApiRequest(a, c, info, requestBody)
+}
+
+func (a *TaskAdaptor) DoResponse(c *gin.Context, resp *http.Response, info *relaycommon.RelayInfo) (taskID string, taskData []byte, taskErr *dto.TaskError) {
+	responseBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		taskErr = service.TaskErrorWrapper(err, "read_response_body_failed", http.StatusInternalServerError)
+		return
+	}
+	_ = resp.Body.Close()
+
+	var job dto.FineTuningJob
+	if err := common.Unmarshal(responseBody, &job); err != nil {
+		taskErr = service.TaskErrorWrapper(errors.Wrapf(err, "body: %s", responseBody), "unmarshal_response_body_failed", http.StatusInternalServerError)
+		return
+	}
+	if job.Id == "" {
+		taskErr = service.TaskErrorWrapper(fmt.Errorf("job id is empty"), "invalid_response", http.StatusInternalServerError)
+		return
+	}
+
+	// 使用公开 task_xxxx ID 返回给客户端
+	publicBody, err := sjson.SetBytes(responseBody, "id", info.PublicTaskID)
+	if err != nil {
+		publicBody = responseBody
+	}
+	c.Data(http.StatusOK, "application/json", publicBody)
+	return job.Id, responseBody, nil
+}
+
+func (a *TaskAdaptor) FetchTask(baseUrl, key string, body map[string]any, proxy string) (*http.Response, error) {
+	taskID, ok := body["task_id"].(string)
+	if !ok {
+		return nil, fmt.Errorf("invalid task_id")
+	}
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/v1/fine_tuning/jobs/%s", baseUrl, taskID), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+key)
+
+	client, err := service.GetHttpClientWithProxy(proxy)
+	if err != nil {
+		return nil, fmt.Errorf("new proxy http client failed: %w", err)
+	}
+	return client.Do(req)
+}
+
+func (a *TaskAdaptor) ParseTaskResult(respBody []byte) (*relaycommon.TaskInfo, error) {
+	var job dto.FineTuningJob
+	if err := common.Unmarshal(respBody, &job); err != nil {
+		return nil, errors.Wrap(err, "unmarshal task result failed")
+	}
+
+	taskResult := relaycommon.TaskInfo{
+		TaskID:      job.Id,
+		TotalTokens: job.TrainedTokens,
+	}
+	switch job.Status {
+	case "validating_files", "queued":
+		taskResult.Status = model.TaskStatusQueued
+	case "running":
+		taskResult.Status = model.TaskStatusInProgress
+	case "succeeded":
+		taskResult.Status = model.TaskStatusSuccess
+		// 任务日志中展示生成的模型名
+		taskResult.Url = job.FineTunedModel
+	case "failed", "cancelled":
+		taskResult.Status = model.TaskStatusFailure
+		if job.Error != nil && job.Error.Message != "" {
+			taskResult.Reason = job.Error.Message
+		} else {
+			taskResult.Reason = "fine-tuning job " + job.Status
+		}
+	default:
+	}
+	return &taskResult, nil
+}
+
+// OnTaskSuccess 将生成的微调模型登记到提交者名下，只有提交者可以调用，请求固定路由到训练所在的渠道和 key
+func (a *TaskAdaptor) OnTaskSuccess(ctx context.Context, task *model.Task, taskResult *relaycommon.TaskInfo) error {
+	var job dto.FineTuningJob
+	if err := common.Unmarshal(task.Data, &job); err != nil {
+		return err
+	}
+	if job.FineTunedModel == "" {
+		return nil
+	}
+	keyIndex := 0
+	if ch, err := model.CacheGetChannel(task.ChannelId); err == nil && ch.ChannelInfo.IsMultiKey {
+		if idx := slices.Index(ch.GetKeys(), task.PrivateData.Key); idx >= 0 {
+			keyIndex = idx
+		}
+	}
+	fineTuned := &model.FineTunedModel{
+		UserId:    task.UserId,
+		ModelName: job.FineTunedModel,
+		BaseModel: job.Model,
+		ChannelId: task.ChannelId,
+		KeyIndex:  keyIndex,
+		TaskId:    task.TaskID,
+	}
+	if err := fineTuned.Insert(); err != nil {
+		return err
+	}
+	logger.LogInfo(ctx, fmt.Sprintf("fine-tuned model %s registered for user #%d on channel #%d", job.FineTunedModel, task.UserId, task.ChannelId))
+	return nil
+}
+
+func (a *TaskAdaptor) GetModelList() []string {
+	return ModelList
+}
+
+func (a *TaskAdaptor) GetChannelName() string {
+	return ChannelName
+}
@@ -0,0 +1,57 @@
+package finetune
+
+import (
+	"testing"
+
+	"github.com/QuantumNous/new-api/model"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/setting/ratio_setting"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTaskResult(t *testing.T) {
+	a := &TaskAdaptor{}
+
+	result, err := a.ParseTaskResult([]byte(`{"id":"ftjob-1","status":"running"}`))
+	require.NoError(t, err)
+	assert.Equal(t, model.TaskStatusInProgress, result.Status)
+
+	result, err = a.ParseTaskResult([]byte(`{"id":"ftjob-1","status":"succeeded","fine_tuned_model":"ft:gpt-4o-mini-2024-07-18:org::abc","trained_tokens":1200}`))
+	require.NoError(t, err)
+	assert.Equal(t, model.TaskStatusSuccess, result.Status)
+	assert.Equal(t, 1200, result.TotalTokens)
+	assert.Equal(t, "ft:gpt-4o-mini-2024-07-18:org::abc", result.Url)
+
+	result, err = a.ParseTaskResult([]byte(`{"id":"ftjob-1","status":"failed","error":{"code":"invalid_training_file","message":"bad file"}}`))
+	require.NoError(t, err)
+	assert.Equal(t, model.TaskStatusFailure, result.Status)
+	assert.Equal(t, "bad file", result.Reason)
+
+	result, err = a.ParseTaskResult([]byte(`{"id":"ftjob-1","status":"cancelled"}`))
+	require.NoError(t, err)
+	assert.Equal(t, model.TaskStatusFailure, result.Status)
+}
+
+func TestAdjustBillingOnComplete(t *testing.T) {
+	originRatio := ratio_setting.ModelRatio2JSONString()
+	originFineTuning := ratio_setting.FineTuningRatio2JSONString()
+	t.Cleanup(func() {
+		require.NoError(t, ratio_setting.UpdateModelRatioByJSONString(originRatio))
+		require.NoError(t, ratio_setting.UpdateFineTuningRatioByJSONString(originFineTuning))
+	})
+	require.NoError(t, ratio_setting.UpdateModelRatioByJSONString(`{"ft-test-base":0.5}`))
+	require.NoError(t, ratio_setting.UpdateFineTuningRatioByJSONString(`{"ft-test-base":10}`))
+
+	task := &model.Task{
+		PrivateData: model.TaskPrivateData{
+			BillingContext: &model.TaskBillingContext{
+				OriginModelName: "ft-test-base",
+				GroupRatio:      2,
+			},
+		},
+	}
+	a := &TaskAdaptor{}
+	assert.Equal(t, 10000, a.AdjustBillingOnComplete(task, &relaycommon.TaskInfo{TotalTokens: 1000}))
+	assert.Zero(t, a.AdjustBillingOnComplete(task, &relaycommon.TaskInfo{}))
+}
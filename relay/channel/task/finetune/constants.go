@@ -0,0 +1,6 @@
+package finetune
+
+// ModelList 微调的基础模型由请求指定，不预置模型列表
+var ModelList = []string{}
+
+var ChannelName = "fine_tuning"
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"regexp"
 	"strings"
 	"time"
@@ -39,6 +40,7 @@ type operationVideo struct {
 	MimeType           string `json:"mimeType"`
 	BytesBase64Encoded string `json:"bytesBase64Encoded"`
 	Encoding           string `json:"encoding"`
+	GcsUri             string `json:"gcsUri"`
 }
 
 type operationResponse struct {
@@ -328,6 +330,10 @@ func (a *TaskAdaptor) ParseTaskResult(respBody []byte) (*relaycommon.TaskInfo, e
 			ti.Url = "data:" + mime + ";base64," + v0.BytesBase64Encoded
 			return ti, nil
 		}
+		if v0.GcsUri != "" {
+			ti.Url = v0.GcsUri
+			return ti, nil
+		}
 	}
 	if op.Response.BytesBase64Encoded != "" {
 		enc := strings.TrimSpace(op.Response.Encoding)
@@ -356,6 +362,29 @@ func (a *TaskAdaptor) ParseTaskResult(respBody []byte) (*relaycommon.TaskInfo, e
 	return ti, nil
 }
 
+// ResolveArtifactURL 将 gs:// 地址转为 Cloud Storage JSON API 下载地址，并使用渠道凭证的访问令牌鉴权
+func (a *TaskAdaptor) ResolveArtifactURL(uri string, key string, proxy string) (string, http.Header, error) {
+	header := http.Header{}
+	if !strings.HasPrefix(uri, "gs://") {
+		return uri, header, nil
+	}
+	bucket, object, ok := strings.Cut(strings.TrimPrefix(uri, "gs://"), "/")
+	if !ok || bucket == "" || object == "" {
+		return "", nil, fmt.Errorf("invalid gcs uri: %s", uri)
+	}
+	adc := &vertexcore.Credentials{}
+	if err := common.Unmarshal([]byte(key), adc); err != nil {
+		return "", nil, fmt.Errorf("failed to decode credentials: %w", err)
+	}
+	token, err := vertexcore.AcquireAccessToken(*adc, proxy)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to acquire access token: %w", err)
+	}
+	header.Set("Authorization", "Bearer "+token)
+	header.Set("x-goog-user-project", adc.ProjectID)
+	return fmt.Sprintf("https://storage.googleapis.com/storage/v1/b/%s/o/%s?alt=media", url.PathEscape(bucket), url.PathEscape(object)), header, nil
+}
+
 func (a *TaskAdaptor) ConvertToOpenAIVideo(task *model.Task) ([]byte, error) {
 	// Use GetUpstreamTaskID() to get the real upstream operation name for model extraction.
 	// task.TaskID is now a public task_xxxx ID, no longer a base64-encoded upstream name.
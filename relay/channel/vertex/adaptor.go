@@ -101,6 +101,29 @@ func (a *Adaptor) ConvertClaudeRequest(c *gin.Context, info *relaycommon.RelayIn
 	return vertexClaudeReq, nil
 }
 
+func (a *Adaptor) CountClaudeTokens(c *gin.Context, info *relaycommon.RelayInfo, request *dto.ClaudeRequest) (*dto.ClaudeCountTokensResponse, error) {
+	if a.RequestMode != RequestModeClaude || info.ChannelOtherSettings.VertexKeyType == dto.VertexKeyTypeAPIKey {
+		return nil, errors.New("count_tokens is only supported for claude models with service account credentials")
+	}
+	countRequest := request.ToCountTokensRequest()
+	if v, ok := claudeModelMap[info.UpstreamModelName]; ok {
+		countRequest.Model = v
+	}
+	return claude.DoCountTokensRequest(a, c, info, countRequest)
+}
+
+func (a *Adaptor) CountGeminiTokens(c *gin.Context, info *relaycommon.RelayInfo, request *dto.GeminiCountTokensRequest) (*dto.GeminiCountTokensResponse, error) {
+	if a.RequestMode != RequestModeGemini {
+		return nil, errors.New("countTokens is only supported for gemini models")
+	}
+	generateRequest := request.ToGenerateContentRequest()
+	return gemini.DoCountTokensRequest(a, c, info, &VertexGeminiCountTokensRequest{
+		Contents:          generateRequest.Contents,
+		SystemInstruction: generateRequest.SystemInstructions,
+		Tools:             generateRequest.Tools,
+	})
+}
+
 func (a *Adaptor) ConvertAudioRequest(c *gin.Context, info *relaycommon.RelayInfo, request dto.AudioRequest) (io.Reader, error) {
 	//TODO implement me
 	return nil, errors.New("not implemented")
@@ -123,6 +146,11 @@ func (a *Adaptor) Init(info *relaycommon.RelayInfo) {
 	}
 }
 
+// SupportsNativeResponses 开源模型（llama / -maas）没有 Responses 转换，需降级到 Chat Completions
+func (a *Adaptor) SupportsNativeResponses() bool {
+	return a.RequestMode == RequestModeClaude || a.RequestMode == RequestModeGemini
+}
+
 func (a *Adaptor) getRequestUrl(info *relaycommon.RelayInfo, modelName, suffix string) (string, error) {
 	region := GetModelRegion(info.ApiVersion, info.OriginModelName)
 	if info.ChannelOtherSettings.VertexKeyType != dto.VertexKeyTypeAPIKey {
@@ -227,12 +255,18 @@ func (a *Adaptor) GetRequestURL(info *relaycommon.RelayInfo) (string, error) {
 		} else {
 			suffix = "generateContent"
 		}
+		if info.RelayMode == constant.RelayModeGemini && constant.GetGeminiAction(info.RequestURLPath) == constant.GeminiActionCountTokens {
+			suffix = constant.GeminiActionCountTokens
+		}
 
 		if strings.HasPrefix(info.UpstreamModelName, "imagen") {
 			suffix = "predict"
 		}
 		return a.getRequestUrl(info, info.UpstreamModelName, suffix)
 	} else if a.RequestMode == RequestModeClaude {
+		if info.RelayMode == constant.RelayModeMessagesCountTokens {
+			return a.getRequestUrl(info, "count-tokens", "rawPredict")
+		}
 		if info.IsStream {
 			suffix = "streamRawPredict?alt=sse"
 		} else {
@@ -357,8 +391,28 @@ func (a *Adaptor) ConvertEmbeddingRequest(c *gin.Context, info *relaycommon.Rela
 }
 
 func (a *Adaptor) ConvertOpenAIResponsesRequest(c *gin.Context, info *relaycommon.RelayInfo, request dto.OpenAIResponsesRequest) (any, error) {
-	// TODO implement me
-	return nil, errors.New("not implemented")
+	if info.RelayMode == constant.RelayModeResponsesCompact {
+		return nil, errors.New("responses compact is not supported by vertex channel")
+	}
+	if a.RequestMode == RequestModeClaude {
+		claudeAdaptor := claude.Adaptor{}
+		claudeReq, err := claudeAdaptor.ConvertOpenAIResponsesRequest(c, info, request)
+		if err != nil {
+			return nil, err
+		}
+		vertexClaudeReq := copyRequest(claudeReq.(*dto.ClaudeRequest), anthropicVersion)
+		c.Set("request_model", request.Model)
+		info.UpstreamModelName = request.Model
+		return vertexClaudeReq, nil
+	} else if a.RequestMode == RequestModeGemini {
+		geminiRequest, err := gemini.CovertOpenAIResponses2Gemini(c, request, info)
+		if err != nil {
+			return nil, err
+		}
+		c.Set("request_model", request.Model)
+		return geminiRequest, nil
+	}
+	return nil, errors.New("unsupported request mode")
 }
 
 func (a *Adaptor) DoRequest(c *gin.Context, info *relaycommon.RelayInfo, requestBody io.Reader) (any, error) {
@@ -0,0 +1,22 @@
+package vertex
+
+import (
+	"testing"
+
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSupportsNativeResponses(t *testing.T) {
+	cases := map[string]bool{
+		"claude-sonnet-4":                   true,
+		"gemini-2.5-pro":                    true,
+		"meta/llama-3.1-405b-instruct-maas": false,
+	}
+	for model, native := range cases {
+		adaptor := &Adaptor{}
+		adaptor.Init(&relaycommon.RelayInfo{ChannelMeta: &relaycommon.ChannelMeta{UpstreamModelName: model}})
+		require.Equal(t, native, adaptor.SupportsNativeResponses(), model)
+	}
+}
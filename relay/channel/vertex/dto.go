@@ -40,3 +40,10 @@ func copyRequest(req *dto.ClaudeRequest, version string) *VertexAIClaudeRequest
 		OutputConfig:     req.OutputConfig,
 	}
 }
+
+// VertexGeminiCountTokensRequest Vertex countTokens 不支持 generateContentRequest 包装，字段直接平铺
+type VertexGeminiCountTokensRequest struct {
+	Contents          []dto.GeminiChatContent `json:"contents"`
+	SystemInstruction *dto.GeminiChatContent  `json:"systemInstruction,omitempty"`
+	Tools             json.RawMessage         `json:"tools,omitempty"`
+}
@@ -0,0 +1,63 @@
+package relay
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/relay/channel"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/relay/helper"
+	"github.com/QuantumNous/new-api/service"
+	"github.com/QuantumNous/new-api/setting/model_setting"
+	"github.com/QuantumNous/new-api/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ClaudeCountTokensHelper 处理 /v1/messages/count_tokens，不计费。
+// 渠道支持上游计数时直接转发，否则（或上游失败时）在本地估算。
+func ClaudeCountTokensHelper(c *gin.Context, info *relaycommon.RelayInfo) *types.NewAPIError {
+	info.InitChannelMeta(c)
+
+	claudeReq, ok := info.Request.(*dto.ClaudeRequest)
+	if !ok {
+		return types.NewErrorWithStatusCode(fmt.Errorf("invalid request type, expected *dto.ClaudeRequest, got %T", info.Request), types.ErrorCodeInvalidRequest, http.StatusBadRequest, types.ErrOptionWithSkipRetry())
+	}
+
+	request, err := common.DeepCopy(claudeReq)
+	if err != nil {
+		return types.NewError(fmt.Errorf("failed to copy request to ClaudeRequest: %w", err), types.ErrorCodeInvalidRequest, types.ErrOptionWithSkipRetry())
+	}
+
+	err = helper.ModelMappedHelper(c, info, request)
+	if err != nil {
+		return types.NewError(err, types.ErrorCodeChannelModelMappedError, types.ErrOptionWithSkipRetry())
+	}
+
+	// Bedrock 按 InvokeModel 请求体计数，要求携带 max_tokens
+	if request.MaxTokens == nil || *request.MaxTokens == 0 {
+		defaultMaxTokens := uint(model_setting.GetClaudeSettings().GetDefaultMaxTokens(request.Model))
+		request.MaxTokens = &defaultMaxTokens
+	}
+
+	adaptor := GetAdaptor(info.ApiType)
+	if counter, ok := adaptor.(channel.ClaudeTokenCounter); ok {
+		adaptor.Init(info)
+		countResponse, err := counter.CountClaudeTokens(c, info, request)
+		if err == nil {
+			c.JSON(http.StatusOK, countResponse)
+			return nil
+		}
+		logger.LogWarn(c, fmt.Sprintf("upstream count_tokens failed, fallback to local estimation: %s", err.Error()))
+	}
+
+	tokens, err := service.EstimateCountTokens(c, claudeReq, info)
+	if err != nil {
+		return types.NewError(err, types.ErrorCodeCountTokenFailed, types.ErrOptionWithSkipRetry())
+	}
+	c.JSON(http.StatusOK, dto.ClaudeCountTokensResponse{InputTokens: tokens})
+	return nil
+}
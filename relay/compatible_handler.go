@@ -13,6 +13,7 @@ import (
 	"github.com/QuantumNous/new-api/dto"
 	"github.com/QuantumNous/new-api/logger"
 	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/pkg/tracing"
 	relaycommon "github.com/QuantumNous/new-api/relay/common"
 	relayconstant "github.com/QuantumNous/new-api/relay/constant"
 	"github.com/QuantumNous/new-api/relay/helper"
@@ -41,6 +42,13 @@ func TextHelper(c *gin.Context, info *relaycommon.RelayInfo) (newAPIError *types
 		return types.NewError(fmt.Errorf("failed to copy request to GeneralOpenAIRequest: %w", err), types.ErrorCodeInvalidRequest, types.ErrOptionWithSkipRetry())
 	}
 
+	cacheKey := service.ResponseCacheKey(info, textReq)
+	if entry, hit := service.GetResponseCache(cacheKey); hit {
+		service.ServeResponseCache(c, info, entry)
+		postConsumeQuota(c, info, &entry.Usage)
+		return nil
+	}
+
 	if request.WebSearchOptions != nil {
 		c.Set("chat_completion_web_search_context_size", request.WebSearchOptions.SearchContextSize)
 	}
@@ -112,7 +120,9 @@ func TextHelper(c *gin.Context, info *relaycommon.RelayInfo) (newAPIError *types
 		}
 		requestBody = common.ReaderOnly(storage)
 	} else {
+		convertSpan := startConvertSpan(c, "openai")
 		convertedRequest, err := adaptor.ConvertOpenAIRequest(c, info, request)
+		tracing.End(convertSpan, err)
 		if err != nil {
 			return types.NewError(err, types.ErrorCodeConvertRequestFailed, types.ErrOptionWithSkipRetry())
 		}
@@ -203,8 +213,14 @@ func TextHelper(c *gin.Context, info *relaycommon.RelayInfo) (newAPIError *types
 		}
 	}
 
+	cacheRecorder := service.StartResponseCacheRecord(c, cacheKey)
+	moderationBuffer := service.StartOutputModeration(c, info)
+	responseSpan := startResponseSpan(c)
 	usage, newApiErr := adaptor.DoResponse(c, httpResp, info)
+	endResponseSpan(responseSpan, newApiErr)
+	moderationBuffer.Finish(c, newApiErr == nil)
 	if newApiErr != nil {
+		cacheRecorder.Finish(c, info, nil)
 		// reset status code 重置状态码
 		service.ResetStatusCode(newApiErr, statusCodeMappingStr)
 		return newApiErr
@@ -214,8 +230,11 @@ func TextHelper(c *gin.Context, info *relaycommon.RelayInfo) (newAPIError *types
 	var containsAudioRatios = ratio_setting.ContainsAudioRatio(info.OriginModelName) || ratio_setting.ContainsAudioCompletionRatio(info.OriginModelName)
 
 	if containAudioTokens && containsAudioRatios {
+		// 音频单独计费，不写入缓存
+		cacheRecorder.Finish(c, info, nil)
 		service.PostAudioConsumeQuota(c, info, usage.(*dto.Usage), "")
 	} else {
+		cacheRecorder.Finish(c, info, usage.(*dto.Usage))
 		postConsumeQuota(c, info, usage.(*dto.Usage))
 	}
 	return nil
@@ -407,6 +426,7 @@ func postConsumeQuota(ctx *gin.Context, relayInfo *relaycommon.RelayInfo, usage
 
 	quota := int(quotaCalculateDecimal.Round(0).IntPart())
 	totalTokens := promptTokens + completionTokens
+	service.ConsumeTokenRateLimitTokens(ctx, totalTokens)
 
 	//var logContent string
 
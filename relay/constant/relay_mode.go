@@ -59,6 +59,8 @@ const (
 	RelayModeGemini
 
 	RelayModeResponsesCompact
+
+	RelayModeMessagesCountTokens
 )
 
 func Path2RelayMode(path string) int {
@@ -83,6 +85,8 @@ func Path2RelayMode(path string) int {
 		relayMode = RelayModeResponsesCompact
 	} else if strings.HasPrefix(path, "/v1/responses") {
 		relayMode = RelayModeResponses
+	} else if strings.HasPrefix(path, "/v1/messages/count_tokens") {
+		relayMode = RelayModeMessagesCountTokens
 	} else if strings.HasPrefix(path, "/v1/audio/speech") {
 		relayMode = RelayModeAudioSpeech
 	} else if strings.HasPrefix(path, "/v1/audio/transcriptions") {
@@ -159,3 +163,22 @@ func Path2RelaySuno(method, path string) int {
 	}
 	return relayMode
 }
+
+// Gemini 原生接口 /v1beta/models/{model}:{action} 的 action
+const (
+	GeminiActionGenerateContent       = "generateContent"
+	GeminiActionStreamGenerateContent = "streamGenerateContent"
+	GeminiActionCountTokens           = "countTokens"
+	GeminiActionEmbedContent          = "embedContent"
+	GeminiActionBatchEmbedContents    = "batchEmbedContents"
+)
+
+// GetGeminiAction 从 Gemini 原生路径中提取 action，如 /v1beta/models/gemini-2.0-flash:countTokens 返回 countTokens
+func GetGeminiAction(path string) string {
+	path = strings.Split(path, "?")[0]
+	idx := strings.LastIndex(path, ":")
+	if idx < 0 || idx < strings.LastIndex(path, "/") {
+		return ""
+	}
+	return path[idx+1:]
+}
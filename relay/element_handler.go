@@ -63,7 +63,9 @@ func ElementHelper(c *gin.Context, info *relaycommon.RelayInfo) (newAPIError *ty
 		}
 	}
 
+	responseSpan := startResponseSpan(c)
 	usage, newAPIError := adaptor.DoResponse(c, httpResp, info)
+	endResponseSpan(responseSpan, newAPIError)
 	if newAPIError != nil {
 		service.ResetStatusCode(newAPIError, statusCodeMappingStr)
 		return newAPIError
@@ -8,6 +8,7 @@ import (
 	"github.com/QuantumNous/new-api/common"
 	"github.com/QuantumNous/new-api/dto"
 	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/pkg/tracing"
 	relaycommon "github.com/QuantumNous/new-api/relay/common"
 	"github.com/QuantumNous/new-api/relay/helper"
 	"github.com/QuantumNous/new-api/service"
@@ -29,6 +30,13 @@ func EmbeddingHelper(c *gin.Context, info *relaycommon.RelayInfo) (newAPIError *
 		return types.NewError(fmt.Errorf("failed to copy request to EmbeddingRequest: %w", err), types.ErrorCodeInvalidRequest, types.ErrOptionWithSkipRetry())
 	}
 
+	cacheKey := service.ResponseCacheKey(info, embeddingReq)
+	if entry, hit := service.GetResponseCache(cacheKey); hit {
+		service.ServeResponseCache(c, info, entry)
+		postConsumeQuota(c, info, &entry.Usage)
+		return nil
+	}
+
 	err = helper.ModelMappedHelper(c, info, request)
 	if err != nil {
 		return types.NewError(err, types.ErrorCodeChannelModelMappedError, types.ErrOptionWithSkipRetry())
@@ -40,7 +48,9 @@ func EmbeddingHelper(c *gin.Context, info *relaycommon.RelayInfo) (newAPIError *
 	}
 	adaptor.Init(info)
 
+	convertSpan := startConvertSpan(c, "embedding")
 	convertedRequest, err := adaptor.ConvertEmbeddingRequest(c, info, *request)
+	tracing.End(convertSpan, err)
 	if err != nil {
 		return types.NewError(err, types.ErrorCodeConvertRequestFailed, types.ErrOptionWithSkipRetry())
 	}
@@ -76,12 +86,17 @@ func EmbeddingHelper(c *gin.Context, info *relaycommon.RelayInfo) (newAPIError *
 		}
 	}
 
+	cacheRecorder := service.StartResponseCacheRecord(c, cacheKey)
+	responseSpan := startResponseSpan(c)
 	usage, newAPIError := adaptor.DoResponse(c, httpResp, info)
+	endResponseSpan(responseSpan, newAPIError)
 	if newAPIError != nil {
+		cacheRecorder.Finish(c, info, nil)
 		// reset status code 重置状态码
 		service.ResetStatusCode(newAPIError, statusCodeMappingStr)
 		return newAPIError
 	}
+	cacheRecorder.Finish(c, info, usage.(*dto.Usage))
 	postConsumeQuota(c, info, usage.(*dto.Usage))
 	return nil
 }
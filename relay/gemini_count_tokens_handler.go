@@ -0,0 +1,56 @@
+package relay
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/relay/channel"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/relay/helper"
+	"github.com/QuantumNous/new-api/service"
+	"github.com/QuantumNous/new-api/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GeminiCountTokensHelper 处理 models/{model}:countTokens，不计费。
+// 渠道支持上游计数时直接转发，否则（或上游失败时）在本地估算。
+func GeminiCountTokensHelper(c *gin.Context, info *relaycommon.RelayInfo) *types.NewAPIError {
+	info.InitChannelMeta(c)
+
+	countReq, ok := info.Request.(*dto.GeminiCountTokensRequest)
+	if !ok {
+		return types.NewErrorWithStatusCode(fmt.Errorf("invalid request type, expected *dto.GeminiCountTokensRequest, got %T", info.Request), types.ErrorCodeInvalidRequest, http.StatusBadRequest, types.ErrOptionWithSkipRetry())
+	}
+
+	request, err := common.DeepCopy(countReq)
+	if err != nil {
+		return types.NewError(fmt.Errorf("failed to copy request to GeminiCountTokensRequest: %w", err), types.ErrorCodeInvalidRequest, types.ErrOptionWithSkipRetry())
+	}
+
+	err = helper.ModelMappedHelper(c, info, request)
+	if err != nil {
+		return types.NewError(err, types.ErrorCodeChannelModelMappedError, types.ErrOptionWithSkipRetry())
+	}
+
+	adaptor := GetAdaptor(info.ApiType)
+	if counter, ok := adaptor.(channel.GeminiTokenCounter); ok {
+		adaptor.Init(info)
+		countResponse, err := counter.CountGeminiTokens(c, info, request)
+		if err == nil {
+			c.JSON(http.StatusOK, countResponse)
+			return nil
+		}
+		logger.LogWarn(c, fmt.Sprintf("upstream countTokens failed, fallback to local estimation: %s", err.Error()))
+	}
+
+	tokens, err := service.EstimateCountTokens(c, countReq, info)
+	if err != nil {
+		return types.NewError(err, types.ErrorCodeCountTokenFailed, types.ErrOptionWithSkipRetry())
+	}
+	c.JSON(http.StatusOK, dto.GeminiCountTokensResponse{TotalTokens: tokens})
+	return nil
+}
@@ -0,0 +1,97 @@
+package relay
+
+import (
+	"bytes"
+	"net/http"
+
+	"github.com/QuantumNous/new-api/common"
+	appconstant "github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/relay/channel"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	relayconstant "github.com/QuantumNous/new-api/relay/constant"
+	"github.com/QuantumNous/new-api/service"
+	"github.com/QuantumNous/new-api/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+// supportsNativeGeminiEmbedding 适配器能直接处理 embedContent / batchEmbedContents，其余类型转换为 OpenAI Embedding
+func supportsNativeGeminiEmbedding(apiType int) bool {
+	return apiType == appconstant.APITypeGemini || apiType == appconstant.APITypeVertexAi
+}
+
+// bufferedResponseWriter 缓存适配器写出的完整响应，由调用方转换格式后再写给客户端
+type bufferedResponseWriter struct {
+	gin.ResponseWriter
+	buf bytes.Buffer
+}
+
+func (w *bufferedResponseWriter) Write(data []byte) (int, error) {
+	return w.buf.Write(data)
+}
+
+func (w *bufferedResponseWriter) WriteString(s string) (int, error) {
+	return w.buf.WriteString(s)
+}
+
+// Flush 转换完成前不能发送响应头
+func (w *bufferedResponseWriter) Flush() {
+}
+
+func (w *bufferedResponseWriter) discard() {
+	if w.buf.Len() > 0 {
+		_, _ = w.ResponseWriter.Write(w.buf.Bytes())
+		w.buf.Reset()
+	}
+}
+
+// flushBody 写出最终响应体，Content-Length 已随格式转换失效
+func (w *bufferedResponseWriter) flushBody(body []byte) {
+	w.Header().Del("Content-Length")
+	_, _ = w.ResponseWriter.Write(body)
+	w.ResponseWriter.Flush()
+}
+
+// geminiEmbeddingViaOpenAI 将 Gemini embedContent / batchEmbedContents 请求转换为 OpenAI Embedding 发往上游，
+// 再把适配器输出的 embedding 响应改写为 Gemini 格式
+func geminiEmbeddingViaOpenAI(c *gin.Context, info *relaycommon.RelayInfo, adaptor channel.Adaptor, requests []*dto.GeminiEmbeddingRequest, isBatch bool) (*dto.Usage, *types.NewAPIError) {
+	embeddingRequest := service.GeminiEmbeddingToOpenAIRequest(requests, info.UpstreamModelName)
+	info.AppendRequestConversion(types.RelayFormatEmbedding)
+
+	var writer *bufferedResponseWriter
+	usage, newAPIError := relayRequestAs(c, info, adaptor, relayAsFormat{
+		relayMode:      relayconstant.RelayModeEmbeddings,
+		requestURLPath: "/v1/embeddings",
+		relayFormat:    types.RelayFormatEmbedding,
+		spanName:       "embedding",
+		convert: func() (any, error) {
+			return adaptor.ConvertEmbeddingRequest(c, info, *embeddingRequest)
+		},
+		newWriter: func() relayAsFormatWriter {
+			writer = &bufferedResponseWriter{ResponseWriter: c.Writer}
+			return writer
+		},
+	})
+	if newAPIError != nil {
+		return nil, newAPIError
+	}
+
+	body := writer.buf.Bytes()
+	var embeddingResponse dto.OpenAIEmbeddingResponse
+	if writer.Status() == http.StatusOK && common.Unmarshal(body, &embeddingResponse) == nil {
+		if converted, err := common.Marshal(service.ResponseOpenAIEmbedding2Gemini(&embeddingResponse, isBatch)); err == nil {
+			body = converted
+		} else {
+			logger.LogError(c, "error marshalling gemini embedding response: "+err.Error())
+		}
+	}
+	writer.flushBody(body)
+
+	usageDto, _ := usage.(*dto.Usage)
+	if usageDto == nil {
+		usageDto = &dto.Usage{}
+	}
+	return usageDto, nil
+}
@@ -11,8 +11,10 @@ import (
 	"github.com/QuantumNous/new-api/constant"
 	"github.com/QuantumNous/new-api/dto"
 	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/pkg/tracing"
 	"github.com/QuantumNous/new-api/relay/channel/gemini"
 	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	relayconstant "github.com/QuantumNous/new-api/relay/constant"
 	"github.com/QuantumNous/new-api/relay/helper"
 	"github.com/QuantumNous/new-api/service"
 	"github.com/QuantumNous/new-api/setting/model_setting"
@@ -145,7 +147,9 @@ func GeminiHelper(c *gin.Context, info *relaycommon.RelayInfo) (newAPIError *typ
 		requestBody = common.ReaderOnly(storage)
 	} else {
 		// 使用 ConvertGeminiRequest 转换请求格式
+		convertSpan := startConvertSpan(c, "gemini")
 		convertedRequest, err := adaptor.ConvertGeminiRequest(c, info, request)
+		tracing.End(convertSpan, err)
 		if err != nil {
 			return types.NewError(err, types.ErrorCodeConvertRequestFailed, types.ErrOptionWithSkipRetry())
 		}
@@ -188,7 +192,9 @@ func GeminiHelper(c *gin.Context, info *relaycommon.RelayInfo) (newAPIError *typ
 		}
 	}
 
+	responseSpan := startResponseSpan(c)
 	usage, openaiErr := adaptor.DoResponse(c, resp.(*http.Response), info)
+	endResponseSpan(responseSpan, openaiErr)
 	if openaiErr != nil {
 		service.ResetStatusCode(openaiErr, statusCodeMappingStr)
 		return openaiErr
@@ -201,12 +207,12 @@ func GeminiHelper(c *gin.Context, info *relaycommon.RelayInfo) (newAPIError *typ
 func GeminiEmbeddingHandler(c *gin.Context, info *relaycommon.RelayInfo) (newAPIError *types.NewAPIError) {
 	info.InitChannelMeta(c)
 
-	isBatch := strings.HasSuffix(c.Request.URL.Path, "batchEmbedContents")
+	isBatch := relayconstant.GetGeminiAction(c.Request.URL.Path) == relayconstant.GeminiActionBatchEmbedContents
 	info.IsGeminiBatchEmbedding = isBatch
 
 	var req dto.Request
 	var err error
-	var inputTexts []string
+	var embeddingRequests []*dto.GeminiEmbeddingRequest
 
 	if isBatch {
 		batchRequest := &dto.GeminiBatchEmbeddingRequest{}
@@ -215,13 +221,7 @@ func GeminiEmbeddingHandler(c *gin.Context, info *relaycommon.RelayInfo) (newAPI
 			return types.NewError(err, types.ErrorCodeInvalidRequest, types.ErrOptionWithSkipRetry())
 		}
 		req = batchRequest
-		for _, r := range batchRequest.Requests {
-			for _, part := range r.Content.Parts {
-				if part.Text != "" {
-					inputTexts = append(inputTexts, part.Text)
-				}
-			}
-		}
+		embeddingRequests = batchRequest.Requests
 	} else {
 		singleRequest := &dto.GeminiEmbeddingRequest{}
 		err = common.UnmarshalBodyReusable(c, singleRequest)
@@ -229,11 +229,7 @@ func GeminiEmbeddingHandler(c *gin.Context, info *relaycommon.RelayInfo) (newAPI
 			return types.NewError(err, types.ErrorCodeInvalidRequest, types.ErrOptionWithSkipRetry())
 		}
 		req = singleRequest
-		for _, part := range singleRequest.Content.Parts {
-			if part.Text != "" {
-				inputTexts = append(inputTexts, part.Text)
-			}
-		}
+		embeddingRequests = []*dto.GeminiEmbeddingRequest{singleRequest}
 	}
 
 	err = helper.ModelMappedHelper(c, info, req)
@@ -249,6 +245,15 @@ func GeminiEmbeddingHandler(c *gin.Context, info *relaycommon.RelayInfo) (newAPI
 	}
 	adaptor.Init(info)
 
+	if !supportsNativeGeminiEmbedding(info.ApiType) {
+		usage, newAPIError := geminiEmbeddingViaOpenAI(c, info, adaptor, embeddingRequests, isBatch)
+		if newAPIError != nil {
+			return newAPIError
+		}
+		postConsumeQuota(c, info, usage)
+		return nil
+	}
+
 	var requestBody io.Reader
 	jsonData, err := common.Marshal(req)
 	if err != nil {
@@ -282,7 +287,9 @@ func GeminiEmbeddingHandler(c *gin.Context, info *relaycommon.RelayInfo) (newAPI
 		}
 	}
 
+	responseSpan := startResponseSpan(c)
 	usage, openaiErr := adaptor.DoResponse(c, resp.(*http.Response), info)
+	endResponseSpan(responseSpan, openaiErr)
 	if openaiErr != nil {
 		service.ResetStatusCode(openaiErr, statusCodeMappingStr)
 		return openaiErr
@@ -78,6 +78,18 @@ func ResponseChunkData(c *gin.Context, resp dto.ResponsesStreamResponse, data st
 	_ = FlushWriter(c)
 }
 
+// ResponsesEventsData 下发本地转换生成的 Responses 流事件
+func ResponsesEventsData(c *gin.Context, events []dto.ResponsesStreamResponse) error {
+	for _, event := range events {
+		data, err := common.Marshal(event)
+		if err != nil {
+			return fmt.Errorf("error marshalling responses event: %w", err)
+		}
+		ResponseChunkData(c, event, string(data))
+	}
+	return nil
+}
+
 func StringData(c *gin.Context, str string) error {
 	if c == nil || c.Writer == nil {
 		return errors.New("context or writer is nil")
@@ -160,6 +172,12 @@ func GetResponseID(c *gin.Context) string {
 	return fmt.Sprintf("chatcmpl-%s", logID)
 }
 
+// GetResponsesID 本地转换生成 /v1/responses 响应时使用的 id
+func GetResponsesID(c *gin.Context) string {
+	logID := c.GetString(common.RequestIdKey)
+	return fmt.Sprintf("resp_%s", logID)
+}
+
 func GetLocalRealtimeID(c *gin.Context) string {
 	logID := c.GetString(common.RequestIdKey)
 	return fmt.Sprintf("evt_%s", logID)
@@ -4,6 +4,7 @@ import (
 	"fmt"
 
 	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
 	"github.com/QuantumNous/new-api/logger"
 	relaycommon "github.com/QuantumNous/new-api/relay/common"
 	"github.com/QuantumNous/new-api/setting/operation_setting"
@@ -42,6 +43,11 @@ func HandleGroupRatio(ctx *gin.Context, relayInfo *relaycommon.RelayInfo) types.
 		groupRatioInfo.GroupRatio = ratio_setting.GetGroupRatio(relayInfo.UsingGroup)
 	}
 
+	// batch requests are billed at the discounted batch ratio
+	if common.GetContextKeyString(ctx, constant.ContextKeyBatchId) != "" {
+		groupRatioInfo.GroupRatio *= ratio_setting.GetBatchRatio(relayInfo.OriginModelName)
+	}
+
 	return groupRatioInfo
 }
 
@@ -24,11 +24,14 @@ func GetAndValidateRequest(c *gin.Context, format types.RelayFormat) (request dt
 	case types.RelayFormatOpenAI:
 		request, err = GetAndValidateTextRequest(c, relayMode)
 	case types.RelayFormatGemini:
-		if strings.Contains(c.Request.URL.Path, ":embedContent") {
+		switch relayconstant.GetGeminiAction(c.Request.URL.Path) {
+		case relayconstant.GeminiActionEmbedContent:
 			request, err = GetAndValidateGeminiEmbeddingRequest(c)
-		} else if strings.Contains(c.Request.URL.Path, ":batchEmbedContents") {
+		case relayconstant.GeminiActionBatchEmbedContents:
 			request, err = GetAndValidateGeminiBatchEmbeddingRequest(c)
-		} else {
+		case relayconstant.GeminiActionCountTokens:
+			request, err = GetAndValidateGeminiCountTokensRequest(c)
+		default:
 			request, err = GetAndValidateGeminiRequest(c)
 		}
 	case types.RelayFormatClaude:
@@ -344,6 +347,18 @@ func GetAndValidateGeminiBatchEmbeddingRequest(c *gin.Context) (*dto.GeminiBatch
 	return request, nil
 }
 
+func GetAndValidateGeminiCountTokensRequest(c *gin.Context) (*dto.GeminiCountTokensRequest, error) {
+	request := &dto.GeminiCountTokensRequest{}
+	err := common.UnmarshalBodyReusable(c, request)
+	if err != nil {
+		return nil, err
+	}
+	if len(request.Contents) == 0 && request.GenerateContentRequest == nil {
+		return nil, errors.New("contents or generateContentRequest is required")
+	}
+	return request, nil
+}
+
 func GetAndValidateElementRequest(c *gin.Context) (*dto.ElementRequest, error) {
 	request := &dto.ElementRequest{}
 	err := common.UnmarshalBodyReusable(c, request)
@@ -62,7 +62,9 @@ func IdentifyFaceHelper(c *gin.Context, info *relaycommon.RelayInfo) (newAPIErro
 		}
 	}
 
+	responseSpan := startResponseSpan(c)
 	usage, newAPIError := adaptor.DoResponse(c, httpResp, info)
+	endResponseSpan(responseSpan, newAPIError)
 	if newAPIError != nil {
 		service.ResetStatusCode(newAPIError, statusCodeMappingStr)
 		return newAPIError
@@ -0,0 +1,111 @@
+package relay
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/pkg/tracing"
+	"github.com/QuantumNous/new-api/relay/channel"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/service"
+	"github.com/QuantumNous/new-api/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+// relayAsFormatWriter 接管适配器写出的响应，转发结束后由调用方改写为客户端请求的格式
+type relayAsFormatWriter interface {
+	gin.ResponseWriter
+	// discard 适配器返回错误时原样写出已缓存的内容
+	discard()
+}
+
+// relayAsFormat 以另一种格式转发请求时的目标格式，RelayInfo 的模式、路径与格式仅在转发期间切换
+type relayAsFormat struct {
+	relayMode      int
+	requestURLPath string
+	relayFormat    types.RelayFormat
+	spanName       string
+	// convert 调用适配器把转换后的请求转为上游格式
+	convert func() (any, error)
+	// newWriter 在收到上游响应后创建，此时 info.IsStream 已按响应类型更新
+	newWriter func() relayAsFormatWriter
+}
+
+// relayRequestAs 按目标格式转换请求并发往上游，请求体与原生路径一样经过禁用字段清理与参数覆盖；
+// 适配器写出的响应交给 newWriter 创建的 writer，返回适配器统计的用量
+func relayRequestAs(c *gin.Context, info *relaycommon.RelayInfo, adaptor channel.Adaptor, target relayAsFormat) (any, *types.NewAPIError) {
+	savedRelayMode := info.RelayMode
+	savedRequestURLPath := info.RequestURLPath
+	savedRelayFormat := info.RelayFormat
+	defer func() {
+		info.RelayMode = savedRelayMode
+		info.RequestURLPath = savedRequestURLPath
+		info.RelayFormat = savedRelayFormat
+	}()
+
+	info.RelayMode = target.relayMode
+	info.RequestURLPath = target.requestURLPath
+	info.RelayFormat = target.relayFormat
+
+	convertSpan := startConvertSpan(c, target.spanName)
+	convertedRequest, err := target.convert()
+	tracing.End(convertSpan, err)
+	if err != nil {
+		return nil, types.NewError(err, types.ErrorCodeConvertRequestFailed, types.ErrOptionWithSkipRetry())
+	}
+	relaycommon.AppendRequestConversionFromRequest(info, convertedRequest)
+
+	jsonData, err := common.Marshal(convertedRequest)
+	if err != nil {
+		return nil, types.NewError(err, types.ErrorCodeConvertRequestFailed, types.ErrOptionWithSkipRetry())
+	}
+
+	jsonData, err = relaycommon.RemoveDisabledFields(jsonData, info.ChannelOtherSettings, info.ChannelSetting.PassThroughBodyEnabled)
+	if err != nil {
+		return nil, types.NewError(err, types.ErrorCodeConvertRequestFailed, types.ErrOptionWithSkipRetry())
+	}
+
+	if len(info.ParamOverride) > 0 {
+		jsonData, err = relaycommon.ApplyParamOverrideWithRelayInfo(jsonData, info)
+		if err != nil {
+			return nil, newAPIErrorFromParamOverride(err)
+		}
+	}
+	logger.LogDebug(c, fmt.Sprintf("converted %s request body: %s", target.spanName, string(jsonData)))
+
+	resp, err := adaptor.DoRequest(c, info, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, types.NewOpenAIError(err, types.ErrorCodeDoRequestFailed, http.StatusInternalServerError)
+	}
+
+	statusCodeMappingStr := c.GetString("status_code_mapping")
+	var httpResp *http.Response
+	if resp != nil {
+		httpResp = resp.(*http.Response)
+		info.IsStream = info.IsStream || strings.HasPrefix(httpResp.Header.Get("Content-Type"), "text/event-stream")
+		if httpResp.StatusCode != http.StatusOK {
+			newAPIError := service.RelayErrorHandler(c.Request.Context(), httpResp, false)
+			service.ResetStatusCode(newAPIError, statusCodeMappingStr)
+			return nil, newAPIError
+		}
+	}
+
+	originWriter := c.Writer
+	writer := target.newWriter()
+	c.Writer = writer
+	responseSpan := startResponseSpan(c)
+	usage, newAPIError := adaptor.DoResponse(c, httpResp, info)
+	endResponseSpan(responseSpan, newAPIError)
+	c.Writer = originWriter
+	if newAPIError != nil {
+		writer.discard()
+		service.ResetStatusCode(newAPIError, statusCodeMappingStr)
+		return nil, newAPIError
+	}
+	return usage, nil
+}
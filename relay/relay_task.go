@@ -36,8 +36,13 @@ type TaskSubmitResult struct {
 // 以及提取 OtherRatios（时长、分辨率）。
 // 该函数在控制器的重试循环之前调用一次，其结果通过 info 字段和上下文持久化。
 func ResolveOriginTask(c *gin.Context, info *relaycommon.RelayInfo) *dto.TaskError {
-	// 检测 remix action
 	path := c.Request.URL.Path
+	if strings.HasPrefix(path, "/v1/fine_tuning/jobs") {
+		info.Action = constant.TaskActionFineTune
+		return resolveFineTuningChannel(c, info)
+	}
+
+	// 检测 remix action
 	if strings.Contains(path, "/v1/videos/") && strings.HasSuffix(path, "/remix") {
 		info.Action = constant.TaskActionRemix
 	}
@@ -136,6 +141,49 @@ func ResolveOriginTask(c *gin.Context, info *relaycommon.RelayInfo) *dto.TaskErr
 	return nil
 }
 
+// resolveFineTuningChannel 微调任务引用的训练/验证文件只存在于上传时的渠道和 key 上，
+// 因此将渠道锁定为训练文件所在渠道，并使用上传时的 key。
+func resolveFineTuningChannel(c *gin.Context, info *relaycommon.RelayInfo) *dto.TaskError {
+	var req dto.FineTuningJobRequest
+	if err := common.UnmarshalBodyReusable(c, &req); err != nil {
+		return service.TaskErrorWrapperLocal(err, "invalid_request", http.StatusBadRequest)
+	}
+	trainingFile, err := model.GetUserFileById(info.UserId, req.TrainingFile)
+	if err != nil {
+		return service.TaskErrorWrapper(err, "get_training_file_failed", http.StatusInternalServerError)
+	}
+	if trainingFile == nil || trainingFile.IsLocal() {
+		return service.TaskErrorWrapperLocal(fmt.Errorf("training file %s not found", req.TrainingFile), "file_not_found", http.StatusBadRequest)
+	}
+	if trainingFile.Purpose != "fine-tune" {
+		return service.TaskErrorWrapperLocal(fmt.Errorf("file %s must be uploaded with purpose 'fine-tune'", req.TrainingFile), "invalid_request", http.StatusBadRequest)
+	}
+	if req.ValidationFile != "" {
+		validationFile, err := model.GetUserFileById(info.UserId, req.ValidationFile)
+		if err != nil {
+			return service.TaskErrorWrapper(err, "get_validation_file_failed", http.StatusInternalServerError)
+		}
+		if validationFile == nil || validationFile.ChannelId != trainingFile.ChannelId {
+			return service.TaskErrorWrapperLocal(fmt.Errorf("validation file %s not found on the channel of the training file", req.ValidationFile), "file_not_found", http.StatusBadRequest)
+		}
+	}
+
+	ch, err := service.GetPinnedFileChannel(trainingFile)
+	if err != nil {
+		return service.TaskErrorWrapperLocal(err, "task_channel_disable", http.StatusBadRequest)
+	}
+	if ch.Type == constant.ChannelTypeAzure {
+		return service.TaskErrorWrapperLocal(errors.New("fine-tuning is not supported on azure channels"), "invalid_api_platform", http.StatusBadRequest)
+	}
+	if setupErr := service.SetupContextForSelectedChannel(c, ch, info.OriginModelName); setupErr != nil {
+		return service.TaskErrorWrapperLocal(setupErr.Err, "setup_locked_channel_failed", http.StatusInternalServerError)
+	}
+	common.SetContextKey(c, constant.ContextKeyChannelKey, service.GetChannelKeyByIndex(ch, trainingFile.ChannelKeyIdx))
+	common.SetContextKey(c, constant.ContextKeyChannelMultiKeyIndex, trainingFile.ChannelKeyIdx)
+	info.LockedChannel = ch
+	return nil
+}
+
 // RelayTaskSubmit 完成 task 提交的全部流程（每次尝试调用一次）：
 // 刷新渠道元数据 → 确定 platform/adaptor → 验证请求 →
 // 估算计费(EstimateBilling) → 计算价格 → 预扣费（仅首次）→
@@ -0,0 +1,201 @@
+package relay
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/QuantumNous/new-api/common"
+	appconstant "github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/relay/channel"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	relayconstant "github.com/QuantumNous/new-api/relay/constant"
+	"github.com/QuantumNous/new-api/relay/helper"
+	"github.com/QuantumNous/new-api/service"
+	"github.com/QuantumNous/new-api/types"
+
+	"github.com/gin-gonic/gin"
+	"github.com/samber/lo"
+)
+
+// supportsNativeResponses 适配器能直接转换 /v1/responses 请求，其余类型降级为 Chat Completions。
+// adaptor 需已 Init，实现 ResponsesCapability 的适配器（Vertex）按模型自行判断
+func supportsNativeResponses(apiType int, adaptor channel.Adaptor) bool {
+	if capability, ok := adaptor.(channel.ResponsesCapability); ok {
+		return capability.SupportsNativeResponses()
+	}
+	switch apiType {
+	case appconstant.APITypeOpenAI, appconstant.APITypeCodex, appconstant.APITypeOpenRouter, appconstant.APITypeXinference,
+		appconstant.APITypeAnthropic, appconstant.APITypeGemini,
+		appconstant.APITypeAli, appconstant.APITypeVolcEngine, appconstant.APITypePerplexity,
+		appconstant.APITypeCloudflare, appconstant.APITypeXai:
+		return true
+	}
+	return false
+}
+
+// responsesViaChatWriter 拦截适配器以 Chat Completions 格式写出的响应，改写为 Responses 格式：
+// 流式响应逐行转换为 Responses 事件，非流式响应缓存到 finish 时整体转换
+type responsesViaChatWriter struct {
+	gin.ResponseWriter
+	c         *gin.Context
+	stream    bool
+	converter *service.ChatResponsesStreamConverter
+	buf       bytes.Buffer
+}
+
+func newResponsesViaChatWriter(c *gin.Context, info *relaycommon.RelayInfo) *responsesViaChatWriter {
+	return &responsesViaChatWriter{
+		ResponseWriter: c.Writer,
+		c:              c,
+		stream:         info.IsStream,
+		converter:      service.NewChatResponsesStreamConverter(helper.GetResponsesID(c), common.GetTimestamp(), info.UpstreamModelName),
+	}
+}
+
+func (w *responsesViaChatWriter) Write(data []byte) (int, error) {
+	w.buf.Write(data)
+	if w.stream {
+		w.processLines()
+	}
+	return len(data), nil
+}
+
+func (w *responsesViaChatWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// Flush 非流式响应需要在转换后才能确定 Content-Length，不能提前发送响应头
+func (w *responsesViaChatWriter) Flush() {
+	if w.stream {
+		w.ResponseWriter.Flush()
+	}
+}
+
+func (w *responsesViaChatWriter) processLines() {
+	for {
+		idx := bytes.IndexByte(w.buf.Bytes(), '\n')
+		if idx < 0 {
+			return
+		}
+		w.handleLine(strings.TrimRight(string(w.buf.Next(idx+1)), "\r\n"))
+	}
+}
+
+func (w *responsesViaChatWriter) handleLine(line string) {
+	if strings.HasPrefix(line, ":") {
+		// 保活注释原样透传
+		_, _ = w.ResponseWriter.WriteString(line + "\n\n")
+		w.ResponseWriter.Flush()
+		return
+	}
+	data, ok := strings.CutPrefix(line, "data:")
+	if !ok {
+		return
+	}
+	data = strings.TrimSpace(data)
+	if data == "" || data == "[DONE]" {
+		return
+	}
+	var chunk dto.ChatCompletionsStreamResponse
+	if err := common.UnmarshalJsonStr(data, &chunk); err != nil {
+		logger.LogError(w.c, "error unmarshalling chat stream response: "+err.Error())
+		return
+	}
+	w.writeEvents(w.converter.Convert(&chunk))
+}
+
+func (w *responsesViaChatWriter) writeEvents(events []dto.ResponsesStreamResponse) {
+	for _, event := range events {
+		data, err := common.Marshal(event)
+		if err != nil {
+			logger.LogError(w.c, "error marshalling responses event: "+err.Error())
+			continue
+		}
+		_, _ = w.ResponseWriter.WriteString(fmt.Sprintf("event: %s\ndata: %s\n\n", event.Type, data))
+	}
+	if len(events) > 0 {
+		w.ResponseWriter.Flush()
+	}
+}
+
+// finish 发送最终事件或转换后的非流式响应，usage 为适配器统计的计费用量
+func (w *responsesViaChatWriter) finish(usage *dto.Usage) {
+	if w.stream {
+		if w.buf.Len() > 0 {
+			w.handleLine(strings.TrimRight(w.buf.String(), "\r\n"))
+			w.buf.Reset()
+		}
+		w.writeEvents(w.converter.Finish(usage))
+		return
+	}
+
+	body := w.buf.Bytes()
+	var chatResponse dto.OpenAITextResponse
+	if w.Status() == http.StatusOK && common.Unmarshal(body, &chatResponse) == nil && len(chatResponse.Choices) > 0 {
+		responsesResponse := service.ChatCompletionsResponseToResponsesResponse(&chatResponse, helper.GetResponsesID(w.c), common.GetTimestamp())
+		if usage != nil {
+			responsesResponse.Usage = service.ChatUsageToResponsesUsage(usage)
+		}
+		if converted, err := common.Marshal(responsesResponse); err == nil {
+			body = converted
+		} else {
+			logger.LogError(w.c, "error marshalling responses response: "+err.Error())
+		}
+	}
+	w.Header().Del("Content-Length")
+	_, _ = w.ResponseWriter.Write(body)
+	w.ResponseWriter.Flush()
+}
+
+// discard 适配器返回错误时原样写出已缓存的内容
+func (w *responsesViaChatWriter) discard() {
+	if w.buf.Len() > 0 {
+		_, _ = w.ResponseWriter.Write(w.buf.Bytes())
+		w.buf.Reset()
+	}
+}
+
+// responsesViaChatCompletions 将 Responses 请求降级为 Chat Completions 发往上游，
+// 再把适配器输出的 chat 响应改写为 Responses 格式
+func responsesViaChatCompletions(c *gin.Context, info *relaycommon.RelayInfo, adaptor channel.Adaptor, request *dto.OpenAIResponsesRequest) (*dto.Usage, *types.NewAPIError) {
+	chatReq, err := service.ResponsesRequestToChatCompletionsRequest(request)
+	if err != nil {
+		return nil, types.NewErrorWithStatusCode(err, types.ErrorCodeInvalidRequest, http.StatusBadRequest, types.ErrOptionWithSkipRetry())
+	}
+	if lo.FromPtrOr(chatReq.Stream, false) {
+		info.ShouldIncludeUsage = true
+		if info.SupportStreamOptions {
+			chatReq.StreamOptions = &dto.StreamOptions{IncludeUsage: true}
+		}
+	}
+	info.AppendRequestConversion(types.RelayFormatOpenAI)
+
+	var writer *responsesViaChatWriter
+	usage, newApiErr := relayRequestAs(c, info, adaptor, relayAsFormat{
+		relayMode:      relayconstant.RelayModeChatCompletions,
+		requestURLPath: "/v1/chat/completions",
+		relayFormat:    types.RelayFormatOpenAI,
+		spanName:       "openai",
+		convert: func() (any, error) {
+			return adaptor.ConvertOpenAIRequest(c, info, chatReq)
+		},
+		newWriter: func() relayAsFormatWriter {
+			writer = newResponsesViaChatWriter(c, info)
+			return writer
+		},
+	})
+	if newApiErr != nil {
+		return nil, newApiErr
+	}
+
+	usageDto, _ := usage.(*dto.Usage)
+	writer.finish(usageDto)
+	if usageDto == nil {
+		usageDto = &dto.Usage{}
+	}
+	return usageDto, nil
+}
@@ -0,0 +1,27 @@
+package relay
+
+import (
+	"testing"
+
+	appconstant "github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/relay/channel"
+
+	"github.com/stretchr/testify/require"
+)
+
+type responsesCapabilityAdaptor struct {
+	channel.Adaptor
+	native bool
+}
+
+func (a *responsesCapabilityAdaptor) SupportsNativeResponses() bool {
+	return a.native
+}
+
+func TestSupportsNativeResponsesCapability(t *testing.T) {
+	// 实现 ResponsesCapability 的适配器按模型判断，优先于 API 类型
+	require.True(t, supportsNativeResponses(appconstant.APITypeVertexAi, &responsesCapabilityAdaptor{native: true}))
+	require.False(t, supportsNativeResponses(appconstant.APITypeVertexAi, &responsesCapabilityAdaptor{native: false}))
+	require.False(t, supportsNativeResponses(appconstant.APITypeVertexAi, nil))
+	require.True(t, supportsNativeResponses(appconstant.APITypeOpenAI, nil))
+}
@@ -0,0 +1,247 @@
+package relay
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	appconstant "github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/relay/channel"
+	"github.com/QuantumNous/new-api/relay/channel/openai"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/relay/helper"
+	"github.com/QuantumNous/new-api/service"
+	"github.com/QuantumNous/new-api/setting"
+	"github.com/QuantumNous/new-api/setting/ratio_setting"
+	"github.com/QuantumNous/new-api/types"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+const responsesWssEventCreate = "response.create"
+
+// ResponsesWssHelper Responses API 的 WebSocket 模式：握手时选定的渠道在整个连接期间固定使用，
+// 客户端的 response.create 依次转发到同一条上游连接，每个响应按普通 Responses 请求预扣与结算
+func ResponsesWssHelper(c *gin.Context, clientWs *websocket.Conn) *types.NewAPIError {
+	pinnedModel := c.Query("model")
+	request := &dto.OpenAIResponsesRequest{Model: pinnedModel}
+	info, err := relaycommon.GenRelayInfo(c, types.RelayFormatOpenAIResponses, request, nil)
+	if err != nil {
+		return types.NewError(err, types.ErrorCodeGenRelayInfoFailed)
+	}
+	info.InitChannelMeta(c)
+	switch info.ApiType {
+	case appconstant.APITypeOpenAI, appconstant.APITypeCodex:
+	default:
+		return types.NewErrorWithStatusCode(
+			fmt.Errorf("responses websocket mode is not supported for api type %d", info.ApiType),
+			types.ErrorCodeInvalidRequest,
+			http.StatusBadRequest,
+			types.ErrOptionWithSkipRetry(),
+		)
+	}
+	if err := helper.ModelMappedHelper(c, info, request); err != nil {
+		return types.NewError(err, types.ErrorCodeChannelModelMappedError, types.ErrOptionWithSkipRetry())
+	}
+	adaptor := GetAdaptor(info.ApiType)
+	if adaptor == nil {
+		return types.NewError(fmt.Errorf("invalid api type: %d", info.ApiType), types.ErrorCodeInvalidApiType, types.ErrOptionWithSkipRetry())
+	}
+	adaptor.Init(info)
+	upstream, err := channel.DoWssRequest(adaptor, c, info, nil)
+	if err != nil {
+		return types.NewError(err, types.ErrorCodeDoRequestFailed)
+	}
+	defer upstream.Close()
+
+	for {
+		_, message, err := clientWs.ReadMessage()
+		if err != nil {
+			if !websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				logger.LogError(c, "error reading from client: "+err.Error())
+			}
+			return nil
+		}
+		var event struct {
+			Type string `json:"type"`
+		}
+		if err := common.Unmarshal(message, &event); err != nil || event.Type != responsesWssEventCreate {
+			helper.WssError(c, clientWs, types.NewError(
+				fmt.Errorf("unsupported event type %q, only %s is supported", event.Type, responsesWssEventCreate),
+				types.ErrorCodeInvalidRequest,
+			).ToOpenAIError())
+			continue
+		}
+		newAPIError, closeConn := responsesWssCreate(c, clientWs, upstream, pinnedModel, message)
+		if newAPIError != nil {
+			logger.LogError(c, fmt.Sprintf("responses websocket error: %s", newAPIError.Error()))
+			if closeConn {
+				return newAPIError
+			}
+			newAPIError.SetMessage(common.MessageWithRequestId(newAPIError.Error(), c.GetString(common.RequestIdKey)))
+			helper.WssError(c, clientWs, newAPIError.ToOpenAIError())
+		} else if closeConn {
+			return nil
+		}
+	}
+}
+
+// responsesWssCheckModel 请求的模型须在令牌可用范围内，且由握手时固定的渠道提供
+func responsesWssCheckModel(c *gin.Context, pinnedModel string, modelName string) error {
+	if modelName == pinnedModel {
+		return nil
+	}
+	if common.GetContextKeyBool(c, appconstant.ContextKeyTokenModelLimitEnabled) {
+		limits, _ := common.GetContextKeyType[map[string]bool](c, appconstant.ContextKeyTokenModelLimit)
+		if _, ok := limits[ratio_setting.FormatMatchingModelName(modelName)]; !ok {
+			return fmt.Errorf("this token has no access to model %s", modelName)
+		}
+	}
+	pinned, err := model.CacheGetChannel(common.GetContextKeyInt(c, appconstant.ContextKeyChannelId))
+	if err != nil {
+		return err
+	}
+	if !slices.Contains(pinned.GetModels(), modelName) {
+		return fmt.Errorf("model %s is not available on the channel of this connection, reconnect with ?model=%s", modelName, modelName)
+	}
+	return nil
+}
+
+// responsesWssCreate 处理一次 response.create，直到上游返回终止事件。
+// 返回的 closeConn 表示连接已不可用（上游读写失败或客户端已断开），需要结束整个会话
+func responsesWssCreate(c *gin.Context, clientWs *websocket.Conn, upstream *websocket.Conn, pinnedModel string, message []byte) (newAPIError *types.NewAPIError, closeConn bool) {
+	request := &dto.OpenAIResponsesRequest{}
+	if err := common.Unmarshal(message, request); err != nil {
+		return types.NewError(err, types.ErrorCodeInvalidRequest, types.ErrOptionWithSkipRetry()), false
+	}
+	if request.Model == "" {
+		request.Model = pinnedModel
+	}
+	if err := responsesWssCheckModel(c, pinnedModel, request.Model); err != nil {
+		return types.NewError(err, types.ErrorCodeInvalidRequest, types.ErrOptionWithSkipRetry()), false
+	}
+	common.SetContextKey(c, appconstant.ContextKeyOriginalModel, request.Model)
+
+	info, err := relaycommon.GenRelayInfo(c, types.RelayFormatOpenAIResponses, request, nil)
+	if err != nil {
+		return types.NewError(err, types.ErrorCodeGenRelayInfoFailed), false
+	}
+	// 同一连接内的每个响应单独计时
+	info.StartTime = time.Now()
+	info.IsStream = true
+
+	meta := request.GetTokenCountMeta()
+	if setting.ShouldCheckPromptSensitive() {
+		if contains, words := service.CheckSensitiveText(meta.CombineText); contains {
+			logger.LogWarn(c, fmt.Sprintf("user sensitive words detected: %s", strings.Join(words, ", ")))
+			return types.NewError(fmt.Errorf("sensitive words detected"), types.ErrorCodeSensitiveWordsDetected), false
+		}
+	}
+	if newAPIError = service.ModerateInput(c, info, request, meta.CombineText); newAPIError != nil {
+		return newAPIError, false
+	}
+	tokens, err := service.EstimateRequestToken(c, meta, info)
+	if err != nil {
+		return types.NewError(err, types.ErrorCodeCountTokenFailed), false
+	}
+	info.SetEstimatePromptTokens(tokens)
+	priceData, err := helper.ModelPriceHelper(c, info, tokens, meta)
+	if err != nil {
+		return types.NewError(err, types.ErrorCodeModelPriceError), false
+	}
+	if !priceData.FreeModel {
+		if newAPIError = service.PreConsumeBilling(c, priceData.QuotaToPreConsume, info); newAPIError != nil {
+			return newAPIError, false
+		}
+	}
+	// 请求发出后的失败按已累计的用量结算，settled 标记避免再次退款
+	settled := false
+	defer func() {
+		if newAPIError != nil && !settled && info.Billing != nil {
+			info.Billing.Refund(c)
+		}
+	}()
+
+	info.InitChannelMeta(c)
+	if err := helper.ModelMappedHelper(c, info, request); err != nil {
+		return types.NewError(err, types.ErrorCodeChannelModelMappedError, types.ErrOptionWithSkipRetry()), false
+	}
+	adaptor := GetAdaptor(info.ApiType)
+	adaptor.Init(info)
+	payload, err := buildResponsesWssPayload(c, info, adaptor, request)
+	if err != nil {
+		return types.NewError(err, types.ErrorCodeConvertRequestFailed, types.ErrOptionWithSkipRetry()), false
+	}
+	if err := upstream.WriteMessage(websocket.TextMessage, payload); err != nil {
+		return types.NewError(fmt.Errorf("error writing to upstream: %w", err), types.ErrorCodeDoRequestFailed), true
+	}
+
+	usage := &openai.ResponsesStreamUsage{}
+	for {
+		_, data, err := upstream.ReadMessage()
+		if err != nil {
+			// 上游中途断开时已输出的内容同样计费
+			postConsumeQuota(c, info, usage.Usage(info))
+			settled = true
+			return types.NewError(fmt.Errorf("error reading from upstream: %w", err), types.ErrorCodeBadResponse), true
+		}
+		info.SetFirstResponseTime()
+		if !closeConn {
+			if err := clientWs.WriteMessage(websocket.TextMessage, data); err != nil {
+				// 客户端已断开，继续读取到本次响应结束，按实际用量结算后关闭
+				logger.LogWarn(c, "error writing to client: "+err.Error())
+				closeConn = true
+			}
+		}
+		var streamResponse dto.ResponsesStreamResponse
+		if err := common.Unmarshal(data, &streamResponse); err != nil {
+			logger.LogError(c, "failed to unmarshal responses websocket event: "+err.Error())
+			continue
+		}
+		usage.Handle(c, info, &streamResponse)
+		switch streamResponse.Type {
+		case "response.completed", "response.incomplete", "response.failed", "error":
+			// 失败事件已转发给客户端，同样按截至目前的用量结算
+			postConsumeQuota(c, info, usage.Usage(info))
+			return nil, closeConn
+		}
+	}
+}
+
+// buildResponsesWssPayload 按 HTTP 模式相同的方式转换请求体，再包装为 response.create 事件；WebSocket 模式下始终流式返回，去掉 stream 字段
+func buildResponsesWssPayload(c *gin.Context, info *relaycommon.RelayInfo, adaptor channel.Adaptor, request *dto.OpenAIResponsesRequest) ([]byte, error) {
+	convertedRequest, err := adaptor.ConvertOpenAIResponsesRequest(c, info, *request)
+	if err != nil {
+		return nil, err
+	}
+	relaycommon.AppendRequestConversionFromRequest(info, convertedRequest)
+	jsonData, err := common.Marshal(convertedRequest)
+	if err != nil {
+		return nil, err
+	}
+	jsonData, err = relaycommon.RemoveDisabledFields(jsonData, info.ChannelOtherSettings, info.ChannelSetting.PassThroughBodyEnabled)
+	if err != nil {
+		return nil, err
+	}
+	if len(info.ParamOverride) > 0 {
+		jsonData, err = relaycommon.ApplyParamOverrideWithRelayInfo(jsonData, info)
+		if err != nil {
+			return nil, err
+		}
+	}
+	var payload map[string]json.RawMessage
+	if err := common.Unmarshal(jsonData, &payload); err != nil {
+		return nil, err
+	}
+	delete(payload, "stream")
+	payload["type"] = json.RawMessage(`"` + responsesWssEventCreate + `"`)
+	return common.Marshal(payload)
+}
@@ -0,0 +1,47 @@
+package relay
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/relay/channel/openai"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildResponsesWssPayload(t *testing.T) {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	info := &relaycommon.RelayInfo{ChannelMeta: &relaycommon.ChannelMeta{}}
+	stream := true
+	payload, err := buildResponsesWssPayload(c, info, &openai.Adaptor{}, &dto.OpenAIResponsesRequest{
+		Model:  "gpt-5-high",
+		Stream: &stream,
+	})
+	require.NoError(t, err)
+
+	var event map[string]any
+	require.NoError(t, common.Unmarshal(payload, &event))
+	require.Equal(t, "response.create", event["type"])
+	require.Equal(t, "gpt-5", event["model"])
+	require.NotContains(t, event, "stream")
+	require.Equal(t, "high", info.ReasoningEffort)
+}
+
+func TestResponsesStreamUsageOnFailed(t *testing.T) {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	info := &relaycommon.RelayInfo{ChannelMeta: &relaycommon.ChannelMeta{}}
+	usage := &openai.ResponsesStreamUsage{}
+
+	// 失败事件携带的用量同样用于结算
+	var event dto.ResponsesStreamResponse
+	require.NoError(t, common.Unmarshal([]byte(`{"type":"response.failed","response":{"status":"failed","usage":{"input_tokens":10,"output_tokens":5,"total_tokens":15}}}`), &event))
+	usage.Handle(c, info, &event)
+	result := usage.Usage(info)
+	require.Equal(t, 10, result.PromptTokens)
+	require.Equal(t, 5, result.CompletionTokens)
+	require.Equal(t, 15, result.TotalTokens)
+}
@@ -0,0 +1,30 @@
+package relay
+
+import (
+	"github.com/QuantumNous/new-api/pkg/tracing"
+	"github.com/QuantumNous/new-api/types"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// startConvertSpan 记录请求格式转换，kind 为目标转换方法，如 openai、claude、gemini
+func startConvertSpan(c *gin.Context, kind string) trace.Span {
+	_, span := tracing.Start(c.Request.Context(), "relay.convert_request", attribute.String("relay.convert", kind))
+	return span
+}
+
+// startResponseSpan 记录上游响应的处理与转换，流式请求包含整个流的转发时间
+func startResponseSpan(c *gin.Context) trace.Span {
+	_, span := tracing.Start(c.Request.Context(), "relay.handle_response")
+	return span
+}
+
+func endResponseSpan(span trace.Span, err *types.NewAPIError) {
+	if err != nil {
+		tracing.End(span, err)
+		return
+	}
+	span.End()
+}
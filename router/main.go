@@ -9,6 +9,7 @@ import (
 
 	"github.com/QuantumNous/new-api/common"
 	"github.com/QuantumNous/new-api/middleware"
+	"github.com/QuantumNous/new-api/pkg/metrics"
 
 	"github.com/gin-gonic/gin"
 )
@@ -19,6 +20,12 @@ func SetRouter(router *gin.Engine, buildFS embed.FS, indexPage []byte) {
 	SetRelayRouter(router)
 	SetVideoRouter(router)
 	SetAudioTaskRouter(router)
+	if common.MetricsEnabled {
+		if common.MetricsToken == "" {
+			common.SysLog("METRICS_TOKEN is not set, /metrics is accessible without authentication")
+		}
+		router.GET("/metrics", middleware.MetricsAuth(), gin.WrapH(metrics.Handler()))
+	}
 	frontendBaseUrl := os.Getenv("FRONTEND_BASE_URL")
 	if common.IsMasterNode && frontendBaseUrl != "" {
 		frontendBaseUrl = ""
@@ -15,6 +15,8 @@ func SetRelayRouter(router *gin.Engine) {
 	router.Use(middleware.DecompressRequestMiddleware())
 	router.Use(middleware.BodyStorageCleanup()) // 清理请求体存储
 	router.Use(middleware.StatsMiddleware())
+	router.Use(middleware.RelayMetrics())
+	router.Use(middleware.RelayTracing())
 	// https://platform.openai.com/docs/api-reference/introduction
 	modelsRouter := router.Group("/v1/models")
 	modelsRouter.Use(middleware.RouteTag("relay"))
@@ -71,6 +73,7 @@ func SetRelayRouter(router *gin.Engine) {
 	relayV1Router.Use(middleware.SystemPerformanceCheck())
 	relayV1Router.Use(middleware.TokenAuth())
 	relayV1Router.Use(middleware.ModelRequestRateLimit())
+	relayV1Router.Use(middleware.TokenRateLimit())
 	{
 		// WebSocket 路由（统一到 Relay）
 		wsRouter := relayV1Router.Group("")
@@ -78,6 +81,30 @@ func SetRelayRouter(router *gin.Engine) {
 		wsRouter.GET("/realtime", func(c *gin.Context) {
 			controller.Relay(c, types.RelayFormatOpenAIRealtime)
 		})
+		wsRouter.GET("/responses", controller.RelayResponsesWebSocket)
+	}
+	{
+		// 文件查询/删除与批处理固定走上传时的渠道，不经过 Distribute
+		fileRouter := relayV1Router.Group("/files")
+		fileRouter.GET("", controller.FileList)
+		fileRouter.GET("/:id", controller.FileRetrieve)
+		fileRouter.DELETE("/:id", controller.FileDelete)
+		fileRouter.GET("/:id/content", controller.FileContent)
+
+		// 批处理使用输入文件所在的渠道
+		batchRouter := relayV1Router.Group("/batches")
+		batchRouter.POST("", controller.BatchCreate)
+		batchRouter.GET("", controller.BatchList)
+		batchRouter.GET("/:id", controller.BatchRetrieve)
+		batchRouter.POST("/:id/cancel", controller.BatchCancel)
+
+		// 微调任务查询固定走提交时的渠道
+		fineTuningRouter := relayV1Router.Group("/fine_tuning/jobs")
+		fineTuningRouter.GET("", controller.FineTuningJobList)
+		fineTuningRouter.GET("/:id", controller.FineTuningJobRetrieve)
+		fineTuningRouter.POST("/:id/cancel", controller.FineTuningJobCancel)
+		fineTuningRouter.GET("/:id/events", controller.FineTuningJobEvents)
+		fineTuningRouter.GET("/:id/checkpoints", controller.FineTuningJobCheckpoints)
 	}
 	{
 		//http router
@@ -88,6 +115,7 @@ func SetRelayRouter(router *gin.Engine) {
 		httpRouter.POST("/messages", func(c *gin.Context) {
 			controller.Relay(c, types.RelayFormatClaude)
 		})
+		httpRouter.POST("/messages/count_tokens", controller.RelayClaudeCountTokens)
 
 		// chat related routes
 		httpRouter.POST("/completions", func(c *gin.Context) {
@@ -151,22 +179,21 @@ func SetRelayRouter(router *gin.Engine) {
 		httpRouter.POST("/engines/:model/embeddings", func(c *gin.Context) {
 			controller.Relay(c, types.RelayFormatGemini)
 		})
-		httpRouter.POST("/models/*path", func(c *gin.Context) {
-			controller.Relay(c, types.RelayFormatGemini)
-		})
+		httpRouter.POST("/models/*path", controller.RelayGemini)
 
 		// other relay routes
 		httpRouter.POST("/moderations", func(c *gin.Context) {
 			controller.Relay(c, types.RelayFormatOpenAI)
 		})
 
+		// file related routes
+		httpRouter.POST("/files", controller.FileUpload)
+
+		// fine-tuning related routes
+		httpRouter.POST("/fine_tuning/jobs", controller.RelayTask)
+
 		// not implemented
 		httpRouter.POST("/images/variations", controller.RelayNotImplemented)
-		httpRouter.GET("/files", controller.RelayNotImplemented)
-		httpRouter.POST("/files", controller.RelayNotImplemented)
-		httpRouter.DELETE("/files/:id", controller.RelayNotImplemented)
-		httpRouter.GET("/files/:id", controller.RelayNotImplemented)
-		httpRouter.GET("/files/:id/content", controller.RelayNotImplemented)
 		httpRouter.POST("/fine-tunes", controller.RelayNotImplemented)
 		httpRouter.GET("/fine-tunes", controller.RelayNotImplemented)
 		httpRouter.GET("/fine-tunes/:id", controller.RelayNotImplemented)
@@ -189,7 +216,7 @@ func SetRelayRouter(router *gin.Engine) {
 	relaySunoRouter := router.Group("/suno")
 	relaySunoRouter.Use(middleware.RouteTag("relay"))
 	relaySunoRouter.Use(middleware.SystemPerformanceCheck())
-	relaySunoRouter.Use(middleware.TokenAuth(), middleware.Distribute())
+	relaySunoRouter.Use(middleware.TokenAuth(), middleware.TokenRateLimit(), middleware.Distribute())
 	{
 		relaySunoRouter.POST("/submit/:action", controller.RelayTask)
 		relaySunoRouter.POST("/fetch", controller.RelayTaskFetch)
@@ -201,18 +228,17 @@ func SetRelayRouter(router *gin.Engine) {
 	relayGeminiRouter.Use(middleware.SystemPerformanceCheck())
 	relayGeminiRouter.Use(middleware.TokenAuth())
 	relayGeminiRouter.Use(middleware.ModelRequestRateLimit())
+	relayGeminiRouter.Use(middleware.TokenRateLimit())
 	relayGeminiRouter.Use(middleware.Distribute())
 	{
 		// Gemini API 路径格式: /v1beta/models/{model_name}:{action}
-		relayGeminiRouter.POST("/models/*path", func(c *gin.Context) {
-			controller.Relay(c, types.RelayFormatGemini)
-		})
+		relayGeminiRouter.POST("/models/*path", controller.RelayGemini)
 	}
 }
 
 func registerMjRouterGroup(relayMjRouter *gin.RouterGroup) {
 	relayMjRouter.GET("/image/:id", relay.RelayMidjourneyImage)
-	relayMjRouter.Use(middleware.TokenAuth(), middleware.Distribute())
+	relayMjRouter.Use(middleware.TokenAuth(), middleware.TokenRateLimit(), middleware.Distribute())
 	{
 		relayMjRouter.POST("/submit/action", controller.RelayMidjourney)
 		relayMjRouter.POST("/submit/shorten", controller.RelayMidjourney)
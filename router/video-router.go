@@ -16,6 +16,13 @@ func SetVideoRouter(router *gin.Engine) {
 		videoProxyRouter.GET("/videos/:task_id/content", controller.VideoProxy)
 	}
 
+	// 持久化产物的签名地址，无需登录
+	mediaRouter := router.Group("/v1")
+	mediaRouter.Use(middleware.RouteTag("relay"))
+	{
+		mediaRouter.GET("/media/:key", controller.MediaContent)
+	}
+
 	videoV1Router := router.Group("/v1")
 	videoV1Router.Use(middleware.RouteTag("relay"))
 	videoV1Router.Use(middleware.TokenAuth(), middleware.Distribute())
@@ -0,0 +1,133 @@
+package service
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+const auditMaskedValue = "******"
+
+// 字段名（小写）以这些后缀结尾时视为敏感信息，与 GetOptions 隐藏配置项的规则保持一致
+var auditSensitiveSuffixes = []string{"key", "secret", "password", "token"}
+
+func isAuditSensitiveField(name string) bool {
+	name = strings.ToLower(name)
+	for _, suffix := range auditSensitiveSuffixes {
+		if strings.HasSuffix(name, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// auditSnapshot 将对象转换为通用结构，便于比较与脱敏
+func auditSnapshot(v any) any {
+	if v == nil {
+		return nil
+	}
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.Ptr && rv.IsNil() {
+		return nil
+	}
+	data, err := common.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	var snapshot any
+	if err := common.Unmarshal(data, &snapshot); err != nil {
+		return string(data)
+	}
+	return snapshot
+}
+
+func maskAuditSnapshot(v any) any {
+	switch value := v.(type) {
+	case map[string]any:
+		masked := make(map[string]any, len(value))
+		for k, item := range value {
+			if isAuditSensitiveField(k) {
+				if item == nil || item == "" {
+					masked[k] = item
+				} else {
+					masked[k] = auditMaskedValue
+				}
+				continue
+			}
+			masked[k] = maskAuditSnapshot(item)
+		}
+		return masked
+	case []any:
+		masked := make([]any, len(value))
+		for i, item := range value {
+			masked[i] = maskAuditSnapshot(item)
+		}
+		return masked
+	default:
+		return v
+	}
+}
+
+// auditChangedFields 比较脱敏前的快照，返回发生变化的顶层字段
+func auditChangedFields(before, after any) []string {
+	beforeMap, _ := before.(map[string]any)
+	afterMap, _ := after.(map[string]any)
+	if beforeMap == nil && afterMap == nil {
+		return nil
+	}
+	changed := make([]string, 0)
+	for k, v := range afterMap {
+		if old, ok := beforeMap[k]; !ok || !reflect.DeepEqual(old, v) {
+			changed = append(changed, k)
+		}
+	}
+	for k := range beforeMap {
+		if _, ok := afterMap[k]; !ok {
+			changed = append(changed, k)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+func marshalAuditSnapshot(v any) string {
+	if v == nil {
+		return ""
+	}
+	data, err := common.Marshal(v)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// BuildAuditLog 根据请求上下文构造审计记录，before / after 中的敏感字段会被脱敏
+func BuildAuditLog(c *gin.Context, action string, targetType string, targetId any, before any, after any) *model.AuditLog {
+	beforeSnapshot := auditSnapshot(before)
+	afterSnapshot := auditSnapshot(after)
+	log := &model.AuditLog{
+		ActorId:         c.GetInt("id"),
+		ActorName:       c.GetString("username"),
+		ActorRole:       c.GetInt("role"),
+		ManagementKeyId: common.GetContextKeyInt(c, constant.ContextKeyManagementKeyId),
+		Ip:              c.ClientIP(),
+		Action:          action,
+		TargetType:      targetType,
+		TargetId:        fmt.Sprintf("%v", targetId),
+		ChangedFields:   strings.Join(auditChangedFields(beforeSnapshot, afterSnapshot), ","),
+		Before:          marshalAuditSnapshot(maskAuditSnapshot(beforeSnapshot)),
+		After:           marshalAuditSnapshot(maskAuditSnapshot(afterSnapshot)),
+	}
+	return log
+}
+
+// RecordAudit 同步写入一条管理操作审计记录，写入失败时返回错误，由调用方记录
+func RecordAudit(c *gin.Context, action string, targetType string, targetId any, before any, after any) error {
+	return BuildAuditLog(c, action, targetType, targetId, before, after).Insert()
+}
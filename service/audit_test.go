@@ -0,0 +1,54 @@
+package service
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/QuantumNous/new-api/model"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditSnapshotMaskAndDiff(t *testing.T) {
+	type channel struct {
+		Name     string            `json:"name"`
+		Key      string            `json:"key"`
+		Settings map[string]string `json:"settings"`
+	}
+	before := auditSnapshot(channel{Name: "a", Key: "sk-old", Settings: map[string]string{"api_key": "x", "region": "us"}})
+	after := auditSnapshot(channel{Name: "a", Key: "sk-new", Settings: map[string]string{"api_key": "x", "region": "eu"}})
+
+	// 密钥变化需要被记录，但快照中不能出现明文
+	require.Equal(t, []string{"key", "settings"}, auditChangedFields(before, after))
+	masked := marshalAuditSnapshot(maskAuditSnapshot(after))
+	require.NotContains(t, masked, "sk-new")
+	require.Contains(t, masked, `"key":"******"`)
+	require.Contains(t, masked, `"api_key":"******"`)
+	require.Contains(t, masked, `"region":"eu"`)
+
+	require.Equal(t, []string{"GitHubClientSecret"}, auditChangedFields(nil, auditSnapshot(map[string]string{"GitHubClientSecret": "s"})))
+	require.Nil(t, auditSnapshot((*channel)(nil)))
+}
+
+func TestRecordAuditSynchronous(t *testing.T) {
+	require.NoError(t, model.LOG_DB.AutoMigrate(&model.AuditLog{}))
+	t.Cleanup(func() {
+		model.LOG_DB.Exec("DELETE FROM audit_logs")
+	})
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("POST", "/api/channel/batch", nil)
+	c.Set("id", 1)
+
+	// 返回时记录已经落库
+	require.NoError(t, RecordAudit(c, model.AuditActionDelete, model.AuditTargetChannel, 7, map[string]any{"id": 7, "key": "sk-x"}, nil))
+	logs, total, err := model.GetAuditLogs(model.AuditLogFilter{TargetId: "7"}, 0, 10)
+	require.NoError(t, err)
+	require.EqualValues(t, 1, total)
+	require.Equal(t, 1, logs[0].ActorId)
+	require.NotContains(t, logs[0].Before, "sk-x")
+
+	// 写入失败时返回错误而不是静默丢弃
+	require.NoError(t, model.LOG_DB.Migrator().DropTable(&model.AuditLog{}))
+	require.Error(t, RecordAudit(c, model.AuditActionDelete, model.AuditTargetChannel, 8, nil, nil))
+}
@@ -0,0 +1,713 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BatchLineExecutor 在本地执行批处理中的一行请求（走完整的 relay 流程并计费）。
+// 由 main 注入 controller 实现，避免 service -> controller 的循环依赖。
+type BatchLineExecutor func(ctx context.Context, token *model.Token, batch *model.Batch, line *dto.BatchRequestLine) *dto.BatchResponseLine
+
+var ExecuteBatchLineFunc BatchLineExecutor
+
+// BatchLineSettler 结算上游批处理结果中的一行，返回实际扣除的额度；余额不足时拒绝该行并返回错误。
+// 由 main 注入 relay 实现，复用实时请求的价格计算与后结算流程。
+type BatchLineSettler func(c *gin.Context, user *model.UserBase, batch *model.Batch, line *dto.BatchResponseLine) (int, error)
+
+var SettleBatchLineFunc BatchLineSettler
+
+// SupportedBatchEndpoints 允许出现在批处理中的接口
+var SupportedBatchEndpoints = []string{
+	"/v1/chat/completions",
+	"/v1/completions",
+	"/v1/embeddings",
+	"/v1/responses",
+}
+
+// IsNativeBatchChannel 渠道是否原生支持 Batch API，其余 OpenAI 兼容渠道由本地 worker 执行
+func IsNativeBatchChannel(channelType int) bool {
+	return channelType == constant.ChannelTypeOpenAI || channelType == constant.ChannelTypeAzure
+}
+
+// upstreamBatchEndpoint Azure 的 batch endpoint 不带 /v1 前缀
+func upstreamBatchEndpoint(channelType int, endpoint string) string {
+	if channelType == constant.ChannelTypeAzure {
+		return strings.TrimPrefix(endpoint, "/v1")
+	}
+	return endpoint
+}
+
+func int64Ptr(v int64) *int64 {
+	if v == 0 {
+		return nil
+	}
+	return &v
+}
+
+func stringPtr(v string) *string {
+	if v == "" {
+		return nil
+	}
+	return &v
+}
+
+func derefInt64(v *int64) int64 {
+	if v == nil {
+		return 0
+	}
+	return *v
+}
+
+func derefString(v *string) string {
+	if v == nil {
+		return ""
+	}
+	return *v
+}
+
+// BatchToOpenAIBatch 转换为 OpenAI 批处理对象
+func BatchToOpenAIBatch(batch *model.Batch) *dto.OpenAIBatch {
+	result := &dto.OpenAIBatch{
+		Id:               batch.BatchId,
+		Object:           "batch",
+		Endpoint:         batch.Endpoint,
+		InputFileId:      batch.InputFileId,
+		CompletionWindow: batch.CompletionWindow,
+		Status:           batch.Status,
+		OutputFileId:     stringPtr(batch.OutputFileId),
+		ErrorFileId:      stringPtr(batch.ErrorFileId),
+		CreatedAt:        batch.CreatedAt,
+		InProgressAt:     int64Ptr(batch.InProgressAt),
+		ExpiresAt:        int64Ptr(batch.ExpiresAt),
+		FinalizingAt:     int64Ptr(batch.FinalizingAt),
+		CompletedAt:      int64Ptr(batch.CompletedAt),
+		FailedAt:         int64Ptr(batch.FailedAt),
+		ExpiredAt:        int64Ptr(batch.ExpiredAt),
+		CancellingAt:     int64Ptr(batch.CancellingAt),
+		CancelledAt:      int64Ptr(batch.CancelledAt),
+		RequestCounts: dto.OpenAIBatchRequestCounts{
+			Total:     batch.RequestTotal,
+			Completed: batch.RequestCompleted,
+			Failed:    batch.RequestFailed,
+		},
+	}
+	if len(batch.Metadata) > 0 {
+		_ = common.Unmarshal(batch.Metadata, &result.Metadata)
+	}
+	if len(batch.Errors) > 0 {
+		var batchErrors dto.OpenAIBatchErrors
+		if err := common.Unmarshal(batch.Errors, &batchErrors); err == nil {
+			result.Errors = &batchErrors
+		}
+	}
+	return result
+}
+
+// applyUpstreamBatch 用上游批处理对象更新本地记录（output/error 文件 id 在结束时单独登记）
+func applyUpstreamBatch(batch *model.Batch, upstream *dto.OpenAIBatch) {
+	batch.Status = upstream.Status
+	batch.RequestTotal = upstream.RequestCounts.Total
+	batch.RequestCompleted = upstream.RequestCounts.Completed
+	batch.RequestFailed = upstream.RequestCounts.Failed
+	batch.InProgressAt = derefInt64(upstream.InProgressAt)
+	batch.ExpiresAt = derefInt64(upstream.ExpiresAt)
+	batch.FinalizingAt = derefInt64(upstream.FinalizingAt)
+	batch.CompletedAt = derefInt64(upstream.CompletedAt)
+	batch.FailedAt = derefInt64(upstream.FailedAt)
+	batch.ExpiredAt = derefInt64(upstream.ExpiredAt)
+	batch.CancellingAt = derefInt64(upstream.CancellingAt)
+	batch.CancelledAt = derefInt64(upstream.CancelledAt)
+	if upstream.Errors != nil && len(upstream.Errors.Data) > 0 {
+		batch.Errors, _ = common.Marshal(upstream.Errors)
+	}
+}
+
+func setBatchError(batch *model.Batch, code string, message string) {
+	batch.Errors, _ = common.Marshal(&dto.OpenAIBatchErrors{
+		Object: "list",
+		Data:   []dto.OpenAIBatchError{{Code: code, Message: message}},
+	})
+}
+
+func decodeUpstreamBatch(resp *http.Response) (*dto.OpenAIBatch, error) {
+	defer CloseResponseBodyGracefully(resp)
+	var upstream dto.OpenAIBatch
+	if err := common.DecodeJson(resp.Body, &upstream); err != nil {
+		return nil, err
+	}
+	if upstream.Id == "" {
+		return nil, errors.New("upstream batch id is empty")
+	}
+	return &upstream, nil
+}
+
+// CreateUpstreamBatch 在输入文件所在渠道上创建原生批处理；返回非 200 响应时由调用方处理错误
+func CreateUpstreamBatch(ctx context.Context, channel *model.Channel, inputFile *model.File, batch *model.Batch,
+	metadata map[string]string) (*http.Response, error) {
+	reqBody, err := common.Marshal(&dto.OpenAIBatchRequest{
+		InputFileId:      inputFile.UpstreamFileId,
+		Endpoint:         upstreamBatchEndpoint(channel.Type, batch.Endpoint),
+		CompletionWindow: batch.CompletionWindow,
+		Metadata:         metadata,
+	})
+	if err != nil {
+		return nil, err
+	}
+	resp, err := DoOpenAIFileRequest(ctx, channel, inputFile.ChannelKeyIdx, http.MethodPost, "batches",
+		bytes.NewReader(reqBody), "application/json", int64(len(reqBody)))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return resp, nil
+	}
+	upstream, err := decodeUpstreamBatch(resp)
+	if err != nil {
+		return nil, err
+	}
+	applyUpstreamBatch(batch, upstream)
+	batch.BatchId = upstream.Id
+	batch.UpstreamBatchId = upstream.Id
+	return nil, nil
+}
+
+// CancelUpstreamBatch 取消上游批处理；返回非 200 响应时由调用方处理错误
+func CancelUpstreamBatch(ctx context.Context, batch *model.Batch) (*http.Response, error) {
+	channel, err := model.CacheGetChannel(batch.ChannelId)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := DoOpenAIFileRequest(ctx, channel, batch.ChannelKeyIdx, http.MethodPost,
+		"batches/"+batch.UpstreamBatchId+"/cancel", nil, "", -1)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return resp, nil
+	}
+	upstream, err := decodeUpstreamBatch(resp)
+	if err != nil {
+		return nil, err
+	}
+	applyUpstreamBatch(batch, upstream)
+	return nil, nil
+}
+
+// ---------------------------------------------------------------------------
+// 后台轮询
+// ---------------------------------------------------------------------------
+
+// runningLocalBatches 记录当前进程中正在执行的本地批处理
+var runningLocalBatches sync.Map
+
+// BatchPollingLoop 轮询未完成的批处理：上游模式同步状态并在结束后逐行结算，本地模式启动执行
+func BatchPollingLoop() {
+	recoverInterruptedLocalBatches()
+	for {
+		time.Sleep(time.Duration(15) * time.Second)
+		ctx := context.Background()
+		batches := model.GetUnfinishedBatches(constant.TaskQueryLimit)
+		for _, batch := range batches {
+			switch batch.Mode {
+			case model.BatchModeUpstream:
+				if err := refreshUpstreamBatch(ctx, batch); err != nil {
+					logger.LogError(ctx, fmt.Sprintf("refresh batch %s failed: %s", batch.BatchId, err.Error()))
+				}
+			case model.BatchModeLocal:
+				startLocalBatch(batch)
+			}
+		}
+	}
+}
+
+// recoverInterruptedLocalBatches 进程重启后，执行中的本地批处理无法续跑（已执行的行已计费），直接结束
+func recoverInterruptedLocalBatches() {
+	for _, batch := range model.GetUnfinishedBatches(constant.TaskQueryLimit) {
+		if batch.Mode != model.BatchModeLocal || batch.Status == model.BatchStatusValidating {
+			continue
+		}
+		fromStatus := batch.Status
+		now := common.GetTimestamp()
+		if fromStatus == model.BatchStatusCancelling {
+			batch.Status = model.BatchStatusCancelled
+			batch.CancelledAt = now
+		} else {
+			batch.Status = model.BatchStatusFailed
+			batch.FailedAt = now
+			setBatchError(batch, "batch_interrupted", "batch execution was interrupted by a server restart")
+		}
+		if _, err := batch.UpdateWithStatus(fromStatus); err != nil {
+			common.SysLog(fmt.Sprintf("failed to recover interrupted batch %s: %s", batch.BatchId, err.Error()))
+			continue
+		}
+		RemoveLocalBatchInput(batch)
+	}
+}
+
+func refreshUpstreamBatch(ctx context.Context, batch *model.Batch) error {
+	channel, err := model.CacheGetChannel(batch.ChannelId)
+	if err != nil {
+		return err
+	}
+	resp, err := DoOpenAIFileRequest(ctx, channel, batch.ChannelKeyIdx, http.MethodGet, "batches/"+batch.UpstreamBatchId, nil, "", -1)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return RelayErrorHandler(ctx, resp, true)
+	}
+	upstream, err := decodeUpstreamBatch(resp)
+	if err != nil {
+		return err
+	}
+	fromStatus := batch.Status
+	applyUpstreamBatch(batch, upstream)
+	if !batch.IsFinished() {
+		if batch.Status == fromStatus {
+			return batch.Update()
+		}
+		_, err = batch.UpdateWithStatus(fromStatus)
+		return err
+	}
+
+	// 先登记结果文件并 CAS 进入终态，抢到的一方负责结算，避免重复计费
+	batch.OutputFileId = registerUpstreamBatchFile(ctx, channel, batch, derefString(upstream.OutputFileId))
+	batch.ErrorFileId = registerUpstreamBatchFile(ctx, channel, batch, derefString(upstream.ErrorFileId))
+	won, err := batch.UpdateWithStatus(fromStatus)
+	if err != nil || !won {
+		return err
+	}
+	if batch.OutputFileId == "" {
+		return nil
+	}
+	quota, err := settleUpstreamBatchOutput(ctx, channel, batch)
+	if err != nil {
+		logger.LogError(ctx, fmt.Sprintf("settle batch %s failed: %s", batch.BatchId, err.Error()))
+	}
+	batch.Quota = quota
+	return batch.Update()
+}
+
+// registerUpstreamBatchFile 将上游生成的结果文件登记为用户文件，固定在批处理所在渠道
+func registerUpstreamBatchFile(ctx context.Context, channel *model.Channel, batch *model.Batch, upstreamFileId string) string {
+	if upstreamFileId == "" {
+		return ""
+	}
+	if existing, err := model.GetUserFileById(batch.UserId, upstreamFileId); err == nil && existing != nil {
+		return upstreamFileId
+	}
+	file := &model.File{
+		FileId:         upstreamFileId,
+		UserId:         batch.UserId,
+		TokenId:        batch.TokenId,
+		Purpose:        "batch_output",
+		Status:         "processed",
+		ChannelId:      channel.Id,
+		ChannelKeyIdx:  batch.ChannelKeyIdx,
+		UpstreamFileId: upstreamFileId,
+	}
+	resp, err := DoOpenAIFileRequest(ctx, channel, batch.ChannelKeyIdx, http.MethodGet, "files/"+upstreamFileId, nil, "", -1)
+	if err == nil {
+		if resp.StatusCode == http.StatusOK {
+			var upstreamFile dto.OpenAIFile
+			if common.DecodeJson(resp.Body, &upstreamFile) == nil {
+				file.Filename = upstreamFile.Filename
+				file.Bytes = upstreamFile.Bytes
+				file.CreatedAt = upstreamFile.CreatedAt
+				file.ExpiresAt = upstreamFile.ExpiresAt
+			}
+		}
+		CloseResponseBodyGracefully(resp)
+	}
+	if err = file.Insert(); err != nil {
+		logger.LogError(ctx, fmt.Sprintf("failed to register output file %s of batch %s: %s", upstreamFileId, batch.BatchId, err.Error()))
+	}
+	return upstreamFileId
+}
+
+func newBatchLineScanner(reader io.Reader) *bufio.Scanner {
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), constant.StreamScannerMaxBufferMB<<20)
+	return scanner
+}
+
+// settleUpstreamBatchOutput 下载上游结果文件，逐行按批处理倍率计费，余额不足的行不计费
+func settleUpstreamBatchOutput(ctx context.Context, channel *model.Channel, batch *model.Batch) (int, error) {
+	if SettleBatchLineFunc == nil {
+		return 0, errors.New("batch line settler is not configured")
+	}
+	token, err := model.GetTokenById(batch.TokenId)
+	if err != nil {
+		logger.LogWarn(ctx, fmt.Sprintf("token #%d of batch %s not found, billing user only: %s", batch.TokenId, batch.BatchId, err.Error()))
+		token = nil
+	}
+	resp, err := DoOpenAIFileRequest(ctx, channel, batch.ChannelKeyIdx, http.MethodGet, "files/"+batch.OutputFileId+"/content", nil, "", -1)
+	if err != nil {
+		return 0, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return 0, RelayErrorHandler(ctx, resp, true)
+	}
+	defer CloseResponseBodyGracefully(resp)
+
+	total := 0
+	scanner := newBatchLineScanner(resp.Body)
+	for scanner.Scan() {
+		raw := bytes.TrimSpace(scanner.Bytes())
+		if len(raw) == 0 {
+			continue
+		}
+		var line dto.BatchResponseLine
+		if err := common.Unmarshal(raw, &line); err != nil {
+			logger.LogWarn(ctx, fmt.Sprintf("skip malformed output line of batch %s: %s", batch.BatchId, err.Error()))
+			continue
+		}
+		// 每行使用独立的上下文，避免工具调用等标记串到下一行
+		c, user, err := NewBackgroundBillingContext(batch.UserId, token, batch.Endpoint)
+		if err != nil {
+			return total, err
+		}
+		quota, err := SettleBatchLineFunc(c, user, batch, &line)
+		if err != nil {
+			logger.LogError(ctx, fmt.Sprintf("bill batch %s line %s rejected: %s", batch.BatchId, line.CustomId, err.Error()))
+			continue
+		}
+		total += quota
+	}
+	return total, scanner.Err()
+}
+
+// ---------------------------------------------------------------------------
+// 本地执行
+// ---------------------------------------------------------------------------
+
+func startLocalBatch(batch *model.Batch) {
+	if batch.Status != model.BatchStatusValidating {
+		return
+	}
+	if _, loaded := runningLocalBatches.LoadOrStore(batch.Id, struct{}{}); loaded {
+		return
+	}
+	go func() {
+		defer runningLocalBatches.Delete(batch.Id)
+		runLocalBatch(context.Background(), batch)
+	}()
+}
+
+func failLocalBatch(ctx context.Context, batch *model.Batch, code string, message string) {
+	fromStatus := batch.Status
+	batch.Status = model.BatchStatusFailed
+	batch.FailedAt = common.GetTimestamp()
+	setBatchError(batch, code, message)
+	if _, err := batch.UpdateWithStatus(fromStatus); err != nil {
+		logger.LogError(ctx, fmt.Sprintf("failed to mark batch %s as failed: %s", batch.BatchId, err.Error()))
+	}
+}
+
+// localBatchExpired 本地批处理超过完成窗口后不再执行剩余行，已执行的行照常保留结果与计费
+func localBatchExpired(batch *model.Batch, now int64) bool {
+	return batch.ExpiresAt > 0 && now >= batch.ExpiresAt
+}
+
+func expireLocalBatch(ctx context.Context, batch *model.Batch) {
+	defer RemoveLocalBatchInput(batch)
+	fromStatus := batch.Status
+	batch.Status = model.BatchStatusExpired
+	batch.ExpiredAt = common.GetTimestamp()
+	setBatchError(batch, "batch_expired", "batch was not completed within the completion window")
+	if _, err := batch.UpdateWithStatus(fromStatus); err != nil {
+		logger.LogError(ctx, fmt.Sprintf("failed to mark batch %s as expired: %s", batch.BatchId, err.Error()))
+	}
+}
+
+// SaveLocalBatchInput 创建本地批处理时把输入文件从所在渠道下载到本地保存，
+// 执行阶段只读取本地副本，不再依赖上游 /v1/files 在整个完成窗口内可用；下载失败时拒绝创建
+func SaveLocalBatchInput(ctx context.Context, channel *model.Channel, inputFile *model.File, batch *model.Batch) error {
+	resp, err := DoOpenAIFileRequest(ctx, channel, inputFile.ChannelKeyIdx, http.MethodGet,
+		"files/"+inputFile.UpstreamFileId+"/content", nil, "", -1)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return RelayErrorHandler(ctx, resp, false)
+	}
+	defer CloseResponseBodyGracefully(resp)
+
+	path := filepath.Join(constant.FileStoragePath, batch.BatchId+"_input.jsonl")
+	if err = os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(file, resp.Body)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(path)
+		return err
+	}
+	batch.InputStoragePath = path
+	return nil
+}
+
+// RemoveLocalBatchInput 本地批处理进入终态后删除输入副本
+func RemoveLocalBatchInput(batch *model.Batch) {
+	if batch.InputStoragePath == "" {
+		return
+	}
+	if err := os.Remove(batch.InputStoragePath); err != nil && !os.IsNotExist(err) {
+		common.SysLog(fmt.Sprintf("failed to remove input copy of batch %s: %s", batch.BatchId, err.Error()))
+	}
+}
+
+// loadLocalBatchInput 读取创建时保存的输入副本并校验每一行
+func loadLocalBatchInput(batch *model.Batch) ([]*dto.BatchRequestLine, string, error) {
+	if batch.InputStoragePath == "" {
+		return nil, "invalid_file", fmt.Errorf("input file %s was not saved when the batch was created", batch.InputFileId)
+	}
+	file, err := os.Open(batch.InputStoragePath)
+	if err != nil {
+		return nil, "invalid_file", err
+	}
+	defer file.Close()
+	return parseLocalBatchInput(batch, file)
+}
+
+func parseLocalBatchInput(batch *model.Batch, reader io.Reader) ([]*dto.BatchRequestLine, string, error) {
+	maxLines := operation_setting.GetBatchSetting().MaxLocalRequests
+	lines := make([]*dto.BatchRequestLine, 0)
+	customIds := make(map[string]struct{})
+	scanner := newBatchLineScanner(reader)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		raw := bytes.TrimSpace(scanner.Bytes())
+		if len(raw) == 0 {
+			continue
+		}
+		var line dto.BatchRequestLine
+		if err := common.Unmarshal(raw, &line); err != nil {
+			return nil, "invalid_json_line", fmt.Errorf("line %d is not valid JSON: %s", lineNo, err.Error())
+		}
+		if line.CustomId == "" {
+			return nil, "missing_custom_id", fmt.Errorf("line %d is missing custom_id", lineNo)
+		}
+		if _, ok := customIds[line.CustomId]; ok {
+			return nil, "duplicate_custom_id", fmt.Errorf("line %d has duplicate custom_id %s", lineNo, line.CustomId)
+		}
+		customIds[line.CustomId] = struct{}{}
+		if line.Url != batch.Endpoint {
+			return nil, "mismatched_url", fmt.Errorf("line %d url %s does not match batch endpoint %s", lineNo, line.Url, batch.Endpoint)
+		}
+		if line.Method != "" && !strings.EqualFold(line.Method, http.MethodPost) {
+			return nil, "invalid_method", fmt.Errorf("line %d method must be POST", lineNo)
+		}
+		var streamFlag struct {
+			Stream bool `json:"stream"`
+		}
+		if err := common.Unmarshal(line.Body, &streamFlag); err != nil {
+			return nil, "invalid_request", fmt.Errorf("line %d body is not a JSON object", lineNo)
+		}
+		if streamFlag.Stream {
+			return nil, "invalid_request", fmt.Errorf("line %d: streaming is not supported in batches", lineNo)
+		}
+		lines = append(lines, &line)
+		if maxLines > 0 && len(lines) > maxLines {
+			return nil, "too_many_requests", fmt.Errorf("batch exceeds the limit of %d requests", maxLines)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, "invalid_file", err
+	}
+	if len(lines) == 0 {
+		return nil, "empty_file", errors.New("input file is empty")
+	}
+	return lines, "", nil
+}
+
+// localBatchWriter 将结果写入本地 jsonl 文件，成功的写 output，失败的写 error
+type localBatchWriter struct {
+	path  string
+	file  *os.File
+	lines int
+}
+
+func (w *localBatchWriter) write(line *dto.BatchResponseLine) error {
+	if w.file == nil {
+		if err := os.MkdirAll(filepath.Dir(w.path), 0755); err != nil {
+			return err
+		}
+		file, err := os.Create(w.path)
+		if err != nil {
+			return err
+		}
+		w.file = file
+	}
+	data, err := common.Marshal(line)
+	if err != nil {
+		return err
+	}
+	w.lines++
+	_, err = w.file.Write(append(data, '\n'))
+	return err
+}
+
+// register 关闭文件并登记为本地文件，没有内容时返回空 id
+func (w *localBatchWriter) register(batch *model.Batch, fileId string) string {
+	if w.file == nil {
+		return ""
+	}
+	_ = w.file.Close()
+	info, err := os.Stat(w.path)
+	if err != nil {
+		return ""
+	}
+	file := &model.File{
+		FileId:      fileId,
+		UserId:      batch.UserId,
+		TokenId:     batch.TokenId,
+		Purpose:     "batch_output",
+		Filename:    filepath.Base(w.path),
+		Bytes:       info.Size(),
+		Status:      "processed",
+		StoragePath: w.path,
+	}
+	if err = file.Insert(); err != nil {
+		common.SysLog(fmt.Sprintf("failed to register output file of batch %s: %s", batch.BatchId, err.Error()))
+		return ""
+	}
+	return fileId
+}
+
+func runLocalBatch(ctx context.Context, batch *model.Batch) {
+	if ExecuteBatchLineFunc == nil {
+		failLocalBatch(ctx, batch, "server_error", "local batch executor is not configured")
+		return
+	}
+	if localBatchExpired(batch, common.GetTimestamp()) {
+		expireLocalBatch(ctx, batch)
+		return
+	}
+	batch.Status = model.BatchStatusInProgress
+	batch.InProgressAt = common.GetTimestamp()
+	if won, err := batch.UpdateWithStatus(model.BatchStatusValidating); err != nil || !won {
+		return
+	}
+	defer RemoveLocalBatchInput(batch)
+
+	lines, code, err := loadLocalBatchInput(batch)
+	if err != nil {
+		failLocalBatch(ctx, batch, code, err.Error())
+		return
+	}
+	token, err := model.GetTokenById(batch.TokenId)
+	if err != nil {
+		failLocalBatch(ctx, batch, "invalid_token", "the token used to create this batch is no longer available")
+		return
+	}
+	batch.RequestTotal = len(lines)
+	if err = batch.UpdateRequestCounts(); err != nil {
+		logger.LogError(ctx, fmt.Sprintf("update batch %s failed: %s", batch.BatchId, err.Error()))
+	}
+
+	outputFileId := "file-" + common.GetRandomString(24)
+	errorFileId := "file-" + common.GetRandomString(24)
+	output := &localBatchWriter{path: filepath.Join(constant.FileStoragePath, outputFileId+".jsonl")}
+	errorOutput := &localBatchWriter{path: filepath.Join(constant.FileStoragePath, errorFileId+".jsonl")}
+
+	concurrency := operation_setting.GetBatchSetting().LocalConcurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	var (
+		mu        sync.Mutex
+		wg        sync.WaitGroup
+		cancelled bool
+		expired   bool
+	)
+	sem := make(chan struct{}, concurrency)
+	for i, line := range lines {
+		// 定期检查是否被取消或超过完成窗口
+		if i%concurrency == 0 {
+			if status, err := model.GetBatchStatus(batch.Id); err == nil && status == model.BatchStatusCancelling {
+				cancelled = true
+				break
+			}
+			if localBatchExpired(batch, common.GetTimestamp()) {
+				expired = true
+				break
+			}
+		}
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(line *dto.BatchRequestLine) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			result := ExecuteBatchLineFunc(ctx, token, batch, line)
+			mu.Lock()
+			defer mu.Unlock()
+			var writeErr error
+			if result.Response != nil && result.Response.StatusCode == http.StatusOK {
+				batch.RequestCompleted++
+				writeErr = output.write(result)
+			} else {
+				batch.RequestFailed++
+				writeErr = errorOutput.write(result)
+			}
+			if writeErr != nil {
+				logger.LogError(ctx, fmt.Sprintf("write result of batch %s failed: %s", batch.BatchId, writeErr.Error()))
+			}
+		}(line)
+	}
+	wg.Wait()
+
+	fromStatus := model.BatchStatusInProgress
+	if status, err := model.GetBatchStatus(batch.Id); err == nil && status == model.BatchStatusCancelling {
+		fromStatus = model.BatchStatusCancelling
+		cancelled = true
+	}
+	now := common.GetTimestamp()
+	batch.FinalizingAt = now
+	batch.OutputFileId = output.register(batch, outputFileId)
+	batch.ErrorFileId = errorOutput.register(batch, errorFileId)
+	switch {
+	case cancelled:
+		batch.Status = model.BatchStatusCancelled
+		batch.CancelledAt = now
+	case expired:
+		batch.Status = model.BatchStatusExpired
+		batch.ExpiredAt = now
+		setBatchError(batch, "batch_expired", "batch was not completed within the completion window")
+	default:
+		batch.Status = model.BatchStatusCompleted
+		batch.CompletedAt = now
+	}
+	if _, err := batch.UpdateWithStatus(fromStatus); err != nil {
+		logger.LogError(ctx, fmt.Sprintf("finalize batch %s failed: %s", batch.BatchId, err.Error()))
+	}
+}
@@ -0,0 +1,37 @@
+package service
+
+import (
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// NewBackgroundBillingContext 为后台任务（批处理结算等）构造一个脱离 HTTP 请求的 gin.Context，
+// 写入用户与令牌信息，使 BillingSession / RecordConsumeLog 可以复用。
+func NewBackgroundBillingContext(userId int, token *model.Token, path string) (*gin.Context, *model.UserBase, error) {
+	user, err := model.GetUserCache(userId)
+	if err != nil {
+		return nil, nil, err
+	}
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, path, nil)
+	c.Set(common.RequestIdKey, common.GetTimeString()+common.GetRandomString(8))
+	c.Set("id", userId)
+	user.WriteContext(c)
+	if token != nil {
+		c.Set("token_id", token.Id)
+		c.Set("token_key", token.Key)
+		c.Set("token_name", token.Name)
+		c.Set("token_unlimited_quota", token.UnlimitedQuota)
+		if !token.UnlimitedQuota {
+			c.Set("token_quota", token.RemainQuota)
+		}
+		common.SetContextKey(c, constant.ContextKeyTokenOrganizationId, token.OrganizationId)
+	}
+	return c, user, nil
+}
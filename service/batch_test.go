@@ -0,0 +1,56 @@
+package service
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/QuantumNous/new-api/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBatchToOpenAIBatch(t *testing.T) {
+	batch := &model.Batch{
+		BatchId:          "batch_abc",
+		Endpoint:         "/v1/chat/completions",
+		InputFileId:      "file-in",
+		CompletionWindow: "24h",
+		Status:           model.BatchStatusInProgress,
+		CreatedAt:        100,
+		InProgressAt:     120,
+		RequestTotal:     3,
+		RequestCompleted: 1,
+	}
+	result := BatchToOpenAIBatch(batch)
+	assert.Equal(t, "batch", result.Object)
+	assert.Equal(t, "batch_abc", result.Id)
+	assert.Nil(t, result.OutputFileId)
+	assert.Nil(t, result.CompletedAt)
+	require.NotNil(t, result.InProgressAt)
+	assert.Equal(t, int64(120), *result.InProgressAt)
+	assert.Equal(t, 3, result.RequestCounts.Total)
+	assert.Equal(t, 1, result.RequestCounts.Completed)
+}
+
+func TestParseLocalBatchInput(t *testing.T) {
+	batch := &model.Batch{Endpoint: "/v1/chat/completions"}
+	input := `{"custom_id":"a","method":"POST","url":"/v1/chat/completions","body":{"model":"gpt-4o-mini"}}
+
+{"custom_id":"b","url":"/v1/chat/completions","body":{"model":"gpt-4o-mini"}}
+`
+	lines, code, err := parseLocalBatchInput(batch, strings.NewReader(input))
+	require.NoError(t, err)
+	assert.Empty(t, code)
+	require.Len(t, lines, 2)
+	assert.Equal(t, "b", lines[1].CustomId)
+
+	_, code, err = parseLocalBatchInput(batch, strings.NewReader(`{"custom_id":"a","url":"/v1/embeddings","body":{}}`))
+	require.Error(t, err)
+	assert.Equal(t, "mismatched_url", code)
+}
+
+func TestLocalBatchExpired(t *testing.T) {
+	assert.False(t, localBatchExpired(&model.Batch{}, 100))
+	assert.False(t, localBatchExpired(&model.Batch{ExpiresAt: 200}, 100))
+	assert.True(t, localBatchExpired(&model.Batch{ExpiresAt: 100}, 100))
+}
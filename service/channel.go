@@ -4,13 +4,18 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/QuantumNous/new-api/common"
 	"github.com/QuantumNous/new-api/constant"
 	"github.com/QuantumNous/new-api/dto"
 	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/pkg/metrics"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
 	"github.com/QuantumNous/new-api/setting/operation_setting"
 	"github.com/QuantumNous/new-api/types"
+
+	"github.com/gin-gonic/gin"
 )
 
 func formatNotifyType(channelId int, status int) string {
@@ -29,6 +34,7 @@ func DisableChannel(channelError types.ChannelError, reason string) {
 
 	success := model.UpdateChannelStatus(channelError.ChannelId, channelError.UsingKey, common.ChannelStatusAutoDisabled, reason)
 	if success {
+		metrics.ObserveChannelStatus(channelError.ChannelId, "disabled")
 		subject := fmt.Sprintf("通道「%s」（#%d）已被禁用", channelError.ChannelName, channelError.ChannelId)
 		content := fmt.Sprintf("通道「%s」（#%d）已被禁用，原因：%s", channelError.ChannelName, channelError.ChannelId, reason)
 		NotifyRootUser(formatNotifyType(channelError.ChannelId, common.ChannelStatusAutoDisabled), subject, content)
@@ -38,6 +44,7 @@ func DisableChannel(channelError types.ChannelError, reason string) {
 func EnableChannel(channelId int, usingKey string, channelName string) {
 	success := model.UpdateChannelStatus(channelId, usingKey, common.ChannelStatusEnabled, "")
 	if success {
+		metrics.ObserveChannelStatus(channelId, "enabled")
 		subject := fmt.Sprintf("通道「%s」（#%d）已被启用", channelName, channelId)
 		content := fmt.Sprintf("通道「%s」（#%d）已被启用", channelName, channelId)
 		NotifyRootUser(formatNotifyType(channelId, common.ChannelStatusEnabled), subject, content)
@@ -113,3 +120,50 @@ func ShouldEnableChannel(newAPIError *types.NewAPIError, status int) bool {
 	}
 	return true
 }
+
+// IsChannelHealthFailure 判断错误是否计入渠道健康统计的失败：只统计上游或渠道侧的问题，不统计用户请求错误
+func IsChannelHealthFailure(err *types.NewAPIError) bool {
+	if err == nil {
+		return false
+	}
+	if types.IsChannelError(err) {
+		return true
+	}
+	if types.IsSkipRetryError(err) {
+		return false
+	}
+	code := err.StatusCode
+	return code < 100 || code >= 500 || code == http.StatusTooManyRequests ||
+		code == http.StatusUnauthorized || code == http.StatusForbidden || code == http.StatusRequestTimeout
+}
+
+// RecordChannelHealth 记录一次渠道请求的结果，供自适应渠道选择、熔断与 Prometheus 指标使用
+func RecordChannelHealth(c *gin.Context, info *relaycommon.RelayInfo, channelId int, attemptStart time.Time, err *types.NewAPIError) {
+	// 命中响应缓存的请求未访问上游
+	if common.GetContextKeyBool(c, constant.ContextKeyResponseCacheHit) {
+		return
+	}
+	success := err == nil
+	duration := time.Since(attemptStart)
+	var ttft time.Duration
+	if success && info.IsStream && info.FirstResponseTime.After(attemptStart) {
+		ttft = info.FirstResponseTime.Sub(attemptStart)
+	}
+	if success {
+		metrics.ObserveUpstreamAttempt(info.OriginModelName, channelId, 0, "", duration, ttft)
+	} else {
+		metrics.ObserveUpstreamAttempt(info.OriginModelName, channelId, err.StatusCode, string(err.GetErrorCode()), duration, 0)
+	}
+
+	if !operation_setting.GetAdaptiveRoutingSetting().Enabled {
+		return
+	}
+	if !success && !IsChannelHealthFailure(err) {
+		return
+	}
+	keyIndex := -1
+	if common.GetContextKeyBool(c, constant.ContextKeyChannelIsMultiKey) {
+		keyIndex = common.GetContextKeyInt(c, constant.ContextKeyChannelMultiKeyIndex)
+	}
+	model.RecordChannelHealth(channelId, keyIndex, success, duration, ttft)
+}
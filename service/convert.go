@@ -3,6 +3,8 @@ package service
 import (
 	"encoding/json"
 	"fmt"
+	"slices"
+	"sort"
 	"strings"
 
 	"github.com/QuantumNous/new-api/common"
@@ -982,3 +984,47 @@ func StreamResponseOpenAI2Gemini(openAIResponse *dto.ChatCompletionsStreamRespon
 
 	return geminiResponse
 }
+
+// GeminiEmbeddingToOpenAIRequest 将 Gemini embedContent / batchEmbedContents 请求转换为 OpenAI Embedding 请求，
+// 每个 Gemini 请求的文本 parts 合并为一条 input
+func GeminiEmbeddingToOpenAIRequest(requests []*dto.GeminiEmbeddingRequest, model string) *dto.EmbeddingRequest {
+	inputs := make([]string, 0, len(requests))
+	var dimensions *int
+	for _, request := range requests {
+		if request == nil {
+			continue
+		}
+		inputs = append(inputs, extractTextFromGeminiParts(request.Content.Parts))
+		if dimensions == nil && request.OutputDimensionality > 0 {
+			dimensions = common.GetPointer(request.OutputDimensionality)
+		}
+	}
+	embeddingRequest := &dto.EmbeddingRequest{
+		Model:      model,
+		Input:      inputs,
+		Dimensions: dimensions,
+	}
+	if len(inputs) == 1 {
+		embeddingRequest.Input = inputs[0]
+	}
+	return embeddingRequest
+}
+
+// ResponseOpenAIEmbedding2Gemini 将 OpenAI Embedding 响应转换为 Gemini embedContent / batchEmbedContents 响应
+func ResponseOpenAIEmbedding2Gemini(openAIResponse *dto.OpenAIEmbeddingResponse, batch bool) any {
+	data := slices.Clone(openAIResponse.Data)
+	sort.SliceStable(data, func(i, j int) bool {
+		return data[i].Index < data[j].Index
+	})
+	embeddings := make([]*dto.ContentEmbedding, 0, len(data))
+	for _, item := range data {
+		embeddings = append(embeddings, &dto.ContentEmbedding{Values: item.Embedding})
+	}
+	if batch {
+		return &dto.GeminiBatchEmbeddingResponse{Embeddings: embeddings}
+	}
+	if len(embeddings) == 0 {
+		return &dto.GeminiEmbeddingResponse{Embedding: dto.ContentEmbedding{Values: []float64{}}}
+	}
+	return &dto.GeminiEmbeddingResponse{Embedding: *embeddings[0]}
+}
@@ -0,0 +1,43 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGeminiEmbeddingToOpenAIRequest(t *testing.T) {
+	single := GeminiEmbeddingToOpenAIRequest([]*dto.GeminiEmbeddingRequest{
+		{Content: dto.GeminiChatContent{Parts: []dto.GeminiPart{{Text: "hello"}, {Text: "world"}}}, OutputDimensionality: 256},
+	}, "text-embedding-3-small")
+	require.Equal(t, "text-embedding-3-small", single.Model)
+	require.Equal(t, "hello\nworld", single.Input)
+	require.Equal(t, 256, *single.Dimensions)
+
+	batch := GeminiEmbeddingToOpenAIRequest([]*dto.GeminiEmbeddingRequest{
+		{Content: dto.GeminiChatContent{Parts: []dto.GeminiPart{{Text: "a"}}}},
+		{Content: dto.GeminiChatContent{Parts: []dto.GeminiPart{{Text: "b"}}}},
+	}, "text-embedding-3-small")
+	require.Equal(t, []string{"a", "b"}, batch.Input)
+	require.Nil(t, batch.Dimensions)
+}
+
+func TestResponseOpenAIEmbedding2Gemini(t *testing.T) {
+	openAIResponse := &dto.OpenAIEmbeddingResponse{
+		Data: []dto.OpenAIEmbeddingResponseItem{
+			{Index: 1, Embedding: []float64{0.3, 0.4}},
+			{Index: 0, Embedding: []float64{0.1, 0.2}},
+		},
+	}
+
+	batch, ok := ResponseOpenAIEmbedding2Gemini(openAIResponse, true).(*dto.GeminiBatchEmbeddingResponse)
+	require.True(t, ok)
+	require.Len(t, batch.Embeddings, 2)
+	require.Equal(t, []float64{0.1, 0.2}, batch.Embeddings[0].Values)
+	require.Equal(t, []float64{0.3, 0.4}, batch.Embeddings[1].Values)
+
+	single, ok := ResponseOpenAIEmbedding2Gemini(openAIResponse, false).(*dto.GeminiEmbeddingResponse)
+	require.True(t, ok)
+	require.Equal(t, []float64{0.1, 0.2}, single.Embedding.Values)
+}
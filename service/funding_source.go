@@ -7,12 +7,12 @@ import (
 )
 
 // ---------------------------------------------------------------------------
-// FundingSource — 资金来源接口（钱包 or 订阅）
+// FundingSource — 资金来源接口（钱包、订阅或组织钱包）
 // ---------------------------------------------------------------------------
 
 // FundingSource 抽象了预扣费的资金来源。
 type FundingSource interface {
-	// Source 返回资金来源标识："wallet" 或 "subscription"
+	// Source 返回资金来源标识："wallet"、"subscription" 或 "organization"
 	Source() string
 	// PreConsume 从该资金来源预扣 amount 额度
 	PreConsume(amount int) error
@@ -63,6 +63,44 @@ func (w *WalletFunding) Refund() error {
 	return model.IncreaseUserQuota(w.userId, w.consumed, false)
 }
 
+// ---------------------------------------------------------------------------
+// OrganizationFunding — 组织钱包资金来源实现
+// ---------------------------------------------------------------------------
+
+// OrganizationFunding 从组织钱包扣费，同时累计成员已用额度以执行成员消费上限
+type OrganizationFunding struct {
+	organizationId int
+	userId         int
+	consumed       int
+}
+
+func (o *OrganizationFunding) Source() string { return BillingSourceOrganization }
+
+func (o *OrganizationFunding) PreConsume(amount int) error {
+	if amount <= 0 {
+		return nil
+	}
+	if err := model.PreConsumeOrganizationQuota(o.organizationId, o.userId, amount); err != nil {
+		return err
+	}
+	o.consumed = amount
+	return nil
+}
+
+func (o *OrganizationFunding) Settle(delta int) error {
+	return model.PostConsumeOrganizationQuota(o.organizationId, o.userId, delta)
+}
+
+func (o *OrganizationFunding) Refund() error {
+	if o.consumed <= 0 {
+		return nil
+	}
+	// 基于事务的调整，失败时整体回滚，可以重试
+	return refundWithRetry(func() error {
+		return model.PostConsumeOrganizationQuota(o.organizationId, o.userId, -o.consumed)
+	})
+}
+
 // ---------------------------------------------------------------------------
 // SubscriptionFunding — 订阅资金来源实现
 // ---------------------------------------------------------------------------
@@ -7,6 +7,7 @@ import (
 	"github.com/QuantumNous/new-api/constant"
 	"github.com/QuantumNous/new-api/dto"
 	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/setting/ratio_setting"
 	"github.com/QuantumNous/new-api/types"
 
 	"github.com/gin-gonic/gin"
@@ -49,6 +50,22 @@ func GenerateTextOtherInfo(ctx *gin.Context, relayInfo *relaycommon.RelayInfo, m
 		other["is_model_mapped"] = true
 		other["upstream_model_name"] = relayInfo.UpstreamModelName
 	}
+	if relayInfo.FallbackFromModel != "" {
+		other["fallback_from_model"] = relayInfo.FallbackFromModel
+	}
+
+	if batchId := common.GetContextKeyString(ctx, constant.ContextKeyBatchId); batchId != "" {
+		other["batch_id"] = batchId
+		other["batch_ratio"] = ratio_setting.GetBatchRatio(relayInfo.OriginModelName)
+	}
+
+	if common.GetContextKeyBool(ctx, constant.ContextKeyResponseCacheHit) {
+		other["response_cache_hit"] = true
+	}
+
+	if moderationResults := GetModerationResults(ctx); len(moderationResults) > 0 {
+		other["moderation"] = moderationResults
+	}
 
 	isSystemPromptOverwritten := common.GetContextKeyBool(ctx, constant.ContextKeySystemPromptOverride)
 	if isSystemPromptOverwritten {
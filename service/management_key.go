@@ -0,0 +1,35 @@
+package service
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequiredManagementScope 返回当前路由声明的管理密钥权限，只声明了资源时按请求方法推导 read / write；
+// 未声明时返回空字符串，表示该路由不允许使用管理密钥访问
+func RequiredManagementScope(c *gin.Context) string {
+	scope := common.GetContextKeyString(c, constant.ContextKeyManagementScope)
+	if scope == "" || strings.Contains(scope, ":") {
+		return scope
+	}
+	switch c.Request.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return scope + ":read"
+	default:
+		return scope + ":write"
+	}
+}
+
+// ManagementKeyAllows 当前请求使用管理密钥时检查其权限，会话与 access token 登录不受限制
+func ManagementKeyAllows(c *gin.Context, scope string) bool {
+	if common.GetContextKeyInt(c, constant.ContextKeyManagementKeyId) == 0 {
+		return true
+	}
+	return model.ManagementScopesAllow(common.GetContextKeyStringSlice(c, constant.ContextKeyManagementKeyScopes), scope)
+}
@@ -0,0 +1,251 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/pkg/mediastore"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+	"github.com/QuantumNous/new-api/setting/system_setting"
+
+	"github.com/bytedance/gopkg/util/gopool"
+)
+
+const (
+	mediaDownloadTimeout      = 10 * time.Minute
+	mediaPurgeInterval        = 1 * time.Hour
+	mediaPurgeBatchSize       = 100
+	mediaDefaultContentType   = "application/octet-stream"
+	mediaContentSniffByteSize = 512
+	mediaPersistConcurrency   = 4
+)
+
+var (
+	mediaStorageTaskOnce     sync.Once
+	mediaStoragePurgeRunning atomic.Bool
+	// 限制同时进行的产物下载，避免大量任务同时完成时占满带宽
+	mediaPersistSlots = make(chan struct{}, mediaPersistConcurrency)
+)
+
+// 常见产物类型的扩展名，标准库的类型表不包含视频格式
+var mediaExtensions = map[string]string{
+	"video/mp4":       ".mp4",
+	"video/webm":      ".webm",
+	"video/quicktime": ".mov",
+	"image/png":       ".png",
+	"image/jpeg":      ".jpg",
+	"image/webp":      ".webp",
+	"image/gif":       ".gif",
+	"audio/mpeg":      ".mp3",
+	"audio/wav":       ".wav",
+}
+
+// GetMediaStore 按后端名称构造存储实例，每次调用时读取当前配置，修改设置后立即生效
+func GetMediaStore(backend string) (mediastore.Store, error) {
+	setting := operation_setting.GetMediaStorageSetting()
+	switch backend {
+	case operation_setting.MediaStorageBackendLocal:
+		return mediastore.NewLocalStore(setting.LocalPath), nil
+	case operation_setting.MediaStorageBackendS3:
+		return mediastore.NewS3Store(mediastore.S3Config{
+			Endpoint:        setting.S3Endpoint,
+			Region:          setting.S3Region,
+			Bucket:          setting.S3Bucket,
+			AccessKeyId:     setting.S3AccessKeyId,
+			SecretAccessKey: setting.S3AccessSecret,
+			PathStyle:       setting.S3PathStyle,
+		}, GetHttpClient())
+	default:
+		return nil, fmt.Errorf("unsupported media storage backend: %s", backend)
+	}
+}
+
+func mediaObjectKey(taskID string, contentType string) string {
+	if ext, ok := mediaExtensions[contentType]; ok {
+		return taskID + ext
+	}
+	if exts, _ := mime.ExtensionsByType(contentType); len(exts) > 0 {
+		return taskID + exts[0]
+	}
+	return taskID
+}
+
+func mediaSignature(key string, expiresAt int64) string {
+	return common.GenerateHMAC(fmt.Sprintf("media:%s:%d", key, expiresAt))
+}
+
+// SignMediaURL 生成本站的产物访问地址，签名有效期与对象保留期一致，expiresAt 为 0 时不过期
+func SignMediaURL(key string, expiresAt int64) string {
+	return fmt.Sprintf("%s/v1/media/%s?expires=%d&signature=%s", system_setting.ServerAddress, key, expiresAt, mediaSignature(key, expiresAt))
+}
+
+// VerifyMediaSignature 校验签名地址，过期或签名不匹配时返回 false
+func VerifyMediaSignature(key string, expiresAt int64, signature string, now int64) bool {
+	if expiresAt != 0 && expiresAt <= now {
+		return false
+	}
+	return hmac.Equal([]byte(signature), []byte(mediaSignature(key, expiresAt)))
+}
+
+// PersistTaskMedia 开启媒体存储时下载任务产物写入存储，并把结果地址改写为带签名的本站地址。
+// 只处理 updateVideoSingleTask 轮询的单产物任务，Suno（task.Data 中的多段音频）不在此列。
+// 失败时保留上游地址，不影响任务状态
+func PersistTaskMedia(ctx context.Context, ch *model.Channel, task *model.Task, taskResult *relaycommon.TaskInfo) error {
+	setting := operation_setting.GetMediaStorageSetting()
+	if !setting.Enabled {
+		return nil
+	}
+	store, err := GetMediaStore(setting.Backend)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, mediaDownloadTimeout)
+	defer cancel()
+
+	artifact, err := OpenTaskArtifact(ctx, ch, task, taskResult)
+	if err != nil {
+		return err
+	}
+	defer artifact.Body.Close()
+	body, contentType := artifact.Body, artifact.Header.Get("Content-Type")
+
+	// 先落到临时文件，得到大小后再上传（S3 要求 Content-Length）
+	tmp, err := os.CreateTemp("", "new-api-media-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		tmp.Close()
+		os.Remove(tmp.Name())
+	}()
+	maxBytes := int64(setting.MaxSizeMB) << 20
+	reader := io.Reader(body)
+	if maxBytes > 0 {
+		reader = io.LimitReader(body, maxBytes+1)
+	}
+	size, err := io.Copy(tmp, reader)
+	if err != nil {
+		return err
+	}
+	if maxBytes > 0 && size > maxBytes {
+		return fmt.Errorf("artifact exceeds %d MB", setting.MaxSizeMB)
+	}
+
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+		contentType = mediaType
+	}
+	if contentType == "" || contentType == mediaDefaultContentType {
+		head := make([]byte, mediaContentSniffByteSize)
+		n, _ := tmp.ReadAt(head, 0)
+		contentType, _, _ = mime.ParseMediaType(http.DetectContentType(head[:n]))
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	key := mediaObjectKey(task.TaskID, contentType)
+	if err := store.Put(ctx, key, tmp, size, contentType); err != nil {
+		return err
+	}
+	var expiresAt int64
+	if setting.RetentionDays > 0 {
+		expiresAt = time.Now().Add(time.Duration(setting.RetentionDays) * 24 * time.Hour).Unix()
+	}
+	object := &model.MediaObject{
+		ExpiresAt:   expiresAt,
+		TaskId:      task.TaskID,
+		UserId:      task.UserId,
+		Backend:     setting.Backend,
+		Key:         key,
+		ContentType: contentType,
+		Size:        size,
+	}
+	if err := object.Insert(); err != nil {
+		return err
+	}
+	task.PrivateData.ResultURL = SignMediaURL(key, expiresAt)
+	return task.UpdatePrivateData()
+}
+
+// PersistTaskMediaAsync 在后台持久化任务产物，不阻塞任务轮询。
+// 无论成功与否，持久化结束后才调用 done（用于回调入队），使回调中的结果地址尽量已是本站地址
+func PersistTaskMediaAsync(ctx context.Context, ch *model.Channel, task *model.Task, taskResult *relaycommon.TaskInfo, done func()) {
+	if !operation_setting.GetMediaStorageSetting().Enabled {
+		done()
+		return
+	}
+	ctx = context.WithoutCancel(ctx)
+	gopool.Go(func() {
+		mediaPersistSlots <- struct{}{}
+		defer func() {
+			<-mediaPersistSlots
+			done()
+		}()
+		if err := PersistTaskMedia(ctx, ch, task, taskResult); err != nil {
+			logger.LogWarn(ctx, fmt.Sprintf("Persist media for task %s failed, keep upstream url: %s", task.TaskID, err.Error()))
+		}
+	})
+}
+
+// StartMediaStorageTask 主节点定时删除超过保留期的产物
+func StartMediaStorageTask() {
+	mediaStorageTaskOnce.Do(func() {
+		if !common.IsMasterNode {
+			return
+		}
+		gopool.Go(func() {
+			logger.LogInfo(context.Background(), fmt.Sprintf("media storage purge task started: interval=%s", mediaPurgeInterval))
+			ticker := time.NewTicker(mediaPurgeInterval)
+			defer ticker.Stop()
+			for {
+				runMediaPurgeOnce()
+				<-ticker.C
+			}
+		})
+	})
+}
+
+func runMediaPurgeOnce() {
+	if !mediaStoragePurgeRunning.CompareAndSwap(false, true) {
+		return
+	}
+	defer mediaStoragePurgeRunning.Store(false)
+
+	ctx := context.Background()
+	objects, err := model.GetExpiredMediaObjects(time.Now().Unix(), mediaPurgeBatchSize)
+	if err != nil {
+		logger.LogWarn(ctx, fmt.Sprintf("load expired media objects failed: %v", err))
+		return
+	}
+	purged := 0
+	for _, object := range objects {
+		store, err := GetMediaStore(object.Backend)
+		if err == nil {
+			err = store.Delete(ctx, object.Key)
+		}
+		if err != nil {
+			logger.LogWarn(ctx, fmt.Sprintf("delete media object %s failed: %v", object.Key, err))
+			continue
+		}
+		if err := object.Delete(); err != nil {
+			logger.LogWarn(ctx, fmt.Sprintf("delete media object record %d failed: %v", object.Id, err))
+			continue
+		}
+		purged++
+	}
+	if purged > 0 {
+		logger.LogInfo(ctx, fmt.Sprintf("media storage purged: count=%d", purged))
+	}
+}
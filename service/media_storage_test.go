@@ -0,0 +1,125 @@
+package service
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/model"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+	"github.com/QuantumNous/new-api/setting/system_setting"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyMediaSignature(t *testing.T) {
+	signature := mediaSignature("task_a.mp4", 2000)
+	require.True(t, VerifyMediaSignature("task_a.mp4", 2000, signature, 1000))
+	require.False(t, VerifyMediaSignature("task_a.mp4", 2000, signature, 2000))
+	require.False(t, VerifyMediaSignature("task_b.mp4", 2000, signature, 1000))
+	require.False(t, VerifyMediaSignature("task_a.mp4", 3000, signature, 1000))
+	require.True(t, VerifyMediaSignature("task_a.mp4", 0, mediaSignature("task_a.mp4", 0), 1000))
+}
+
+func TestPersistTaskMedia(t *testing.T) {
+	truncate(t)
+	InitHttpClient()
+	require.NoError(t, model.DB.AutoMigrate(&model.MediaObject{}))
+	t.Cleanup(func() { model.DB.Exec("DELETE FROM media_objects") })
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "video/mp4")
+		_, _ = w.Write([]byte("video-bytes"))
+	}))
+	defer upstream.Close()
+
+	setting := operation_setting.GetMediaStorageSetting()
+	saved := *setting
+	t.Cleanup(func() { *setting = saved })
+	setting.Enabled = true
+	setting.Backend = operation_setting.MediaStorageBackendLocal
+	setting.LocalPath = t.TempDir()
+	setting.RetentionDays = 1
+
+	task := &model.Task{TaskID: "task_media", UserId: 1, Status: model.TaskStatusSuccess}
+	require.NoError(t, task.Insert())
+	ch := &model.Channel{Type: constant.ChannelTypeKling}
+
+	// 任务结果中的上游地址需通过 SSRF 校验
+	fetchSetting := system_setting.GetFetchSetting()
+	savedFetch := *fetchSetting
+	t.Cleanup(func() { *fetchSetting = savedFetch })
+	fetchSetting.EnableSSRFProtection = true
+	fetchSetting.AllowPrivateIp = false
+	err := PersistTaskMedia(context.Background(), ch, task, &relaycommon.TaskInfo{Url: upstream.URL + "/result.mp4"})
+	require.ErrorContains(t, err, "request reject")
+	require.Empty(t, task.PrivateData.ResultURL)
+
+	fetchSetting.EnableSSRFProtection = false
+	err = PersistTaskMedia(context.Background(), ch, task, &relaycommon.TaskInfo{Url: upstream.URL + "/result.mp4"})
+	require.NoError(t, err)
+
+	resultURL, err := url.Parse(task.PrivateData.ResultURL)
+	require.NoError(t, err)
+	require.Equal(t, "/v1/media/task_media.mp4", resultURL.Path)
+	expiresAt, _ := strconv.ParseInt(resultURL.Query().Get("expires"), 10, 64)
+	require.True(t, VerifyMediaSignature("task_media.mp4", expiresAt, resultURL.Query().Get("signature"), common.GetTimestamp()))
+
+	object, err := model.GetMediaObjectByTaskId("task_media")
+	require.NoError(t, err)
+	require.Equal(t, "video/mp4", object.ContentType)
+	require.EqualValues(t, len("video-bytes"), object.Size)
+
+	store, err := GetMediaStore(object.Backend)
+	require.NoError(t, err)
+	body, _, err := store.Get(context.Background(), object.Key)
+	require.NoError(t, err)
+	data, _ := io.ReadAll(body)
+	body.Close()
+	require.Equal(t, "video-bytes", string(data))
+
+	stored, exists, err := model.GetByOnlyTaskId("task_media")
+	require.NoError(t, err)
+	require.True(t, exists)
+	require.Equal(t, task.PrivateData.ResultURL, stored.PrivateData.ResultURL)
+}
+
+func TestPersistTaskMediaAsyncCallsDoneOnFailure(t *testing.T) {
+	InitHttpClient()
+	setting := operation_setting.GetMediaStorageSetting()
+	saved := *setting
+	t.Cleanup(func() { *setting = saved })
+	setting.Enabled = true
+	setting.Backend = operation_setting.MediaStorageBackendLocal
+	setting.LocalPath = t.TempDir()
+
+	done := make(chan struct{})
+	task := &model.Task{TaskID: "task_media_async", UserId: 1}
+	ch := &model.Channel{Type: constant.ChannelTypeKling}
+	PersistTaskMediaAsync(context.Background(), ch, task, &relaycommon.TaskInfo{Url: "not-a-url"}, func() { close(done) })
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("done was not called")
+	}
+	require.Empty(t, task.PrivateData.ResultURL)
+}
+
+func TestOpenTaskArtifactDataURL(t *testing.T) {
+	task := &model.Task{TaskID: "task_data_url"}
+	ch := &model.Channel{Type: constant.ChannelTypeVertexAi}
+	artifact, err := OpenTaskArtifact(context.Background(), ch, task, &relaycommon.TaskInfo{Url: "data:video/mp4;base64,dmlkZW8="})
+	require.NoError(t, err)
+	defer artifact.Body.Close()
+	data, _ := io.ReadAll(artifact.Body)
+	require.Equal(t, "video", string(data))
+	require.Equal(t, "video/mp4", artifact.Header.Get("Content-Type"))
+}
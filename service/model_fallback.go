@@ -0,0 +1,84 @@
+package service
+
+import (
+	"slices"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/setting/model_setting"
+	"github.com/QuantumNous/new-api/setting/ratio_setting"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ModelFallbackHeader 发生模型降级时在响应头中返回实际使用的模型
+const ModelFallbackHeader = "X-New-Api-Fallback-Model"
+
+// IsModelFallbackAllowed 判断当前令牌是否允许模型降级，令牌未设置时跟随系统默认值
+func IsModelFallbackAllowed(c *gin.Context) bool {
+	settings := model_setting.GetModelFallbackSettings()
+	if !settings.Enabled {
+		return false
+	}
+	switch common.GetContextKeyInt(c, constant.ContextKeyTokenModelFallback) {
+	case common.TokenModelFallbackEnabled:
+		return true
+	case common.TokenModelFallbackDisabled:
+		return false
+	default:
+		return settings.DefaultEnabled
+	}
+}
+
+// GetModelFallbackCandidates 返回请求模型可用的降级模型，过滤掉令牌模型限制之外的模型
+func GetModelFallbackCandidates(c *gin.Context, modelName string) []string {
+	if !IsModelFallbackAllowed(c) {
+		return nil
+	}
+	chain := model_setting.GetModelFallbackChain(modelName)
+	if len(chain) == 0 || !common.GetContextKeyBool(c, constant.ContextKeyTokenModelLimitEnabled) {
+		return chain
+	}
+	limit, _ := common.GetContextKeyType[map[string]bool](c, constant.ContextKeyTokenModelLimit)
+	candidates := make([]string, 0, len(chain))
+	for _, fallback := range chain {
+		if limit[ratio_setting.FormatMatchingModelName(fallback)] {
+			candidates = append(candidates, fallback)
+		}
+	}
+	return candidates
+}
+
+// GetRemainingModelFallbackCandidates 分发阶段已经降级到 currentModel 时，重试只继续使用降级链中排在它之后的模型
+func GetRemainingModelFallbackCandidates(c *gin.Context, requestedModel string, currentModel string) []string {
+	candidates := GetModelFallbackCandidates(c, requestedModel)
+	if index := slices.Index(candidates, currentModel); index >= 0 {
+		return candidates[index+1:]
+	}
+	return candidates
+}
+
+// SelectModelFallbackChannel 请求模型没有可用渠道时（例如渠道全部被自动禁用），按降级链选择第一个已定价且有可用渠道的模型，
+// 返回选中的渠道、降级模型与实际分组；没有可用的降级模型时返回 nil
+func SelectModelFallbackChannel(c *gin.Context, modelName string, group string) (*model.Channel, string, string) {
+	for _, fallback := range GetModelFallbackCandidates(c, modelName) {
+		if _, usePrice := ratio_setting.GetModelPrice(fallback, false); !usePrice {
+			if _, ok, _ := ratio_setting.GetModelRatio(fallback); !ok {
+				continue
+			}
+		}
+		common.SetContextKey(c, constant.ContextKeyAutoGroupIndex, 0)
+		common.SetContextKey(c, constant.ContextKeyAutoGroupRetryIndex, 0)
+		channel, selectGroup, err := CacheGetRandomSatisfiedChannel(&RetryParam{
+			Ctx:        c,
+			ModelName:  fallback,
+			TokenGroup: group,
+			Retry:      common.GetPointer(0),
+		})
+		if err == nil && channel != nil {
+			return channel, fallback, selectGroup
+		}
+	}
+	return nil, "", ""
+}
@@ -0,0 +1,54 @@
+package service
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/setting/model_setting"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetModelFallbackCandidates(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	settings := model_setting.GetModelFallbackSettings()
+	saved := *settings
+	t.Cleanup(func() { *settings = saved })
+
+	settings.Enabled = true
+	settings.DefaultEnabled = true
+	settings.Chains = map[string][]string{
+		"gpt-4o": {"gpt-4.1", " ", "gpt-4o", "claude-sonnet-4-5", "gpt-4.1"},
+	}
+
+	newContext := func() *gin.Context {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		return c
+	}
+
+	c := newContext()
+	require.Equal(t, []string{"gpt-4.1", "claude-sonnet-4-5"}, GetModelFallbackCandidates(c, "gpt-4o"))
+	require.Empty(t, GetModelFallbackCandidates(c, "gpt-4.1"))
+
+	// 令牌模型限制之外的降级模型会被跳过
+	common.SetContextKey(c, constant.ContextKeyTokenModelLimitEnabled, true)
+	common.SetContextKey(c, constant.ContextKeyTokenModelLimit, map[string]bool{"gpt-4o": true, "claude-sonnet-4-5": true})
+	require.Equal(t, []string{"claude-sonnet-4-5"}, GetModelFallbackCandidates(c, "gpt-4o"))
+
+	// 令牌显式关闭降级
+	c = newContext()
+	common.SetContextKey(c, constant.ContextKeyTokenModelFallback, common.TokenModelFallbackDisabled)
+	require.Empty(t, GetModelFallbackCandidates(c, "gpt-4o"))
+
+	// 系统默认关闭时，令牌可以显式开启
+	settings.DefaultEnabled = false
+	require.Empty(t, GetModelFallbackCandidates(newContext(), "gpt-4o"))
+	c = newContext()
+	common.SetContextKey(c, constant.ContextKeyTokenModelFallback, common.TokenModelFallbackEnabled)
+	require.Len(t, GetModelFallbackCandidates(c, "gpt-4o"), 2)
+
+	settings.Enabled = false
+	require.Empty(t, GetModelFallbackCandidates(c, "gpt-4o"))
+}
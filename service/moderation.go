@@ -0,0 +1,346 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/model"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+	"github.com/QuantumNous/new-api/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	ModerationStageInput  = "input"
+	ModerationStageOutput = "output"
+
+	moderationRedactedText = "[redacted]"
+)
+
+// ModerationResult 一次审核的结果，记录在消费日志中
+type ModerationResult struct {
+	Stage      string   `json:"stage"`
+	Action     string   `json:"action"`
+	Flagged    bool     `json:"flagged"`
+	Categories []string `json:"categories,omitempty"`
+	Error      string   `json:"error,omitempty"`
+}
+
+type moderationResponse struct {
+	Results []struct {
+		Flagged        bool               `json:"flagged"`
+		Categories     map[string]bool    `json:"categories"`
+		CategoryScores map[string]float64 `json:"category_scores"`
+	} `json:"results"`
+}
+
+// ModerationPolicyFor 返回请求分组生效的审核策略，未启用时返回 nil
+func ModerationPolicyFor(info *relaycommon.RelayInfo) *operation_setting.ModerationPolicy {
+	return operation_setting.GetModerationPolicy(info.UsingGroup)
+}
+
+// doModerationRequest 请求审核渠道的 /v1/moderations，多 key 渠道按渠道配置的轮询/随机方式选 key，跳过已禁用的 key
+func doModerationRequest(ctx context.Context, channel *model.Channel, body []byte) (*http.Response, error) {
+	if channel.Status != common.ChannelStatusEnabled {
+		return nil, fmt.Errorf("moderation channel #%d is disabled", channel.Id)
+	}
+	key, _, apiErr := channel.GetNextEnabledKey()
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	return DoOpenAIFileRequestWithKey(ctx, channel, key, http.MethodPost, "moderations", bytes.NewReader(body), "application/json", int64(len(body)))
+}
+
+// moderateTexts 调用审核渠道，返回每段文本命中的类别
+func moderateTexts(ctx context.Context, policy *operation_setting.ModerationPolicy, texts []string) ([][]string, error) {
+	setting := operation_setting.GetModerationSetting()
+	channel, err := model.CacheGetChannel(setting.ChannelId)
+	if err != nil {
+		return nil, fmt.Errorf("moderation channel #%d is unavailable: %w", setting.ChannelId, err)
+	}
+	payload := map[string]any{"input": texts}
+	if setting.Model != "" {
+		payload["model"] = setting.Model
+	}
+	body, err := common.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(max(setting.TimeoutSeconds, 1))*time.Second)
+	defer cancel()
+	resp, err := doModerationRequest(ctx, channel, body)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("moderation request failed with status %d: %s", resp.StatusCode, string(data))
+	}
+	var result moderationResponse
+	if err := common.Unmarshal(data, &result); err != nil {
+		return nil, err
+	}
+	if len(result.Results) != len(texts) {
+		return nil, fmt.Errorf("moderation returned %d results for %d inputs", len(result.Results), len(texts))
+	}
+	hits := make([][]string, len(texts))
+	for i, r := range result.Results {
+		hits[i] = moderationHitCategories(policy, r.Flagged, r.Categories, r.CategoryScores)
+	}
+	return hits, nil
+}
+
+// moderationHitCategories 按策略阈值判断命中的类别，未配置阈值的类别使用审核服务的判断
+func moderationHitCategories(policy *operation_setting.ModerationPolicy, flagged bool, categories map[string]bool, scores map[string]float64) []string {
+	var hit []string
+	for category, score := range scores {
+		if threshold, ok := policy.Thresholds[category]; ok && score >= threshold {
+			hit = append(hit, category)
+		}
+	}
+	if flagged {
+		for category, matched := range categories {
+			if _, ok := policy.Thresholds[category]; !ok && matched {
+				hit = append(hit, category)
+			}
+		}
+	}
+	sort.Strings(hit)
+	return hit
+}
+
+func addModerationResult(c *gin.Context, result ModerationResult) {
+	results, _ := common.GetContextKeyType[[]ModerationResult](c, constant.ContextKeyModerationResults)
+	common.SetContextKey(c, constant.ContextKeyModerationResults, append(results, result))
+}
+
+// GetModerationResults 返回请求的审核结果，用于写入日志
+func GetModerationResults(c *gin.Context) []ModerationResult {
+	results, _ := common.GetContextKeyType[[]ModerationResult](c, constant.ContextKeyModerationResults)
+	return results
+}
+
+func moderationError(categories []string) *types.NewAPIError {
+	return types.NewErrorWithStatusCode(fmt.Errorf("content blocked by moderation: %s", strings.Join(categories, ", ")),
+		types.ErrorCodeModerationBlocked, http.StatusBadRequest, types.ErrOptionWithSkipRetry())
+}
+
+// ModerateInput 审核请求内容。对话补全请求逐条消息审核，redact 时屏蔽命中的消息；
+// 其他请求审核合并后的文本，无法定位到具体内容，redact 按 block 处理。开启请求体透传的渠道不受 redact 影响
+func ModerateInput(c *gin.Context, info *relaycommon.RelayInfo, request dto.Request, combineText string) *types.NewAPIError {
+	policy := ModerationPolicyFor(info)
+	if policy == nil {
+		return nil
+	}
+	textReq, isChat := request.(*dto.GeneralOpenAIRequest)
+	var texts []string
+	var indexes []int
+	if isChat {
+		for i := range textReq.Messages {
+			if text := textReq.Messages[i].StringContent(); strings.TrimSpace(text) != "" {
+				texts = append(texts, text)
+				indexes = append(indexes, i)
+			}
+		}
+	} else if strings.TrimSpace(combineText) != "" {
+		texts = []string{combineText}
+	}
+	if len(texts) == 0 {
+		return nil
+	}
+
+	result := ModerationResult{Stage: ModerationStageInput, Action: policy.Action}
+	hits, err := moderateTexts(c.Request.Context(), policy, texts)
+	if err != nil {
+		logger.LogError(c, "input moderation failed: "+err.Error())
+		result.Error = err.Error()
+		addModerationResult(c, result)
+		if operation_setting.GetModerationSetting().FailOpen {
+			return nil
+		}
+		return types.NewErrorWithStatusCode(err, types.ErrorCodeModerationFailed, http.StatusServiceUnavailable, types.ErrOptionWithSkipRetry())
+	}
+	categorySet := make(map[string]struct{})
+	for i, categories := range hits {
+		if len(categories) == 0 {
+			continue
+		}
+		for _, category := range categories {
+			categorySet[category] = struct{}{}
+		}
+		if policy.Action == operation_setting.ModerationActionRedact && isChat {
+			textReq.Messages[indexes[i]].SetStringContent(moderationRedactedText)
+		}
+	}
+	for category := range categorySet {
+		result.Categories = append(result.Categories, category)
+	}
+	sort.Strings(result.Categories)
+	result.Flagged = len(result.Categories) > 0
+	addModerationResult(c, result)
+	if !result.Flagged {
+		return nil
+	}
+
+	logger.LogWarn(c, fmt.Sprintf("input moderation flagged (%s): %s", policy.Action, strings.Join(result.Categories, ", ")))
+	if policy.Action == operation_setting.ModerationActionBlock ||
+		(policy.Action == operation_setting.ModerationActionRedact && !isChat) {
+		return moderationError(result.Categories)
+	}
+	return nil
+}
+
+// ModerationOutputBuffer 缓冲非流式响应，审核通过后再写给客户端
+type ModerationOutputBuffer struct {
+	gin.ResponseWriter
+	original gin.ResponseWriter
+	policy   *operation_setting.ModerationPolicy
+	mu       sync.Mutex
+	buf      bytes.Buffer
+}
+
+// StartOutputModeration 策略要求审核输出且为非流式请求时替换 c.Writer，否则返回 nil。
+// 输出审核只接入了 TextHelper 的非流式对话补全：流式响应、Responses、Claude/Gemini 原生格式、
+// 图像/音频以及 WebSocket 会话都不审核输出，这些请求仍按策略审核输入
+func StartOutputModeration(c *gin.Context, info *relaycommon.RelayInfo) *ModerationOutputBuffer {
+	if info.IsStream {
+		return nil
+	}
+	policy := ModerationPolicyFor(info)
+	if policy == nil || !policy.CheckOutput {
+		return nil
+	}
+	buffer := &ModerationOutputBuffer{
+		ResponseWriter: c.Writer,
+		original:       c.Writer,
+		policy:         policy,
+	}
+	c.Writer = buffer
+	return buffer
+}
+
+func (b *ModerationOutputBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *ModerationOutputBuffer) WriteString(s string) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.WriteString(s)
+}
+
+// Flush 在审核完成前不向客户端输出
+func (b *ModerationOutputBuffer) Flush() {}
+
+// Finish 恢复 c.Writer 并审核缓冲的响应：通过或 flag 时原样输出，redact 时屏蔽命中的回复，block 时返回错误。
+// 上游已产生消耗，被拦截的响应仍正常计费；响应处理失败时丢弃缓冲内容，由上层返回错误
+func (b *ModerationOutputBuffer) Finish(c *gin.Context, success bool) {
+	if b == nil {
+		return
+	}
+	c.Writer = b.original
+	if !success {
+		return
+	}
+	b.mu.Lock()
+	body := b.buf.Bytes()
+	b.mu.Unlock()
+
+	var response map[string]any
+	if err := common.Unmarshal(body, &response); err != nil {
+		_, _ = c.Writer.Write(body)
+		return
+	}
+	choices, _ := response["choices"].([]any)
+	var texts []string
+	var messages []map[string]any
+	for _, choice := range choices {
+		choiceMap, _ := choice.(map[string]any)
+		message, _ := choiceMap["message"].(map[string]any)
+		if content, ok := message["content"].(string); ok && strings.TrimSpace(content) != "" {
+			texts = append(texts, content)
+			messages = append(messages, message)
+		}
+	}
+	if len(texts) == 0 {
+		_, _ = c.Writer.Write(body)
+		return
+	}
+
+	result := ModerationResult{Stage: ModerationStageOutput, Action: b.policy.Action}
+	hits, err := moderateTexts(c.Request.Context(), b.policy, texts)
+	if err != nil {
+		logger.LogError(c, "output moderation failed: "+err.Error())
+		result.Error = err.Error()
+		addModerationResult(c, result)
+		if operation_setting.GetModerationSetting().FailOpen {
+			_, _ = c.Writer.Write(body)
+			return
+		}
+		b.writeError(c, types.NewErrorWithStatusCode(err, types.ErrorCodeModerationFailed, http.StatusServiceUnavailable))
+		return
+	}
+	categorySet := make(map[string]struct{})
+	for i, categories := range hits {
+		if len(categories) == 0 {
+			continue
+		}
+		for _, category := range categories {
+			categorySet[category] = struct{}{}
+		}
+		if b.policy.Action == operation_setting.ModerationActionRedact {
+			messages[i]["content"] = moderationRedactedText
+		}
+	}
+	for category := range categorySet {
+		result.Categories = append(result.Categories, category)
+	}
+	sort.Strings(result.Categories)
+	result.Flagged = len(result.Categories) > 0
+	addModerationResult(c, result)
+	if !result.Flagged {
+		_, _ = c.Writer.Write(body)
+		return
+	}
+
+	logger.LogWarn(c, fmt.Sprintf("output moderation flagged (%s): %s", b.policy.Action, strings.Join(result.Categories, ", ")))
+	switch b.policy.Action {
+	case operation_setting.ModerationActionBlock:
+		b.writeError(c, moderationError(result.Categories))
+	case operation_setting.ModerationActionRedact:
+		if redacted, err := common.Marshal(response); err == nil {
+			body = redacted
+		}
+		c.Writer.Header().Del("Content-Length")
+		_, _ = c.Writer.Write(body)
+	default:
+		_, _ = c.Writer.Write(body)
+	}
+}
+
+func (b *ModerationOutputBuffer) writeError(c *gin.Context, apiErr *types.NewAPIError) {
+	apiErr.SetMessage(common.MessageWithRequestId(apiErr.Error(), c.GetString(common.RequestIdKey)))
+	c.Writer.Header().Del("Content-Length")
+	c.JSON(apiErr.StatusCode, gin.H{
+		"error": apiErr.ToOpenAIError(),
+	})
+}
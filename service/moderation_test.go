@@ -0,0 +1,39 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestModerationHitCategories(t *testing.T) {
+	policy := &operation_setting.ModerationPolicy{
+		Action:     operation_setting.ModerationActionBlock,
+		Thresholds: map[string]float64{"violence": 0.5, "harassment": 0.9},
+	}
+	categories := map[string]bool{"violence": false, "harassment": true, "self-harm": true}
+	scores := map[string]float64{"violence": 0.6, "harassment": 0.7, "self-harm": 0.95}
+
+	// 配置了阈值的类别按分数判断，其余类别沿用审核服务的结果
+	require.Equal(t, []string{"self-harm", "violence"}, moderationHitCategories(policy, true, categories, scores))
+	require.Equal(t, []string{"violence"}, moderationHitCategories(policy, false, categories, scores))
+	require.Empty(t, moderationHitCategories(&operation_setting.ModerationPolicy{}, false, categories, scores))
+}
+
+func TestSensitiveWordAcCache(t *testing.T) {
+	dict := []string{"foo", "Bar"}
+	m := getOrBuildAC(dict)
+	require.NotNil(t, m)
+	require.Same(t, m, getOrBuildAC(dict))
+
+	ok, words := AcSearch("xx bar yy", dict, false)
+	require.True(t, ok)
+	require.Equal(t, []string{"bar"}, words)
+
+	// 替换词表后重新构建
+	dict = []string{"baz"}
+	ok, _ = AcSearch("xx bar yy", dict, false)
+	require.False(t, ok)
+}
@@ -0,0 +1,113 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/model"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+)
+
+// IsOpenAIFileChannel 判断渠道是否可以承载 OpenAI 平台类接口（files / batches / fine_tuning）。
+// 只有走 OpenAI 适配器且 URL 可推导的渠道才支持，自定义渠道需要完整 URL，无法拼接子路径。
+func IsOpenAIFileChannel(channelType int) bool {
+	if channelType == constant.ChannelTypeCustom {
+		return false
+	}
+	apiType, _ := common.ChannelType2APIType(channelType)
+	return apiType == constant.APITypeOpenAI
+}
+
+// GetChannelKeyByIndex 返回渠道指定下标的 key，单 key 渠道直接返回 Key
+func GetChannelKeyByIndex(channel *model.Channel, index int) string {
+	if !channel.ChannelInfo.IsMultiKey {
+		return channel.Key
+	}
+	keys := channel.GetKeys()
+	if index >= 0 && index < len(keys) {
+		return keys[index]
+	}
+	if len(keys) > 0 {
+		return keys[0]
+	}
+	return ""
+}
+
+// BuildOpenAIFileURL 拼接渠道上的 OpenAI 平台接口地址，path 形如 "files/file-xxx/content"。
+// Azure 使用 /openai/{path}?api-version=xxx 形式。
+func BuildOpenAIFileURL(channel *model.Channel, path string) string {
+	baseURL := channel.GetBaseURL()
+	if baseURL == "" {
+		baseURL = constant.ChannelBaseURLs[channel.Type]
+	}
+	baseURL = strings.TrimSuffix(baseURL, "/")
+	path = strings.TrimPrefix(path, "/")
+	if channel.Type == constant.ChannelTypeAzure {
+		apiVersion := channel.Other
+		if apiVersion == "" {
+			apiVersion = constant.AzureDefaultAPIVersion
+		}
+		sep := "?"
+		if strings.Contains(path, "?") {
+			sep = "&"
+		}
+		requestURL := fmt.Sprintf("/openai/%s%sapi-version=%s", path, sep, apiVersion)
+		return relaycommon.GetFullRequestURL(baseURL, requestURL, channel.Type)
+	}
+	return relaycommon.GetFullRequestURL(baseURL, "/v1/"+path, channel.Type)
+}
+
+// DoOpenAIFileRequest 使用渠道的指定 key 向上游发送 OpenAI 平台接口请求。
+// contentLength < 0 时由 http 包自行决定（分块传输）。
+func DoOpenAIFileRequest(ctx context.Context, channel *model.Channel, keyIndex int, method string, path string,
+	body io.Reader, contentType string, contentLength int64) (*http.Response, error) {
+	return DoOpenAIFileRequestWithKey(ctx, channel, GetChannelKeyByIndex(channel, keyIndex), method, path, body, contentType, contentLength)
+}
+
+// DoOpenAIFileRequestWithKey 同 DoOpenAIFileRequest，直接指定 key（任务记录中保存的是 key 本身）
+func DoOpenAIFileRequestWithKey(ctx context.Context, channel *model.Channel, key string, method string, path string,
+	body io.Reader, contentType string, contentLength int64) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, BuildOpenAIFileURL(channel, path), body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil && contentLength >= 0 {
+		req.ContentLength = contentLength
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if channel.Type == constant.ChannelTypeAzure {
+		req.Header.Set("api-key", key)
+	} else {
+		req.Header.Set("Authorization", "Bearer "+key)
+		if channel.OpenAIOrganization != nil && *channel.OpenAIOrganization != "" {
+			req.Header.Set("OpenAI-Organization", *channel.OpenAIOrganization)
+		}
+	}
+	client, err := GetHttpClientWithProxy(channel.GetSetting().Proxy)
+	if err != nil {
+		return nil, err
+	}
+	return client.Do(req)
+}
+
+// GetPinnedFileChannel 返回文件上传时所用的渠道，引用该文件的后续请求必须发往同一渠道
+func GetPinnedFileChannel(file *model.File) (*model.Channel, error) {
+	if file == nil {
+		return nil, fmt.Errorf("file is nil")
+	}
+	channel, err := model.CacheGetChannel(file.ChannelId)
+	if err != nil {
+		return nil, fmt.Errorf("channel #%d of file %s is unavailable: %w", file.ChannelId, file.FileId, err)
+	}
+	if channel.Status != common.ChannelStatusEnabled {
+		return nil, fmt.Errorf("channel #%d of file %s is disabled", file.ChannelId, file.FileId)
+	}
+	return channel, nil
+}
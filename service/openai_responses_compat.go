@@ -0,0 +1,44 @@
+package service
+
+import (
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/service/openaicompat"
+)
+
+type ResponsesStreamBuilder = openaicompat.ResponsesStreamBuilder
+
+type ClaudeResponsesStreamConverter = openaicompat.ClaudeResponsesStreamConverter
+
+type ChatResponsesStreamConverter = openaicompat.ChatResponsesStreamConverter
+
+func NewResponsesStreamBuilder(responseID string, createdAt int64, model string) *ResponsesStreamBuilder {
+	return openaicompat.NewResponsesStreamBuilder(responseID, createdAt, model)
+}
+
+func ResponsesRequestToClaudeRequest(req *dto.OpenAIResponsesRequest) (*dto.ClaudeRequest, error) {
+	return openaicompat.ResponsesRequestToClaudeRequest(req)
+}
+
+func ClaudeResponseToResponsesResponse(resp *dto.ClaudeResponse, id string, createdAt int64) *dto.OpenAIResponsesResponse {
+	return openaicompat.ClaudeResponseToResponsesResponse(resp, id, createdAt)
+}
+
+func NewClaudeResponsesStreamConverter(responseID string, createdAt int64, model string) *ClaudeResponsesStreamConverter {
+	return openaicompat.NewClaudeResponsesStreamConverter(responseID, createdAt, model)
+}
+
+func ResponsesRequestToChatCompletionsRequest(req *dto.OpenAIResponsesRequest) (*dto.GeneralOpenAIRequest, error) {
+	return openaicompat.ResponsesRequestToChatCompletionsRequest(req)
+}
+
+func ChatCompletionsResponseToResponsesResponse(resp *dto.OpenAITextResponse, id string, createdAt int64) *dto.OpenAIResponsesResponse {
+	return openaicompat.ChatCompletionsResponseToResponsesResponse(resp, id, createdAt)
+}
+
+func ChatUsageToResponsesUsage(usage *dto.Usage) *dto.Usage {
+	return openaicompat.ChatUsageToResponsesUsage(usage)
+}
+
+func NewChatResponsesStreamConverter(responseID string, createdAt int64, model string) *ChatResponsesStreamConverter {
+	return openaicompat.NewChatResponsesStreamConverter(responseID, createdAt, model)
+}
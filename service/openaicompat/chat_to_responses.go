@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strconv"
 	"strings"
 
 	"github.com/QuantumNous/new-api/common"
@@ -400,3 +401,173 @@ func ChatCompletionsRequestToResponsesRequest(req *dto.GeneralOpenAIRequest) (*d
 
 	return out, nil
 }
+
+// ChatUsageToResponsesUsage 将 chat 的 prompt/completion 计数转换为 Responses 的 input/output 计数
+func ChatUsageToResponsesUsage(usage *dto.Usage) *dto.Usage {
+	if usage == nil {
+		return &dto.Usage{InputTokensDetails: &dto.InputTokenDetails{}}
+	}
+	totalTokens := usage.TotalTokens
+	if totalTokens == 0 {
+		totalTokens = usage.PromptTokens + usage.CompletionTokens
+	}
+	return &dto.Usage{
+		InputTokens:  usage.PromptTokens,
+		OutputTokens: usage.CompletionTokens,
+		TotalTokens:  totalTokens,
+		InputTokensDetails: &dto.InputTokenDetails{
+			CachedTokens: usage.PromptTokensDetails.CachedTokens,
+		},
+	}
+}
+
+func chatFinishReasonToResponsesStatus(finishReason string) (string, *dto.IncompleteDetails) {
+	switch finishReason {
+	case "length":
+		return "incomplete", &dto.IncompleteDetails{Reason: "max_output_tokens"}
+	case "content_filter":
+		return "incomplete", &dto.IncompleteDetails{Reason: "content_filter"}
+	default:
+		return "completed", nil
+	}
+}
+
+// ChatCompletionsResponseToResponsesResponse 将 Chat Completions 非流式响应转换为 Responses 响应，仅取第一个 choice
+func ChatCompletionsResponseToResponsesResponse(resp *dto.OpenAITextResponse, id string, createdAt int64) *dto.OpenAIResponsesResponse {
+	if len(resp.Choices) == 0 {
+		out := newResponsesResponse(id, createdAt, resp.Model, "completed")
+		out.Usage = ChatUsageToResponsesUsage(&resp.Usage)
+		return out
+	}
+	choice := resp.Choices[0]
+	status, incomplete := chatFinishReasonToResponsesStatus(choice.FinishReason)
+	out := newResponsesResponse(id, createdAt, resp.Model, status)
+	out.IncompleteDetails = incomplete
+	out.Usage = ChatUsageToResponsesUsage(&resp.Usage)
+
+	reasoning := choice.Message.ReasoningContent
+	if reasoning == "" {
+		reasoning = choice.Message.Reasoning
+	}
+	if reasoning != "" {
+		out.Output = append(out.Output, dto.ResponsesOutput{
+			Type:    "reasoning",
+			ID:      newResponsesItemID("rs"),
+			Summary: []dto.ResponsesReasoningSummaryPart{{Type: "summary_text", Text: reasoning}},
+		})
+	}
+	if text := choice.Message.StringContent(); text != "" {
+		out.Output = append(out.Output, dto.ResponsesOutput{
+			Type:   "message",
+			ID:     newResponsesItemID("msg"),
+			Status: "completed",
+			Role:   "assistant",
+			Content: []dto.ResponsesOutputContent{{
+				Type:        "output_text",
+				Text:        text,
+				Annotations: []interface{}{},
+			}},
+		})
+	}
+	for _, call := range choice.Message.ParseToolCalls() {
+		arguments := call.Function.Arguments
+		if strings.TrimSpace(arguments) == "" {
+			arguments = "{}"
+		}
+		out.Output = append(out.Output, dto.ResponsesOutput{
+			Type:      "function_call",
+			ID:        newResponsesItemID("fc"),
+			Status:    "completed",
+			CallId:    call.ID,
+			Name:      call.Function.Name,
+			Arguments: arguments,
+		})
+	}
+	return out
+}
+
+const (
+	chatResponsesMessageKey   = "message"
+	chatResponsesReasoningKey = "reasoning"
+)
+
+// ChatResponsesStreamConverter 将 Chat Completions 流式分片转换为 Responses 流事件，仅取第一个 choice：
+// 连续的文本 / 推理内容各自合并为一个输出项，每个 tool_call 按 index 对应一个 function_call 项
+type ChatResponsesStreamConverter struct {
+	builder      *ResponsesStreamBuilder
+	finishReason string
+	usage        *dto.Usage
+}
+
+func NewChatResponsesStreamConverter(responseID string, createdAt int64, model string) *ChatResponsesStreamConverter {
+	return &ChatResponsesStreamConverter{
+		builder: NewResponsesStreamBuilder(responseID, createdAt, model),
+	}
+}
+
+// Finished 是否已经发送 response.completed / response.incomplete
+func (s *ChatResponsesStreamConverter) Finished() bool {
+	return s.builder.Finished()
+}
+
+func (s *ChatResponsesStreamConverter) appendText(key string, text string) []dto.ResponsesStreamResponse {
+	var events []dto.ResponsesStreamResponse
+	if !s.builder.IsOpen(key) {
+		if key == chatResponsesMessageKey {
+			events = append(events, s.builder.Close(chatResponsesReasoningKey)...)
+			events = append(events, s.builder.OpenMessage(key)...)
+		} else {
+			events = append(events, s.builder.Close(chatResponsesMessageKey)...)
+			events = append(events, s.builder.OpenReasoning(key)...)
+		}
+	}
+	return append(events, s.builder.AppendDelta(key, text)...)
+}
+
+// Convert 处理一个 chat 流式分片，返回需要按顺序下发的 Responses 事件
+func (s *ChatResponsesStreamConverter) Convert(chunk *dto.ChatCompletionsStreamResponse) []dto.ResponsesStreamResponse {
+	if s.builder.Finished() {
+		return nil
+	}
+	s.builder.SetModel(chunk.Model)
+	if chunk.Usage != nil {
+		s.usage = chunk.Usage
+	}
+	events := s.builder.Start()
+	if len(chunk.Choices) == 0 {
+		return events
+	}
+	choice := chunk.Choices[0]
+	if reasoning := choice.Delta.GetReasoningContent(); reasoning != "" {
+		events = append(events, s.appendText(chatResponsesReasoningKey, reasoning)...)
+	}
+	if content := choice.Delta.GetContentString(); content != "" {
+		events = append(events, s.appendText(chatResponsesMessageKey, content)...)
+	}
+	for i, call := range choice.Delta.ToolCalls {
+		index := i
+		if call.Index != nil {
+			index = *call.Index
+		}
+		key := "call_" + strconv.Itoa(index)
+		if !s.builder.IsOpen(key) {
+			events = append(events, s.builder.Close(chatResponsesReasoningKey)...)
+			events = append(events, s.builder.Close(chatResponsesMessageKey)...)
+			events = append(events, s.builder.OpenFunctionCall(key, call.ID, call.Function.Name)...)
+		}
+		events = append(events, s.builder.AppendDelta(key, call.Function.Arguments)...)
+	}
+	if choice.FinishReason != nil && *choice.FinishReason != "" {
+		s.finishReason = *choice.FinishReason
+	}
+	return events
+}
+
+// Finish 关闭未结束的输出项并发送最终事件，usage 为空时使用流中携带的用量
+func (s *ChatResponsesStreamConverter) Finish(usage *dto.Usage) []dto.ResponsesStreamResponse {
+	if usage == nil {
+		usage = s.usage
+	}
+	status, incomplete := chatFinishReasonToResponsesStatus(s.finishReason)
+	return s.builder.Finish(status, incomplete, ChatUsageToResponsesUsage(usage))
+}
@@ -0,0 +1,240 @@
+package openaicompat
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/dto"
+)
+
+// ClaudeUsageToResponsesUsage Responses 的 input_tokens 包含缓存命中与缓存写入，Claude 的 input_tokens 不包含
+func ClaudeUsageToResponsesUsage(usage *dto.ClaudeUsage) *dto.Usage {
+	if usage == nil {
+		return &dto.Usage{InputTokensDetails: &dto.InputTokenDetails{}}
+	}
+	inputTokens := usage.InputTokens + usage.CacheReadInputTokens + usage.GetCacheCreationTotalTokens()
+	return &dto.Usage{
+		InputTokens:  inputTokens,
+		OutputTokens: usage.OutputTokens,
+		TotalTokens:  inputTokens + usage.OutputTokens,
+		InputTokensDetails: &dto.InputTokenDetails{
+			CachedTokens: usage.CacheReadInputTokens,
+		},
+	}
+}
+
+func claudeStopReasonToResponsesStatus(stopReason string) (string, *dto.IncompleteDetails) {
+	switch stopReason {
+	case "max_tokens":
+		return "incomplete", &dto.IncompleteDetails{Reason: "max_output_tokens"}
+	case "refusal":
+		return "incomplete", &dto.IncompleteDetails{Reason: "content_filter"}
+	default:
+		return "completed", nil
+	}
+}
+
+func newResponsesItemID(prefix string) string {
+	return prefix + "_" + strings.ReplaceAll(common.GetUUID(), "-", "")
+}
+
+func newResponsesResponse(id string, createdAt int64, model string, status string) *dto.OpenAIResponsesResponse {
+	statusJson, _ := common.Marshal(status)
+	return &dto.OpenAIResponsesResponse{
+		ID:        id,
+		Object:    "response",
+		CreatedAt: int(createdAt),
+		Status:    statusJson,
+		Model:     model,
+		Output:    []dto.ResponsesOutput{},
+	}
+}
+
+func claudeToolInputToArguments(input any) string {
+	if input == nil {
+		return "{}"
+	}
+	arguments, err := common.Marshal(input)
+	if err != nil {
+		return "{}"
+	}
+	return string(arguments)
+}
+
+// ClaudeResponseToResponsesResponse 将 Claude Messages 非流式响应转换为 Responses 响应。
+// thinking 块映射为 reasoning 项（签名放入 encrypted_content），tool_use 映射为 function_call。
+func ClaudeResponseToResponsesResponse(resp *dto.ClaudeResponse, id string, createdAt int64) *dto.OpenAIResponsesResponse {
+	status, incomplete := claudeStopReasonToResponsesStatus(resp.StopReason)
+	out := newResponsesResponse(id, createdAt, resp.Model, status)
+	out.IncompleteDetails = incomplete
+	out.Usage = ClaudeUsageToResponsesUsage(resp.Usage)
+
+	var message *dto.ResponsesOutput
+	flushMessage := func() {
+		if message != nil {
+			out.Output = append(out.Output, *message)
+			message = nil
+		}
+	}
+	for _, block := range resp.Content {
+		switch block.Type {
+		case "text":
+			if message == nil {
+				message = &dto.ResponsesOutput{
+					Type:   "message",
+					ID:     newResponsesItemID("msg"),
+					Status: "completed",
+					Role:   "assistant",
+				}
+			}
+			message.Content = append(message.Content, dto.ResponsesOutputContent{
+				Type:        "output_text",
+				Text:        block.GetText(),
+				Annotations: []interface{}{},
+			})
+		case "thinking":
+			flushMessage()
+			item := dto.ResponsesOutput{
+				Type:             "reasoning",
+				ID:               newResponsesItemID("rs"),
+				Summary:          []dto.ResponsesReasoningSummaryPart{},
+				EncryptedContent: block.Signature,
+			}
+			if block.Thinking != nil && *block.Thinking != "" {
+				item.Summary = append(item.Summary, dto.ResponsesReasoningSummaryPart{Type: "summary_text", Text: *block.Thinking})
+			}
+			out.Output = append(out.Output, item)
+		case "tool_use":
+			flushMessage()
+			out.Output = append(out.Output, dto.ResponsesOutput{
+				Type:      "function_call",
+				ID:        newResponsesItemID("fc"),
+				Status:    "completed",
+				CallId:    block.Id,
+				Name:      block.Name,
+				Arguments: claudeToolInputToArguments(block.Input),
+			})
+		}
+	}
+	flushMessage()
+	return out
+}
+
+// ClaudeResponsesStreamConverter 将 Claude Messages 流事件逐条转换为 Responses 流事件，
+// 每个内容块按 index 对应一个输出项
+type ClaudeResponsesStreamConverter struct {
+	builder    *ResponsesStreamBuilder
+	usage      dto.ClaudeUsage
+	stopReason string
+}
+
+func NewClaudeResponsesStreamConverter(responseID string, createdAt int64, model string) *ClaudeResponsesStreamConverter {
+	return &ClaudeResponsesStreamConverter{
+		builder: NewResponsesStreamBuilder(responseID, createdAt, model),
+	}
+}
+
+// Finished 是否已经发送 response.completed / response.incomplete
+func (s *ClaudeResponsesStreamConverter) Finished() bool {
+	return s.builder.Finished()
+}
+
+func (s *ClaudeResponsesStreamConverter) mergeUsage(usage *dto.ClaudeUsage) {
+	if usage == nil {
+		return
+	}
+	if usage.InputTokens > 0 {
+		s.usage.InputTokens = usage.InputTokens
+	}
+	if usage.CacheReadInputTokens > 0 {
+		s.usage.CacheReadInputTokens = usage.CacheReadInputTokens
+	}
+	if usage.CacheCreationInputTokens > 0 {
+		s.usage.CacheCreationInputTokens = usage.CacheCreationInputTokens
+	}
+	if usage.CacheCreation != nil {
+		s.usage.CacheCreation = usage.CacheCreation
+	}
+	if usage.OutputTokens > 0 {
+		s.usage.OutputTokens = usage.OutputTokens
+	}
+}
+
+// Convert 处理一条 Claude 流事件，返回需要按顺序下发的 Responses 事件
+func (s *ClaudeResponsesStreamConverter) Convert(claudeResponse *dto.ClaudeResponse) []dto.ResponsesStreamResponse {
+	if s.builder.Finished() {
+		return nil
+	}
+	key := strconv.Itoa(claudeResponse.GetIndex())
+	switch claudeResponse.Type {
+	case "message_start":
+		if claudeResponse.Message != nil {
+			s.builder.SetModel(claudeResponse.Message.Model)
+			s.mergeUsage(claudeResponse.Message.Usage)
+		}
+		return s.builder.Start()
+	case "content_block_start":
+		if claudeResponse.ContentBlock != nil {
+			return s.startBlock(key, claudeResponse.ContentBlock)
+		}
+	case "content_block_delta":
+		if claudeResponse.Delta != nil {
+			return s.deltaBlock(key, claudeResponse.Delta)
+		}
+	case "content_block_stop":
+		return s.builder.Close(key)
+	case "message_delta":
+		if claudeResponse.Delta != nil && claudeResponse.Delta.StopReason != nil {
+			s.stopReason = *claudeResponse.Delta.StopReason
+		}
+		s.mergeUsage(claudeResponse.Usage)
+	case "message_stop":
+		return s.Finish()
+	}
+	return nil
+}
+
+func (s *ClaudeResponsesStreamConverter) startBlock(key string, contentBlock *dto.ClaudeMediaMessage) []dto.ResponsesStreamResponse {
+	switch contentBlock.Type {
+	case "text":
+		events := s.builder.OpenMessage(key)
+		return append(events, s.builder.AppendDelta(key, contentBlock.GetText())...)
+	case "thinking":
+		return s.builder.OpenReasoning(key)
+	case "tool_use":
+		return s.builder.OpenFunctionCall(key, contentBlock.Id, contentBlock.Name)
+	}
+	// server_tool_use 等服务端工具块不透出
+	return nil
+}
+
+func (s *ClaudeResponsesStreamConverter) deltaBlock(key string, delta *dto.ClaudeMediaMessage) []dto.ResponsesStreamResponse {
+	switch delta.Type {
+	case "text_delta":
+		if delta.Text != nil {
+			return s.builder.AppendDelta(key, *delta.Text)
+		}
+	case "thinking_delta":
+		if delta.Thinking != nil {
+			return s.builder.AppendDelta(key, *delta.Thinking)
+		}
+	case "signature_delta":
+		s.builder.AppendEncryptedContent(key, delta.Signature)
+	case "input_json_delta":
+		if delta.PartialJson != nil {
+			return s.builder.AppendDelta(key, *delta.PartialJson)
+		}
+	}
+	return nil
+}
+
+// Finish 关闭未结束的内容块并发送最终事件；上游未正常结束时发送 response.incomplete
+func (s *ClaudeResponsesStreamConverter) Finish() []dto.ResponsesStreamResponse {
+	status, incomplete := claudeStopReasonToResponsesStatus(s.stopReason)
+	if s.stopReason == "" {
+		status, incomplete = "incomplete", &dto.IncompleteDetails{Reason: "interrupted"}
+	}
+	usage := s.usage
+	return s.builder.Finish(status, incomplete, ClaudeUsageToResponsesUsage(&usage))
+}
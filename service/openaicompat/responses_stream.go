@@ -0,0 +1,244 @@
+package openaicompat
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/dto"
+)
+
+type responsesStreamItem struct {
+	outputIndex int
+	item        dto.ResponsesOutput
+	text        strings.Builder
+}
+
+// ResponsesStreamBuilder 按 /v1/responses 流式协议生成事件（序号、输出项生命周期、最终响应），
+// 各上游格式的流转换只需按 key 打开、追加、关闭输出项。
+type ResponsesStreamBuilder struct {
+	responseID string
+	createdAt  int64
+	model      string
+
+	sequence int
+	output   []dto.ResponsesOutput
+	items    map[string]*responsesStreamItem
+	started  bool
+	response *dto.OpenAIResponsesResponse
+}
+
+func NewResponsesStreamBuilder(responseID string, createdAt int64, model string) *ResponsesStreamBuilder {
+	return &ResponsesStreamBuilder{
+		responseID: responseID,
+		createdAt:  createdAt,
+		model:      model,
+		items:      make(map[string]*responsesStreamItem),
+	}
+}
+
+func (b *ResponsesStreamBuilder) SetModel(model string) {
+	if model != "" {
+		b.model = model
+	}
+}
+
+func (b *ResponsesStreamBuilder) event(ev dto.ResponsesStreamResponse) dto.ResponsesStreamResponse {
+	ev.SequenceNumber = b.sequence
+	b.sequence++
+	return ev
+}
+
+func (b *ResponsesStreamBuilder) snapshot(status string) *dto.OpenAIResponsesResponse {
+	resp := newResponsesResponse(b.responseID, b.createdAt, b.model, status)
+	resp.Output = append(resp.Output, b.output...)
+	return resp
+}
+
+// Start 发送 response.created / response.in_progress，仅首次调用生效
+func (b *ResponsesStreamBuilder) Start() []dto.ResponsesStreamResponse {
+	if b.started {
+		return nil
+	}
+	b.started = true
+	return []dto.ResponsesStreamResponse{
+		b.event(dto.ResponsesStreamResponse{Type: "response.created", Response: b.snapshot("in_progress")}),
+		b.event(dto.ResponsesStreamResponse{Type: "response.in_progress", Response: b.snapshot("in_progress")}),
+	}
+}
+
+// Finished 是否已经发送最终事件
+func (b *ResponsesStreamBuilder) Finished() bool {
+	return b.response != nil
+}
+
+// Response 最终响应，Finish 之前为 nil
+func (b *ResponsesStreamBuilder) Response() *dto.OpenAIResponsesResponse {
+	return b.response
+}
+
+func (b *ResponsesStreamBuilder) IsOpen(key string) bool {
+	_, ok := b.items[key]
+	return ok
+}
+
+func (b *ResponsesStreamBuilder) open(key string, item dto.ResponsesOutput) (*responsesStreamItem, []dto.ResponsesStreamResponse) {
+	events := b.Start()
+	streamItem := &responsesStreamItem{outputIndex: len(b.output), item: item}
+	b.items[key] = streamItem
+	b.output = append(b.output, item)
+	events = append(events, b.event(dto.ResponsesStreamResponse{
+		Type:        dto.ResponsesOutputTypeItemAdded,
+		OutputIndex: common.GetPointer(streamItem.outputIndex),
+		Item:        &item,
+	}))
+	return streamItem, events
+}
+
+// OpenMessage 打开 assistant 消息输出项
+func (b *ResponsesStreamBuilder) OpenMessage(key string) []dto.ResponsesStreamResponse {
+	streamItem, events := b.open(key, dto.ResponsesOutput{
+		Type:    "message",
+		ID:      newResponsesItemID("msg"),
+		Status:  "in_progress",
+		Role:    "assistant",
+		Content: []dto.ResponsesOutputContent{},
+	})
+	return append(events, b.event(dto.ResponsesStreamResponse{
+		Type:         "response.content_part.added",
+		ItemID:       streamItem.item.ID,
+		OutputIndex:  common.GetPointer(streamItem.outputIndex),
+		ContentIndex: common.GetPointer(0),
+		Part:         &dto.ResponsesReasoningSummaryPart{Type: "output_text"},
+	}))
+}
+
+// OpenReasoning 打开 reasoning 输出项，思考内容作为 summary_text 输出
+func (b *ResponsesStreamBuilder) OpenReasoning(key string) []dto.ResponsesStreamResponse {
+	streamItem, events := b.open(key, dto.ResponsesOutput{
+		Type:    "reasoning",
+		ID:      newResponsesItemID("rs"),
+		Summary: []dto.ResponsesReasoningSummaryPart{},
+	})
+	return append(events, b.event(dto.ResponsesStreamResponse{
+		Type:         "response.reasoning_summary_part.added",
+		ItemID:       streamItem.item.ID,
+		OutputIndex:  common.GetPointer(streamItem.outputIndex),
+		SummaryIndex: common.GetPointer(0),
+		Part:         &dto.ResponsesReasoningSummaryPart{Type: "summary_text"},
+	}))
+}
+
+// OpenFunctionCall 打开 function_call 输出项
+func (b *ResponsesStreamBuilder) OpenFunctionCall(key string, callId string, name string) []dto.ResponsesStreamResponse {
+	_, events := b.open(key, dto.ResponsesOutput{
+		Type:   "function_call",
+		ID:     newResponsesItemID("fc"),
+		Status: "in_progress",
+		CallId: callId,
+		Name:   name,
+	})
+	return events
+}
+
+// AppendDelta 向已打开的输出项追加文本 / 思考内容 / 函数参数
+func (b *ResponsesStreamBuilder) AppendDelta(key string, delta string) []dto.ResponsesStreamResponse {
+	streamItem := b.items[key]
+	if streamItem == nil || delta == "" {
+		return nil
+	}
+	streamItem.text.WriteString(delta)
+	ev := dto.ResponsesStreamResponse{
+		ItemID:      streamItem.item.ID,
+		OutputIndex: common.GetPointer(streamItem.outputIndex),
+		Delta:       delta,
+	}
+	switch streamItem.item.Type {
+	case "message":
+		ev.Type = "response.output_text.delta"
+		ev.ContentIndex = common.GetPointer(0)
+	case "reasoning":
+		ev.Type = "response.reasoning_summary_text.delta"
+		ev.SummaryIndex = common.GetPointer(0)
+	case "function_call":
+		ev.Type = "response.function_call_arguments.delta"
+	}
+	return []dto.ResponsesStreamResponse{b.event(ev)}
+}
+
+// AppendEncryptedContent 追加 reasoning 的签名内容（不单独下发事件）
+func (b *ResponsesStreamBuilder) AppendEncryptedContent(key string, content string) {
+	if streamItem := b.items[key]; streamItem != nil {
+		streamItem.item.EncryptedContent += content
+	}
+}
+
+// Close 关闭输出项并发送对应的 done 事件
+func (b *ResponsesStreamBuilder) Close(key string) []dto.ResponsesStreamResponse {
+	streamItem := b.items[key]
+	if streamItem == nil {
+		return nil
+	}
+	delete(b.items, key)
+
+	var events []dto.ResponsesStreamResponse
+	text := streamItem.text.String()
+	outputIndex := common.GetPointer(streamItem.outputIndex)
+	item := streamItem.item
+	switch item.Type {
+	case "message":
+		part := &dto.ResponsesReasoningSummaryPart{Type: "output_text", Text: text}
+		events = append(events,
+			b.event(dto.ResponsesStreamResponse{Type: "response.output_text.done", ItemID: item.ID, OutputIndex: outputIndex, ContentIndex: common.GetPointer(0), Text: text}),
+			b.event(dto.ResponsesStreamResponse{Type: "response.content_part.done", ItemID: item.ID, OutputIndex: outputIndex, ContentIndex: common.GetPointer(0), Part: part}),
+		)
+		item.Status = "completed"
+		item.Content = []dto.ResponsesOutputContent{{Type: "output_text", Text: text, Annotations: []interface{}{}}}
+	case "reasoning":
+		part := &dto.ResponsesReasoningSummaryPart{Type: "summary_text", Text: text}
+		events = append(events,
+			b.event(dto.ResponsesStreamResponse{Type: "response.reasoning_summary_text.done", ItemID: item.ID, OutputIndex: outputIndex, SummaryIndex: common.GetPointer(0), Text: text}),
+			b.event(dto.ResponsesStreamResponse{Type: "response.reasoning_summary_part.done", ItemID: item.ID, OutputIndex: outputIndex, SummaryIndex: common.GetPointer(0), Part: part}),
+		)
+		if text != "" {
+			item.Summary = []dto.ResponsesReasoningSummaryPart{*part}
+		}
+	case "function_call":
+		if strings.TrimSpace(text) == "" {
+			text = "{}"
+		}
+		events = append(events, b.event(dto.ResponsesStreamResponse{Type: "response.function_call_arguments.done", ItemID: item.ID, OutputIndex: outputIndex, Arguments: text}))
+		item.Status = "completed"
+		item.Arguments = text
+	}
+	b.output[streamItem.outputIndex] = item
+	events = append(events, b.event(dto.ResponsesStreamResponse{Type: dto.ResponsesOutputTypeItemDone, OutputIndex: outputIndex, Item: &item}))
+	return events
+}
+
+// Finish 按输出顺序关闭剩余输出项，并发送 response.completed（status 为 incomplete 时发送 response.incomplete）
+func (b *ResponsesStreamBuilder) Finish(status string, incomplete *dto.IncompleteDetails, usage *dto.Usage) []dto.ResponsesStreamResponse {
+	if b.Finished() {
+		return nil
+	}
+	events := b.Start()
+	keys := make([]string, 0, len(b.items))
+	for key := range b.items {
+		keys = append(keys, key)
+	}
+	slices.SortFunc(keys, func(x, y string) int {
+		return b.items[x].outputIndex - b.items[y].outputIndex
+	})
+	for _, key := range keys {
+		events = append(events, b.Close(key)...)
+	}
+
+	b.response = b.snapshot(status)
+	b.response.IncompleteDetails = incomplete
+	b.response.Usage = usage
+	eventType := "response.completed"
+	if status == "incomplete" {
+		eventType = "response.incomplete"
+	}
+	return append(events, b.event(dto.ResponsesStreamResponse{Type: eventType, Response: b.response}))
+}
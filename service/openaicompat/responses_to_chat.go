@@ -2,8 +2,10 @@ package openaicompat
 
 import (
 	"errors"
+	"fmt"
 	"strings"
 
+	"github.com/QuantumNous/new-api/common"
 	"github.com/QuantumNous/new-api/dto"
 )
 
@@ -131,3 +133,309 @@ func ExtractOutputTextFromResponses(resp *dto.OpenAIResponsesResponse) string {
 	}
 	return sb.String()
 }
+
+// chatMessageBuilder 按顺序累积 chat 消息，连续的 function_call 合并到同一条 assistant 消息的 tool_calls 中
+type chatMessageBuilder struct {
+	messages  []dto.Message
+	toolCalls map[int][]dto.ToolCallRequest
+}
+
+func (b *chatMessageBuilder) flushToolCalls() {
+	if n := len(b.messages); n > 0 {
+		if calls := b.toolCalls[n-1]; len(calls) > 0 {
+			b.messages[n-1].SetToolCalls(calls)
+		}
+	}
+}
+
+func (b *chatMessageBuilder) append(msg dto.Message) {
+	b.flushToolCalls()
+	b.messages = append(b.messages, msg)
+}
+
+func (b *chatMessageBuilder) appendToolCall(call dto.ToolCallRequest) {
+	n := len(b.messages)
+	if n == 0 || b.messages[n-1].Role != "assistant" {
+		b.append(dto.Message{Role: "assistant", Content: ""})
+		n = len(b.messages)
+	}
+	b.toolCalls[n-1] = append(b.toolCalls[n-1], call)
+}
+
+func (b *chatMessageBuilder) build() []dto.Message {
+	b.flushToolCalls()
+	return b.messages
+}
+
+// responsesContentToChatContent 转换 message 中的内容，纯文本保持为字符串，其余转为 chat 内容数组
+func responsesContentToChatContent(content any) (any, error) {
+	switch v := content.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case []any:
+		parts := make([]any, 0, len(v))
+		for _, partAny := range v {
+			part, ok := partAny.(map[string]any)
+			if !ok {
+				continue
+			}
+			switch partType := common.Interface2String(part["type"]); partType {
+			case "input_text", "output_text", "text":
+				parts = append(parts, map[string]any{
+					"type": dto.ContentTypeText,
+					"text": common.Interface2String(part["text"]),
+				})
+			case "refusal":
+				parts = append(parts, map[string]any{
+					"type": dto.ContentTypeText,
+					"text": common.Interface2String(part["refusal"]),
+				})
+			case "input_image":
+				url := common.Interface2String(normalizeChatImageURLToString(part["image_url"]))
+				if url == "" {
+					return nil, fmt.Errorf("input_image without image_url is not supported")
+				}
+				imageUrl := map[string]any{"url": url}
+				if detail := common.Interface2String(part["detail"]); detail != "" {
+					imageUrl["detail"] = detail
+				}
+				parts = append(parts, map[string]any{
+					"type":      dto.ContentTypeImageURL,
+					"image_url": imageUrl,
+				})
+			case "input_file":
+				file := map[string]any{}
+				for _, key := range []string{"file_id", "file_data", "filename"} {
+					if value := common.Interface2String(part[key]); value != "" {
+						file[key] = value
+					}
+				}
+				if len(file) == 0 {
+					return nil, fmt.Errorf("input_file without file_id or file_data is not supported")
+				}
+				parts = append(parts, map[string]any{
+					"type": dto.ContentTypeFile,
+					"file": file,
+				})
+			case "input_audio":
+				parts = append(parts, map[string]any{
+					"type":        dto.ContentTypeInputAudio,
+					"input_audio": part["input_audio"],
+				})
+			default:
+				return nil, fmt.Errorf("unsupported content type %q", partType)
+			}
+		}
+		if len(parts) == 1 {
+			if part := parts[0].(map[string]any); part["type"] == dto.ContentTypeText {
+				return part["text"], nil
+			}
+		}
+		return parts, nil
+	default:
+		return nil, fmt.Errorf("unsupported content format %T", content)
+	}
+}
+
+func (b *chatMessageBuilder) addInputItem(item map[string]any) error {
+	itemType := common.Interface2String(item["type"])
+	if itemType == "" && item["role"] != nil {
+		itemType = "message"
+	}
+	switch itemType {
+	case "message":
+		content, err := responsesContentToChatContent(item["content"])
+		if err != nil {
+			return err
+		}
+		role := common.Interface2String(item["role"])
+		if role == "developer" {
+			role = "system"
+		}
+		b.append(dto.Message{Role: role, Content: content})
+	case "function_call":
+		b.appendToolCall(dto.ToolCallRequest{
+			ID:   common.Interface2String(item["call_id"]),
+			Type: "function",
+			Function: dto.FunctionRequest{
+				Name:      common.Interface2String(item["name"]),
+				Arguments: common.Interface2String(item["arguments"]),
+			},
+		})
+	case "function_call_output":
+		var output string
+		if s, ok := item["output"].(string); ok {
+			output = s
+		} else if item["output"] != nil {
+			outputBytes, err := common.Marshal(item["output"])
+			if err != nil {
+				return err
+			}
+			output = string(outputBytes)
+		}
+		b.append(dto.Message{
+			Role:       "tool",
+			Content:    output,
+			ToolCallId: common.Interface2String(item["call_id"]),
+		})
+	case "reasoning":
+		// chat 接口无法回传推理内容
+	default:
+		return fmt.Errorf("unsupported input item type %q", itemType)
+	}
+	return nil
+}
+
+func responsesToolsToChatTools(raw []byte) ([]dto.ToolCallRequest, error) {
+	var tools []map[string]any
+	if err := common.Unmarshal(raw, &tools); err != nil {
+		return nil, err
+	}
+	chatTools := make([]dto.ToolCallRequest, 0, len(tools))
+	for _, tool := range tools {
+		if toolType := common.Interface2String(tool["type"]); toolType != "function" {
+			return nil, fmt.Errorf("unsupported tool type %q", toolType)
+		}
+		chatTools = append(chatTools, dto.ToolCallRequest{
+			Type: "function",
+			Function: dto.FunctionRequest{
+				Name:        common.Interface2String(tool["name"]),
+				Description: common.Interface2String(tool["description"]),
+				Parameters:  tool["parameters"],
+			},
+		})
+	}
+	return chatTools, nil
+}
+
+func responsesToolChoiceToChat(raw []byte) any {
+	switch common.GetJsonType(raw) {
+	case "string":
+		var mode string
+		_ = common.Unmarshal(raw, &mode)
+		return mode
+	case "object":
+		var obj map[string]any
+		_ = common.Unmarshal(raw, &obj)
+		// Responses: {"type":"function","name":"..."}
+		// Chat: {"type":"function","function":{"name":"..."}}
+		if common.Interface2String(obj["type"]) == "function" {
+			if name := common.Interface2String(obj["name"]); name != "" {
+				return map[string]any{
+					"type":     "function",
+					"function": map[string]any{"name": name},
+				}
+			}
+		}
+		return obj
+	}
+	return nil
+}
+
+func responsesTextToChatResponseFormat(raw []byte) *dto.ResponseFormat {
+	if common.GetJsonType(raw) != "object" {
+		return nil
+	}
+	var text struct {
+		Format map[string]any `json:"format"`
+	}
+	if err := common.Unmarshal(raw, &text); err != nil || text.Format == nil {
+		return nil
+	}
+	formatType := common.Interface2String(text.Format["type"])
+	switch formatType {
+	case "json_object":
+		return &dto.ResponseFormat{Type: formatType}
+	case "json_schema":
+		jsonSchema := make(map[string]any, len(text.Format))
+		for key, value := range text.Format {
+			if key != "type" {
+				jsonSchema[key] = value
+			}
+		}
+		schemaRaw, _ := common.Marshal(jsonSchema)
+		return &dto.ResponseFormat{Type: formatType, JsonSchema: schemaRaw}
+	}
+	return nil
+}
+
+// ResponsesRequestToChatCompletionsRequest 将 /v1/responses 请求降级为 Chat Completions 请求，
+// 用于没有原生 Responses 支持的渠道
+func ResponsesRequestToChatCompletionsRequest(req *dto.OpenAIResponsesRequest) (*dto.GeneralOpenAIRequest, error) {
+	if req == nil {
+		return nil, errors.New("request is nil")
+	}
+	if req.Model == "" {
+		return nil, errors.New("model is required")
+	}
+	if req.PreviousResponseID != "" {
+		return nil, errors.New("previous_response_id is not supported for this model")
+	}
+
+	builder := &chatMessageBuilder{toolCalls: make(map[int][]dto.ToolCallRequest)}
+	if common.GetJsonType(req.Instructions) == "string" {
+		var instructions string
+		if err := common.Unmarshal(req.Instructions, &instructions); err != nil {
+			return nil, err
+		}
+		if instructions != "" {
+			builder.append(dto.Message{Role: "system", Content: instructions})
+		}
+	}
+	switch common.GetJsonType(req.Input) {
+	case "string":
+		var input string
+		if err := common.Unmarshal(req.Input, &input); err != nil {
+			return nil, err
+		}
+		builder.append(dto.Message{Role: "user", Content: input})
+	case "array":
+		var items []map[string]any
+		if err := common.Unmarshal(req.Input, &items); err != nil {
+			return nil, err
+		}
+		for _, item := range items {
+			if err := builder.addInputItem(item); err != nil {
+				return nil, err
+			}
+		}
+	}
+	messages := builder.build()
+	if len(messages) == 0 {
+		return nil, errors.New("input is required")
+	}
+
+	out := &dto.GeneralOpenAIRequest{
+		Model:          req.Model,
+		Messages:       messages,
+		Stream:         req.Stream,
+		MaxTokens:      req.MaxOutputTokens,
+		Temperature:    req.Temperature,
+		TopP:           req.TopP,
+		User:           req.User,
+		ResponseFormat: responsesTextToChatResponseFormat(req.Text),
+	}
+	if len(req.Tools) > 0 && common.GetJsonType(req.Tools) == "array" {
+		tools, err := responsesToolsToChatTools(req.Tools)
+		if err != nil {
+			return nil, err
+		}
+		if len(tools) > 0 {
+			out.Tools = tools
+		}
+	}
+	if toolChoice := responsesToolChoiceToChat(req.ToolChoice); toolChoice != nil {
+		out.ToolChoice = toolChoice
+	}
+	if common.GetJsonType(req.ParallelToolCalls) == "boolean" {
+		var parallel bool
+		_ = common.Unmarshal(req.ParallelToolCalls, &parallel)
+		out.ParallelTooCalls = common.GetPointer(parallel)
+	}
+	if req.Reasoning != nil && req.Reasoning.Effort != "" {
+		out.ReasoningEffort = req.Reasoning.Effort
+	}
+	return out, nil
+}
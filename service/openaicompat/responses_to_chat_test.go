@@ -0,0 +1,115 @@
+package openaicompat
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResponsesRequestToChatCompletionsRequest(t *testing.T) {
+	req := &dto.OpenAIResponsesRequest{
+		Model:        "qwen-max",
+		Instructions: json.RawMessage(`"be brief"`),
+		Input: json.RawMessage(`[
+			{"role":"user","content":[{"type":"input_text","text":"weather?"},{"type":"input_image","image_url":"https://example.com/a.png"}]},
+			{"type":"reasoning","summary":[]},
+			{"type":"function_call","call_id":"call_1","name":"get_weather","arguments":"{\"city\":\"Paris\"}"},
+			{"type":"function_call","call_id":"call_2","name":"get_weather","arguments":"{\"city\":\"Rome\"}"},
+			{"type":"function_call_output","call_id":"call_1","output":"sunny"},
+			{"type":"function_call_output","call_id":"call_2","output":"rainy"}
+		]`),
+		Tools:           json.RawMessage(`[{"type":"function","name":"get_weather","parameters":{"type":"object"}}]`),
+		ToolChoice:      json.RawMessage(`{"type":"function","name":"get_weather"}`),
+		Text:            json.RawMessage(`{"format":{"type":"json_schema","name":"weather","schema":{"type":"object"}}}`),
+		MaxOutputTokens: common.GetPointer[uint](256),
+		Reasoning:       &dto.Reasoning{Effort: "high"},
+	}
+
+	chatReq, err := ResponsesRequestToChatCompletionsRequest(req)
+	require.NoError(t, err)
+
+	require.Len(t, chatReq.Messages, 5)
+	require.Equal(t, "system", chatReq.Messages[0].Role)
+	require.Equal(t, "be brief", chatReq.Messages[0].StringContent())
+
+	userParts := chatReq.Messages[1].ParseContent()
+	require.Len(t, userParts, 2)
+	require.Equal(t, "https://example.com/a.png", userParts[1].GetImageMedia().Url)
+
+	assistant := chatReq.Messages[2]
+	require.Equal(t, "assistant", assistant.Role)
+	toolCalls := assistant.ParseToolCalls()
+	require.Len(t, toolCalls, 2)
+	require.Equal(t, "call_2", toolCalls[1].ID)
+	require.Equal(t, `{"city":"Rome"}`, toolCalls[1].Function.Arguments)
+
+	require.Equal(t, "tool", chatReq.Messages[3].Role)
+	require.Equal(t, "call_1", chatReq.Messages[3].ToolCallId)
+
+	require.Len(t, chatReq.Tools, 1)
+	require.Equal(t, map[string]any{"type": "function", "function": map[string]any{"name": "get_weather"}}, chatReq.ToolChoice)
+	require.Equal(t, "json_schema", chatReq.ResponseFormat.Type)
+	require.JSONEq(t, `{"name":"weather","schema":{"type":"object"}}`, string(chatReq.ResponseFormat.JsonSchema))
+	require.Equal(t, uint(256), *chatReq.MaxTokens)
+	require.Equal(t, "high", chatReq.ReasoningEffort)
+}
+
+func TestChatResponsesStreamConverter(t *testing.T) {
+	converter := NewChatResponsesStreamConverter("resp_1", 1, "qwen-max")
+	var events []dto.ResponsesStreamResponse
+	for _, data := range []string{
+		`{"id":"c1","model":"qwen-max","choices":[{"index":0,"delta":{"role":"assistant","content":""}}]}`,
+		`{"id":"c1","model":"qwen-max","choices":[{"index":0,"delta":{"reasoning_content":"hmm"}}]}`,
+		`{"id":"c1","model":"qwen-max","choices":[{"index":0,"delta":{"content":"Hel"}}]}`,
+		`{"id":"c1","model":"qwen-max","choices":[{"index":0,"delta":{"content":"lo"}}]}`,
+		`{"id":"c1","model":"qwen-max","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":"f","arguments":""}}]}}]}`,
+		`{"id":"c1","model":"qwen-max","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"{\"a\":1}"}}]}}]}`,
+		`{"id":"c1","model":"qwen-max","choices":[{"index":0,"delta":{},"finish_reason":"tool_calls"}]}`,
+		`{"id":"c1","model":"qwen-max","choices":[],"usage":{"prompt_tokens":10,"completion_tokens":5,"total_tokens":15}}`,
+	} {
+		var chunk dto.ChatCompletionsStreamResponse
+		require.NoError(t, common.UnmarshalJsonStr(data, &chunk))
+		events = append(events, converter.Convert(&chunk)...)
+	}
+	events = append(events, converter.Finish(nil)...)
+	require.True(t, converter.Finished())
+
+	eventTypes := make([]string, 0, len(events))
+	for i, ev := range events {
+		require.Equal(t, i, ev.SequenceNumber)
+		eventTypes = append(eventTypes, ev.Type)
+	}
+	require.Equal(t, []string{
+		"response.created",
+		"response.in_progress",
+		"response.output_item.added",
+		"response.reasoning_summary_part.added",
+		"response.reasoning_summary_text.delta",
+		"response.reasoning_summary_text.done",
+		"response.reasoning_summary_part.done",
+		"response.output_item.done",
+		"response.output_item.added",
+		"response.content_part.added",
+		"response.output_text.delta",
+		"response.output_text.delta",
+		"response.output_text.done",
+		"response.content_part.done",
+		"response.output_item.done",
+		"response.output_item.added",
+		"response.function_call_arguments.delta",
+		"response.function_call_arguments.done",
+		"response.output_item.done",
+		"response.completed",
+	}, eventTypes)
+
+	completed := events[len(events)-1].Response
+	require.Len(t, completed.Output, 3)
+	require.Equal(t, "Hello", completed.Output[1].Content[0].Text)
+	require.Equal(t, "call_1", completed.Output[2].CallId)
+	require.Equal(t, `{"a":1}`, completed.Output[2].Arguments)
+	require.Equal(t, 10, completed.Usage.InputTokens)
+	require.Equal(t, 15, completed.Usage.TotalTokens)
+}
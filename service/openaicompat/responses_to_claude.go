@@ -0,0 +1,326 @@
+package openaicompat
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/dto"
+)
+
+// Claude thinking 预算，与 chat 转换中 reasoning_effort 的取值保持一致
+var responsesEffortThinkingBudget = map[string]int{
+	"low":    1280,
+	"medium": 2048,
+	"high":   4096,
+}
+
+// claudeMessageBuilder 按顺序累积 Claude 消息，相同角色的连续内容块合并为一条消息，
+// 以满足 tool_use / tool_result 必须成组出现的要求。
+type claudeMessageBuilder struct {
+	system   []dto.ClaudeMediaMessage
+	messages []dto.ClaudeMessage
+}
+
+func (b *claudeMessageBuilder) append(role string, blocks ...dto.ClaudeMediaMessage) {
+	if len(blocks) == 0 {
+		return
+	}
+	if n := len(b.messages); n > 0 && b.messages[n-1].Role == role {
+		content := b.messages[n-1].Content.([]dto.ClaudeMediaMessage)
+		b.messages[n-1].Content = append(content, blocks...)
+		return
+	}
+	b.messages = append(b.messages, dto.ClaudeMessage{Role: role, Content: blocks})
+}
+
+func claudeTextBlock(text string) dto.ClaudeMediaMessage {
+	return dto.ClaudeMediaMessage{Type: "text", Text: common.GetPointer(text)}
+}
+
+// claudeSourceFromURL 将 data URL 转为 base64 source，其余按 url source 交给上游拉取
+func claudeSourceFromURL(url string) *dto.ClaudeMessageSource {
+	if strings.HasPrefix(url, "data:") {
+		if meta, data, ok := strings.Cut(strings.TrimPrefix(url, "data:"), ","); ok {
+			return &dto.ClaudeMessageSource{
+				Type:      "base64",
+				MediaType: strings.TrimSuffix(meta, ";base64"),
+				Data:      data,
+			}
+		}
+	}
+	return &dto.ClaudeMessageSource{Type: "url", Url: url}
+}
+
+// responsesContentToClaudeBlocks 转换 message / function_call_output 中的内容，支持字符串或内容数组
+func responsesContentToClaudeBlocks(content any) ([]dto.ClaudeMediaMessage, error) {
+	switch v := content.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		if v == "" {
+			return nil, nil
+		}
+		return []dto.ClaudeMediaMessage{claudeTextBlock(v)}, nil
+	case []any:
+		blocks := make([]dto.ClaudeMediaMessage, 0, len(v))
+		for _, partAny := range v {
+			part, ok := partAny.(map[string]any)
+			if !ok {
+				continue
+			}
+			partType := common.Interface2String(part["type"])
+			switch partType {
+			case "input_text", "output_text", "text":
+				blocks = append(blocks, claudeTextBlock(common.Interface2String(part["text"])))
+			case "refusal":
+				blocks = append(blocks, claudeTextBlock(common.Interface2String(part["refusal"])))
+			case "input_image":
+				url := common.Interface2String(normalizeChatImageURLToString(part["image_url"]))
+				if url == "" {
+					return nil, fmt.Errorf("input_image without image_url is not supported")
+				}
+				blocks = append(blocks, dto.ClaudeMediaMessage{Type: "image", Source: claudeSourceFromURL(url)})
+			case "input_file":
+				if fileData := common.Interface2String(part["file_data"]); fileData != "" {
+					blocks = append(blocks, dto.ClaudeMediaMessage{Type: "document", Source: claudeSourceFromURL(fileData)})
+				} else if fileUrl := common.Interface2String(part["file_url"]); fileUrl != "" {
+					blocks = append(blocks, dto.ClaudeMediaMessage{Type: "document", Source: claudeSourceFromURL(fileUrl)})
+				} else {
+					return nil, fmt.Errorf("input_file without file_data or file_url is not supported")
+				}
+			default:
+				return nil, fmt.Errorf("unsupported content type %q", partType)
+			}
+		}
+		return blocks, nil
+	default:
+		return nil, fmt.Errorf("unsupported content format %T", content)
+	}
+}
+
+func (b *claudeMessageBuilder) addInputItem(item map[string]any) error {
+	itemType := common.Interface2String(item["type"])
+	if itemType == "" && item["role"] != nil {
+		itemType = "message"
+	}
+	switch itemType {
+	case "message":
+		blocks, err := responsesContentToClaudeBlocks(item["content"])
+		if err != nil {
+			return err
+		}
+		switch role := common.Interface2String(item["role"]); role {
+		case "system", "developer":
+			b.system = append(b.system, blocks...)
+		case "assistant":
+			b.append("assistant", blocks...)
+		default:
+			b.append("user", blocks...)
+		}
+	case "function_call":
+		input := make(map[string]any)
+		if arguments := strings.TrimSpace(common.Interface2String(item["arguments"])); arguments != "" {
+			if err := common.UnmarshalJsonStr(arguments, &input); err != nil {
+				return fmt.Errorf("invalid arguments of function call %s: %w", common.Interface2String(item["call_id"]), err)
+			}
+		}
+		b.append("assistant", dto.ClaudeMediaMessage{
+			Type:  "tool_use",
+			Id:    common.Interface2String(item["call_id"]),
+			Name:  common.Interface2String(item["name"]),
+			Input: input,
+		})
+	case "function_call_output":
+		result := dto.ClaudeMediaMessage{
+			Type:      "tool_result",
+			ToolUseId: common.Interface2String(item["call_id"]),
+		}
+		if output, ok := item["output"].(string); ok {
+			result.Content = output
+		} else {
+			blocks, err := responsesContentToClaudeBlocks(item["output"])
+			if err != nil {
+				return err
+			}
+			result.Content = blocks
+		}
+		b.append("user", result)
+	case "reasoning":
+		// 只有带签名（encrypted_content）的思考块才能回传给 Claude
+		signature := common.Interface2String(item["encrypted_content"])
+		if signature == "" {
+			return nil
+		}
+		var thinking strings.Builder
+		if summary, ok := item["summary"].([]any); ok {
+			for _, partAny := range summary {
+				if part, ok := partAny.(map[string]any); ok {
+					thinking.WriteString(common.Interface2String(part["text"]))
+				}
+			}
+		}
+		b.append("assistant", dto.ClaudeMediaMessage{
+			Type:      "thinking",
+			Thinking:  common.GetPointer(thinking.String()),
+			Signature: signature,
+		})
+	default:
+		return fmt.Errorf("unsupported input item type %q", itemType)
+	}
+	return nil
+}
+
+func responsesToolsToClaudeTools(raw []byte) ([]any, error) {
+	var tools []map[string]any
+	if err := common.Unmarshal(raw, &tools); err != nil {
+		return nil, err
+	}
+	claudeTools := make([]any, 0, len(tools))
+	for _, tool := range tools {
+		switch toolType := common.Interface2String(tool["type"]); toolType {
+		case "function":
+			claudeTool := &dto.Tool{
+				Name:        common.Interface2String(tool["name"]),
+				Description: common.Interface2String(tool["description"]),
+				InputSchema: map[string]any{"type": "object"},
+			}
+			if params, ok := tool["parameters"].(map[string]any); ok {
+				for k, v := range params {
+					claudeTool.InputSchema[k] = v
+				}
+			}
+			claudeTools = append(claudeTools, claudeTool)
+		case dto.BuildInToolWebSearchPreview, "web_search":
+			claudeTools = append(claudeTools, &dto.ClaudeWebSearchTool{
+				Type: "web_search_20250305",
+				Name: "web_search",
+			})
+		default:
+			return nil, fmt.Errorf("unsupported tool type %q", toolType)
+		}
+	}
+	return claudeTools, nil
+}
+
+func responsesToolChoiceToClaude(raw []byte, parallelToolCalls []byte) *dto.ClaudeToolChoice {
+	var choice *dto.ClaudeToolChoice
+	switch common.GetJsonType(raw) {
+	case "string":
+		var mode string
+		_ = common.Unmarshal(raw, &mode)
+		switch mode {
+		case "auto":
+			choice = &dto.ClaudeToolChoice{Type: "auto"}
+		case "required":
+			choice = &dto.ClaudeToolChoice{Type: "any"}
+		case "none":
+			choice = &dto.ClaudeToolChoice{Type: "none"}
+		}
+	case "object":
+		var obj map[string]any
+		_ = common.Unmarshal(raw, &obj)
+		if name := common.Interface2String(obj["name"]); name != "" {
+			choice = &dto.ClaudeToolChoice{Type: "tool", Name: name}
+		}
+	}
+	if common.GetJsonType(parallelToolCalls) == "boolean" {
+		var parallel bool
+		_ = common.Unmarshal(parallelToolCalls, &parallel)
+		if !parallel {
+			if choice == nil {
+				choice = &dto.ClaudeToolChoice{Type: "auto"}
+			}
+			if choice.Type != "none" {
+				choice.DisableParallelToolUse = true
+			}
+		}
+	}
+	return choice
+}
+
+// ResponsesRequestToClaudeRequest 将 /v1/responses 请求转换为 Claude Messages 请求。
+// max_tokens 未指定时保持为空，由调用方按渠道设置补全。
+func ResponsesRequestToClaudeRequest(req *dto.OpenAIResponsesRequest) (*dto.ClaudeRequest, error) {
+	if req == nil {
+		return nil, fmt.Errorf("request is nil")
+	}
+	if req.PreviousResponseID != "" {
+		return nil, fmt.Errorf("previous_response_id is not supported for this model")
+	}
+
+	claudeRequest := &dto.ClaudeRequest{
+		Model:       req.Model,
+		MaxTokens:   req.MaxOutputTokens,
+		Temperature: req.Temperature,
+		TopP:        req.TopP,
+		Stream:      req.Stream,
+	}
+
+	builder := &claudeMessageBuilder{}
+	if common.GetJsonType(req.Instructions) == "string" {
+		var instructions string
+		if err := common.Unmarshal(req.Instructions, &instructions); err != nil {
+			return nil, err
+		}
+		if instructions != "" {
+			builder.system = append(builder.system, claudeTextBlock(instructions))
+		}
+	}
+
+	switch common.GetJsonType(req.Input) {
+	case "string":
+		var input string
+		if err := common.Unmarshal(req.Input, &input); err != nil {
+			return nil, err
+		}
+		builder.append("user", claudeTextBlock(input))
+	case "array":
+		var items []map[string]any
+		if err := common.Unmarshal(req.Input, &items); err != nil {
+			return nil, err
+		}
+		for _, item := range items {
+			if err := builder.addInputItem(item); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if len(builder.messages) == 0 {
+		return nil, fmt.Errorf("input is required")
+	}
+	if builder.messages[0].Role != "user" {
+		// Claude 要求首条消息为 user
+		builder.messages = append([]dto.ClaudeMessage{{
+			Role:    "user",
+			Content: []dto.ClaudeMediaMessage{claudeTextBlock("...")},
+		}}, builder.messages...)
+	}
+	claudeRequest.Messages = builder.messages
+	if len(builder.system) > 0 {
+		claudeRequest.System = builder.system
+	}
+
+	if len(req.Tools) > 0 && common.GetJsonType(req.Tools) == "array" {
+		tools, err := responsesToolsToClaudeTools(req.Tools)
+		if err != nil {
+			return nil, err
+		}
+		if len(tools) > 0 {
+			claudeRequest.Tools = tools
+		}
+	}
+	if toolChoice := responsesToolChoiceToClaude(req.ToolChoice, req.ParallelToolCalls); toolChoice != nil {
+		claudeRequest.ToolChoice = toolChoice
+	}
+
+	if req.Reasoning != nil {
+		if budget, ok := responsesEffortThinkingBudget[req.Reasoning.Effort]; ok {
+			claudeRequest.Thinking = &dto.Thinking{
+				Type:         "enabled",
+				BudgetTokens: common.GetPointer(budget),
+			}
+		}
+	}
+	return claudeRequest, nil
+}
@@ -0,0 +1,139 @@
+package openaicompat
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResponsesRequestToClaudeRequest(t *testing.T) {
+	req := &dto.OpenAIResponsesRequest{
+		Model:        "claude-sonnet-4-5",
+		Instructions: json.RawMessage(`"be brief"`),
+		Input: json.RawMessage(`[
+			{"role":"user","content":[{"type":"input_text","text":"weather?"},{"type":"input_image","image_url":"data:image/png;base64,AAAA"}]},
+			{"type":"reasoning","summary":[{"type":"summary_text","text":"need tool"}],"encrypted_content":"sig"},
+			{"type":"function_call","call_id":"call_1","name":"get_weather","arguments":"{\"city\":\"Paris\"}"},
+			{"type":"function_call","call_id":"call_2","name":"get_weather","arguments":"{\"city\":\"Rome\"}"},
+			{"type":"function_call_output","call_id":"call_1","output":"sunny"},
+			{"type":"function_call_output","call_id":"call_2","output":"rainy"}
+		]`),
+		Tools:             json.RawMessage(`[{"type":"function","name":"get_weather","parameters":{"type":"object","properties":{"city":{"type":"string"}}}}]`),
+		ToolChoice:        json.RawMessage(`"required"`),
+		ParallelToolCalls: json.RawMessage(`false`),
+		Reasoning:         &dto.Reasoning{Effort: "medium"},
+	}
+
+	claudeReq, err := ResponsesRequestToClaudeRequest(req)
+	require.NoError(t, err)
+
+	system := claudeReq.System.([]dto.ClaudeMediaMessage)
+	require.Len(t, system, 1)
+	require.Equal(t, "be brief", system[0].GetText())
+
+	require.Len(t, claudeReq.Messages, 3)
+	user := claudeReq.Messages[0].Content.([]dto.ClaudeMediaMessage)
+	require.Equal(t, "image", user[1].Type)
+	require.Equal(t, "image/png", user[1].Source.MediaType)
+	require.Equal(t, "AAAA", user[1].Source.Data)
+
+	assistant := claudeReq.Messages[1]
+	require.Equal(t, "assistant", assistant.Role)
+	blocks := assistant.Content.([]dto.ClaudeMediaMessage)
+	require.Len(t, blocks, 3)
+	require.Equal(t, "thinking", blocks[0].Type)
+	require.Equal(t, "sig", blocks[0].Signature)
+	require.Equal(t, "tool_use", blocks[1].Type)
+	require.Equal(t, map[string]any{"city": "Paris"}, blocks[1].Input)
+
+	results := claudeReq.Messages[2].Content.([]dto.ClaudeMediaMessage)
+	require.Len(t, results, 2)
+	require.Equal(t, "call_2", results[1].ToolUseId)
+
+	require.Len(t, claudeReq.Tools, 1)
+	toolChoice := claudeReq.ToolChoice.(*dto.ClaudeToolChoice)
+	require.Equal(t, "any", toolChoice.Type)
+	require.True(t, toolChoice.DisableParallelToolUse)
+	require.Equal(t, 2048, claudeReq.Thinking.GetBudgetTokens())
+}
+
+func TestClaudeResponseToResponsesResponseUsage(t *testing.T) {
+	resp := &dto.ClaudeResponse{
+		Model:      "claude-sonnet-4-5",
+		StopReason: "tool_use",
+		Content: []dto.ClaudeMediaMessage{
+			{Type: "thinking", Thinking: common.GetPointer("hmm"), Signature: "sig"},
+			{Type: "text", Text: common.GetPointer("calling")},
+			{Type: "tool_use", Id: "toolu_1", Name: "get_weather", Input: map[string]any{"city": "Paris"}},
+		},
+		Usage: &dto.ClaudeUsage{InputTokens: 10, CacheReadInputTokens: 100, CacheCreationInputTokens: 20, OutputTokens: 5},
+	}
+
+	out := ClaudeResponseToResponsesResponse(resp, "resp_1", 1)
+	require.Len(t, out.Output, 3)
+	require.Equal(t, "reasoning", out.Output[0].Type)
+	require.Equal(t, "sig", out.Output[0].EncryptedContent)
+	require.Equal(t, "message", out.Output[1].Type)
+	require.Equal(t, "function_call", out.Output[2].Type)
+	require.Equal(t, "toolu_1", out.Output[2].CallId)
+	require.JSONEq(t, `{"city":"Paris"}`, out.Output[2].Arguments)
+	require.Equal(t, 130, out.Usage.InputTokens)
+	require.Equal(t, 100, out.Usage.InputTokensDetails.CachedTokens)
+	require.Equal(t, 135, out.Usage.TotalTokens)
+}
+
+func TestClaudeResponsesStreamConverter(t *testing.T) {
+	converter := NewClaudeResponsesStreamConverter("resp_1", 1, "claude")
+	var events []dto.ResponsesStreamResponse
+	for _, data := range []string{
+		`{"type":"message_start","message":{"id":"msg_1","model":"claude-sonnet-4-5","usage":{"input_tokens":10,"cache_read_input_tokens":4,"output_tokens":1}}}`,
+		`{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Hel"}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"lo"}}`,
+		`{"type":"content_block_stop","index":0}`,
+		`{"type":"content_block_start","index":1,"content_block":{"type":"tool_use","id":"toolu_1","name":"f","input":{}}}`,
+		`{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"{\"a\":1}"}}`,
+		`{"type":"content_block_stop","index":1}`,
+		`{"type":"message_delta","delta":{"stop_reason":"tool_use"},"usage":{"output_tokens":7}}`,
+		`{"type":"message_stop"}`,
+	} {
+		var claudeResponse dto.ClaudeResponse
+		require.NoError(t, common.UnmarshalJsonStr(data, &claudeResponse))
+		events = append(events, converter.Convert(&claudeResponse)...)
+	}
+	require.True(t, converter.Finished())
+	require.Empty(t, converter.Finish())
+
+	eventTypes := make([]string, 0, len(events))
+	for i, ev := range events {
+		require.Equal(t, i, ev.SequenceNumber)
+		eventTypes = append(eventTypes, ev.Type)
+	}
+	require.Equal(t, []string{
+		"response.created",
+		"response.in_progress",
+		"response.output_item.added",
+		"response.content_part.added",
+		"response.output_text.delta",
+		"response.output_text.delta",
+		"response.output_text.done",
+		"response.content_part.done",
+		"response.output_item.done",
+		"response.output_item.added",
+		"response.function_call_arguments.delta",
+		"response.function_call_arguments.done",
+		"response.output_item.done",
+		"response.completed",
+	}, eventTypes)
+
+	completed := events[len(events)-1].Response
+	require.Len(t, completed.Output, 2)
+	require.Equal(t, "Hello", completed.Output[0].Content[0].Text)
+	require.Equal(t, `{"a":1}`, completed.Output[1].Arguments)
+	require.Equal(t, 14, completed.Usage.InputTokens)
+	require.Equal(t, 4, completed.Usage.InputTokensDetails.CachedTokens)
+	require.Equal(t, 7, completed.Usage.OutputTokens)
+}
@@ -0,0 +1,227 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/model"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+	"github.com/QuantumNous/new-api/types"
+
+	"github.com/bytedance/gopkg/util/gopool"
+	"github.com/gin-gonic/gin"
+)
+
+// 规则快照，按 目标类型 -> 目标 id 索引
+type payloadCaptureRuleSet map[string]map[int]*model.PayloadCaptureRule
+
+var payloadCaptureRules atomic.Pointer[payloadCaptureRuleSet]
+
+// ReloadPayloadCaptureRules 从数据库刷新生效中的抓包规则
+func ReloadPayloadCaptureRules() error {
+	rules, err := model.GetActivePayloadCaptureRules()
+	if err != nil {
+		return err
+	}
+	set := make(payloadCaptureRuleSet)
+	for _, rule := range rules {
+		if set[rule.TargetType] == nil {
+			set[rule.TargetType] = make(map[int]*model.PayloadCaptureRule)
+		}
+		// 同一目标存在多条规则时取最晚到期的一条
+		if old, ok := set[rule.TargetType][rule.TargetId]; !ok || old.ExpiresAt < rule.ExpiresAt {
+			set[rule.TargetType][rule.TargetId] = rule
+		}
+	}
+	payloadCaptureRules.Store(&set)
+	return nil
+}
+
+// matchPayloadCaptureRule 按令牌、用户、渠道的顺序匹配生效中的规则
+func matchPayloadCaptureRule(c *gin.Context, channelId int) *model.PayloadCaptureRule {
+	set := payloadCaptureRules.Load()
+	if set == nil || len(*set) == 0 {
+		return nil
+	}
+	now := common.GetTimestamp()
+	targets := []struct {
+		targetType string
+		id         int
+	}{
+		{model.PayloadCaptureTargetToken, c.GetInt("token_id")},
+		{model.PayloadCaptureTargetUser, c.GetInt("id")},
+		{model.PayloadCaptureTargetChannel, channelId},
+	}
+	for _, target := range targets {
+		if target.id == 0 {
+			continue
+		}
+		if rule, ok := (*set)[target.targetType][target.id]; ok && rule.ExpiresAt > now {
+			return rule
+		}
+	}
+	return nil
+}
+
+// PayloadCaptureSession 记录一次上游尝试的请求与响应
+type PayloadCaptureSession struct {
+	rule            *model.PayloadCaptureRule
+	limit           int
+	upstreamRequest []byte
+	mu              sync.Mutex
+	response        bytes.Buffer
+	truncated       bool
+}
+
+func (s *PayloadCaptureSession) writeResponse(p []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	remain := s.limit - s.response.Len()
+	if remain <= 0 {
+		if len(p) > 0 {
+			s.truncated = true
+		}
+		return
+	}
+	if len(p) > remain {
+		p = p[:remain]
+		s.truncated = true
+	}
+	s.response.Write(p)
+}
+
+type payloadCaptureReadCloser struct {
+	io.ReadCloser
+	session *PayloadCaptureSession
+}
+
+func (r *payloadCaptureReadCloser) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	if n > 0 {
+		r.session.writeResponse(p[:n])
+	}
+	return n, err
+}
+
+// CapturePayloadRequest 在发送上游请求前调用：命中抓包规则时记录最终的上游请求体（已应用参数覆盖），
+// 并包装响应体以记录返回内容或流式输出
+func CapturePayloadRequest(c *gin.Context, channelId int, req *http.Request) {
+	rule := matchPayloadCaptureRule(c, channelId)
+	if rule == nil {
+		return
+	}
+	session := &PayloadCaptureSession{
+		rule:  rule,
+		limit: max(operation_setting.GetPayloadCaptureSetting().MaxBodyKB, 1) * 1024,
+	}
+	if req.Body != nil && req.Body != http.NoBody {
+		data, err := io.ReadAll(req.Body)
+		_ = req.Body.Close()
+		req.Body = io.NopCloser(bytes.NewReader(data))
+		if err != nil {
+			common.SysError("failed to capture upstream request: " + err.Error())
+			return
+		}
+		if strings.HasPrefix(req.Header.Get("Content-Type"), "multipart/") {
+			data = []byte("[multipart body omitted]")
+		}
+		session.upstreamRequest = data
+	}
+	common.SetContextKey(c, constant.ContextKeyPayloadCapture, session)
+}
+
+// CapturePayloadResponse 包装上游响应体，未开启抓包时不做处理
+func CapturePayloadResponse(c *gin.Context, resp *http.Response) {
+	session, ok := common.GetContextKeyType[*PayloadCaptureSession](c, constant.ContextKeyPayloadCapture)
+	if !ok || session == nil || resp == nil || resp.Body == nil {
+		return
+	}
+	resp.Body = &payloadCaptureReadCloser{ReadCloser: resp.Body, session: session}
+}
+
+// FinishPayloadCapture 在一次上游尝试结束后保存抓包记录
+func FinishPayloadCapture(c *gin.Context, info *relaycommon.RelayInfo, apiErr *types.NewAPIError) {
+	session, ok := common.GetContextKeyType[*PayloadCaptureSession](c, constant.ContextKeyPayloadCapture)
+	if !ok || session == nil {
+		return
+	}
+	common.SetContextKey(c, constant.ContextKeyPayloadCapture, nil)
+
+	secrets := []string{
+		common.GetContextKeyString(c, constant.ContextKeyChannelKey),
+		c.GetString("token_key"),
+	}
+	var clientRequest []byte
+	if storage, err := common.GetBodyStorage(c); err == nil {
+		clientRequest, _ = storage.Bytes()
+	}
+	capture := &model.PayloadCapture{
+		RuleId:    session.rule.Id,
+		RequestId: c.GetString(common.RequestIdKey),
+		UserId:    c.GetInt("id"),
+		TokenId:   c.GetInt("token_id"),
+		ChannelId: info.ChannelId,
+		ModelName: info.OriginModelName,
+		Path:      c.Request.URL.Path,
+		IsStream:  info.IsStream,
+	}
+	var truncated [3]bool
+	capture.ClientRequest, truncated[0] = redactPayload(clientRequest, session.limit, secrets)
+	capture.UpstreamRequest, truncated[1] = redactPayload(session.upstreamRequest, session.limit, secrets)
+	session.mu.Lock()
+	capture.Response, truncated[2] = redactPayload(session.response.Bytes(), session.limit, secrets)
+	capture.Truncated = session.truncated || truncated[0] || truncated[1] || truncated[2]
+	session.mu.Unlock()
+	if apiErr != nil {
+		capture.StatusCode = apiErr.StatusCode
+		capture.ErrorMessage = redactString(apiErr.Error(), secrets)
+	} else {
+		capture.StatusCode = c.Writer.Status()
+	}
+	gopool.Go(func() {
+		if err := capture.Insert(); err != nil {
+			common.SysError("failed to save payload capture: " + err.Error())
+		}
+	})
+}
+
+// redactPayload 脱敏并截断请求/响应内容：JSON 中名称以 key、secret、password、token 结尾的字段被隐藏，
+// 渠道密钥与令牌等已知密钥在原文中被替换
+func redactPayload(data []byte, limit int, secrets []string) (string, bool) {
+	if len(data) == 0 {
+		return "", false
+	}
+	text := string(data)
+	if json.Valid(data) {
+		var v any
+		if err := common.Unmarshal(data, &v); err == nil {
+			if masked, err := common.Marshal(maskAuditSnapshot(v)); err == nil {
+				text = string(masked)
+			}
+		}
+	}
+	text = redactString(text, secrets)
+	if len(text) > limit {
+		return strings.ToValidUTF8(text[:limit], ""), true
+	}
+	return text, false
+}
+
+func redactString(text string, secrets []string) string {
+	for _, secret := range secrets {
+		// 过短的值替换后可能误伤正常内容
+		if len(secret) < 8 {
+			continue
+		}
+		text = strings.ReplaceAll(text, secret, auditMaskedValue)
+	}
+	return text
+}
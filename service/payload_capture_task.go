@@ -0,0 +1,66 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+
+	"github.com/bytedance/gopkg/util/gopool"
+)
+
+const (
+	payloadCaptureReloadInterval = 30 * time.Second
+	payloadCapturePurgeInterval  = 1 * time.Hour
+)
+
+var (
+	payloadCaptureTaskOnce     sync.Once
+	payloadCapturePurgeRunning atomic.Bool
+)
+
+// StartPayloadCaptureTask 所有节点定时刷新抓包规则，主节点额外清理过期的抓包记录与规则
+func StartPayloadCaptureTask() {
+	payloadCaptureTaskOnce.Do(func() {
+		gopool.Go(func() {
+			logger.LogInfo(context.Background(), fmt.Sprintf("payload capture task started: reload=%s", payloadCaptureReloadInterval))
+			ticker := time.NewTicker(payloadCaptureReloadInterval)
+			defer ticker.Stop()
+
+			lastPurge := time.Time{}
+			for {
+				if err := ReloadPayloadCaptureRules(); err != nil {
+					logger.LogWarn(context.Background(), fmt.Sprintf("payload capture rule reload failed: %v", err))
+				}
+				if common.IsMasterNode && time.Since(lastPurge) >= payloadCapturePurgeInterval {
+					lastPurge = time.Now()
+					runPayloadCapturePurgeOnce()
+				}
+				<-ticker.C
+			}
+		})
+	})
+}
+
+func runPayloadCapturePurgeOnce() {
+	if !payloadCapturePurgeRunning.CompareAndSwap(false, true) {
+		return
+	}
+	defer payloadCapturePurgeRunning.Store(false)
+
+	retention := time.Duration(max(operation_setting.GetPayloadCaptureSetting().RetentionHours, 1)) * time.Hour
+	n, err := model.PurgePayloadCaptures(time.Now().Add(-retention).Unix())
+	if err != nil {
+		logger.LogWarn(context.Background(), fmt.Sprintf("payload capture purge failed: %v", err))
+		return
+	}
+	if n > 0 {
+		logger.LogInfo(context.Background(), fmt.Sprintf("payload capture purged: count=%d", n))
+	}
+}
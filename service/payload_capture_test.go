@@ -0,0 +1,61 @@
+package service
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/model"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedactPayload(t *testing.T) {
+	body := []byte(`{"model":"gpt-4o","api_key":"abc","max_tokens":10,"messages":[{"content":"key is sk-channel-secret-1"}]}`)
+	text, truncated := redactPayload(body, 1024, []string{"sk-channel-secret-1", "short"})
+	require.False(t, truncated)
+	require.Contains(t, text, `"api_key":"******"`)
+	require.Contains(t, text, `"max_tokens":10`)
+	require.NotContains(t, text, "sk-channel-secret-1")
+
+	text, truncated = redactPayload([]byte(strings.Repeat("data: x\n", 100)), 16, nil)
+	require.True(t, truncated)
+	require.Len(t, text, 16)
+}
+
+func TestCapturePayloadRequestAndResponse(t *testing.T) {
+	set := payloadCaptureRuleSet{
+		model.PayloadCaptureTargetChannel: {7: {Id: 1, TargetType: model.PayloadCaptureTargetChannel, TargetId: 7, ExpiresAt: common.GetTimestamp() + 60}},
+	}
+	payloadCaptureRules.Store(&set)
+	t.Cleanup(func() { payloadCaptureRules.Store(nil) })
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", bytes.NewBufferString(`{"model":"gpt-4o"}`))
+
+	// 未命中规则时不做处理
+	CapturePayloadRequest(c, 8, req)
+	_, ok := common.GetContextKeyType[*PayloadCaptureSession](c, constant.ContextKeyPayloadCapture)
+	require.False(t, ok)
+
+	CapturePayloadRequest(c, 7, req)
+	session, ok := common.GetContextKeyType[*PayloadCaptureSession](c, constant.ContextKeyPayloadCapture)
+	require.True(t, ok)
+	require.Equal(t, `{"model":"gpt-4o"}`, string(session.upstreamRequest))
+	// 请求体被读取后仍可正常发送
+	sent, err := io.ReadAll(req.Body)
+	require.NoError(t, err)
+	require.Equal(t, `{"model":"gpt-4o"}`, string(sent))
+
+	resp := &http.Response{Body: io.NopCloser(strings.NewReader("data: {\"id\":1}\n\ndata: [DONE]\n\n"))}
+	CapturePayloadResponse(c, resp)
+	received, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Equal(t, string(received), session.response.String())
+}
@@ -138,7 +138,8 @@ func PreWssConsumeQuota(ctx *gin.Context, relayInfo *relaycommon.RelayInfo, usag
 
 	quota := calculateAudioQuota(quotaInfo)
 
-	if userQuota < quota {
+	// 组织令牌由组织钱包付费，余额与成员消费上限在预扣时一并校验
+	if relayInfo.OrganizationId == 0 && userQuota < quota {
 		return fmt.Errorf("user quota is not enough, user quota: %s, need quota: %s", logger.FormatQuota(userQuota), logger.FormatQuota(quota))
 	}
 
@@ -146,8 +147,18 @@ func PreWssConsumeQuota(ctx *gin.Context, relayInfo *relaycommon.RelayInfo, usag
 		return fmt.Errorf("token quota is not enough, token remain quota: %s, need quota: %s", logger.FormatQuota(token.RemainQuota), logger.FormatQuota(quota))
 	}
 
-	err = PostConsumeQuota(relayInfo, quota, 0, false)
-	if err != nil {
+	if err := checkTokenBudget(token, quota); err != nil {
+		return err
+	}
+
+	if relayInfo.OrganizationId > 0 {
+		if err := model.PreConsumeOrganizationQuota(relayInfo.OrganizationId, relayInfo.UserId, quota); err != nil {
+			return err
+		}
+		if err := model.DecreaseTokenQuota(relayInfo.TokenId, relayInfo.TokenKey, quota); err != nil {
+			return err
+		}
+	} else if err := PostConsumeQuota(relayInfo, quota, 0, false); err != nil {
 		return err
 	}
 	logger.LogInfo(ctx, "realtime streaming consume quota success, quota: "+fmt.Sprintf("%d", quota))
@@ -163,6 +174,7 @@ func PostWssConsumeQuota(ctx *gin.Context, relayInfo *relaycommon.RelayInfo, mod
 
 	audioInputTokens := usage.InputTokenDetails.AudioTokens
 	audioOutTokens := usage.OutputTokenDetails.AudioTokens
+	ConsumeTokenRateLimitTokens(ctx, usage.TotalTokens)
 
 	tokenName := ctx.GetString("token_name")
 	completionRatio := decimal.NewFromFloat(ratio_setting.GetCompletionRatio(modelName))
@@ -244,6 +256,7 @@ func PostClaudeConsumeQuota(ctx *gin.Context, relayInfo *relaycommon.RelayInfo,
 	promptTokens := usage.PromptTokens
 	completionTokens := usage.CompletionTokens
 	modelName := relayInfo.OriginModelName
+	ConsumeTokenRateLimitTokens(ctx, promptTokens+completionTokens)
 
 	tokenName := ctx.GetString("token_name")
 	completionRatio := relayInfo.PriceData.CompletionRatio
@@ -366,6 +379,7 @@ func PostAudioConsumeQuota(ctx *gin.Context, relayInfo *relaycommon.RelayInfo, u
 
 	audioInputTokens := usage.PromptTokensDetails.AudioTokens
 	audioOutTokens := usage.CompletionTokenDetails.AudioTokens
+	ConsumeTokenRateLimitTokens(ctx, usage.TotalTokens)
 
 	tokenName := ctx.GetString("token_name")
 	completionRatio := decimal.NewFromFloat(ratio_setting.GetCompletionRatio(relayInfo.OriginModelName))
@@ -459,6 +473,9 @@ func PreConsumeTokenQuota(relayInfo *relaycommon.RelayInfo, quota int) error {
 	if !relayInfo.TokenUnlimited && token.RemainQuota < quota {
 		return fmt.Errorf("token quota is not enough, token remain quota: %s, need quota: %s", logger.FormatQuota(token.RemainQuota), logger.FormatQuota(quota))
 	}
+	if err := checkTokenBudget(token, quota); err != nil {
+		return err
+	}
 	err = model.DecreaseTokenQuota(relayInfo.TokenId, relayInfo.TokenKey, quota)
 	if err != nil {
 		return err
@@ -466,10 +483,43 @@ func PreConsumeTokenQuota(relayInfo *relaycommon.RelayInfo, quota int) error {
 	return nil
 }
 
+// checkRelayTokenBudget 按请求令牌校验周期预算，用于不预扣令牌额度的路径
+func checkRelayTokenBudget(relayInfo *relaycommon.RelayInfo, quota int) error {
+	if relayInfo.IsPlayground {
+		return nil
+	}
+	token, err := model.GetTokenByKey(relayInfo.TokenKey, false)
+	if err != nil {
+		return err
+	}
+	return checkTokenBudget(token, quota)
+}
+
+// checkTokenBudget 周期预算独立于用户钱包和令牌剩余额度，无限额度令牌同样受其约束
+func checkTokenBudget(token *model.Token, quota int) error {
+	if !token.IsBudgetEnabled() {
+		return nil
+	}
+	now := common.GetTimestamp()
+	if _, err := token.RollBudgetPeriod(now); err != nil {
+		return err
+	}
+	remain := token.GetBudgetRemain(now)
+	if remain < quota {
+		return fmt.Errorf("token budget is not enough, budget remain quota: %s, need quota: %s, resets at %s", logger.FormatQuota(remain), logger.FormatQuota(quota), time.Unix(token.BudgetResetTime, 0).Format(time.RFC3339))
+	}
+	return nil
+}
+
 func PostConsumeQuota(relayInfo *relaycommon.RelayInfo, quota int, preConsumedQuota int, sendEmail bool) (err error) {
 
-	// 1) Consume from wallet quota OR subscription item
-	if relayInfo != nil && relayInfo.BillingSource == BillingSourceSubscription {
+	// 1) Consume from wallet quota, organization wallet OR subscription item
+	// 组织令牌始终从组织钱包扣费（含未创建 BillingSession 的按次计费路径）
+	if relayInfo != nil && relayInfo.OrganizationId > 0 {
+		if err := model.PostConsumeOrganizationQuota(relayInfo.OrganizationId, relayInfo.UserId, quota); err != nil {
+			return err
+		}
+	} else if relayInfo != nil && relayInfo.BillingSource == BillingSourceSubscription {
 		if relayInfo.SubscriptionId == 0 {
 			return errors.New("subscription id is missing")
 		}
@@ -503,7 +553,7 @@ func PostConsumeQuota(relayInfo *relaycommon.RelayInfo, quota int, preConsumedQu
 		}
 	}
 
-	if sendEmail {
+	if sendEmail && relayInfo.OrganizationId == 0 {
 		if (quota + preConsumedQuota) != 0 {
 			checkAndSendQuotaNotify(relayInfo, quota, preConsumedQuota)
 		}
@@ -0,0 +1,255 @@
+package service
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/pkg/cachex"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+
+	"github.com/gin-gonic/gin"
+	"github.com/samber/hot"
+)
+
+const (
+	responseCacheNamespace = "new-api:response_cache:v1"
+	responseCacheCapacity  = 10000
+	responseCacheOtherKey  = "response_cache"
+	// 内存缓存的默认过期时间，实际以写入时的 ttl_seconds 为准
+	responseCacheMemoryTTL = 24 * time.Hour
+)
+
+var ssePing = []byte(": PING\n\n")
+
+// ResponseCacheEntry 缓存的客户端响应，流式响应保存完整的 SSE 输出
+type ResponseCacheEntry struct {
+	Body        []byte    `json:"body"`
+	ContentType string    `json:"content_type"`
+	IsStream    bool      `json:"is_stream"`
+	Usage       dto.Usage `json:"usage"`
+	CreatedAt   int64     `json:"created_at"`
+}
+
+var (
+	responseCache     *cachex.HybridCache[ResponseCacheEntry]
+	responseCacheOnce sync.Once
+)
+
+func getResponseCache() *cachex.HybridCache[ResponseCacheEntry] {
+	responseCacheOnce.Do(func() {
+		responseCache = cachex.NewHybridCache[ResponseCacheEntry](cachex.HybridCacheConfig[ResponseCacheEntry]{
+			Namespace: cachex.Namespace(responseCacheNamespace),
+			Redis:     common.RDB,
+			RedisEnabled: func() bool {
+				return common.RedisEnabled && common.RDB != nil
+			},
+			RedisCodec: cachex.JSONCodec[ResponseCacheEntry]{},
+			Memory: func() *hot.HotCache[string, ResponseCacheEntry] {
+				return hot.NewHotCache[string, ResponseCacheEntry](hot.LRU, responseCacheCapacity).
+					WithTTL(responseCacheMemoryTTL).
+					WithJanitor().
+					Build()
+			},
+		})
+	})
+	return responseCache
+}
+
+// ResponseCacheKey 计算请求的缓存键，请求不可缓存时返回空字符串。
+// 对话请求仅在显式指定 temperature=0 时缓存；键由原始模型名、分组与归一化后的请求参数计算，
+// 与计费和缓存结果无关的字段（user、metadata 等）不参与计算
+func ResponseCacheKey(info *relaycommon.RelayInfo, request any) string {
+	setting := operation_setting.GetResponseCacheSetting()
+	if !setting.ResponseCacheEnabledFor(info.OriginModelName, info.UsingGroup) {
+		return ""
+	}
+	var kind string
+	var normalized any
+	switch req := request.(type) {
+	case *dto.GeneralOpenAIRequest:
+		if !setting.ChatEnabled || req.Temperature == nil || *req.Temperature != 0 {
+			return ""
+		}
+		r := *req
+		r.User = nil
+		r.Metadata = nil
+		r.SafetyIdentifier = nil
+		r.Store = nil
+		r.PromptCacheKey = ""
+		kind, normalized = "chat", &r
+	case *dto.EmbeddingRequest:
+		if !setting.EmbeddingEnabled {
+			return ""
+		}
+		r := *req
+		r.User = ""
+		kind, normalized = "embedding", &r
+	default:
+		return ""
+	}
+	data, err := common.Marshal(normalized)
+	if err != nil {
+		return ""
+	}
+	scope := "shared"
+	if !setting.ShareAcrossUsers {
+		scope = strconv.Itoa(info.UserId)
+	}
+	h := sha256.New()
+	for _, part := range []string{kind, info.UsingGroup, scope} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	h.Write(data)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// GetResponseCache 查询缓存，出错时视为未命中
+func GetResponseCache(key string) (*ResponseCacheEntry, bool) {
+	if key == "" {
+		return nil, false
+	}
+	entry, found, err := getResponseCache().Get(key)
+	if err != nil {
+		common.SysError("failed to get response cache: " + err.Error())
+		return nil, false
+	}
+	if !found {
+		return nil, false
+	}
+	return &entry, true
+}
+
+// ServeResponseCache 将缓存结果返回给客户端：普通响应原样输出，流式响应按事件逐条回放 SSE。
+// 同时标记本次请求命中缓存，并以缓存倍率参与后续计费
+func ServeResponseCache(c *gin.Context, info *relaycommon.RelayInfo, entry *ResponseCacheEntry) {
+	common.SetContextKey(c, constant.ContextKeyResponseCacheHit, true)
+	// AddOtherRatio 会忽略 0，缓存倍率为 0 表示命中免费
+	if info.PriceData.OtherRatios == nil {
+		info.PriceData.OtherRatios = make(map[string]float64)
+	}
+	info.PriceData.OtherRatios[responseCacheOtherKey] = max(operation_setting.GetResponseCacheSetting().CacheRatio, 0)
+	info.SetFirstResponseTime()
+	c.Header("X-New-Api-Cache", "hit")
+
+	if !entry.IsStream {
+		c.Data(http.StatusOK, entry.ContentType, entry.Body)
+		return
+	}
+	c.Writer.Header().Set("Content-Type", "text/event-stream")
+	c.Writer.Header().Set("Cache-Control", "no-cache")
+	c.Writer.Header().Set("Connection", "keep-alive")
+	c.Writer.Header().Set("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	for _, event := range splitSSEEvents(entry.Body) {
+		if _, err := c.Writer.Write(event); err != nil {
+			return
+		}
+		c.Writer.Flush()
+	}
+}
+
+// splitSSEEvents 按空行拆分 SSE 事件，每个事件保留结尾的分隔符
+func splitSSEEvents(body []byte) [][]byte {
+	var events [][]byte
+	for len(body) > 0 {
+		idx := bytes.Index(body, []byte("\n\n"))
+		if idx < 0 {
+			events = append(events, body)
+			break
+		}
+		events = append(events, body[:idx+2])
+		body = body[idx+2:]
+	}
+	return events
+}
+
+// ResponseCacheRecorder 在缓存未命中时记录写给客户端的响应
+type ResponseCacheRecorder struct {
+	gin.ResponseWriter
+	key      string
+	original gin.ResponseWriter
+	limit    int
+	mu       sync.Mutex
+	buf      bytes.Buffer
+	overflow bool
+}
+
+// StartResponseCacheRecord 替换 c.Writer 以记录响应，key 为空时返回 nil
+func StartResponseCacheRecord(c *gin.Context, key string) *ResponseCacheRecorder {
+	if key == "" {
+		return nil
+	}
+	recorder := &ResponseCacheRecorder{
+		ResponseWriter: c.Writer,
+		key:            key,
+		original:       c.Writer,
+		limit:          max(operation_setting.GetResponseCacheSetting().MaxEntryKB, 1) * 1024,
+	}
+	c.Writer = recorder
+	return recorder
+}
+
+func (r *ResponseCacheRecorder) record(p []byte) {
+	// 保活的 PING 与流式输出可能来自不同 goroutine
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.overflow {
+		return
+	}
+	if r.buf.Len()+len(p) > r.limit {
+		r.overflow = true
+		r.buf.Reset()
+		return
+	}
+	r.buf.Write(p)
+}
+
+func (r *ResponseCacheRecorder) Write(p []byte) (int, error) {
+	r.record(p)
+	return r.ResponseWriter.Write(p)
+}
+
+func (r *ResponseCacheRecorder) WriteString(s string) (int, error) {
+	r.record([]byte(s))
+	return r.ResponseWriter.WriteString(s)
+}
+
+// Finish 恢复 c.Writer，usage 非空且响应完整时写入缓存；请求失败时传入 nil
+func (r *ResponseCacheRecorder) Finish(c *gin.Context, info *relaycommon.RelayInfo, usage *dto.Usage) {
+	if r == nil {
+		return
+	}
+	c.Writer = r.original
+	if usage == nil || usage.TotalTokens <= 0 || c.Writer.Status() != http.StatusOK {
+		return
+	}
+	r.mu.Lock()
+	if r.overflow || r.buf.Len() == 0 {
+		r.mu.Unlock()
+		return
+	}
+	body := bytes.ReplaceAll(r.buf.Bytes(), ssePing, nil)
+	r.mu.Unlock()
+
+	entry := ResponseCacheEntry{
+		Body:        body,
+		ContentType: c.Writer.Header().Get("Content-Type"),
+		IsStream:    info.IsStream,
+		Usage:       *usage,
+		CreatedAt:   common.GetTimestamp(),
+	}
+	ttl := time.Duration(operation_setting.GetResponseCacheSetting().TTLSeconds) * time.Second
+	if err := getResponseCache().SetWithTTL(r.key, entry, ttl); err != nil {
+		common.SysError("failed to set response cache: " + err.Error())
+	}
+}
@@ -0,0 +1,81 @@
+package service
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/dto"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+func enableResponseCache(t *testing.T) {
+	setting := operation_setting.GetResponseCacheSetting()
+	old := *setting
+	setting.Enabled = true
+	t.Cleanup(func() { *setting = old })
+}
+
+func TestResponseCacheKey(t *testing.T) {
+	enableResponseCache(t)
+	info := &relaycommon.RelayInfo{OriginModelName: "gpt-4o", UsingGroup: "default", UserId: 1}
+	zero := 0.0
+	req := &dto.GeneralOpenAIRequest{Model: "gpt-4o", Temperature: &zero, Messages: []dto.Message{{Role: "user", Content: "hi"}}}
+
+	key := ResponseCacheKey(info, req)
+	require.NotEmpty(t, key)
+
+	other := *req
+	other.User = json.RawMessage(`"someone"`)
+	require.Equal(t, key, ResponseCacheKey(info, &other))
+
+	other.Messages = []dto.Message{{Role: "user", Content: "hello"}}
+	require.NotEqual(t, key, ResponseCacheKey(info, &other))
+
+	require.NotEqual(t, key, ResponseCacheKey(&relaycommon.RelayInfo{OriginModelName: "gpt-4o", UsingGroup: "default", UserId: 2}, req))
+
+	hot := 0.7
+	other = *req
+	other.Temperature = &hot
+	require.Empty(t, ResponseCacheKey(info, &other))
+
+	operation_setting.GetResponseCacheSetting().Models = []string{"text-embedding-*"}
+	require.Empty(t, ResponseCacheKey(info, req))
+	require.NotEmpty(t, ResponseCacheKey(&relaycommon.RelayInfo{OriginModelName: "text-embedding-3-small"}, &dto.EmbeddingRequest{Model: "text-embedding-3-small", Input: "hi"}))
+}
+
+func TestResponseCacheRecordAndServe(t *testing.T) {
+	enableResponseCache(t)
+	key := "test-" + common.GetRandomString(8)
+	usage := &dto.Usage{PromptTokens: 3, CompletionTokens: 2, TotalTokens: 5}
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	recorder := StartResponseCacheRecord(c, key)
+	c.Header("Content-Type", "text/event-stream")
+	_, _ = c.Writer.WriteString("data: {\"a\":1}\n\n")
+	_, _ = c.Writer.Write([]byte(": PING\n\n"))
+	_, _ = c.Writer.WriteString("data: [DONE]\n\n")
+	recorder.Finish(c, &relaycommon.RelayInfo{IsStream: true}, usage)
+	require.NotSame(t, recorder, c.Writer)
+
+	entry, hit := GetResponseCache(key)
+	require.True(t, hit)
+	require.Equal(t, "data: {\"a\":1}\n\ndata: [DONE]\n\n", string(entry.Body))
+	require.Equal(t, *usage, entry.Usage)
+
+	w = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(w)
+	info := &relaycommon.RelayInfo{IsStream: true}
+	ServeResponseCache(c, info, entry)
+	require.Equal(t, string(entry.Body), w.Body.String())
+	require.Equal(t, "text/event-stream", w.Header().Get("Content-Type"))
+	require.True(t, common.GetContextKeyBool(c, constant.ContextKeyResponseCacheHit))
+	require.Contains(t, info.PriceData.OtherRatios, "response_cache")
+}
@@ -38,23 +38,28 @@ func CheckSensitiveText(text string) (bool, []string) {
 
 // SensitiveWordContains 是否包含敏感词，返回是否包含敏感词和敏感词列表
 func SensitiveWordContains(text string) (bool, []string) {
-	if len(setting.SensitiveWords) == 0 {
+	m := setting.GetSensitiveWordMatcher()
+	if m == nil || len(text) == 0 {
 		return false, nil
 	}
-	if len(text) == 0 {
+	hits := m.MultiPatternSearch([]rune(strings.ToLower(text)), true)
+	if len(hits) == 0 {
 		return false, nil
 	}
-	checkText := strings.ToLower(text)
-	return AcSearch(checkText, setting.SensitiveWords, true)
+	words := make([]string, 0, len(hits))
+	for _, hit := range hits {
+		words = append(words, string(hit.Word))
+	}
+	return true, words
 }
 
 // SensitiveWordReplace 敏感词替换，返回是否包含敏感词和替换后的文本
 func SensitiveWordReplace(text string, returnImmediately bool) (bool, []string, string) {
-	if len(setting.SensitiveWords) == 0 {
+	m := setting.GetSensitiveWordMatcher()
+	if m == nil {
 		return false, nil, text
 	}
 	checkText := strings.ToLower(text)
-	m := getOrBuildAC(setting.SensitiveWords)
 	hits := m.MultiPatternSearch([]rune(checkText), returnImmediately)
 	if len(hits) > 0 {
 		words := make([]string, 0, len(hits))
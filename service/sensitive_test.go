@@ -0,0 +1,69 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/QuantumNous/new-api/setting"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSensitiveWordMatcherRebuiltOnChange(t *testing.T) {
+	origin := setting.SensitiveWordsToString()
+	t.Cleanup(func() { setting.SensitiveWordsFromString(origin) })
+
+	setting.SensitiveWordsFromString("foo\nBar")
+	ok, words := SensitiveWordContains("xx BAR yy")
+	require.True(t, ok)
+	require.Equal(t, []string{"bar"}, words)
+
+	setting.SensitiveWordsFromString("baz")
+	ok, _ = SensitiveWordContains("xx bar yy")
+	require.False(t, ok)
+	ok, _, replaced := SensitiveWordReplace("a baz b", false)
+	require.True(t, ok)
+	require.Equal(t, "a **###** b", replaced)
+
+	setting.SensitiveWordsFromString("")
+	require.Nil(t, setting.GetSensitiveWordMatcher())
+	ok, _ = SensitiveWordContains("baz")
+	require.False(t, ok)
+}
+
+func benchmarkSensitiveWords() ([]string, string) {
+	words := make([]string, 2000)
+	for i := range words {
+		words[i] = fmt.Sprintf("sensitive_word_%d", i)
+	}
+	text := strings.Repeat("the quick brown fox jumps over the lazy dog ", 100)
+	return words, text
+}
+
+func BenchmarkSensitiveWordContains(b *testing.B) {
+	origin := setting.SensitiveWordsToString()
+	b.Cleanup(func() { setting.SensitiveWordsFromString(origin) })
+	words, text := benchmarkSensitiveWords()
+	setting.SensitiveWordsFromString(strings.Join(words, "\n"))
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		SensitiveWordContains(text)
+	}
+}
+
+// BenchmarkSensitiveWordLinearScan 逐词 strings.Contains 作为对照
+func BenchmarkSensitiveWordLinearScan(b *testing.B) {
+	words, text := benchmarkSensitiveWords()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		lower := strings.ToLower(text)
+		for _, word := range words {
+			if strings.Contains(lower, word) {
+				break
+			}
+		}
+	}
+}
@@ -1,13 +1,14 @@
 package service
 
 import (
-	"bytes"
 	"fmt"
 	"hash/fnv"
 	"sort"
 	"strings"
 	"sync"
 
+	"github.com/QuantumNous/new-api/common"
+
 	goahocorasick "github.com/anknown/ahocorasick"
 )
 
@@ -60,16 +61,6 @@ func RemoveDuplicate(s []string) []string {
 	return result
 }
 
-func InitAc(dict []string) *goahocorasick.Machine {
-	m := new(goahocorasick.Machine)
-	runes := readRunes(dict)
-	if err := m.Build(runes); err != nil {
-		fmt.Println(err)
-		return nil
-	}
-	return m
-}
-
 var acCache sync.Map
 
 func acKey(dict []string) string {
@@ -105,7 +96,7 @@ func getOrBuildAC(dict []string) *goahocorasick.Machine {
 			return m
 		}
 	}
-	m := InitAc(dict)
+	m := common.NewAcMachine(dict)
 	if m == nil {
 		return nil
 	}
@@ -117,18 +108,6 @@ func getOrBuildAC(dict []string) *goahocorasick.Machine {
 	return m
 }
 
-func readRunes(dict []string) [][]rune {
-	var runes [][]rune
-
-	for _, word := range dict {
-		word = strings.ToLower(word)
-		l := bytes.TrimSpace([]byte(word))
-		runes = append(runes, bytes.Runes(l))
-	}
-
-	return runes
-}
-
 func AcSearch(findText string, dict []string, stopImmediately bool) (bool, []string) {
 	if len(dict) == 0 {
 		return false, nil
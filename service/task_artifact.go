@@ -0,0 +1,405 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/model"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/setting/system_setting"
+)
+
+// TaskArtifactURLResolver 可选接口：产物地址需要转换或鉴权才能下载的任务适配器实现，
+// 例如 Vertex 的 gs:// 地址需转为 Cloud Storage 下载地址并携带访问令牌。
+type TaskArtifactURLResolver interface {
+	ResolveArtifactURL(uri string, key string, proxy string) (string, http.Header, error)
+}
+
+// TaskArtifact 任务产物内容，Header 为上游响应头（data URL 时仅含 Content-Type）
+type TaskArtifact struct {
+	Body   io.ReadCloser
+	Header http.Header
+}
+
+// OpenTaskArtifact 按渠道类型获取任务产物，视频代理与产物持久化共用。
+// result 为本轮轮询结果（可为 nil），其中的地址优先于任务中保存的地址
+func OpenTaskArtifact(ctx context.Context, ch *model.Channel, task *model.Task, result *relaycommon.TaskInfo) (*TaskArtifact, error) {
+	if ch == nil || task == nil {
+		return nil, fmt.Errorf("invalid channel or task")
+	}
+	artifactURL, header, err := resolveTaskArtifactURL(ch, task, result)
+	if err != nil {
+		return nil, err
+	}
+	if strings.HasPrefix(artifactURL, "data:") {
+		data, contentType, err := decodeMediaDataURL(artifactURL)
+		if err != nil {
+			return nil, err
+		}
+		dataHeader := http.Header{}
+		if contentType != "" {
+			dataHeader.Set("Content-Type", contentType)
+		}
+		return &TaskArtifact{Body: io.NopCloser(bytes.NewReader(data)), Header: dataHeader}, nil
+	}
+	if !strings.HasPrefix(artifactURL, "http://") && !strings.HasPrefix(artifactURL, "https://") {
+		return nil, fmt.Errorf("no downloadable artifact url")
+	}
+
+	client, err := GetHttpClientWithProxy(ch.GetSetting().Proxy)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, artifactURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header = header
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("artifact download returned status %d", resp.StatusCode)
+	}
+	return &TaskArtifact{Body: resp.Body, Header: resp.Header}, nil
+}
+
+// resolveTaskArtifactURL 确定产物的下载地址与请求头。
+// 渠道固定的下载地址可信，其余来自任务结果的上游地址需通过 SSRF 校验
+func resolveTaskArtifactURL(ch *model.Channel, task *model.Task, result *relaycommon.TaskInfo) (string, http.Header, error) {
+	header := http.Header{}
+	var resultURL string
+	if result != nil {
+		resultURL = strings.TrimSpace(result.Url)
+	}
+	switch ch.Type {
+	case constant.ChannelTypeGemini:
+		key := getTaskArtifactKey(ch, task)
+		if key == "" {
+			return "", nil, fmt.Errorf("api key not available for task")
+		}
+		header.Set("x-goog-api-key", key)
+		if result != nil && result.RemoteUrl != "" {
+			return ensureAPIKey(result.RemoteUrl, key), header, nil
+		}
+		artifactURL, err := getGeminiVideoURL(ch, task, key)
+		return artifactURL, header, err
+	case constant.ChannelTypeVertexAi:
+		artifactURL := resultURL
+		if artifactURL == "" {
+			var err error
+			if artifactURL, err = getVertexVideoURL(ch, task); err != nil {
+				return "", nil, err
+			}
+		}
+		if resolver, ok := getTaskArtifactAdaptor(ch).(TaskArtifactURLResolver); ok {
+			return resolver.ResolveArtifactURL(artifactURL, getTaskArtifactKey(ch, task), ch.GetSetting().Proxy)
+		}
+		return artifactURL, header, nil
+	case constant.ChannelTypeOpenAI, constant.ChannelTypeSora:
+		baseURL := ch.GetBaseURL()
+		if baseURL == "" {
+			baseURL = "https://api.openai.com"
+		}
+		header.Set("Authorization", "Bearer "+ch.Key)
+		return fmt.Sprintf("%s/v1/videos/%s/content", baseURL, task.GetUpstreamTaskID()), header, nil
+	}
+
+	artifactURL := resultURL
+	if artifactURL == "" {
+		// Video URL is stored in PrivateData.ResultURL (fallback to FailReason for old data)
+		artifactURL = strings.TrimSpace(task.GetResultURL())
+	}
+	if strings.HasPrefix(artifactURL, "http://") || strings.HasPrefix(artifactURL, "https://") {
+		fetchSetting := system_setting.GetFetchSetting()
+		if err := common.ValidateURLWithFetchSetting(artifactURL, fetchSetting.EnableSSRFProtection, fetchSetting.AllowPrivateIp, fetchSetting.DomainFilterMode, fetchSetting.IpFilterMode, fetchSetting.DomainList, fetchSetting.IpList, fetchSetting.AllowedPorts, fetchSetting.ApplyIPFilterForDomain); err != nil {
+			return "", nil, fmt.Errorf("request reject: %v", err)
+		}
+	}
+	return artifactURL, header, nil
+}
+
+func getTaskArtifactAdaptor(ch *model.Channel) TaskPollingAdaptor {
+	if GetTaskAdaptorFunc == nil {
+		return nil
+	}
+	return GetTaskAdaptorFunc(constant.TaskPlatform(strconv.Itoa(ch.Type)))
+}
+
+func getTaskArtifactBaseURL(ch *model.Channel) string {
+	if baseURL := ch.GetBaseURL(); baseURL != "" {
+		return baseURL
+	}
+	return constant.ChannelBaseURLs[ch.Type]
+}
+
+// getTaskArtifactKey 优先使用提交任务时记录的密钥，其次为渠道的第一个密钥
+func getTaskArtifactKey(ch *model.Channel, task *model.Task) string {
+	if task != nil {
+		if key := strings.TrimSpace(task.PrivateData.Key); key != "" {
+			return key
+		}
+	}
+	for _, key := range ch.GetKeys() {
+		if key = strings.TrimSpace(key); key != "" {
+			return key
+		}
+	}
+	return strings.TrimSpace(ch.Key)
+}
+
+// fetchTaskArtifactPayload 重新查询上游任务，返回原始响应与适配器的解析结果
+func fetchTaskArtifactPayload(ch *model.Channel, task *model.Task, key string) ([]byte, *relaycommon.TaskInfo, error) {
+	adaptor := getTaskArtifactAdaptor(ch)
+	if adaptor == nil {
+		return nil, nil, fmt.Errorf("task adaptor not found")
+	}
+	resp, err := adaptor.FetchTask(getTaskArtifactBaseURL(ch), key, map[string]any{
+		"task_id": task.GetUpstreamTaskID(),
+		"action":  task.Action,
+	}, ch.GetSetting().Proxy)
+	if err != nil {
+		return nil, nil, fmt.Errorf("fetch task failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("read task response failed: %w", err)
+	}
+	taskInfo, err := adaptor.ParseTaskResult(body)
+	return body, taskInfo, err
+}
+
+func getGeminiVideoURL(ch *model.Channel, task *model.Task, apiKey string) (string, error) {
+	if url := extractGeminiVideoURLFromTaskData(task); url != "" {
+		return ensureAPIKey(url, apiKey), nil
+	}
+
+	body, taskInfo, parseErr := fetchTaskArtifactPayload(ch, task, apiKey)
+	if body == nil {
+		return "", parseErr
+	}
+	if parseErr == nil && taskInfo != nil && taskInfo.RemoteUrl != "" {
+		return ensureAPIKey(taskInfo.RemoteUrl, apiKey), nil
+	}
+	if url := extractGeminiVideoURLFromPayload(body); url != "" {
+		return ensureAPIKey(url, apiKey), nil
+	}
+	if parseErr != nil {
+		return "", fmt.Errorf("parse task result failed: %w", parseErr)
+	}
+	return "", fmt.Errorf("gemini video url not found")
+}
+
+func getVertexVideoURL(ch *model.Channel, task *model.Task) (string, error) {
+	if url := strings.TrimSpace(task.GetResultURL()); url != "" && !isTaskProxyContentURL(url, task.TaskID) {
+		return url, nil
+	}
+	if url := extractVertexVideoURLFromTaskData(task); url != "" {
+		return url, nil
+	}
+
+	key := getTaskArtifactKey(ch, task)
+	if key == "" {
+		return "", fmt.Errorf("vertex key not available for task")
+	}
+	body, taskInfo, parseErr := fetchTaskArtifactPayload(ch, task, key)
+	if body == nil {
+		return "", parseErr
+	}
+	if parseErr == nil && taskInfo != nil && strings.TrimSpace(taskInfo.Url) != "" {
+		return taskInfo.Url, nil
+	}
+	if url := extractVertexVideoURLFromPayload(body); url != "" {
+		return url, nil
+	}
+	if parseErr != nil {
+		return "", fmt.Errorf("parse task result failed: %w", parseErr)
+	}
+	return "", fmt.Errorf("vertex video url not found")
+}
+
+func decodeMediaDataURL(dataURL string) ([]byte, string, error) {
+	header, payload, ok := strings.Cut(dataURL, ",")
+	if !ok || !strings.HasSuffix(header, ";base64") {
+		return nil, "", fmt.Errorf("unsupported data url")
+	}
+	contentType := strings.TrimSuffix(strings.TrimPrefix(header, "data:"), ";base64")
+	data, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil {
+		if data, err = base64.RawStdEncoding.DecodeString(payload); err != nil {
+			return nil, "", err
+		}
+	}
+	return data, contentType, nil
+}
+
+func extractGeminiVideoURLFromTaskData(task *model.Task) string {
+	if task == nil || len(task.Data) == 0 {
+		return ""
+	}
+	var payload map[string]any
+	if err := common.Unmarshal(task.Data, &payload); err != nil {
+		return ""
+	}
+	return extractGeminiVideoURLFromMap(payload)
+}
+
+func extractGeminiVideoURLFromPayload(body []byte) string {
+	var payload map[string]any
+	if err := common.Unmarshal(body, &payload); err != nil {
+		return ""
+	}
+	return extractGeminiVideoURLFromMap(payload)
+}
+
+func extractGeminiVideoURLFromMap(payload map[string]any) string {
+	if payload == nil {
+		return ""
+	}
+	if uri, ok := payload["uri"].(string); ok && uri != "" {
+		return uri
+	}
+	if resp, ok := payload["response"].(map[string]any); ok {
+		if uri := extractGeminiVideoURLFromResponse(resp); uri != "" {
+			return uri
+		}
+	}
+	return ""
+}
+
+func extractGeminiVideoURLFromResponse(resp map[string]any) string {
+	if resp == nil {
+		return ""
+	}
+	if gvr, ok := resp["generateVideoResponse"].(map[string]any); ok {
+		if uri := extractGeminiVideoURLFromGeneratedSamples(gvr); uri != "" {
+			return uri
+		}
+	}
+	if videos, ok := resp["videos"].([]any); ok {
+		for _, video := range videos {
+			if vm, ok := video.(map[string]any); ok {
+				if uri, ok := vm["uri"].(string); ok && uri != "" {
+					return uri
+				}
+			}
+		}
+	}
+	if uri, ok := resp["video"].(string); ok && uri != "" {
+		return uri
+	}
+	if uri, ok := resp["uri"].(string); ok && uri != "" {
+		return uri
+	}
+	return ""
+}
+
+func extractGeminiVideoURLFromGeneratedSamples(gvr map[string]any) string {
+	if gvr == nil {
+		return ""
+	}
+	if samples, ok := gvr["generatedSamples"].([]any); ok {
+		for _, sample := range samples {
+			if sm, ok := sample.(map[string]any); ok {
+				if video, ok := sm["video"].(map[string]any); ok {
+					if uri, ok := video["uri"].(string); ok && uri != "" {
+						return uri
+					}
+				}
+			}
+		}
+	}
+	return ""
+}
+
+func isTaskProxyContentURL(url string, taskID string) bool {
+	if strings.TrimSpace(url) == "" || strings.TrimSpace(taskID) == "" {
+		return false
+	}
+	return strings.Contains(url, "/v1/videos/"+taskID+"/content")
+}
+
+func extractVertexVideoURLFromTaskData(task *model.Task) string {
+	if task == nil || len(task.Data) == 0 {
+		return ""
+	}
+	return extractVertexVideoURLFromPayload(task.Data)
+}
+
+func extractVertexVideoURLFromPayload(body []byte) string {
+	var payload map[string]any
+	if err := common.Unmarshal(body, &payload); err != nil {
+		return ""
+	}
+	resp, ok := payload["response"].(map[string]any)
+	if !ok || resp == nil {
+		return ""
+	}
+
+	if videos, ok := resp["videos"].([]any); ok && len(videos) > 0 {
+		if video, ok := videos[0].(map[string]any); ok && video != nil {
+			if b64, _ := video["bytesBase64Encoded"].(string); strings.TrimSpace(b64) != "" {
+				mime, _ := video["mimeType"].(string)
+				enc, _ := video["encoding"].(string)
+				return buildVideoDataURL(mime, enc, b64)
+			}
+			// 请求指定 storageUri 时视频写入 Cloud Storage，只返回 gs:// 地址
+			if gcsUri, _ := video["gcsUri"].(string); strings.TrimSpace(gcsUri) != "" {
+				return gcsUri
+			}
+		}
+	}
+	if b64, _ := resp["bytesBase64Encoded"].(string); strings.TrimSpace(b64) != "" {
+		enc, _ := resp["encoding"].(string)
+		return buildVideoDataURL("", enc, b64)
+	}
+	if video, _ := resp["video"].(string); strings.TrimSpace(video) != "" {
+		if strings.HasPrefix(video, "data:") || strings.HasPrefix(video, "http://") || strings.HasPrefix(video, "https://") {
+			return video
+		}
+		enc, _ := resp["encoding"].(string)
+		return buildVideoDataURL("", enc, video)
+	}
+	return ""
+}
+
+func buildVideoDataURL(mimeType string, encoding string, base64Data string) string {
+	mime := strings.TrimSpace(mimeType)
+	if mime == "" {
+		enc := strings.TrimSpace(encoding)
+		if enc == "" {
+			enc = "mp4"
+		}
+		if strings.Contains(enc, "/") {
+			mime = enc
+		} else {
+			mime = "video/" + enc
+		}
+	}
+	return "data:" + mime + ";base64," + base64Data
+}
+
+func ensureAPIKey(uri, key string) string {
+	if key == "" || uri == "" {
+		return uri
+	}
+	if strings.Contains(uri, "key=") {
+		return uri
+	}
+	if strings.Contains(uri, "?") {
+		return fmt.Sprintf("%s&key=%s", uri, key)
+	}
+	return fmt.Sprintf("%s?key=%s", uri, key)
+}
@@ -0,0 +1,208 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/model"
+
+	"github.com/bytedance/gopkg/util/gopool"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	taskCallbackPollInterval = 10 * time.Second
+	taskCallbackBatchSize    = 50
+	taskCallbackMaxAttempts  = 6
+	taskCallbackBaseBackoff  = 30 * time.Second
+	taskCallbackMaxBackoff   = 1 * time.Hour
+	taskCallbackMaxErrorLen  = 500
+)
+
+var (
+	taskCallbackTaskOnce sync.Once
+	taskCallbackRunning  atomic.Bool
+)
+
+// ValidateTaskCallbackURL 回调地址只允许 http/https，SSRF 校验在投递时按当时的系统设置进行
+func ValidateTaskCallbackURL(rawURL string) error {
+	if len(rawURL) > 512 {
+		return fmt.Errorf("callback_url is too long")
+	}
+	u, err := url.ParseRequestURI(rawURL)
+	if err != nil {
+		return fmt.Errorf("invalid callback_url: %v", err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("callback_url must be an http or https address")
+	}
+	return nil
+}
+
+// ResolveTaskCallbackURL 请求体中的 callback_url 优先，未提供时使用令牌配置的默认回调地址
+func ResolveTaskCallbackURL(c *gin.Context) (string, error) {
+	var req struct {
+		CallbackURL string `json:"callback_url"`
+	}
+	// 请求体格式错误由适配器校验时返回，这里只负责提取回调地址
+	_ = common.UnmarshalBodyReusable(c, &req)
+	callbackURL := req.CallbackURL
+	if callbackURL == "" {
+		callbackURL = common.GetContextKeyString(c, constant.ContextKeyTokenCallbackUrl)
+	}
+	if callbackURL == "" {
+		return "", nil
+	}
+	if err := ValidateTaskCallbackURL(callbackURL); err != nil {
+		return "", err
+	}
+	// 回调始终签名：首次使用时为用户生成签名密钥，生成失败则不接受回调地址
+	if _, err := model.EnsureUserTaskCallbackSecret(c.GetInt("id")); err != nil {
+		return "", fmt.Errorf("failed to prepare callback secret: %w", err)
+	}
+	return callbackURL, nil
+}
+
+func buildTaskCallbackPayload(task *model.Task) *dto.TaskCallbackPayload {
+	payload := &dto.TaskCallbackPayload{
+		TaskID:     task.TaskID,
+		Platform:   string(task.Platform),
+		Action:     task.Action,
+		Model:      task.Properties.OriginModelName,
+		Progress:   task.Progress,
+		Quota:      task.Quota,
+		SubmitTime: task.SubmitTime,
+		FinishTime: task.FinishTime,
+		Data:       task.Data,
+		Timestamp:  time.Now().Unix(),
+	}
+	if task.Status == model.TaskStatusSuccess {
+		payload.Event = dto.TaskCallbackEventSucceeded
+		payload.Status = "succeeded"
+		payload.ResultURL = task.GetResultURL()
+	} else {
+		payload.Event = dto.TaskCallbackEventFailed
+		payload.Status = "failed"
+		payload.FailReason = task.FailReason
+	}
+	return payload
+}
+
+// EnqueueTaskCallback 任务到达终态后写入投递记录，由回调任务异步发送
+func EnqueueTaskCallback(ctx context.Context, task *model.Task) {
+	if task == nil || task.PrivateData.CallbackURL == "" {
+		return
+	}
+	if task.Status != model.TaskStatusSuccess && task.Status != model.TaskStatusFailure {
+		return
+	}
+	payload := buildTaskCallbackPayload(task)
+	payloadBytes, err := common.Marshal(payload)
+	if err != nil {
+		logger.LogError(ctx, fmt.Sprintf("marshal task callback payload failed for task %s: %v", task.TaskID, err))
+		return
+	}
+	callback := &model.TaskCallback{
+		TaskId:  task.TaskID,
+		UserId:  task.UserId,
+		Url:     task.PrivateData.CallbackURL,
+		Event:   payload.Event,
+		Payload: string(payloadBytes),
+	}
+	if err := callback.Insert(); err != nil {
+		logger.LogError(ctx, fmt.Sprintf("insert task callback failed for task %s: %v", task.TaskID, err))
+	}
+}
+
+// taskCallbackBackoff 第 n 次失败后的等待时间：30s 起按 2 的幂增长，最长 1 小时
+func taskCallbackBackoff(attempts int) time.Duration {
+	backoff := taskCallbackBaseBackoff
+	for i := 1; i < attempts && backoff < taskCallbackMaxBackoff; i++ {
+		backoff *= 2
+	}
+	return min(backoff, taskCallbackMaxBackoff)
+}
+
+// recordTaskCallbackAttempt 根据本次投递结果推进状态：成功结束，失败按退避重试，超过最大次数标记为 failed
+func recordTaskCallbackAttempt(callback *model.TaskCallback, statusCode int, err error, now time.Time) {
+	callback.Attempts++
+	callback.LastStatusCode = statusCode
+	if err == nil {
+		callback.Status = model.TaskCallbackStatusSucceeded
+		callback.LastError = ""
+		return
+	}
+	lastError := []rune(err.Error())
+	if len(lastError) > taskCallbackMaxErrorLen {
+		lastError = lastError[:taskCallbackMaxErrorLen]
+	}
+	callback.LastError = string(lastError)
+	if callback.Attempts >= taskCallbackMaxAttempts {
+		callback.Status = model.TaskCallbackStatusFailed
+		return
+	}
+	callback.NextAttemptAt = now.Add(taskCallbackBackoff(callback.Attempts)).Unix()
+}
+
+func deliverTaskCallback(ctx context.Context, callback *model.TaskCallback) {
+	// 使用用户的任务回调密钥签名，取不到密钥时本次不投递，按失败重试
+	statusCode := 0
+	secret, err := model.EnsureUserTaskCallbackSecret(callback.UserId)
+	if err == nil {
+		statusCode, err = postWebhook(callback.Url, secret, []byte(callback.Payload), map[string]string{
+			"X-Webhook-Event":    callback.Event,
+			"X-Webhook-Delivery": strconv.Itoa(callback.Id),
+		})
+	}
+	recordTaskCallbackAttempt(callback, statusCode, err, time.Now())
+	if err != nil {
+		logger.LogWarn(ctx, fmt.Sprintf("task callback #%d for task %s failed (attempt %d): %v", callback.Id, callback.TaskId, callback.Attempts, err))
+	}
+	if err := callback.SaveAttempt(); err != nil {
+		logger.LogError(ctx, fmt.Sprintf("save task callback #%d failed: %v", callback.Id, err))
+	}
+}
+
+// StartTaskCallbackTask 主节点定时投递到期的任务回调
+func StartTaskCallbackTask() {
+	taskCallbackTaskOnce.Do(func() {
+		if !common.IsMasterNode {
+			return
+		}
+		gopool.Go(func() {
+			logger.LogInfo(context.Background(), fmt.Sprintf("task callback delivery task started: interval=%s", taskCallbackPollInterval))
+			ticker := time.NewTicker(taskCallbackPollInterval)
+			defer ticker.Stop()
+			for {
+				runTaskCallbackDeliveryOnce()
+				<-ticker.C
+			}
+		})
+	})
+}
+
+func runTaskCallbackDeliveryOnce() {
+	if !taskCallbackRunning.CompareAndSwap(false, true) {
+		return
+	}
+	defer taskCallbackRunning.Store(false)
+
+	ctx := context.Background()
+	callbacks, err := model.GetDueTaskCallbacks(time.Now().Unix(), taskCallbackBatchSize)
+	if err != nil {
+		logger.LogWarn(ctx, fmt.Sprintf("load due task callbacks failed: %v", err))
+		return
+	}
+	for _, callback := range callbacks {
+		deliverTaskCallback(ctx, callback)
+	}
+}
@@ -0,0 +1,96 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/setting/system_setting"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTaskCallbackBackoff(t *testing.T) {
+	require.Equal(t, 30*time.Second, taskCallbackBackoff(1))
+	require.Equal(t, 2*time.Minute, taskCallbackBackoff(3))
+	require.Equal(t, time.Hour, taskCallbackBackoff(20))
+}
+
+func TestRecordTaskCallbackAttempt(t *testing.T) {
+	now := time.Unix(1000, 0)
+	callback := &model.TaskCallback{Status: model.TaskCallbackStatusPending}
+
+	recordTaskCallbackAttempt(callback, 500, errors.New("webhook request failed with status code: 500"), now)
+	require.Equal(t, model.TaskCallbackStatusPending, callback.Status)
+	require.Equal(t, 1, callback.Attempts)
+	require.Equal(t, int64(1030), callback.NextAttemptAt)
+
+	callback.Attempts = taskCallbackMaxAttempts - 1
+	recordTaskCallbackAttempt(callback, 0, errors.New("timeout"), now)
+	require.Equal(t, model.TaskCallbackStatusFailed, callback.Status)
+
+	recordTaskCallbackAttempt(callback, 200, nil, now)
+	require.Equal(t, model.TaskCallbackStatusSucceeded, callback.Status)
+	require.Empty(t, callback.LastError)
+}
+
+func TestEnqueueTaskCallback(t *testing.T) {
+	require.NoError(t, model.DB.AutoMigrate(&model.TaskCallback{}))
+	t.Cleanup(func() { model.DB.Exec("DELETE FROM task_callbacks") })
+
+	task := &model.Task{TaskID: "task_cb", UserId: 7, Status: model.TaskStatusInProgress}
+	task.PrivateData.CallbackURL = "https://example.com/hook"
+	EnqueueTaskCallback(context.Background(), task)
+
+	task.Status = model.TaskStatusFailure
+	task.FailReason = "upstream error"
+	EnqueueTaskCallback(context.Background(), task)
+
+	callbacks, total, err := model.GetTaskCallbacks(model.TaskCallbackFilter{UserId: 7}, 0, 10)
+	require.NoError(t, err)
+	require.EqualValues(t, 1, total)
+	require.Equal(t, dto.TaskCallbackEventFailed, callbacks[0].Event)
+	require.Equal(t, model.TaskCallbackStatusPending, callbacks[0].Status)
+	require.Contains(t, callbacks[0].Payload, `"fail_reason":"upstream error"`)
+
+	due, err := model.GetDueTaskCallbacks(time.Now().Unix(), 10)
+	require.NoError(t, err)
+	require.Len(t, due, 1)
+}
+
+func TestDeliverTaskCallbackAlwaysSigned(t *testing.T) {
+	truncate(t)
+	InitHttpClient()
+	require.NoError(t, model.DB.AutoMigrate(&model.TaskCallback{}))
+	t.Cleanup(func() { model.DB.Exec("DELETE FROM task_callbacks") })
+	fetchSetting := system_setting.GetFetchSetting()
+	savedFetch := *fetchSetting
+	t.Cleanup(func() { *fetchSetting = savedFetch })
+	fetchSetting.EnableSSRFProtection = false
+	seedUser(t, 8, 0)
+
+	var signature string
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		signature = r.Header.Get("X-Webhook-Signature")
+	}))
+	defer receiver.Close()
+
+	callback := &model.TaskCallback{TaskId: "task_sign", UserId: 8, Url: receiver.URL, Event: dto.TaskCallbackEventSucceeded, Payload: `{"task_id":"task_sign"}`}
+	require.NoError(t, callback.Insert())
+	deliverTaskCallback(context.Background(), callback)
+	require.Equal(t, model.TaskCallbackStatusSucceeded, callback.Status)
+
+	// 用户未配置密钥时首次投递自动生成，之后保持不变
+	secret, err := model.EnsureUserTaskCallbackSecret(8)
+	require.NoError(t, err)
+	require.NotEmpty(t, secret)
+	require.Equal(t, generateSignature(secret, []byte(callback.Payload)), signature)
+	again, err := model.EnsureUserTaskCallbackSecret(8)
+	require.NoError(t, err)
+	require.Equal(t, secret, again)
+}
@@ -15,6 +15,7 @@ import (
 	"github.com/QuantumNous/new-api/dto"
 	"github.com/QuantumNous/new-api/logger"
 	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/pkg/metrics"
 	"github.com/QuantumNous/new-api/relay/channel/task/taskcommon"
 	relaycommon "github.com/QuantumNous/new-api/relay/common"
 
@@ -31,6 +32,12 @@ type TaskPollingAdaptor interface {
 	AdjustBillingOnComplete(task *model.Task, taskResult *relaycommon.TaskInfo) int
 }
 
+// TaskSuccessHandler 可选接口：任务成功（CAS 推进成功后）需要额外处理的适配器实现，
+// 例如微调任务完成后注册生成的模型。
+type TaskSuccessHandler interface {
+	OnTaskSuccess(ctx context.Context, task *model.Task, taskResult *relaycommon.TaskInfo) error
+}
+
 // GetTaskAdaptorFunc 由 main 包注入，用于获取指定平台的任务适配器。
 // 打破 service -> relay -> relay/channel -> service 的循环依赖。
 var GetTaskAdaptorFunc func(platform constant.TaskPlatform) TaskPollingAdaptor
@@ -80,6 +87,7 @@ func sweepTimedOutTasks(ctx context.Context) {
 		if !isLegacy && task.Quota != 0 {
 			RefundTaskQuota(ctx, task, reason)
 		}
+		EnqueueTaskCallback(ctx, task)
 	}
 
 	if timedOutCount > 0 {
@@ -99,6 +107,10 @@ func TaskPollingLoop() {
 		for _, t := range allTasks {
 			platformTask[t.Platform] = append(platformTask[t.Platform], t)
 		}
+		metrics.ResetTaskBacklog()
+		for platform, tasks := range platformTask {
+			metrics.SetTaskBacklog(string(platform), len(tasks))
+		}
 		for platform, tasks := range platformTask {
 			if len(tasks) == 0 {
 				continue
@@ -225,6 +237,7 @@ func updateSunoTasks(ctx context.Context, channelId int, taskIds []string, taskM
 		if !taskNeedsUpdate(task, responseItem) {
 			continue
 		}
+		wasDone := task.Status == model.TaskStatusSuccess || task.Status == model.TaskStatusFailure
 
 		task.Status = lo.If(model.TaskStatus(responseItem.Status) != "", model.TaskStatus(responseItem.Status)).Else(task.Status)
 		task.FailReason = lo.If(responseItem.FailReason != "", responseItem.FailReason).Else(task.FailReason)
@@ -244,6 +257,8 @@ func updateSunoTasks(ctx context.Context, channelId int, taskIds []string, taskM
 		err = task.Update()
 		if err != nil {
 			common.SysLog("UpdateSunoTask task error: " + err.Error())
+		} else if !wasDone {
+			EnqueueTaskCallback(ctx, task)
 		}
 	}
 	return nil
@@ -471,6 +486,7 @@ func updateVideoSingleTask(ctx context.Context, adaptor TaskPollingAdaptor, ch *
 	}
 
 	isDone := task.Status == model.TaskStatusSuccess || task.Status == model.TaskStatusFailure
+	shouldNotify := false
 	if isDone && snap.Status != task.Status {
 		won, err := task.UpdateWithStatus(snap.Status)
 		if err != nil {
@@ -481,6 +497,8 @@ func updateVideoSingleTask(ctx context.Context, adaptor TaskPollingAdaptor, ch *
 			logger.LogWarn(ctx, fmt.Sprintf("Task %s already transitioned by another process, skip billing", task.TaskID))
 			shouldRefund = false
 			shouldSettle = false
+		} else {
+			shouldNotify = true
 		}
 	} else if !snap.Equal(task.Snapshot()) {
 		if _, err := task.UpdateWithStatus(snap.Status); err != nil {
@@ -492,11 +510,26 @@ func updateVideoSingleTask(ctx context.Context, adaptor TaskPollingAdaptor, ch *
 	}
 
 	if shouldSettle {
+		if handler, ok := adaptor.(TaskSuccessHandler); ok {
+			if err := handler.OnTaskSuccess(ctx, task, taskResult); err != nil {
+				logger.LogError(ctx, fmt.Sprintf("OnTaskSuccess failed for task %s: %s", task.TaskID, err.Error()))
+			}
+		}
 		settleTaskBillingOnComplete(ctx, adaptor, task, taskResult)
 	}
 	if shouldRefund {
 		RefundTaskQuota(ctx, task, task.FailReason)
 	}
+	if shouldSettle {
+		// 产物持久化在后台进行，结束后再入队回调
+		PersistTaskMediaAsync(ctx, ch, task, taskResult, func() {
+			if shouldNotify {
+				EnqueueTaskCallback(ctx, task)
+			}
+		})
+	} else if shouldNotify {
+		EnqueueTaskCallback(ctx, task)
+	}
 
 	return nil
 }
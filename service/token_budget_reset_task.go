@@ -0,0 +1,67 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/model"
+
+	"github.com/bytedance/gopkg/util/gopool"
+)
+
+const (
+	tokenBudgetResetTickInterval = 1 * time.Minute
+	tokenBudgetResetBatchSize    = 300
+)
+
+var (
+	tokenBudgetResetOnce    sync.Once
+	tokenBudgetResetRunning atomic.Bool
+)
+
+func StartTokenBudgetResetTask() {
+	tokenBudgetResetOnce.Do(func() {
+		if !common.IsMasterNode {
+			return
+		}
+		gopool.Go(func() {
+			logger.LogInfo(context.Background(), fmt.Sprintf("token budget reset task started: tick=%s", tokenBudgetResetTickInterval))
+			ticker := time.NewTicker(tokenBudgetResetTickInterval)
+			defer ticker.Stop()
+
+			runTokenBudgetResetOnce()
+			for range ticker.C {
+				runTokenBudgetResetOnce()
+			}
+		})
+	})
+}
+
+func runTokenBudgetResetOnce() {
+	if !tokenBudgetResetRunning.CompareAndSwap(false, true) {
+		return
+	}
+	defer tokenBudgetResetRunning.Store(false)
+
+	ctx := context.Background()
+	totalReset := 0
+	for {
+		n, err := model.ResetDueTokenBudgets(tokenBudgetResetBatchSize)
+		if err != nil {
+			logger.LogWarn(ctx, fmt.Sprintf("token budget reset task failed: %v", err))
+			return
+		}
+		totalReset += n
+		if n < tokenBudgetResetBatchSize {
+			break
+		}
+	}
+	if common.DebugEnabled && totalReset > 0 {
+		logger.LogDebug(ctx, "token budget reset: reset_count=%d", totalReset)
+	}
+}
@@ -300,6 +300,17 @@ func EstimateRequestToken(c *gin.Context, meta *types.TokenCountMeta, info *rela
 	return tkm, nil
 }
 
+// EstimateCountTokens 本地估算 count_tokens / countTokens 的输入 token，该接口本身用于计数，不受 CountToken 开关影响
+func EstimateCountTokens(c *gin.Context, request dto.Request, info *relaycommon.RelayInfo) (int, error) {
+	meta := request.GetTokenCountMeta()
+	if constant.CountToken {
+		return EstimateRequestToken(c, meta, info)
+	}
+	// 未开启 CountToken 时不下载媒体文件，图片按默认估算值计入
+	model := common.GetContextKeyString(c, constant.ContextKeyOriginalModel)
+	return CountTextToken(meta.CombineText, model) + len(meta.Files)*520, nil
+}
+
 func CountTokenRealtime(info *relaycommon.RelayInfo, request dto.RealtimeEvent, model string) (int, int, error) {
 	audioToken := 0
 	textToken := 0
@@ -309,14 +320,16 @@ func CountTokenRealtime(info *relaycommon.RelayInfo, request dto.RealtimeEvent,
 			msgTokens := CountTextToken(request.Session.Instructions, model)
 			textToken += msgTokens
 		}
-	case dto.RealtimeEventResponseAudioDelta:
+	case dto.RealtimeEventResponseAudioDelta, dto.RealtimeEventResponseOutputAudioDelta:
 		// count audio token
 		atk, err := CountAudioTokenOutput(request.Delta, info.OutputAudioFormat)
 		if err != nil {
 			return 0, 0, fmt.Errorf("error counting audio token: %v", err)
 		}
 		audioToken += atk
-	case dto.RealtimeEventResponseAudioTranscriptionDelta, dto.RealtimeEventResponseFunctionCallArgumentsDelta:
+	case dto.RealtimeEventResponseAudioTranscriptionDelta, dto.RealtimeEventResponseOutputAudioTranscriptDelta,
+		dto.RealtimeEventResponseTextDelta, dto.RealtimeEventResponseOutputTextDelta,
+		dto.RealtimeEventResponseFunctionCallArgumentsDelta:
 		// count text token
 		tkm := CountTextToken(request.Delta, model)
 		textToken += tkm
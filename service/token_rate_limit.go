@@ -0,0 +1,132 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/common/limiter"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/logger"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	tokenRateLimitPeriod = time.Minute
+	// 并发名额的最长占用时间，进程异常退出后未释放的名额超时后不再计入
+	tokenConcurrencyTTL = 30 * time.Minute
+)
+
+// TokenRateLimitError 令牌触发了 RPM / TPM / 并发限制，Type 对应 OpenAI 429 错误中的 type 字段
+type TokenRateLimitError struct {
+	Type    string
+	Message string
+}
+
+func (e *TokenRateLimitError) Error() string {
+	return e.Message
+}
+
+func tokenRateLimitKey(kind string, tokenId int) string {
+	return fmt.Sprintf("tokenRateLimit:%s:%d", kind, tokenId)
+}
+
+func formatRateLimitReset(d time.Duration) string {
+	return d.Round(time.Millisecond).String()
+}
+
+func setRateLimitHeaders(c *gin.Context, kind string, limit int, result limiter.BucketResult) {
+	c.Header("x-ratelimit-limit-"+kind, strconv.Itoa(limit))
+	c.Header("x-ratelimit-remaining-"+kind, strconv.FormatInt(max(result.Remaining, 0), 10))
+	c.Header("x-ratelimit-reset-"+kind, formatRateLimitReset(result.Reset))
+}
+
+// CheckTokenRateLimit 检查令牌的 RPM / TPM / 并发限制并写入 x-ratelimit-* 响应头。
+// 触发限制时返回 *TokenRateLimitError；占用了并发名额时返回的 release 需在请求结束后调用。
+// WebSocket 连接（/v1/realtime、GET /v1/responses）会长时间保持，不计入并发限制，只检查 RPM / TPM
+func CheckTokenRateLimit(c *gin.Context) (release func(), err error) {
+	tokenId := common.GetContextKeyInt(c, constant.ContextKeyTokenId)
+	rpm := common.GetContextKeyInt(c, constant.ContextKeyTokenRpmLimit)
+	tpm := common.GetContextKeyInt(c, constant.ContextKeyTokenTpmLimit)
+	concurrency := common.GetContextKeyInt(c, constant.ContextKeyTokenConcurrencyLimit)
+	if tokenId == 0 || rpm <= 0 && tpm <= 0 && concurrency <= 0 {
+		return nil, nil
+	}
+
+	ctx := c.Request.Context()
+	lim := limiter.Get(ctx)
+
+	if rpm > 0 {
+		result, err := lim.Bucket(ctx, tokenRateLimitKey("rpm", tokenId), limiter.BucketTake, 1, int64(rpm), tokenRateLimitPeriod)
+		if err != nil {
+			return nil, err
+		}
+		setRateLimitHeaders(c, "requests", rpm, result)
+		if !result.Allowed {
+			return nil, &TokenRateLimitError{
+				Type:    "requests",
+				Message: fmt.Sprintf("Rate limit reached for requests: limit %d per minute. Please try again in %s.", rpm, formatRateLimitReset(result.Reset)),
+			}
+		}
+	}
+
+	if tpm > 0 {
+		// TPM 按结算后的实际用量扣减，这里只检查是否已经用完
+		result, err := lim.Bucket(ctx, tokenRateLimitKey("tpm", tokenId), limiter.BucketPeek, 0, int64(tpm), tokenRateLimitPeriod)
+		if err != nil {
+			return nil, err
+		}
+		setRateLimitHeaders(c, "tokens", tpm, result)
+		if !result.Allowed {
+			return nil, &TokenRateLimitError{
+				Type:    "tokens",
+				Message: fmt.Sprintf("Rate limit reached for tokens: limit %d per minute. Please try again in %s.", tpm, formatRateLimitReset(result.Reset)),
+			}
+		}
+	}
+
+	if concurrency > 0 && !websocket.IsWebSocketUpgrade(c.Request) {
+		key := tokenRateLimitKey("concurrency", tokenId)
+		requestId := c.GetString(common.RequestIdKey)
+		if requestId == "" {
+			requestId = common.GetRandomString(16)
+		}
+		acquired, current, err := lim.AcquireConcurrency(ctx, key, requestId, int64(concurrency), tokenConcurrencyTTL)
+		if err != nil {
+			return nil, err
+		}
+		c.Header("x-ratelimit-limit-concurrency", strconv.Itoa(concurrency))
+		c.Header("x-ratelimit-remaining-concurrency", strconv.FormatInt(max(int64(concurrency)-current, 0), 10))
+		if !acquired {
+			return nil, &TokenRateLimitError{
+				Type:    "concurrency",
+				Message: fmt.Sprintf("Too many concurrent requests: limit %d in flight.", concurrency),
+			}
+		}
+		release = func() {
+			// 请求上下文可能已取消，释放时使用独立的 context
+			if err := lim.ReleaseConcurrency(context.Background(), key, requestId); err != nil {
+				common.SysError("failed to release token concurrency: " + err.Error())
+			}
+		}
+	}
+	return release, nil
+}
+
+// ConsumeTokenRateLimitTokens 结算后按实际用量扣减令牌的 TPM 额度，允许透支，透支部分在后续时间窗口内恢复
+func ConsumeTokenRateLimitTokens(c *gin.Context, tokens int) {
+	tpm := common.GetContextKeyInt(c, constant.ContextKeyTokenTpmLimit)
+	tokenId := common.GetContextKeyInt(c, constant.ContextKeyTokenId)
+	if tpm <= 0 || tokenId == 0 || tokens <= 0 {
+		return
+	}
+	ctx := context.Background()
+	_, err := limiter.Get(ctx).Bucket(ctx, tokenRateLimitKey("tpm", tokenId), limiter.BucketForce, int64(tokens), int64(tpm), tokenRateLimitPeriod)
+	if err != nil {
+		logger.LogError(c, "failed to consume token tpm: "+err.Error())
+	}
+}
@@ -0,0 +1,39 @@
+package service
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckTokenRateLimitSkipsWebSocketConcurrency(t *testing.T) {
+	newContext := func(upgrade bool) *gin.Context {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest("GET", "/v1/responses", nil)
+		if upgrade {
+			c.Request.Header.Set("Connection", "Upgrade")
+			c.Request.Header.Set("Upgrade", "websocket")
+		}
+		common.SetContextKey(c, constant.ContextKeyTokenId, 9101)
+		common.SetContextKey(c, constant.ContextKeyTokenConcurrencyLimit, 1)
+		return c
+	}
+
+	// 长连接不占用并发名额，普通请求仍受限制
+	for i := 0; i < 2; i++ {
+		release, err := CheckTokenRateLimit(newContext(true))
+		require.NoError(t, err)
+		require.Nil(t, release)
+	}
+	release, err := CheckTokenRateLimit(newContext(false))
+	require.NoError(t, err)
+	require.NotNil(t, release)
+	_, err = CheckTokenRateLimit(newContext(false))
+	require.Error(t, err)
+	release()
+}
@@ -0,0 +1,55 @@
+package model_setting
+
+import (
+	"strings"
+
+	"github.com/QuantumNous/new-api/setting/config"
+)
+
+// ModelFallbackSettings 模型降级配置：某个模型的所有渠道重试失败后，依次尝试降级链中的模型
+type ModelFallbackSettings struct {
+	Enabled bool `json:"enabled"`
+	// DefaultEnabled 令牌未单独设置降级策略时是否允许降级
+	DefaultEnabled bool `json:"default_enabled"`
+	// Chains 模型名 -> 降级模型列表，按顺序尝试
+	Chains map[string][]string `json:"chains"`
+}
+
+// 默认配置
+var modelFallbackSettings = ModelFallbackSettings{
+	Enabled:        false,
+	DefaultEnabled: true,
+	Chains:         map[string][]string{},
+}
+
+func init() {
+	// 注册到全局配置管理器
+	config.GlobalConfig.Register("model_fallback", &modelFallbackSettings)
+}
+
+func GetModelFallbackSettings() *ModelFallbackSettings {
+	return &modelFallbackSettings
+}
+
+// GetModelFallbackChain 返回模型的降级链，已去除空值、重复项以及模型自身
+func GetModelFallbackChain(modelName string) []string {
+	modelName = strings.TrimSpace(modelName)
+	if !modelFallbackSettings.Enabled || modelName == "" {
+		return nil
+	}
+	chain := modelFallbackSettings.Chains[modelName]
+	if len(chain) == 0 {
+		return nil
+	}
+	seen := map[string]bool{modelName: true}
+	result := make([]string, 0, len(chain))
+	for _, fallback := range chain {
+		fallback = strings.TrimSpace(fallback)
+		if fallback == "" || seen[fallback] {
+			continue
+		}
+		seen[fallback] = true
+		result = append(result, fallback)
+	}
+	return result
+}
@@ -0,0 +1,44 @@
+package operation_setting
+
+import "github.com/QuantumNous/new-api/setting/config"
+
+// AdaptiveRoutingSetting 自适应渠道选择配置：根据渠道近期成功率与延迟调整同优先级内的有效权重，
+// 并通过熔断器临时摘除故障渠道（或多密钥渠道中的故障密钥）
+type AdaptiveRoutingSetting struct {
+	Enabled         bool    `json:"enabled"`
+	WindowSeconds   int     `json:"window_seconds"`    // 统计窗口（秒）
+	MinSamples      int     `json:"min_samples"`       // 窗口内样本数低于该值时不调整权重
+	MinWeightFactor float64 `json:"min_weight_factor"` // 有效权重相对静态权重的最低比例
+
+	BreakerEnabled             bool    `json:"breaker_enabled"`
+	BreakerErrorRate           float64 `json:"breaker_error_rate"`           // 窗口内错误率达到该值时熔断
+	BreakerMinRequests         int     `json:"breaker_min_requests"`         // 按错误率熔断所需的最少请求数
+	BreakerConsecutiveFailures int     `json:"breaker_consecutive_failures"` // 连续失败达到该次数时熔断
+	BreakerOpenSeconds         int     `json:"breaker_open_seconds"`         // 首次熔断时长，半开探测失败后翻倍
+	BreakerMaxOpenSeconds      int     `json:"breaker_max_open_seconds"`     // 熔断时长上限
+	HalfOpenProbeTimeout       int     `json:"half_open_probe_timeout"`      // 半开状态下单次探测的超时（秒），超时后允许新的探测
+}
+
+// 默认配置
+var adaptiveRoutingSetting = AdaptiveRoutingSetting{
+	Enabled:                    false,
+	WindowSeconds:              300,
+	MinSamples:                 10,
+	MinWeightFactor:            0.05,
+	BreakerEnabled:             true,
+	BreakerErrorRate:           0.5,
+	BreakerMinRequests:         10,
+	BreakerConsecutiveFailures: 5,
+	BreakerOpenSeconds:         30,
+	BreakerMaxOpenSeconds:      600,
+	HalfOpenProbeTimeout:       60,
+}
+
+func init() {
+	// 注册到全局配置管理器
+	config.GlobalConfig.Register("adaptive_routing_setting", &adaptiveRoutingSetting)
+}
+
+func GetAdaptiveRoutingSetting() *AdaptiveRoutingSetting {
+	return &adaptiveRoutingSetting
+}
@@ -0,0 +1,24 @@
+package operation_setting
+
+import "github.com/QuantumNous/new-api/setting/config"
+
+// BatchSetting Batch API (/v1/batches) 配置
+type BatchSetting struct {
+	// 本地执行批处理时单个批次的并发请求数
+	LocalConcurrency int `json:"local_concurrency"`
+	// 本地执行时输入文件允许的最大行数
+	MaxLocalRequests int `json:"max_local_requests"`
+}
+
+var batchSetting = BatchSetting{
+	LocalConcurrency: 4,
+	MaxLocalRequests: 50000,
+}
+
+func init() {
+	config.GlobalConfig.Register("batch_setting", &batchSetting)
+}
+
+func GetBatchSetting() *BatchSetting {
+	return &batchSetting
+}
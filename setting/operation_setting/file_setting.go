@@ -0,0 +1,24 @@
+package operation_setting
+
+import "github.com/QuantumNous/new-api/setting/config"
+
+// FileSetting Files API (/v1/files) 配置
+type FileSetting struct {
+	// 上传文件时用于选择渠道的模型名，请求可通过 ?model= 覆盖
+	UploadModel string `json:"upload_model"`
+	// 单个文件大小上限（MB），0 表示不限制（仍受请求体大小限制）
+	MaxFileSizeMB int `json:"max_file_size_mb"`
+}
+
+var fileSetting = FileSetting{
+	UploadModel:   "gpt-4o-mini",
+	MaxFileSizeMB: 512,
+}
+
+func init() {
+	config.GlobalConfig.Register("file_setting", &fileSetting)
+}
+
+func GetFileSetting() *FileSetting {
+	return &fileSetting
+}
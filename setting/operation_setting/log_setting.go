@@ -0,0 +1,73 @@
+package operation_setting
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/setting/config"
+)
+
+// LogSetting 运行时日志级别，ModuleLevels 为 JSON 对象，按模块覆盖全局级别，
+// 模块为 system、access 或请求的路由标签（relay、api、web 等），如 {"relay":"debug"}
+type LogSetting struct {
+	Level        string `json:"level"`
+	ModuleLevels string `json:"module_levels"`
+}
+
+// 默认配置
+var logSetting = LogSetting{
+	Level:        "info",
+	ModuleLevels: "",
+}
+
+func init() {
+	// 注册到全局配置管理器
+	config.GlobalConfig.Register("log_setting", &logSetting)
+}
+
+func GetLogSetting() *LogSetting {
+	return &logSetting
+}
+
+func parseLogModuleLevels(value string) (map[string]string, error) {
+	levels := make(map[string]string)
+	if strings.TrimSpace(value) == "" {
+		return levels, nil
+	}
+	if err := common.UnmarshalJsonStr(value, &levels); err != nil {
+		return nil, errors.New("模块日志级别必须是 JSON 对象")
+	}
+	for module, level := range levels {
+		if strings.TrimSpace(module) == "" {
+			return nil, errors.New("模块名不能为空")
+		}
+		if _, ok := common.ParseLogLevel(level); !ok {
+			return nil, errors.New("无效的日志级别: " + level)
+		}
+	}
+	return levels, nil
+}
+
+func ValidateLogLevel(value string) error {
+	if _, ok := common.ParseLogLevel(value); !ok {
+		return errors.New("无效的日志级别: " + value)
+	}
+	return nil
+}
+
+func ValidateLogModuleLevels(value string) error {
+	_, err := parseLogModuleLevels(value)
+	return err
+}
+
+// SyncLogLevels 将配置同步到 common 包，配置更新后调用
+func SyncLogLevels() {
+	modules, err := parseLogModuleLevels(logSetting.ModuleLevels)
+	if err == nil {
+		err = common.SetLogLevels(logSetting.Level, modules)
+	}
+	if err != nil {
+		common.SysError("failed to apply log setting: " + err.Error())
+	}
+}
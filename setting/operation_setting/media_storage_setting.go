@@ -0,0 +1,45 @@
+package operation_setting
+
+import "github.com/QuantumNous/new-api/setting/config"
+
+// 媒体存储后端
+const (
+	MediaStorageBackendLocal = "local"
+	MediaStorageBackendS3    = "s3"
+)
+
+// MediaStorageSetting 异步任务产物持久化配置，开启后任务成功时下载产物并改写结果地址。
+// 仅覆盖单产物的视频类任务（通用任务轮询）；Suno 的多段音频与 Midjourney 图片仍使用上游地址
+type MediaStorageSetting struct {
+	Enabled        bool   `json:"enabled"`
+	Backend        string `json:"backend"`          // local 或 s3
+	LocalPath      string `json:"local_path"`       // 本地存储目录
+	S3Endpoint     string `json:"s3_endpoint"`      // S3 兼容服务地址，如 https://s3.us-east-1.amazonaws.com 或 MinIO 地址
+	S3Region       string `json:"s3_region"`        // 签名使用的区域
+	S3Bucket       string `json:"s3_bucket"`        // 存储桶
+	S3AccessKeyId  string `json:"s3_access_key_id"` // 访问密钥 ID
+	S3AccessSecret string `json:"s3_access_secret"` // 访问密钥
+	S3PathStyle    bool   `json:"s3_path_style"`    // 使用路径风格地址（MinIO 等需要开启）
+	RetentionDays  int    `json:"retention_days"`   // 保留天数，到期后删除对象，签名地址同时失效；0 表示永久保留
+	MaxSizeMB      int    `json:"max_size_mb"`      // 单个产物大小上限，超出时保留上游地址
+}
+
+// 默认配置
+var mediaStorageSetting = MediaStorageSetting{
+	Enabled:       false,
+	Backend:       MediaStorageBackendLocal,
+	LocalPath:     "./data/media",
+	S3Region:      "us-east-1",
+	S3PathStyle:   true,
+	RetentionDays: 7,
+	MaxSizeMB:     512,
+}
+
+func init() {
+	// 注册到全局配置管理器
+	config.GlobalConfig.Register("media_storage_setting", &mediaStorageSetting)
+}
+
+func GetMediaStorageSetting() *MediaStorageSetting {
+	return &mediaStorageSetting
+}
@@ -0,0 +1,113 @@
+package operation_setting
+
+import (
+	"errors"
+	"strings"
+	"sync/atomic"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/setting/config"
+)
+
+// 审核命中后的处理方式
+const (
+	ModerationActionBlock  = "block"  // 拒绝请求
+	ModerationActionFlag   = "flag"   // 放行并记录
+	ModerationActionRedact = "redact" // 屏蔽命中的内容后继续
+)
+
+// ModerationPolicy 分组的审核策略
+type ModerationPolicy struct {
+	Action string `json:"action"`
+	// Thresholds 类别分数阈值，如 {"violence":0.8}；未配置阈值的类别以审核服务返回的 flagged 结果为准
+	Thresholds map[string]float64 `json:"thresholds,omitempty"`
+	// CheckOutput 同时审核模型输出，仅对 /v1/chat/completions 的非流式响应生效，其他接口与流式响应只审核输入
+	CheckOutput bool `json:"check_output,omitempty"`
+}
+
+// ModerationSetting 内容审核配置：调用指定渠道的 /v1/moderations（或兼容的分类接口）审核请求内容。
+// Policies 为 JSON 对象，按分组配置审核策略，"*" 为其他分组的默认策略，未匹配的分组不审核
+type ModerationSetting struct {
+	Enabled        bool   `json:"enabled"`
+	ChannelId      int    `json:"channel_id"`      // 审核服务使用的渠道
+	Model          string `json:"model"`           // 审核模型
+	TimeoutSeconds int    `json:"timeout_seconds"` // 审核请求超时
+	FailOpen       bool   `json:"fail_open"`       // 审核服务异常时放行，否则拒绝请求
+	Policies       string `json:"policies"`
+}
+
+// 默认配置
+var moderationSetting = ModerationSetting{
+	Model:          "omni-moderation-latest",
+	TimeoutSeconds: 10,
+	FailOpen:       true,
+	Policies:       `{"*":{"action":"block"}}`,
+}
+
+var moderationPolicies atomic.Pointer[map[string]*ModerationPolicy]
+
+func init() {
+	// 注册到全局配置管理器
+	config.GlobalConfig.Register("moderation_setting", &moderationSetting)
+	SyncModerationPolicies()
+}
+
+func GetModerationSetting() *ModerationSetting {
+	return &moderationSetting
+}
+
+func parseModerationPolicies(value string) (map[string]*ModerationPolicy, error) {
+	policies := make(map[string]*ModerationPolicy)
+	if strings.TrimSpace(value) == "" {
+		return policies, nil
+	}
+	if err := common.UnmarshalJsonStr(value, &policies); err != nil {
+		return nil, errors.New("审核策略必须是 JSON 对象")
+	}
+	for group, policy := range policies {
+		if policy == nil {
+			return nil, errors.New("分组 " + group + " 的审核策略不能为空")
+		}
+		switch policy.Action {
+		case ModerationActionBlock, ModerationActionFlag, ModerationActionRedact:
+		default:
+			return nil, errors.New("无效的审核动作: " + policy.Action)
+		}
+		for category, threshold := range policy.Thresholds {
+			if threshold <= 0 || threshold > 1 {
+				return nil, errors.New("类别 " + category + " 的阈值必须在 (0, 1] 之间")
+			}
+		}
+	}
+	return policies, nil
+}
+
+func ValidateModerationPolicies(value string) error {
+	_, err := parseModerationPolicies(value)
+	return err
+}
+
+// SyncModerationPolicies 解析审核策略，配置更新后调用
+func SyncModerationPolicies() {
+	policies, err := parseModerationPolicies(moderationSetting.Policies)
+	if err != nil {
+		common.SysError("failed to apply moderation setting: " + err.Error())
+		return
+	}
+	moderationPolicies.Store(&policies)
+}
+
+// GetModerationPolicy 返回分组生效的审核策略，未启用审核或分组未配置时返回 nil
+func GetModerationPolicy(group string) *ModerationPolicy {
+	if !moderationSetting.Enabled || moderationSetting.ChannelId == 0 {
+		return nil
+	}
+	policies := moderationPolicies.Load()
+	if policies == nil {
+		return nil
+	}
+	if policy, ok := (*policies)[group]; ok {
+		return policy
+	}
+	return (*policies)["*"]
+}
@@ -0,0 +1,39 @@
+package operation_setting
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseModerationPolicies(t *testing.T) {
+	policies, err := parseModerationPolicies(`{"vip":{"action":"flag","check_output":true},"*":{"action":"block","thresholds":{"violence":0.8}}}`)
+	require.NoError(t, err)
+	require.Equal(t, ModerationActionFlag, policies["vip"].Action)
+	require.True(t, policies["vip"].CheckOutput)
+	require.Equal(t, 0.8, policies["*"].Thresholds["violence"])
+
+	require.Error(t, ValidateModerationPolicies(`{"*":{"action":"drop"}}`))
+	require.Error(t, ValidateModerationPolicies(`{"*":{"action":"block","thresholds":{"violence":1.5}}}`))
+	require.Error(t, ValidateModerationPolicies(`[]`))
+}
+
+func TestGetModerationPolicy(t *testing.T) {
+	old := moderationSetting
+	t.Cleanup(func() {
+		moderationSetting = old
+		SyncModerationPolicies()
+	})
+	moderationSetting.Policies = `{"vip":{"action":"flag"},"*":{"action":"block"}}`
+	SyncModerationPolicies()
+	require.Nil(t, GetModerationPolicy("vip"))
+
+	moderationSetting.Enabled = true
+	moderationSetting.ChannelId = 1
+	require.Equal(t, ModerationActionFlag, GetModerationPolicy("vip").Action)
+	require.Equal(t, ModerationActionBlock, GetModerationPolicy("default").Action)
+
+	moderationSetting.Policies = `{"vip":{"action":"flag"}}`
+	SyncModerationPolicies()
+	require.Nil(t, GetModerationPolicy("default"))
+}
@@ -0,0 +1,26 @@
+package operation_setting
+
+import "github.com/QuantumNous/new-api/setting/config"
+
+// PayloadCaptureSetting 请求/响应抓包配置，抓包本身通过管理端规则按令牌、用户或渠道开启
+type PayloadCaptureSetting struct {
+	RetentionHours  int `json:"retention_hours"`    // 抓包记录保留时长，超时后自动清理
+	MaxBodyKB       int `json:"max_body_kb"`        // 单个请求 / 响应体的最大记录大小，超出部分截断
+	MaxRuleTTLHours int `json:"max_rule_ttl_hours"` // 规则的最长有效期
+}
+
+// 默认配置
+var payloadCaptureSetting = PayloadCaptureSetting{
+	RetentionHours:  72,
+	MaxBodyKB:       256,
+	MaxRuleTTLHours: 168,
+}
+
+func init() {
+	// 注册到全局配置管理器
+	config.GlobalConfig.Register("payload_capture_setting", &payloadCaptureSetting)
+}
+
+func GetPayloadCaptureSetting() *PayloadCaptureSetting {
+	return &payloadCaptureSetting
+}
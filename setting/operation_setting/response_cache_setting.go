@@ -0,0 +1,63 @@
+package operation_setting
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/QuantumNous/new-api/setting/config"
+)
+
+// ResponseCacheSetting 响应缓存配置：相同的确定性请求（对话请求需 temperature=0）直接返回缓存结果，按缓存倍率计费
+type ResponseCacheSetting struct {
+	Enabled          bool     `json:"enabled"`
+	TTLSeconds       int      `json:"ttl_seconds"`        // 缓存有效期
+	CacheRatio       float64  `json:"cache_ratio"`        // 命中缓存时的计费倍率，0 表示免费
+	ChatEnabled      bool     `json:"chat_enabled"`       // 缓存对话补全请求
+	EmbeddingEnabled bool     `json:"embedding_enabled"`  // 缓存向量请求
+	Models           []string `json:"models"`             // 启用缓存的模型，支持以 * 结尾的前缀匹配，为空表示全部模型
+	Groups           []string `json:"groups"`             // 启用缓存的分组，为空表示全部分组
+	ShareAcrossUsers bool     `json:"share_across_users"` // 不同用户之间共享缓存，默认仅对同一用户生效
+	MaxEntryKB       int      `json:"max_entry_kb"`       // 单条缓存的最大大小，超出时不缓存
+}
+
+// 默认配置
+var responseCacheSetting = ResponseCacheSetting{
+	TTLSeconds:       3600,
+	CacheRatio:       0.1,
+	ChatEnabled:      true,
+	EmbeddingEnabled: true,
+	MaxEntryKB:       512,
+}
+
+func init() {
+	// 注册到全局配置管理器
+	config.GlobalConfig.Register("response_cache_setting", &responseCacheSetting)
+}
+
+func GetResponseCacheSetting() *ResponseCacheSetting {
+	return &responseCacheSetting
+}
+
+// ResponseCacheEnabledFor 判断模型与分组是否启用响应缓存
+func (s *ResponseCacheSetting) ResponseCacheEnabledFor(modelName string, group string) bool {
+	if !s.Enabled || s.TTLSeconds <= 0 {
+		return false
+	}
+	if len(s.Groups) > 0 && !slices.Contains(s.Groups, group) {
+		return false
+	}
+	if len(s.Models) == 0 {
+		return true
+	}
+	for _, pattern := range s.Models {
+		pattern = strings.TrimSpace(pattern)
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+			if strings.HasPrefix(modelName, prefix) {
+				return true
+			}
+		} else if pattern == modelName {
+			return true
+		}
+	}
+	return false
+}
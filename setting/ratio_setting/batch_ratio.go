@@ -0,0 +1,28 @@
+package ratio_setting
+
+import (
+	"github.com/QuantumNous/new-api/types"
+)
+
+// DefaultBatchRatio 未单独配置的模型在 Batch API 中的计费倍率（与 OpenAI 的 50% 折扣一致）
+const DefaultBatchRatio = 0.5
+
+var batchRatioMap = types.NewRWMap[string, float64]()
+
+// BatchRatio2JSONString converts the batch ratio map to a JSON string
+func BatchRatio2JSONString() string {
+	return batchRatioMap.MarshalJSONString()
+}
+
+// UpdateBatchRatioByJSONString updates the batch ratio map from a JSON string
+func UpdateBatchRatioByJSONString(jsonStr string) error {
+	return types.LoadFromJsonStringWithCallback(batchRatioMap, jsonStr, nil)
+}
+
+// GetBatchRatio 返回模型在 Batch API 中的计费倍率，未配置时返回 DefaultBatchRatio
+func GetBatchRatio(name string) float64 {
+	if ratio, ok := batchRatioMap.Get(FormatMatchingModelName(name)); ok {
+		return ratio
+	}
+	return DefaultBatchRatio
+}
@@ -0,0 +1,56 @@
+package ratio_setting
+
+import (
+	"github.com/QuantumNous/new-api/types"
+)
+
+// DefaultFineTuningRatio 未单独配置的模型训练 token 相对模型倍率的倍数
+const DefaultFineTuningRatio = 1.0
+
+// 训练价格相对输入价格的倍数（参考 OpenAI 官方定价）
+var defaultFineTuningRatio = map[string]float64{
+	"gpt-3.5-turbo-0125":      16,
+	"gpt-4o-mini-2024-07-18":  20,
+	"gpt-4o-2024-08-06":       10,
+	"gpt-4.1-2025-04-14":      12.5,
+	"gpt-4.1-mini-2025-04-14": 12.5,
+	"gpt-4.1-nano-2025-04-14": 15,
+}
+
+var fineTuningRatioMap = types.NewRWMap[string, float64]()
+
+func init() {
+	fineTuningRatioMap.AddAll(defaultFineTuningRatio)
+}
+
+// FineTuningRatio2JSONString converts the fine-tuning ratio map to a JSON string
+func FineTuningRatio2JSONString() string {
+	return fineTuningRatioMap.MarshalJSONString()
+}
+
+// UpdateFineTuningRatioByJSONString updates the fine-tuning ratio map from a JSON string
+func UpdateFineTuningRatioByJSONString(jsonStr string) error {
+	return types.LoadFromJsonStringWithCallback(fineTuningRatioMap, jsonStr, nil)
+}
+
+// GetFineTuningRatio 返回模型训练 token 的计费倍数（在模型倍率基础上），未配置时返回 DefaultFineTuningRatio
+func GetFineTuningRatio(name string) float64 {
+	if ratio, ok := fineTuningRatioMap.Get(FormatMatchingModelName(name)); ok {
+		return ratio
+	}
+	return DefaultFineTuningRatio
+}
+
+// fineTunedModelBase 本站微调任务生成的模型到基础模型的映射
+var fineTunedModelBase = types.NewRWMap[string, string]()
+
+// SetFineTunedModelBase 登记一个微调模型的基础模型
+func SetFineTunedModelBase(name string, base string) {
+	fineTunedModelBase.Set(name, base)
+}
+
+// SetFineTunedModelBases 整体替换微调模型映射
+func SetFineTunedModelBases(bases map[string]string) {
+	fineTunedModelBase.Clear()
+	fineTunedModelBase.AddAll(bases)
+}
@@ -714,6 +714,10 @@ func FormatMatchingModelName(name string) string {
 	if strings.HasPrefix(name, "gpt-4o-gizmo") {
 		name = "gpt-4o-gizmo-*"
 	}
+	// 仅本站微调任务登记过的模型按基础模型定价
+	if base, ok := fineTunedModelBase.Get(name); ok && base != "" {
+		name = base
+	}
 	return name
 }
 
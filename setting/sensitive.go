@@ -1,6 +1,13 @@
 package setting
 
-import "strings"
+import (
+	"strings"
+	"sync/atomic"
+
+	"github.com/QuantumNous/new-api/common"
+
+	goahocorasick "github.com/anknown/ahocorasick"
+)
 
 var CheckSensitiveEnabled = true
 var CheckSensitiveOnPromptEnabled = true
@@ -23,15 +30,37 @@ func SensitiveWordsToString() string {
 	return strings.Join(SensitiveWords, "\n")
 }
 
+// sensitiveWordMatcher 敏感词的 AC 自动机，只在敏感词变更时重建，检测时直接读取
+var sensitiveWordMatcher atomic.Pointer[goahocorasick.Machine]
+
+func init() {
+	rebuildSensitiveWordMatcher(SensitiveWords)
+}
+
 func SensitiveWordsFromString(s string) {
-	SensitiveWords = []string{}
+	words := []string{}
 	sw := strings.Split(s, "\n")
 	for _, w := range sw {
 		w = strings.TrimSpace(w)
 		if w != "" {
-			SensitiveWords = append(SensitiveWords, w)
+			words = append(words, w)
 		}
 	}
+	rebuildSensitiveWordMatcher(words)
+	SensitiveWords = words
+}
+
+func rebuildSensitiveWordMatcher(words []string) {
+	if len(words) == 0 {
+		sensitiveWordMatcher.Store(nil)
+		return
+	}
+	sensitiveWordMatcher.Store(common.NewAcMachine(words))
+}
+
+// GetSensitiveWordMatcher 返回当前敏感词的 AC 自动机，没有敏感词时返回 nil
+func GetSensitiveWordMatcher() *goahocorasick.Machine {
+	return sensitiveWordMatcher.Load()
 }
 
 func ShouldCheckPromptSensitive() bool {
@@ -40,6 +40,8 @@ type ErrorCode string
 const (
 	ErrorCodeInvalidRequest         ErrorCode = "invalid_request"
 	ErrorCodeSensitiveWordsDetected ErrorCode = "sensitive_words_detected"
+	ErrorCodeModerationBlocked      ErrorCode = "moderation_blocked"
+	ErrorCodeModerationFailed       ErrorCode = "moderation_failed"
 	ErrorCodeViolationFeeGrokCSAM   ErrorCode = "violation_fee.grok.csam"
 
 	// new api error